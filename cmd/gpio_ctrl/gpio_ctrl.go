@@ -20,7 +20,7 @@ import (
 	"github.com/olekukonko/tablewriter"
 
 	// Modules
-	_ "github.com/djthorpe/gopi-hw/sys/gpio"
+	gpio "github.com/djthorpe/gopi-hw/sys/gpio"
 	_ "github.com/djthorpe/gopi-hw/sys/hw"
 	_ "github.com/djthorpe/gopi/sys/logger"
 )
@@ -75,6 +75,29 @@ func edgePins(app *gopi.AppInstance) ([]gopi.GPIOPin, error) {
 	}
 }
 
+// physicalPin returns the name, mode, value and number of a physical pin,
+// where the mode is the alternate function name when known
+func physicalPin(app *gopi.AppInstance, pin uint) []string {
+	var name, mode, value string
+	if header, ok := app.GPIO.(gpio.GPIOHeader); ok {
+		name = header.PhysicalPinName(pin)
+	}
+	if logical := app.GPIO.PhysicalPin(pin); logical != gopi.GPIO_PIN_NONE {
+		pin_mode := app.GPIO.GetPinMode(logical)
+		if name == "" {
+			name = fmt.Sprint(logical)
+		}
+		mode = fmt.Sprint(pin_mode)
+		value = fmt.Sprint(app.GPIO.ReadPin(logical))
+		if alt, ok := app.GPIO.(gpio.GPIOAltFunction); ok {
+			if function := alt.AltFunction(logical, pin_mode); function != "" {
+				mode = function
+			}
+		}
+	}
+	return []string{name, mode, value, fmt.Sprint(pin)}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 func eventLoop(app *gopi.AppInstance, done <-chan struct{}) error {
@@ -125,23 +148,21 @@ func mainLoop(app *gopi.AppInstance, done chan<- struct{}) error {
 		}
 	}
 
-	// Output current state of pins
+	// Output current state of pins as a header diagram, with odd
+	// pins on the left and even pins on the right
 	if app.GPIO.NumberOfPhysicalPins() > 0 {
 		table := tablewriter.NewWriter(os.Stdout)
 
-		table.SetHeader([]string{"Physical", "Logical", "Direction", "Value"})
+		table.SetHeader([]string{"Name", "Mode", "Value", "Pin", "Pin", "Value", "Mode", "Name"})
 
 		// Physical pins start at index 1
-		for pin := uint(1); pin <= app.GPIO.NumberOfPhysicalPins(); pin++ {
-			var l, d, v string
-			if logical := app.GPIO.PhysicalPin(pin); logical != gopi.GPIO_PIN_NONE {
-				l = fmt.Sprint(logical)
-				d = fmt.Sprint(app.GPIO.GetPinMode(logical))
-				v = fmt.Sprint(app.GPIO.ReadPin(logical))
+		for pin := uint(1); pin <= app.GPIO.NumberOfPhysicalPins(); pin += 2 {
+			row := physicalPin(app, pin)
+			if pin+1 <= app.GPIO.NumberOfPhysicalPins() {
+				right := physicalPin(app, pin+1)
+				row = append(row, right[3], right[2], right[1], right[0])
 			}
-			table.Append([]string{
-				fmt.Sprintf("%v", pin), l, d, v,
-			})
+			table.Append(row)
 		}
 
 		table.Render()
@@ -0,0 +1,284 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+// Dumps, reads and writes registers of a slave on the I2C bus
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi-hw/cmd/internal/output"
+
+	// Modules
+	_ "github.com/djthorpe/gopi-hw/sys/i2c"
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type Mode uint
+
+type Options struct {
+	mode   Mode
+	big    bool
+	length uint8
+	verify bool
+	format output.Format
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	MODE_BYTE Mode = iota
+	MODE_WORD
+	MODE_BLOCK
+)
+
+const (
+	// Number of registers in a dump, and bytes read in each block
+	DUMP_SIZE  = 256
+	BLOCK_SIZE = 32
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPTIONS
+
+func getOptions(app *gopi.AppInstance) (*Options, error) {
+	options := &Options{length: BLOCK_SIZE}
+	mode, _ := app.AppFlags.GetString("mode")
+	switch strings.ToLower(mode) {
+	case "byte", "b":
+		options.mode = MODE_BYTE
+	case "word", "w":
+		options.mode = MODE_WORD
+	case "block", "i":
+		options.mode = MODE_BLOCK
+	default:
+		return nil, fmt.Errorf("Invalid -mode value: %v", mode)
+	}
+	endian, _ := app.AppFlags.GetString("endian")
+	switch strings.ToLower(endian) {
+	case "little", "le":
+		options.big = false
+	case "big", "be":
+		options.big = true
+	default:
+		return nil, fmt.Errorf("Invalid -endian value: %v", endian)
+	}
+	if length, _ := app.AppFlags.GetUint("length"); length == 0 || length > BLOCK_SIZE {
+		return nil, fmt.Errorf("Invalid -length value: %v", length)
+	} else {
+		options.length = uint8(length)
+	}
+	if format, _ := app.AppFlags.GetString("format"); format != "" {
+		if value, err := output.ParseFormat(format); err != nil {
+			return nil, err
+		} else {
+			options.format = value
+		}
+	}
+	options.verify, _ = app.AppFlags.GetBool("verify")
+	return options, nil
+}
+
+func parseUint(value string, bits int) (uint64, error) {
+	if v, err := strconv.ParseUint(value, 0, bits); err != nil {
+		return 0, fmt.Errorf("Invalid value: %v", value)
+	} else {
+		return v, nil
+	}
+}
+
+// swap converts between a word on the bus, which is sent low byte
+// first, and the word in the requested byte order
+func swap(value uint16, big bool) uint16 {
+	if big {
+		return value<<8 | value>>8
+	} else {
+		return value
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// COMMANDS
+
+// dump reads all 256 registers and writes them as a table, with
+// registers which could not be read marked as XX
+func dump(bus gopi.I2C, options *Options) error {
+	data := make([]byte, DUMP_SIZE)
+	valid := make([]bool, DUMP_SIZE)
+	switch options.mode {
+	case MODE_BYTE:
+		for reg := 0; reg < DUMP_SIZE; reg++ {
+			if value, err := bus.ReadUint8(uint8(reg)); err == nil {
+				data[reg], valid[reg] = value, true
+			}
+		}
+	case MODE_WORD:
+		for reg := 0; reg < DUMP_SIZE; reg += 2 {
+			// Bytes are shown in the order they are sent on the bus
+			if value, err := bus.ReadUint16(uint8(reg)); err == nil {
+				data[reg], data[reg+1] = byte(value), byte(value>>8)
+				valid[reg], valid[reg+1] = true, true
+			}
+		}
+	case MODE_BLOCK:
+		for reg := 0; reg < DUMP_SIZE; reg += BLOCK_SIZE {
+			if block, err := bus.ReadBlock(uint8(reg), BLOCK_SIZE); err == nil {
+				for i := range block {
+					data[reg+i], valid[reg+i] = block[i], true
+				}
+			}
+		}
+	}
+	return output.Table(os.Stdout, 0, data, valid)
+}
+
+func get(bus gopi.I2C, reg uint8, options *Options) error {
+	switch options.mode {
+	case MODE_BYTE:
+		if value, err := bus.ReadUint8(reg); err != nil {
+			return err
+		} else {
+			fmt.Println(output.Value(uint64(value), 8, options.format))
+		}
+	case MODE_WORD:
+		if value, err := bus.ReadUint16(reg); err != nil {
+			return err
+		} else {
+			fmt.Println(output.Value(uint64(swap(value, options.big)), 16, options.format))
+		}
+	case MODE_BLOCK:
+		if block, err := bus.ReadBlock(reg, options.length); err != nil {
+			return err
+		} else {
+			fmt.Println(output.Bytes(block, options.format))
+		}
+	}
+	return nil
+}
+
+// set writes a byte or word to a register and then reads it back
+// to verify the write, unless verification is disabled
+func set(bus gopi.I2C, reg uint8, value string, options *Options) error {
+	var read uint64
+	var written uint64
+	var bits uint
+	switch options.mode {
+	case MODE_BYTE:
+		if v, err := parseUint(value, 8); err != nil {
+			return err
+		} else if err := bus.WriteUint8(reg, uint8(v)); err != nil {
+			return err
+		} else if options.verify {
+			if r, err := bus.ReadUint8(reg); err != nil {
+				return err
+			} else {
+				written, read, bits = v, uint64(r), 8
+			}
+		}
+	case MODE_WORD:
+		if v, err := parseUint(value, 16); err != nil {
+			return err
+		} else if err := bus.WriteUint16(reg, swap(uint16(v), options.big)); err != nil {
+			return err
+		} else if options.verify {
+			if r, err := bus.ReadUint16(reg); err != nil {
+				return err
+			} else {
+				written, read, bits = v, uint64(swap(r, options.big)), 16
+			}
+		}
+	default:
+		return fmt.Errorf("Cannot set registers in block mode")
+	}
+	if options.verify && read != written {
+		return fmt.Errorf("Verification failed: wrote %v, read %v", output.Value(written, bits, options.format), output.Value(read, bits, options.format))
+	}
+	return nil
+}
+
+func run(bus gopi.I2C, args []string, options *Options) error {
+	switch {
+	case args[0] == "dump" && len(args) == 1:
+		return dump(bus, options)
+	case args[0] == "get" && len(args) == 2:
+		if reg, err := parseUint(args[1], 8); err != nil {
+			return err
+		} else {
+			return get(bus, uint8(reg), options)
+		}
+	case args[0] == "set" && len(args) == 3:
+		if reg, err := parseUint(args[1], 8); err != nil {
+			return err
+		} else {
+			return set(bus, uint8(reg), args[2], options)
+		}
+	default:
+		return fmt.Errorf("Syntax: i2c_ctrl [dump | get <reg> | set <reg> <value>]")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func mainLoop(app *gopi.AppInstance, done chan<- struct{}) error {
+
+	if app.I2C == nil {
+		return app.Logger.Error("Missing I2C module instance")
+	}
+
+	options, err := getOptions(app)
+	if err != nil {
+		return err
+	}
+
+	// Set the slave address
+	if slave, exists := app.AppFlags.GetUint("slave"); exists == false || slave > 0x7F {
+		return fmt.Errorf("Missing or invalid -slave flag")
+	} else if err := app.I2C.SetSlave(uint8(slave)); err != nil {
+		return err
+	}
+
+	// Run the command
+	args := app.AppFlags.Args()
+	if len(args) == 0 {
+		args = []string{"dump"}
+	}
+	if err := run(app.I2C, args, options); err != nil {
+		return err
+	}
+
+	// Finished
+	done <- gopi.DONE
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func main() {
+	// Create the configuration, load the i2c instance
+	config := gopi.NewAppConfig("i2c")
+
+	// Flags
+	config.AppFlags.FlagUint("slave", 0, "Slave address")
+	config.AppFlags.FlagString("mode", "byte", "Register access (byte, word, block)")
+	config.AppFlags.FlagString("endian", "little", "Byte order for word access (little, big)")
+	config.AppFlags.FlagUint("length", BLOCK_SIZE, "Number of bytes to get in block mode")
+	config.AppFlags.FlagBool("verify", true, "Read back registers after set")
+	config.AppFlags.FlagString("format", "hex", "Output format (hex, dec, bin)")
+
+	// Run the command line tool
+	os.Exit(gopi.CommandLineTool(config, mainLoop))
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	i2c "github.com/djthorpe/gopi-hw/sys/i2c"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// MOCK BUS
+
+// openMock returns a simulated bus with a slave at 0x20, which is the
+// current slave
+func openMock(t *testing.T, slave i2c.MockSlave) (i2c.I2CMockInterface, func()) {
+	t.Helper()
+	app, err := gopi.NewAppInstance(gopi.NewAppConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	slave.Address = 0x20
+	driver, err := gopi.Open(i2c.Mock{Bus: 1, Slaves: []i2c.MockSlave{slave}}, app.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := driver.(i2c.I2CMockInterface)
+	if err := bus.SetSlave(0x20); err != nil {
+		t.Fatal(err)
+	}
+	return bus, func() { driver.Close() }
+}
+
+// stdout returns what a command writes to standard output
+func stdout(t *testing.T, command func() error) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	saved := os.Stdout
+	os.Stdout = w
+	err = command()
+	os.Stdout = saved
+	w.Close()
+	if err != nil {
+		t.Error(err)
+	}
+	if data, err := ioutil.ReadAll(r); err != nil {
+		t.Fatal(err)
+		return ""
+	} else {
+		return strings.TrimSpace(string(data))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestSet_000(t *testing.T) {
+	bus, close := openMock(t, i2c.MockSlave{Responses: map[uint8][][]byte{0x11: {{0x00}}}})
+	defer close()
+
+	// Set a byte and read it back
+	if err := set(bus, 0x10, "0xAB", &Options{mode: MODE_BYTE, verify: true}); err != nil {
+		t.Error(err)
+	} else if registers, _ := bus.Registers(0x20); registers[0x10] != 0xAB {
+		t.Errorf("Unexpected register value %02X", registers[0x10])
+	} else if tx := bus.Transactions(); len(tx) != 2 || tx[0].Op != i2c.I2C_MOCK_WRITE || tx[1].Op != i2c.I2C_MOCK_READ {
+		t.Error("Unexpected transactions", tx)
+	}
+
+	// Verification fails when a different value is read back, and the
+	// register is not read when verification is disabled
+	bus.ClearTransactions()
+	if err := set(bus, 0x11, "0xCD", &Options{mode: MODE_BYTE, verify: true}); err == nil || strings.HasPrefix(err.Error(), "Verification failed") == false {
+		t.Error("Expected verification error, got", err)
+	} else if err := set(bus, 0x12, "0xEF", &Options{mode: MODE_BYTE}); err != nil {
+		t.Error(err)
+	} else if tx := bus.Transactions(); len(tx) != 3 || tx[2].Op != i2c.I2C_MOCK_WRITE {
+		t.Error("Unexpected transactions", tx)
+	}
+
+	// Values which do not fit and block mode
+	if err := set(bus, 0x10, "0x100", &Options{mode: MODE_BYTE}); err == nil {
+		t.Error("Expected error for invalid byte")
+	} else if err := set(bus, 0x10, "0x00", &Options{mode: MODE_BLOCK}); err == nil {
+		t.Error("Expected error setting in block mode")
+	}
+}
+
+func TestWord_000(t *testing.T) {
+	bus, close := openMock(t, i2c.MockSlave{})
+	defer close()
+
+	// Words are sent low byte first in little endian order, and high
+	// byte first in big endian order
+	for _, test := range []struct {
+		big       bool
+		reg       uint8
+		registers [2]byte
+		word      string
+	}{
+		{false, 0x20, [2]byte{0x34, 0x12}, "0x1234"},
+		{true, 0x30, [2]byte{0x12, 0x34}, "0x1234"},
+	} {
+		options := &Options{mode: MODE_WORD, big: test.big, verify: true}
+		if err := set(bus, test.reg, test.word, options); err != nil {
+			t.Error(err)
+		} else if registers, _ := bus.Registers(0x20); registers[test.reg] != test.registers[0] || registers[test.reg+1] != test.registers[1] {
+			t.Errorf("big=%v: Unexpected registers %02X %02X", test.big, registers[test.reg], registers[test.reg+1])
+		} else if value := stdout(t, func() error { return get(bus, test.reg, options) }); value != test.word {
+			t.Errorf("big=%v: Unexpected value %v", test.big, value)
+		}
+	}
+
+	// Reading registers written in the other byte order
+	if value := stdout(t, func() error { return get(bus, 0x20, &Options{mode: MODE_WORD, big: true}) }); value != "0x3412" {
+		t.Error("Unexpected value", value)
+	}
+}
+
+func TestRun_000(t *testing.T) {
+	bus, close := openMock(t, i2c.MockSlave{Registers: []byte{0x00, 0x41, 0x42}})
+	defer close()
+
+	// Dump reads every register
+	if table := stdout(t, func() error { return run(bus, []string{"dump"}, &Options{mode: MODE_BYTE}) }); strings.Contains(table, "41 42") == false {
+		t.Error("Unexpected dump", table)
+	} else if tx := bus.Transactions(); len(tx) != DUMP_SIZE {
+		t.Error("Unexpected number of transactions", len(tx))
+	}
+
+	// Get a block of bytes
+	if value := stdout(t, func() error { return run(bus, []string{"get", "1"}, &Options{mode: MODE_BLOCK, length: 2}) }); value != "0x41 0x42" {
+		t.Error("Unexpected block", value)
+	}
+
+	// Syntax errors
+	for _, args := range [][]string{{"get"}, {"set", "0x10"}, {"get", "0x100"}, {"put", "0x10", "0x00"}} {
+		if err := run(bus, args, &Options{mode: MODE_BYTE}); err == nil {
+			t.Error("Expected error for", args)
+		}
+	}
+}
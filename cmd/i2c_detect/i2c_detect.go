@@ -10,42 +10,145 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	// Frameworks
 	"github.com/djthorpe/gopi"
 	"github.com/olekukonko/tablewriter"
 
 	// Modules
-	_ "github.com/djthorpe/gopi-hw/sys/i2c"
+	i2c "github.com/djthorpe/gopi-hw/sys/i2c"
 	_ "github.com/djthorpe/gopi/sys/logger"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
+// TYPES
 
-func mainLoop(app *gopi.AppInstance, done chan<- struct{}) error {
+type Slave struct {
+	Address uint8  `json:"address"`
+	Driver  string `json:"driver,omitempty"`
+}
+
+type Bus struct {
+	Bus    uint    `json:"bus"`
+	Slaves []Slave `json:"slaves"`
+}
+
+type Scan struct {
+	mode        i2c.I2CDetectMode
+	first, last uint8
+	reserved    bool
+}
+
+////////////////////////////////////////////////////////////////////////////////
 
-	if app.I2C == nil {
-		return app.Logger.Error("Missing I2C module instance")
+func parseMode(value string) (i2c.I2CDetectMode, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "auto":
+		return i2c.I2C_DETECT_AUTO, nil
+	case "quick":
+		return i2c.I2C_DETECT_QUICK, nil
+	case "read":
+		return i2c.I2C_DETECT_READ, nil
+	default:
+		return i2c.I2C_DETECT_AUTO, fmt.Errorf("Invalid -mode value: %v", value)
 	}
+}
 
-	// Output detected I2C addresses
-	table := tablewriter.NewWriter(os.Stdout)
+// parseRange parses a first and last address separated by a hyphen
+func parseRange(value string) (uint8, uint8, error) {
+	if parts := strings.Split(value, "-"); len(parts) != 2 {
+		return 0, 0, fmt.Errorf("Invalid -range value: %v", value)
+	} else if first, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 0, 7); err != nil {
+		return 0, 0, fmt.Errorf("Invalid -range value: %v", value)
+	} else if last, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 0, 7); err != nil || last < first {
+		return 0, 0, fmt.Errorf("Invalid -range value: %v", value)
+	} else {
+		return uint8(first), uint8(last), nil
+	}
+}
+
+func getScan(app *gopi.AppInstance) (*Scan, error) {
+	scan := new(Scan)
+	if mode, _ := app.AppFlags.GetString("mode"); mode != "" {
+		if value, err := parseMode(mode); err != nil {
+			return nil, err
+		} else {
+			scan.mode = value
+		}
+	}
+	if value, _ := app.AppFlags.GetString("range"); value == "" {
+		scan.first, scan.last = 0x00, 0x7F
+	} else if first, last, err := parseRange(value); err != nil {
+		return nil, err
+	} else {
+		scan.first, scan.last = first, last
+	}
+	scan.reserved, _ = app.AppFlags.GetBool("reserved")
+	return scan, nil
+}
+
+// detect probes the addresses on a bus, returning the detected slaves
+// and the probed addresses. Addresses bound to a kernel driver are not
+// probed
+func detect(driver gopi.I2C, bus uint, scan *Scan) (*Bus, map[uint8]bool, error) {
+	bound, err := i2c.I2CBoundSlaves(bus)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := &Bus{Bus: bus, Slaves: make([]Slave, 0)}
+	probed := make(map[uint8]bool)
+	for slave := uint(scan.first); slave <= uint(scan.last); slave++ {
+		if i2c.I2CReservedSlave(uint8(slave)) && scan.reserved == false {
+			continue
+		}
+		probed[uint8(slave)] = true
+		if name, exists := bound[uint8(slave)]; exists {
+			result.Slaves = append(result.Slaves, Slave{uint8(slave), name})
+			continue
+		}
+		var detected bool
+		if driver, ok := driver.(i2c.I2CInterface); ok {
+			detected, err = driver.DetectSlaveMode(uint8(slave), scan.mode)
+		} else {
+			detected, err = driver.DetectSlave(uint8(slave))
+		}
+		if err != nil {
+			return nil, nil, err
+		} else if detected {
+			result.Slaves = append(result.Slaves, Slave{Address: uint8(slave)})
+		}
+	}
+	return result, probed, nil
+}
 
+func renderTable(bus *Bus, probed map[uint8]bool) {
+	fmt.Printf("Bus %v\n", bus.Bus)
+	table := tablewriter.NewWriter(os.Stdout)
 	table.SetHeader([]string{"", "-0", "-1", "-2", "-3", "-4", "-5", "-6", "-7", "-8", "-9", "-A", "-B", "-C", "-D", "-E", "-F"})
-	row := make([]string, 0)
 
+	slaves := make(map[uint8]Slave, len(bus.Slaves))
+	for _, slave := range bus.Slaves {
+		slaves[slave.Address] = slave
+	}
+
+	row := make([]string, 0)
 	for slave := uint8(0); slave < 0x80; slave++ {
 		if len(row) == 0 {
 			row = append(row, fmt.Sprintf("0x%02X", slave&0xF0))
 		}
-		if detected, err := app.I2C.DetectSlave(slave); err != nil {
-			return err
-		} else if detected {
-			row = append(row, fmt.Sprintf("%02X", slave))
-		} else {
+		if probed[slave] == false {
+			row = append(row, "")
+		} else if value, exists := slaves[slave]; exists == false {
 			row = append(row, "--")
+		} else if value.Driver != "" {
+			row = append(row, "UU")
+		} else {
+			row = append(row, fmt.Sprintf("%02X", slave))
 		}
 		if len(row) >= 17 {
 			table.Append(row)
@@ -53,6 +156,65 @@ func mainLoop(app *gopi.AppInstance, done chan<- struct{}) error {
 		}
 	}
 	table.Render()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func mainLoop(app *gopi.AppInstance, done chan<- struct{}) error {
+
+	scan, err := getScan(app)
+	if err != nil {
+		return err
+	}
+
+	// Scan either every bus, or the bus for the I2C module instance
+	results := make([]*Bus, 0)
+	probed := make([]map[uint8]bool, 0)
+	if all, _ := app.AppFlags.GetBool("all"); all {
+		buses, err := i2c.I2CBuses()
+		if err != nil {
+			return err
+		} else if len(buses) == 0 {
+			return app.Logger.Error("No I2C buses found")
+		}
+		for _, bus := range buses {
+			if driver, err := gopi.Open(i2c.I2C{Bus: bus}, app.Logger); err != nil {
+				return err
+			} else {
+				result, addrs, err := detect(driver.(gopi.I2C), bus, scan)
+				driver.Close()
+				if err != nil {
+					return err
+				}
+				results, probed = append(results, result), append(probed, addrs)
+			}
+		}
+	} else if module := gopi.ModuleByName("i2c"); module == nil {
+		return app.Logger.Error("Missing I2C module")
+	} else if driver, err := module.New(app); err != nil {
+		return err
+	} else {
+		bus, _ := app.AppFlags.GetUint("i2c.bus")
+		result, addrs, err := detect(driver.(gopi.I2C), bus, scan)
+		driver.Close()
+		if err != nil {
+			return err
+		}
+		results, probed = append(results, result), append(probed, addrs)
+	}
+
+	// Output detected I2C addresses
+	if as_json, _ := app.AppFlags.GetBool("json"); as_json {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for i, result := range results {
+			renderTable(result, probed[i])
+		}
+	}
 
 	// Finished
 	done <- gopi.DONE
@@ -61,10 +223,23 @@ func mainLoop(app *gopi.AppInstance, done chan<- struct{}) error {
 
 ////////////////////////////////////////////////////////////////////////////////
 
-func main() {
-	// Create the configuration, load the i2c instance
-	config := gopi.NewAppConfig("i2c")
+// newConfig returns the configuration, which has the flags of the i2c
+// module but does not load it, as the default bus may not exist when
+// every bus is scanned. The module is opened in mainLoop otherwise
+func newConfig() gopi.AppConfig {
+	config := gopi.NewAppConfig()
+	if module := gopi.ModuleByName("i2c"); module != nil && module.Config != nil {
+		module.Config(&config)
+	}
+	config.AppFlags.FlagString("mode", "auto", "Probe with quick, read or auto")
+	config.AppFlags.FlagString("range", "0x00-0x7F", "First and last address to scan")
+	config.AppFlags.FlagBool("reserved", false, "Probe reserved addresses")
+	config.AppFlags.FlagBool("json", false, "Output in JSON format")
+	config.AppFlags.FlagBool("all", false, "Scan every I2C bus")
+	return config
+}
 
+func main() {
 	// Run the command line tool
-	os.Exit(gopi.CommandLineTool(config, mainLoop))
+	os.Exit(gopi.CommandLineTool(newConfig(), mainLoop))
 }
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	i2c "github.com/djthorpe/gopi-hw/sys/i2c"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestDetect_000(t *testing.T) {
+	app, err := gopi.NewAppInstance(gopi.NewAppConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	driver, err := gopi.Open(i2c.Mock{Bus: 99, Slaves: []i2c.MockSlave{{Address: 0x03}, {Address: 0x20}, {Address: 0x50}, {Address: 0x68}}}, app.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver.Close()
+	bus := driver.(i2c.I2CMockInterface)
+
+	// Reserved addresses are skipped, and addresses outside the range
+	// are not probed
+	if result, probed, err := detect(bus, 99, &Scan{first: 0x00, last: 0x5F}); err != nil {
+		t.Fatal(err)
+	} else if len(result.Slaves) != 2 || result.Slaves[0].Address != 0x20 || result.Slaves[1].Address != 0x50 {
+		t.Error("Unexpected slaves", result.Slaves)
+	} else if probed[0x03] || probed[0x68] || probed[0x08] == false {
+		t.Error("Unexpected probed addresses")
+	}
+
+	// Auto mode probes EEPROM addresses with a read and others with a
+	// quick write
+	for _, tx := range bus.Transactions() {
+		if tx.Slave == 0x50 && tx.Op != i2c.I2C_MOCK_READ {
+			t.Error("Unexpected probe of 0x50", tx)
+		} else if tx.Slave == 0x20 && tx.Op != i2c.I2C_MOCK_QUICK {
+			t.Error("Unexpected probe of 0x20", tx)
+		}
+	}
+
+	// Reserved addresses are probed when requested
+	if result, _, err := detect(bus, 99, &Scan{first: 0x00, last: 0x07, reserved: true, mode: i2c.I2C_DETECT_READ}); err != nil {
+		t.Fatal(err)
+	} else if len(result.Slaves) != 1 || result.Slaves[0].Address != 0x03 {
+		t.Error("Unexpected slaves", result.Slaves)
+	}
+}
+
+func TestParse_000(t *testing.T) {
+	if first, last, err := parseRange("0x08-0x77"); err != nil {
+		t.Error(err)
+	} else if first != 0x08 || last != 0x77 {
+		t.Error("Unexpected range", first, last)
+	}
+	for _, value := range []string{"0x08", "0x77-0x08", "0x00-0x80", "a-b"} {
+		if _, _, err := parseRange(value); err == nil {
+			t.Error("Expected error for", value)
+		}
+	}
+	if mode, err := parseMode("quick"); err != nil || mode != i2c.I2C_DETECT_QUICK {
+		t.Error("Unexpected mode", mode, err)
+	} else if _, err := parseMode("write"); err == nil {
+		t.Error("Expected error for invalid mode")
+	}
+}
+
+func TestConfig_000(t *testing.T) {
+	// Flags of the i2c module are parsed with -all, and -all is not
+	// read from the values of other flags
+	for _, test := range []struct {
+		args []string
+		all  bool
+		bus  uint
+	}{
+		{[]string{}, false, 1},
+		{[]string{"-all", "-i2c.bus", "2"}, true, 2},
+		{[]string{"-mode", "quick", "-all=true"}, true, 1},
+		{[]string{"-range", "all"}, false, 1},
+		{[]string{"-mode", "all", "-i2c.bus=3"}, false, 3},
+	} {
+		config := newConfig()
+		if err := config.AppFlags.Parse(test.args); err != nil {
+			t.Error(test.args, err)
+		} else if all, _ := config.AppFlags.GetBool("all"); all != test.all {
+			t.Error(test.args, "Unexpected all", all)
+		} else if bus, _ := config.AppFlags.GetUint("i2c.bus"); bus != test.bus {
+			t.Error(test.args, "Unexpected bus", bus)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+// Output formats shared by the i2c_ctrl and spi_ctrl commands
+package output
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Format is how values are written
+type Format uint
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	FORMAT_HEX Format = iota
+	FORMAT_DEC
+	FORMAT_BIN
+)
+
+const (
+	// Number of bytes in each row of a table
+	TABLE_ROW = 16
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC FUNCTIONS
+
+// ParseFormat returns a format from a name, which is one of hex, dec or bin
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "hex":
+		return FORMAT_HEX, nil
+	case "dec":
+		return FORMAT_DEC, nil
+	case "bin":
+		return FORMAT_BIN, nil
+	default:
+		return FORMAT_HEX, fmt.Errorf("Invalid -format value: %v", value)
+	}
+}
+
+// Value returns a value with a number of bits in a format
+func Value(value uint64, bits uint, format Format) string {
+	switch format {
+	case FORMAT_DEC:
+		return strconv.FormatUint(value, 10)
+	case FORMAT_BIN:
+		return fmt.Sprintf("0b%0*b", bits, value)
+	default:
+		return fmt.Sprintf("0x%0*X", (bits+3)/4, value)
+	}
+}
+
+// Bytes returns bytes in a format, separated by spaces
+func Bytes(data []byte, format Format) string {
+	values := make([]string, len(data))
+	for i, value := range data {
+		values[i] = Value(uint64(value), 8, format)
+	}
+	return strings.Join(values, " ")
+}
+
+// Table writes bytes as rows of sixteen hex values followed by their
+// ASCII representation. The offset is the address of the first byte,
+// and bytes which are not valid are written as XX. When valid is nil
+// all bytes are valid
+func Table(w io.Writer, offset uint, data []byte, valid []bool) error {
+	header := "   "
+	for i := 0; i < TABLE_ROW; i++ {
+		header += fmt.Sprintf(" %2x", i)
+	}
+	if _, err := fmt.Fprintf(w, "%v    %v\n", header, "0123456789abcdef"); err != nil {
+		return err
+	}
+	for row := 0; row < len(data); row += TABLE_ROW {
+		hex, ascii := "", ""
+		for i := row; i < row+TABLE_ROW; i++ {
+			switch {
+			case i >= len(data):
+				hex, ascii = hex+"   ", ascii+" "
+			case valid != nil && valid[i] == false:
+				hex, ascii = hex+" XX", ascii+"X"
+			default:
+				hex += fmt.Sprintf(" %02x", data[i])
+				ascii += string(printable(data[i]))
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%02x:%v    %v\n", offset+uint(row), hex, ascii); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func printable(value byte) byte {
+	if value < 0x20 || value > 0x7E {
+		return '.'
+	} else {
+		return value
+	}
+}
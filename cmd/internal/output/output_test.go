@@ -0,0 +1,66 @@
+package output_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi-hw/cmd/internal/output"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestFormat_000(t *testing.T) {
+	tests := []struct {
+		value    uint64
+		bits     uint
+		format   string
+		expected string
+	}{
+		{0x0A, 8, "hex", "0x0A"},
+		{0x0A, 8, "dec", "10"},
+		{0x0A, 8, "bin", "0b00001010"},
+		{0x1234, 16, "hex", "0x1234"},
+		{0x1234, 16, "DEC", "4660"},
+	}
+	for _, test := range tests {
+		if format, err := output.ParseFormat(test.format); err != nil {
+			t.Error(err)
+		} else if value := output.Value(test.value, test.bits, format); value != test.expected {
+			t.Errorf("Expected %v, got %v", test.expected, value)
+		}
+	}
+	if _, err := output.ParseFormat("oct"); err == nil {
+		t.Error("Expected error for invalid format")
+	}
+	if value := output.Bytes([]byte{0x01, 0xFF}, output.FORMAT_HEX); value != "0x01 0xFF" {
+		t.Error("Unexpected bytes", value)
+	}
+}
+
+func TestTable_000(t *testing.T) {
+	data := []byte("Hello, World\x00\x01\x02\x03ABC")
+	valid := make([]bool, len(data))
+	for i := range valid {
+		valid[i] = i != 1
+	}
+	buf := new(bytes.Buffer)
+	if err := output.Table(buf, 0x10, data, valid); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatal("Unexpected number of lines", len(lines))
+	}
+	if lines[0] != "     0  1  2  3  4  5  6  7  8  9  a  b  c  d  e  f    0123456789abcdef" {
+		t.Errorf("Unexpected header %q", lines[0])
+	}
+	if lines[1] != "10: 48 XX 6c 6c 6f 2c 20 57 6f 72 6c 64 00 01 02 03    HXllo, World...." {
+		t.Errorf("Unexpected row %q", lines[1])
+	}
+	if lines[2] != "20: 41 42 43                                           ABC             " {
+		t.Errorf("Unexpected row %q", lines[2])
+	}
+}
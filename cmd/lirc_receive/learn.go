@@ -0,0 +1,296 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	ir "github.com/djthorpe/gopi-hw/sys/ir"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Remote is a learned remote, with a protocol when every key was decoded
+// with the same protocol, or raw codes otherwise
+type Remote struct {
+	Name     string `json:"name"`
+	Protocol string `json:"protocol,omitempty"`
+	Keys     []*Key `json:"keys"`
+
+	protocol ir.Protocol
+}
+
+// Key is a learned key, with a device and scancode when the key was
+// decoded, or a raw code otherwise
+type Key struct {
+	Name     string   `json:"name"`
+	Device   *uint32  `json:"device,omitempty"`
+	Scancode *uint32  `json:"scancode,omitempty"`
+	Raw      []uint32 `json:"raw,omitempty"`
+
+	protocol ir.Protocol
+}
+
+// framer assembles frames from LIRC events
+type framer struct {
+	frame []uint32
+	end   time.Time
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Frames with fewer values are noise
+	LEARN_FRAME_MIN = 3
+
+	// Gap after each frame of a remote with raw codes, in microseconds
+	LEARN_RAW_GAP = 100000
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FRAMES
+
+// feed adds a pulse or space to the frame, and returns the frame when it
+// ends with a long space or timeout. A frame which starts soon after the
+// end of the last frame is a repeat
+func (this *framer) feed(t gopi.LIRCType, value uint32, now time.Time) ([]uint32, bool) {
+	switch {
+	case t == gopi.LIRC_TYPE_PULSE:
+		if len(this.frame)%2 == 0 {
+			this.frame = append(this.frame, value)
+		} else {
+			this.frame[len(this.frame)-1] += value
+		}
+	case t != gopi.LIRC_TYPE_SPACE && t != gopi.LIRC_TYPE_TIMEOUT:
+		// Ignore other events
+	case len(this.frame) == 0:
+		// Ignore spaces before the first pulse
+	case t == gopi.LIRC_TYPE_TIMEOUT || value >= ir.IR_FRAME_GAP:
+		frame := this.frame
+		this.frame = nil
+		if len(frame)%2 == 0 {
+			frame = frame[:len(frame)-1]
+		}
+		repeat := now.Sub(this.end) < time.Duration(ir.IR_REPEAT_WINDOW)*time.Microsecond
+		this.end = now
+		return frame, repeat
+	case len(this.frame)%2 == 1:
+		this.frame = append(this.frame, value)
+	default:
+		this.frame[len(this.frame)-1] += value
+	}
+	return nil, false
+}
+
+// average returns the average of the largest group of similar frames.
+// Frames of the same key can differ, such as RC5 and RC6 frames with the
+// toggle bit changed, so only similar frames are averaged
+func average(frames [][]uint32) []uint32 {
+	groups := make([][][]uint32, 0, 1)
+	largest := 0
+FRAMES_LOOP:
+	for _, frame := range frames {
+		for i, group := range groups {
+			if similar(group[0], frame) {
+				if groups[i] = append(group, frame); len(groups[i]) > len(groups[largest]) {
+					largest = i
+				}
+				continue FRAMES_LOOP
+			}
+		}
+		groups = append(groups, [][]uint32{frame})
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	group := groups[largest]
+	sum := make([]uint64, len(group[0]))
+	for _, frame := range group {
+		for i, value := range frame {
+			sum[i] += uint64(value)
+		}
+	}
+	result := make([]uint32, len(sum))
+	for i := range result {
+		result[i] = uint32((sum[i] + uint64(len(group))/2) / uint64(len(group)))
+	}
+	return result
+}
+
+// similar returns true if two frames have the same length and their
+// values are within IR_TOLERANCE percent
+func similar(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		diff := int64(a[i]) - int64(b[i])
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff*100 > int64(a[i])*ir.IR_TOLERANCE {
+			return false
+		}
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// LEARN
+
+// learn captures presses of each key and returns the learned remote
+func learn(app *gopi.AppInstance, name string, keys []string, presses uint, timeout time.Duration) (*Remote, error) {
+	events := app.LIRC.Subscribe()
+	defer app.LIRC.Unsubscribe(events)
+
+	remote := &Remote{Name: name, Keys: make([]*Key, 0, len(keys))}
+	framer := new(framer)
+	for _, name := range keys {
+		fmt.Fprintf(os.Stderr, "Press %v %v times\n", name, presses)
+		frames := make([][]uint32, 0, presses)
+		deadline := time.After(timeout)
+		for uint(len(frames)) < presses {
+			select {
+			case evt := <-events:
+				if lirc_event, ok := evt.(gopi.LIRCEvent); ok {
+					if frame, repeat := framer.feed(lirc_event.Type(), lirc_event.Value(), time.Now()); len(frame) >= LEARN_FRAME_MIN && repeat == false {
+						frames = append(frames, frame)
+						fmt.Fprintf(os.Stderr, "  %v/%v\n", len(frames), presses)
+					}
+				}
+			case <-deadline:
+				return nil, fmt.Errorf("Timeout learning %v", name)
+			}
+		}
+		remote.Keys = append(remote.Keys, learnKey(name, frames))
+	}
+	remote.detect()
+	return remote, nil
+}
+
+// learnKey decodes the frames of a key, and sets the protocol and code
+// which most frames decode as, ignoring the toggle bit. The raw code is
+// the average of the frames with that code, or of all the frames when
+// none are decoded
+func learnKey(name string, frames [][]uint32) *Key {
+	type code struct {
+		protocol         ir.Protocol
+		device, scancode uint32
+	}
+	codes := make(map[code][][]uint32)
+	best := code{}
+	for _, frame := range frames {
+		decoded := code{}
+		decoded.protocol, decoded.device, decoded.scancode = ir.Decode(frame, ir.IR_TOLERANCE)
+		if decoded.protocol == ir.IR_PROTOCOL_NONE {
+			continue
+		}
+		if codes[decoded] = append(codes[decoded], frame); len(codes[decoded]) > len(codes[best]) {
+			best = decoded
+		}
+	}
+	key := &Key{Name: name, protocol: best.protocol}
+	if best.protocol == ir.IR_PROTOCOL_NONE {
+		key.Raw = average(frames)
+	} else {
+		key.Device, key.Scancode = &best.device, &best.scancode
+		key.Raw = average(codes[best])
+	}
+	return key
+}
+
+// detect sets the protocol of the remote when every key was decoded with
+// the same protocol, or otherwise falls back to raw codes
+func (this *Remote) detect() {
+	this.protocol = ir.IR_PROTOCOL_NONE
+	for i, key := range this.Keys {
+		if key.protocol == ir.IR_PROTOCOL_NONE || (i > 0 && key.protocol != this.protocol) {
+			this.protocol = ir.IR_PROTOCOL_NONE
+			break
+		}
+		this.protocol = key.protocol
+	}
+	if this.protocol == ir.IR_PROTOCOL_NONE {
+		this.Protocol = ""
+	} else {
+		this.Protocol = strings.ToLower(strings.TrimPrefix(this.protocol.String(), "IR_PROTOCOL_"))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// OUTPUT
+
+// write the learned remote in lircd.conf or JSON format
+func (this *Remote) write(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		// Keys have codes when the protocol was detected, or raw codes
+		remote := &Remote{Name: this.Name, Protocol: this.Protocol, Keys: make([]*Key, len(this.Keys))}
+		for i, key := range this.Keys {
+			if this.protocol == ir.IR_PROTOCOL_NONE {
+				remote.Keys[i] = &Key{Name: key.Name, Raw: key.Raw}
+			} else {
+				remote.Keys[i] = &Key{Name: key.Name, Device: key.Device, Scancode: key.Scancode}
+			}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(remote)
+	case "conf":
+		if remote, err := this.remote(); err != nil {
+			return err
+		} else {
+			return ir.WriteRemotes(w, []*ir.Remote{remote})
+		}
+	default:
+		return fmt.Errorf("Invalid format: %v", format)
+	}
+}
+
+// remote returns the remote definition of the learned remote
+func (this *Remote) remote() (*ir.Remote, error) {
+	if this.protocol == ir.IR_PROTOCOL_NONE {
+		remote := &ir.Remote{
+			Name:      this.Name,
+			Flags:     ir.REMOTE_FLAG_RAW_CODES,
+			Eps:       ir.REMOTE_EPS,
+			Aeps:      ir.REMOTE_AEPS,
+			Gap:       LEARN_RAW_GAP,
+			Frequency: ir.REMOTE_FREQUENCY,
+			DutyCycle: ir.REMOTE_DUTY_CYCLE,
+		}
+		for _, key := range this.Keys {
+			remote.Keys = append(remote.Keys, &ir.RemoteKey{Name: key.Name, Raw: key.Raw})
+		}
+		return remote, nil
+	}
+	remote, err := ir.NewRemote(this.Name, this.protocol)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range this.Keys {
+		if key.Device == nil || key.Scancode == nil {
+			return nil, fmt.Errorf("Missing code for %v", key.Name)
+		} else if code, err := ir.RemoteCode(this.protocol, *key.Device, *key.Scancode); err != nil {
+			return nil, err
+		} else {
+			remote.Keys = append(remote.Keys, &ir.RemoteKey{Name: key.Name, Code: code})
+		}
+	}
+	return remote, nil
+}
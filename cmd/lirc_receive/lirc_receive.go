@@ -6,13 +6,14 @@
 	For Licensing and Usage information, please see LICENSE.md
 */
 
-// Example command for discovery of RPC microservices using mDNS
+// Displays, learns, records and replays IR pulses with the LIRC interface
 package main
 
 import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	// Frameworks
 	gopi "github.com/djthorpe/gopi"
@@ -26,8 +27,28 @@ import (
 
 func EventLoop(app *gopi.AppInstance, start chan<- struct{}, stop <-chan struct{}) error {
 	messages := app.LIRC.Subscribe()
-	fmt.Printf("%20s %12s\n", "Type", "Value")
-	fmt.Printf("%20s %12s\n", "--------------------", "------------")
+
+	// Events are not displayed when learning or replaying
+	learn, _ := app.AppFlags.GetBool("learn")
+	replay, _ := app.AppFlags.GetString("replay")
+	display := learn == false && replay == ""
+
+	// Record events to a file
+	var record *os.File
+	if filename, _ := app.AppFlags.GetString("record"); filename != "" && display {
+		if fh, err := os.Create(filename); err != nil {
+			start <- gopi.DONE
+			return err
+		} else {
+			record = fh
+			defer record.Close()
+		}
+	}
+
+	if display {
+		fmt.Printf("%20s %12s\n", "Type", "Value")
+		fmt.Printf("%20s %12s\n", "--------------------", "------------")
+	}
 
 	start <- gopi.DONE
 
@@ -35,8 +56,15 @@ FOR_LOOP:
 	for {
 		select {
 		case evt := <-messages:
-			if event, ok := evt.(gopi.LIRCEvent); ok {
+			if display == false {
+				continue
+			} else if event, ok := evt.(gopi.LIRCEvent); ok {
 				fmt.Printf("%20s %10sms\n", event.Type(), fmt.Sprint(event.Value()))
+				if record != nil {
+					if err := writeEvent(record, &Event{time.Now(), event.Type(), event.Value()}); err != nil {
+						app.Logger.Error("%v", err)
+					}
+				}
 			} else {
 				fmt.Println(evt)
 			}
@@ -56,6 +84,23 @@ func Main(app *gopi.AppInstance, done chan<- struct{}) error {
 		return errors.New("Missing LIRC module")
 	}
 
+	// Events are only recorded when they are displayed
+	learn, _ := app.AppFlags.GetBool("learn")
+	if record, _ := app.AppFlags.GetString("record"); record != "" {
+		if replay, _ := app.AppFlags.GetString("replay"); learn || replay != "" {
+			return errors.New("-record cannot be used with -learn or -replay")
+		}
+	}
+
+	// Replay a recording
+	if filename, _ := app.AppFlags.GetString("replay"); filename != "" {
+		if err := replay(app.LIRC, filename); err != nil {
+			return err
+		}
+		done <- gopi.DONE
+		return nil
+	}
+
 	// Set receive mode to be MODE2
 	// Ref: https://linuxtv.org/downloads/v4l-dvb-apis/uapi/rc/lirc-dev-intro.html#lirc-modes
 	if err := app.LIRC.SetRcvMode(gopi.LIRC_MODE_MODE2); err != nil {
@@ -69,6 +114,15 @@ func Main(app *gopi.AppInstance, done chan<- struct{}) error {
 		return err
 	}
 
+	// Learn keys
+	if learn {
+		if err := learnMain(app); err != nil {
+			return err
+		}
+		done <- gopi.DONE
+		return nil
+	}
+
 	// Wait for interrupt
 	app.WaitForSignal()
 
@@ -77,12 +131,50 @@ func Main(app *gopi.AppInstance, done chan<- struct{}) error {
 	return nil
 }
 
+func learnMain(app *gopi.AppInstance) error {
+	keys := app.AppFlags.Args()
+	if len(keys) == 0 {
+		return errors.New("Missing key names to learn, such as KEY_POWER")
+	}
+	name, _ := app.AppFlags.GetString("name")
+	presses, _ := app.AppFlags.GetUint("presses")
+	timeout, _ := app.AppFlags.GetDuration("timeout")
+	format, _ := app.AppFlags.GetString("format")
+	if presses == 0 || timeout <= 0 {
+		return gopi.ErrBadParameter
+	}
+
+	// Learn the keys and write the remote
+	remote, err := learn(app, name, keys, presses, timeout)
+	if err != nil {
+		return err
+	}
+	if filename, _ := app.AppFlags.GetString("output"); filename == "" {
+		return remote.write(os.Stdout, format)
+	} else if fh, err := os.Create(filename); err != nil {
+		return err
+	} else {
+		defer fh.Close()
+		return remote.write(fh, format)
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 
 func main() {
 	// Create the configuration, load the lirc instance
 	config := gopi.NewAppConfig("lirc")
 
+	// Flags
+	config.AppFlags.FlagBool("learn", false, "Learn the keys named on the command line")
+	config.AppFlags.FlagString("name", "learned", "Name of the learned remote")
+	config.AppFlags.FlagUint("presses", 3, "Number of presses of each key when learning")
+	config.AppFlags.FlagDuration("timeout", 30*time.Second, "Time to wait for the presses of each key when learning")
+	config.AppFlags.FlagString("format", "conf", "Format of the learned remote (conf, json)")
+	config.AppFlags.FlagString("output", "", "File for the learned remote, or stdout when empty")
+	config.AppFlags.FlagString("record", "", "Record the events with timestamps to a file")
+	config.AppFlags.FlagString("replay", "", "Send the events recorded in a file")
+
 	// Run the command line tool
 	os.Exit(gopi.CommandLineTool2(config, Main, EventLoop))
 }
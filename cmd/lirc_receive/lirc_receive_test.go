@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	ir "github.com/djthorpe/gopi-hw/sys/ir"
+)
+
+func TestRecord_000(t *testing.T) {
+	// Events are written and read back
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	events := []*Event{
+		{now, gopi.LIRC_TYPE_FREQUENCY, 38000},
+		{now.Add(9000 * time.Microsecond), gopi.LIRC_TYPE_PULSE, 9000},
+		{now.Add(11250 * time.Microsecond), gopi.LIRC_TYPE_SPACE, 2250},
+		{now.Add(11810 * time.Microsecond), gopi.LIRC_TYPE_PULSE, 560},
+		{now.Add(21810 * time.Microsecond), gopi.LIRC_TYPE_TIMEOUT, 10000},
+		{now.Add(118000 * time.Microsecond), gopi.LIRC_TYPE_PULSE, 9000},
+		{now.Add(120250 * time.Microsecond), gopi.LIRC_TYPE_SPACE, 2250},
+		{now.Add(120810 * time.Microsecond), gopi.LIRC_TYPE_PULSE, 560},
+	}
+	buf := new(bytes.Buffer)
+	for _, evt := range events {
+		if err := writeEvent(buf, evt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if events2, err := readEvents(buf); err != nil {
+		t.Fatal(err)
+	} else if len(events2) != len(events) {
+		t.Fatal("Unexpected events", events2)
+	} else {
+		for i := range events {
+			if events[i].Timestamp.Equal(events2[i].Timestamp) == false || events[i].Type != events2[i].Type || events[i].Value != events2[i].Value {
+				t.Error("Unexpected event", events2[i])
+			}
+		}
+	}
+
+	// Frames with the gap between the end of one and the start of the next
+	if frames := frames(events); len(frames) != 2 {
+		t.Error("Unexpected frames", frames)
+	} else if len(frames[0].Values) != 3 || frames[0].Carrier != 38000 {
+		t.Error("Unexpected frame", frames[0])
+	} else if frames[0].Gap != (118000-9000-11810)*time.Microsecond {
+		t.Error("Unexpected gap", frames[0].Gap)
+	}
+
+	// Invalid lines
+	if _, err := readEvents(strings.NewReader("2018-06-01T12:00:00Z burst 100\n")); err == nil {
+		t.Error("Expected error")
+	}
+}
+
+func TestLearn_000(t *testing.T) {
+	// Frames are averaged, and repeat frames are ignored
+	f := new(framer)
+	now := time.Now()
+	presses := [][]uint32{}
+	for i, values := range [][]uint32{
+		{2380, 620, 1180, 610, 600},
+		{2400, 600, 1200, 600, 600},
+		{2420, 580, 1220, 590, 610},
+	} {
+		for j, value := range values {
+			t := gopi.LIRC_TYPE_PULSE
+			if j%2 == 1 {
+				t = gopi.LIRC_TYPE_SPACE
+			}
+			f.feed(t, value, now)
+		}
+		now = now.Add([]time.Duration{0, 50 * time.Millisecond, time.Second}[i])
+		if frame, repeat := f.feed(gopi.LIRC_TYPE_TIMEOUT, 10000, now); repeat == false {
+			presses = append(presses, frame)
+		}
+	}
+	if len(presses) != 2 {
+		t.Error("Unexpected presses", presses)
+	} else if avg := average(presses); len(avg) != 5 || avg[0] != 2400 || avg[2] != 1200 || avg[4] != 605 {
+		t.Error("Unexpected average", avg)
+	}
+}
+
+func TestLearn_001(t *testing.T) {
+	encoder, err := ir.NewRemote("test", ir.IR_PROTOCOL_NEC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"KEY_POWER", "KEY_MUTE"} {
+		if code, err := ir.RemoteCode(ir.IR_PROTOCOL_NEC, 0x04, uint32(0x08+i)); err != nil {
+			t.Fatal(err)
+		} else {
+			encoder.Keys = append(encoder.Keys, &ir.RemoteKey{Name: name, Code: code})
+		}
+	}
+
+	// Keys of the same protocol are written with the protocol
+	remote := &Remote{Name: "tv"}
+	for _, key := range encoder.Keys {
+		if values, err := encoder.Encode(key.Name, false, false); err != nil {
+			t.Fatal(err)
+		} else {
+			remote.Keys = append(remote.Keys, learnKey(key.Name, [][]uint32{values}))
+		}
+	}
+	remote.detect()
+	if remote.Protocol != "nec" || *remote.Keys[1].Device != 0x04 || *remote.Keys[1].Scancode != 0x09 {
+		t.Error("Unexpected remote", remote)
+	}
+	buf := new(bytes.Buffer)
+	if err := remote.write(buf, "conf"); err != nil {
+		t.Fatal(err)
+	} else if remotes, err := ir.ParseRemotes(buf); err != nil {
+		t.Fatal(err)
+	} else if len(remotes) != 1 || remotes[0].Key("KEY_MUTE") == nil || remotes[0].Key("KEY_MUTE").Code != encoder.Keys[1].Code {
+		t.Error("Unexpected remotes", remotes)
+	}
+
+	// Keys which are not decoded are written as raw codes
+	remote.Keys = append(remote.Keys, learnKey("KEY_UNKNOWN", [][]uint32{{1000, 1000, 1000}}))
+	remote.detect()
+	if remote.Protocol != "" {
+		t.Error("Unexpected remote", remote)
+	}
+	buf.Reset()
+	if err := remote.write(buf, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var remote2 Remote
+	if err := json.Unmarshal(buf.Bytes(), &remote2); err != nil {
+		t.Fatal(err)
+	} else if remote2.Name != "tv" || len(remote2.Keys) != 3 || len(remote2.Keys[2].Raw) != 3 {
+		t.Error("Unexpected remote", remote2)
+	}
+	buf.Reset()
+	if err := remote.write(buf, "conf"); err != nil {
+		t.Fatal(err)
+	} else if remotes, err := ir.ParseRemotes(buf); err != nil {
+		t.Fatal(err)
+	} else if remotes[0].Flags != ir.REMOTE_FLAG_RAW_CODES || len(remotes[0].Keys) != 3 {
+		t.Error("Unexpected remotes", remotes)
+	}
+}
+
+func TestLearn_002(t *testing.T) {
+	encoder, err := ir.NewRemote("tv", ir.IR_PROTOCOL_RC5)
+	if err != nil {
+		t.Fatal(err)
+	} else if code, err := ir.RemoteCode(ir.IR_PROTOCOL_RC5, 0x05, 0x0C); err != nil {
+		t.Fatal(err)
+	} else {
+		encoder.Keys = append(encoder.Keys, &ir.RemoteKey{Name: "KEY_POWER", Code: code})
+	}
+
+	// RC5 frames with the toggle bit changed on each press are decoded
+	// as the same code, and only frames with the same toggle bit are
+	// averaged
+	frames := make([][]uint32, 0, 3)
+	for _, toggle := range []bool{false, true, false} {
+		if values, err := encoder.Encode("KEY_POWER", toggle, false); err != nil {
+			t.Fatal(err)
+		} else {
+			frames = append(frames, values)
+		}
+	}
+	key := learnKey("KEY_POWER", frames)
+	if key.protocol != ir.IR_PROTOCOL_RC5 || *key.Device != 0x05 || *key.Scancode != 0x0C {
+		t.Error("Unexpected key", key)
+	} else if similar(key.Raw, frames[0]) == false {
+		t.Error("Unexpected raw code", key.Raw, frames[0])
+	} else if protocol, device, scancode := ir.Decode(key.Raw, ir.IR_TOLERANCE); protocol != ir.IR_PROTOCOL_RC5 || device != 0x05 || scancode != 0x0C {
+		t.Error("Unexpected decode of raw code", protocol, device, scancode)
+	}
+}
+
+func TestLearn_003(t *testing.T) {
+	encoder, err := ir.NewRemote("car_mp3", ir.IR_PROTOCOL_NEC)
+	if err != nil {
+		t.Fatal(err)
+	} else if code, err := ir.RemoteCode(ir.IR_PROTOCOL_NEC, 0x00, 0x00); err != nil {
+		t.Fatal(err)
+	} else {
+		encoder.Keys = append(encoder.Keys, &ir.RemoteKey{Name: "KEY_POWER", Code: code})
+	}
+
+	// A device and scancode of zero are written, and raw keys have no
+	// device or scancode
+	remote := &Remote{Name: "car_mp3"}
+	if values, err := encoder.Encode("KEY_POWER", false, false); err != nil {
+		t.Fatal(err)
+	} else {
+		remote.Keys = append(remote.Keys, learnKey("KEY_POWER", [][]uint32{values}))
+	}
+	remote.detect()
+	buf := new(bytes.Buffer)
+	var remote2 Remote
+	if err := remote.write(buf, "json"); err != nil {
+		t.Fatal(err)
+	} else if err := json.Unmarshal(buf.Bytes(), &remote2); err != nil {
+		t.Fatal(err)
+	} else if len(remote2.Keys) != 1 || remote2.Keys[0].Device == nil || *remote2.Keys[0].Device != 0 || remote2.Keys[0].Scancode == nil || *remote2.Keys[0].Scancode != 0 {
+		t.Error("Unexpected remote", buf.String())
+	}
+	if key := learnKey("KEY_UNKNOWN", [][]uint32{{1000, 1000, 1000}}); key.Device != nil || key.Scancode != nil {
+		t.Error("Unexpected key", key)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	ir "github.com/djthorpe/gopi-hw/sys/ir"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Event is a recorded LIRC event
+type Event struct {
+	Timestamp time.Time
+	Type      gopi.LIRCType
+	Value     uint32
+}
+
+// Frame is a pulse train to replay, with the carrier frequency and the
+// gap before the next frame
+type Frame struct {
+	Values  []uint32
+	Carrier uint32
+	Gap     time.Duration
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// RECORD
+
+// typeName returns the name of an event type, such as pulse or space
+func typeName(t gopi.LIRCType) string {
+	return strings.ToLower(strings.TrimPrefix(t.String(), "LIRC_TYPE_"))
+}
+
+// writeEvent writes an event as a line with the timestamp, type and value
+func writeEvent(w io.Writer, evt *Event) error {
+	_, err := fmt.Fprintf(w, "%v %v %v\n", evt.Timestamp.Format(time.RFC3339Nano), typeName(evt.Type), evt.Value)
+	return err
+}
+
+// readEvents reads events written by writeEvent. Blank lines and
+// comments starting with # are ignored
+func readEvents(r io.Reader) ([]*Event, error) {
+	events := make([]*Event, 0)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("Line %v: Expected timestamp, type and value", line)
+		}
+		evt := new(Event)
+		if ts, err := time.Parse(time.RFC3339Nano, fields[0]); err != nil {
+			return nil, fmt.Errorf("Line %v: Invalid timestamp: %v", line, fields[0])
+		} else {
+			evt.Timestamp = ts
+		}
+		if t, err := parseType(fields[1]); err != nil {
+			return nil, fmt.Errorf("Line %v: %v", line, err)
+		} else {
+			evt.Type = t
+		}
+		if value, err := strconv.ParseUint(fields[2], 10, 32); err != nil {
+			return nil, fmt.Errorf("Line %v: Invalid value: %v", line, fields[2])
+		} else {
+			evt.Value = uint32(value)
+		}
+		events = append(events, evt)
+	}
+	return events, scanner.Err()
+}
+
+func parseType(value string) (gopi.LIRCType, error) {
+	for _, t := range []gopi.LIRCType{gopi.LIRC_TYPE_SPACE, gopi.LIRC_TYPE_PULSE, gopi.LIRC_TYPE_FREQUENCY, gopi.LIRC_TYPE_TIMEOUT} {
+		if value == typeName(t) {
+			return t, nil
+		}
+	}
+	return 0, fmt.Errorf("Invalid type: %v", value)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// REPLAY
+
+// frames returns the frames of recorded events. A frame ends with a long
+// space or a timeout, and the gap before the next frame is the time
+// between the end of the frame and the start of the next one
+func frames(events []*Event) []*Frame {
+	frames := make([]*Frame, 0)
+	var frame *Frame
+	var carrier uint32
+	var end time.Time
+	for _, evt := range events {
+		switch {
+		case evt.Type == gopi.LIRC_TYPE_FREQUENCY:
+			carrier = evt.Value
+		case evt.Type == gopi.LIRC_TYPE_PULSE:
+			if frame == nil {
+				// The pulse event is received at the end of the pulse
+				start := evt.Timestamp.Add(-time.Duration(evt.Value) * time.Microsecond)
+				if len(frames) > 0 {
+					frames[len(frames)-1].Gap = start.Sub(end)
+				}
+				frame = &Frame{Values: []uint32{evt.Value}, Carrier: carrier}
+			} else if len(frame.Values)%2 == 1 {
+				frame.Values[len(frame.Values)-1] += evt.Value
+			} else {
+				frame.Values = append(frame.Values, evt.Value)
+			}
+			end = evt.Timestamp
+		case frame == nil:
+			// Ignore spaces and timeouts between frames
+		case evt.Type == gopi.LIRC_TYPE_TIMEOUT || evt.Value >= ir.IR_FRAME_GAP:
+			frames = append(frames, frame)
+			frame = nil
+		case len(frame.Values)%2 == 0:
+			frame.Values[len(frame.Values)-1] += evt.Value
+		default:
+			frame.Values = append(frame.Values, evt.Value)
+		}
+	}
+	if frame != nil {
+		if len(frame.Values)%2 == 0 {
+			frame.Values = frame.Values[:len(frame.Values)-1]
+		}
+		frames = append(frames, frame)
+	}
+
+	// The gap is at least the gap which ends a frame
+	for _, frame := range frames {
+		if frame.Gap < time.Duration(ir.IR_FRAME_GAP)*time.Microsecond {
+			frame.Gap = time.Duration(ir.IR_FRAME_GAP) * time.Microsecond
+		}
+	}
+	return frames
+}
+
+// replay sends the frames of a recording
+func replay(lirc gopi.LIRC, filename string) error {
+	fh, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	events, err := readEvents(fh)
+	if err != nil {
+		return err
+	}
+	frames := frames(events)
+	for i, frame := range frames {
+		if frame.Carrier != 0 {
+			if err := lirc.SetSendCarrierHz(frame.Carrier); err != nil && err != gopi.ErrNotImplemented {
+				return err
+			}
+		}
+		if err := lirc.PulseSend(frame.Values); err != nil {
+			return err
+		}
+		if i < len(frames)-1 {
+			time.Sleep(frame.Gap)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+// Sends remote control codes with the LIRC interface
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	ir "github.com/djthorpe/gopi-hw/sys/ir"
+
+	// Modules
+	_ "github.com/djthorpe/gopi-hw/sys/lirc"
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Code to send, which is a protocol code or a key of a remote definition
+type Code struct {
+	Protocol ir.Protocol
+	Device   uint32
+	Scancode uint32
+	Remote   *ir.Remote
+	Key      string
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// parseProtocol returns a protocol from a name such as nec or sony12
+func parseProtocol(value string) (ir.Protocol, error) {
+	for protocol := ir.IR_PROTOCOL_NONE + 1; protocol <= ir.IR_PROTOCOL_MAX; protocol++ {
+		if strings.EqualFold(value, strings.TrimPrefix(protocol.String(), "IR_PROTOCOL_")) {
+			return protocol, nil
+		}
+	}
+	return ir.IR_PROTOCOL_NONE, fmt.Errorf("Invalid protocol: %v", value)
+}
+
+// readRemotes returns the remote definitions in a lircd.conf file
+func readRemotes(filename string) ([]*ir.Remote, error) {
+	if fh, err := os.Open(filename); err != nil {
+		return nil, err
+	} else {
+		defer fh.Close()
+		return ir.ParseRemotes(fh)
+	}
+}
+
+// parseKey returns a code from a key name such as KEY_POWER, or a remote
+// and key name such as tv:KEY_POWER
+func parseKey(value string, remotes []*ir.Remote) (*Code, error) {
+	name, key := "", value
+	if parts := strings.Split(value, ":"); len(parts) == 2 {
+		name, key = parts[0], parts[1]
+	}
+	for _, remote := range remotes {
+		if name != "" && remote.Name != name {
+			continue
+		} else if remote.Key(key) != nil {
+			return &Code{Remote: remote, Key: key}, nil
+		}
+	}
+	return nil, fmt.Errorf("Invalid key: %v", value)
+}
+
+// parseCode returns a code from a name such as nec:0x00:0x45, or a key
+// of a remote definition
+func parseCode(value string, remotes []*ir.Remote) (*Code, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 && len(remotes) > 0 {
+		return parseKey(value, remotes)
+	} else if len(parts) != 3 {
+		return nil, fmt.Errorf("Invalid code: %v (expected protocol:device:scancode)", value)
+	}
+	code := new(Code)
+	if protocol, err := parseProtocol(parts[0]); err != nil {
+		return nil, err
+	} else {
+		code.Protocol = protocol
+	}
+	if device, err := strconv.ParseUint(parts[1], 0, 32); err != nil {
+		return nil, fmt.Errorf("Invalid device: %v", parts[1])
+	} else {
+		code.Device = uint32(device)
+	}
+	if scancode, err := strconv.ParseUint(parts[2], 0, 32); err != nil {
+		return nil, fmt.Errorf("Invalid scancode: %v", parts[2])
+	} else {
+		code.Scancode = uint32(scancode)
+	}
+	return code, nil
+}
+
+func mainLoop(app *gopi.AppInstance, done chan<- struct{}) error {
+
+	if app.LIRC == nil {
+		return errors.New("Missing LIRC module")
+	}
+
+	// Read remote definitions
+	var remotes []*ir.Remote
+	if filename, _ := app.AppFlags.GetString("remote"); filename != "" {
+		var err error
+		if remotes, err = readRemotes(filename); err != nil {
+			return err
+		}
+	}
+
+	// Parse the codes to send
+	args := app.AppFlags.Args()
+	if len(args) == 0 {
+		return errors.New("Missing codes to send, such as nec:0x00:0x45")
+	}
+	codes := make([]*Code, len(args))
+	for i, arg := range args {
+		if code, err := parseCode(arg, remotes); err != nil {
+			return err
+		} else {
+			codes[i] = code
+		}
+	}
+
+	// Open the encoder
+	driver, err := gopi.Open(ir.Encoder{LIRC: app.LIRC}, app.Logger)
+	if err != nil {
+		return err
+	}
+	defer driver.Close()
+	encoder := driver.(ir.IREncoderInterface)
+
+	// Send the codes
+	repeats, _ := app.AppFlags.GetUint("repeat")
+	gap, _ := app.AppFlags.GetDuration("gap")
+	for _, code := range codes {
+		if code.Remote != nil {
+			if err := encoder.SendKey(code.Remote, code.Key, repeats, gap); err != nil {
+				return err
+			}
+		} else if err := encoder.Send(code.Protocol, code.Device, code.Scancode, repeats, gap); err != nil {
+			return err
+		}
+	}
+
+	// Finish gracefully
+	done <- gopi.DONE
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func main() {
+	// Create the configuration, load the lirc instance
+	config := gopi.NewAppConfig("lirc")
+
+	// Flags
+	config.AppFlags.FlagUint("repeat", 0, "Number of repeat frames after each code")
+	config.AppFlags.FlagDuration("gap", 0, "Gap between frames, or zero for the protocol frame period")
+	config.AppFlags.FlagString("remote", "", "Remote definitions in lircd.conf format, to send keys by name")
+
+	// Run the command line tool
+	os.Exit(gopi.CommandLineTool(config, mainLoop))
+}
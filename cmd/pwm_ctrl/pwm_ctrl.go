@@ -16,6 +16,7 @@ import (
 
 	// Frameworks
 	"github.com/djthorpe/gopi"
+	actuator "github.com/djthorpe/gopi-hw/sys/actuator"
 
 	// Modules
 	_ "github.com/djthorpe/gopi-hw/sys/gpio"
@@ -26,18 +27,73 @@ import (
 
 ////////////////////////////////////////////////////////////////////////////////
 
+// servo moves a servo to an angle
+func servo(app *gopi.AppInstance, pin gopi.GPIOPin, angle float64) error {
+	if driver, err := gopi.Open(actuator.Servo{PWM: app.PWM, Pin: pin}, app.Logger); err != nil {
+		return err
+	} else {
+		defer driver.Close()
+		if err := driver.(actuator.ServoDriver).SetAngle(float32(angle)); err != nil {
+			return err
+		}
+		fmt.Println(driver)
+	}
+
+	// Success
+	return nil
+}
+
+// fade fades an LED to a brightness, or breathes until interrupted
+func fade(app *gopi.AppInstance, pin gopi.GPIOPin, brightness float64) error {
+	duration, _ := app.AppFlags.GetDuration("fade")
+	breathe, _ := app.AppFlags.GetBool("breathe")
+	if driver, err := gopi.Open(actuator.LED{PWM: app.PWM, Pin: pin}, app.Logger); err != nil {
+		return err
+	} else {
+		defer driver.Close()
+		led := driver.(actuator.LEDDriver)
+		if breathe {
+			if err := led.Breathe(0, float32(brightness), duration); err != nil {
+				return err
+			}
+			fmt.Println("Breathing, press CTRL+C to stop")
+			app.WaitForSignal()
+		} else if err := led.Fade(float32(brightness), duration); err != nil {
+			return err
+		} else {
+			led.Wait()
+		}
+		fmt.Println(driver)
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
 func mainLoop(app *gopi.AppInstance, done chan<- struct{}) error {
 
 	if app.PWM == nil {
 		return app.Logger.Error("Missing PWM module instance")
 	}
 
-	if err := app.PWM.SetDutyCycle(0, 4); err != nil {
+	pin, _ := app.AppFlags.GetUint("pin")
+	if angle, exists := app.AppFlags.GetFloat64("servo"); exists {
+		if err := servo(app, gopi.GPIOPin(pin), angle); err != nil {
+			return err
+		}
+	} else if _, exists := app.AppFlags.GetDuration("fade"); exists {
+		brightness, _ := app.AppFlags.GetFloat64("brightness")
+		if err := fade(app, gopi.GPIOPin(pin), brightness); err != nil {
+			return err
+		}
+	} else if err := app.PWM.SetDutyCycle(0, gopi.GPIOPin(pin)); err != nil {
 		return err
+	} else {
+		fmt.Println(app.PWM)
 	}
 
-	fmt.Println(app.PWM)
-
 	// Finished
 	done <- gopi.DONE
 	return nil
@@ -48,6 +104,11 @@ func mainLoop(app *gopi.AppInstance, done chan<- struct{}) error {
 func main() {
 	// Create the configuration, load the spi instance
 	config := gopi.NewAppConfig("pwm")
+	config.AppFlags.FlagUint("pin", 4, "Pin to control")
+	config.AppFlags.FlagFloat64("servo", 90, "Move a servo to an angle in degrees")
+	config.AppFlags.FlagDuration("fade", 0, "Fade an LED to the brightness over a duration")
+	config.AppFlags.FlagFloat64("brightness", 1.0, "Brightness between 0.0 and 1.0 for -fade")
+	config.AppFlags.FlagBool("breathe", false, "Breathe the LED with -fade as the period until interrupted")
 
 	// Run the command line tool
 	os.Exit(gopi.CommandLineTool(config, mainLoop))
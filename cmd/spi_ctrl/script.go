@@ -0,0 +1,267 @@
+/*
+	Go Language Raspberry Pi Interface
+	(c) Copyright David Thorpe 2016-2018
+	All Rights Reserved
+	Documentation http://djthorpe.github.io/gopi/
+	For Licensing and Usage information, please see LICENSE.md
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	spi "github.com/djthorpe/gopi-hw/sys/spi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Step is a tx line of a script, with the delay after the data is sent
+// and the data expected to be received
+type Step struct {
+	Line   int
+	Tx     []byte
+	Delay  time.Duration
+	Expect []byte
+	Mask   []byte
+}
+
+// Transaction is a set of steps sent while the chip select is asserted,
+// or a pause between transactions when there are no steps
+type Transaction struct {
+	Steps []*Step
+	Pause time.Duration
+}
+
+// Script is a set of transactions, which is parsed from lines of the
+// following form:
+//
+//	tx <bytes>       Send bytes and receive the same number of bytes
+//	expect <bytes>   Check the bytes received by the last tx line,
+//	                 where xx matches any value
+//	delay <duration> Delay after the last tx line, or pause between
+//	                 transactions when there is no tx line before it
+//	cs               Release the chip select, ending the transaction
+//
+// Bytes are hex values separated by commas or spaces. Lines starting
+// with # are comments
+type Script []*Transaction
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Maximum delay after a segment
+	SCRIPT_DELAY_MAX = time.Duration(0xFFFF) * time.Microsecond
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PARSE
+
+// parseBytes returns bytes from hex values separated by commas or
+// spaces. Values may have a 0x prefix, and values without a prefix may
+// contain several bytes such as 9f000000
+func parseBytes(value string) ([]byte, error) {
+	data, mask, err := parsePattern(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mask {
+		if m == 0 {
+			return nil, fmt.Errorf("Invalid bytes: %v", value)
+		}
+	}
+	return data, nil
+}
+
+// parsePattern returns bytes and a mask from hex values, where the
+// value xx matches any byte and has a zero mask
+func parsePattern(value string) ([]byte, []byte, error) {
+	data, mask := []byte{}, []byte{}
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\r' || r == '\n'
+	})
+	for _, field := range fields {
+		field = strings.ToLower(field)
+		if field == "xx" {
+			data, mask = append(data, 0x00), append(mask, 0x00)
+		} else if strings.HasPrefix(field, "0x") {
+			if value, err := strconv.ParseUint(field[2:], 16, 8); err != nil {
+				return nil, nil, fmt.Errorf("Invalid byte: %v", field)
+			} else {
+				data, mask = append(data, byte(value)), append(mask, 0xFF)
+			}
+		} else if len(field) == 1 {
+			if value, err := strconv.ParseUint(field, 16, 8); err != nil {
+				return nil, nil, fmt.Errorf("Invalid byte: %v", field)
+			} else {
+				data, mask = append(data, byte(value)), append(mask, 0xFF)
+			}
+		} else if values, err := hex.DecodeString(field); err != nil {
+			return nil, nil, fmt.Errorf("Invalid bytes: %v", field)
+		} else {
+			data = append(data, values...)
+			mask = append(mask, bytes.Repeat([]byte{0xFF}, len(values))...)
+		}
+	}
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("Missing bytes")
+	}
+	return data, mask, nil
+}
+
+// parseScript returns a script from lines of text
+func parseScript(r io.Reader) (Script, error) {
+	script := Script{}
+	scanner := bufio.NewScanner(r)
+	transaction := (*Transaction)(nil)
+	step := (*Step)(nil)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		args := strings.SplitN(text, " ", 2)
+		command, arg := strings.ToLower(args[0]), ""
+		if len(args) > 1 {
+			arg = strings.TrimSpace(args[1])
+		}
+		switch command {
+		case "tx":
+			if data, err := parseBytes(arg); err != nil {
+				return nil, fmt.Errorf("Line %v: %v", line, err)
+			} else {
+				if transaction == nil {
+					transaction = &Transaction{}
+					script = append(script, transaction)
+				}
+				step = &Step{Line: line, Tx: data}
+				transaction.Steps = append(transaction.Steps, step)
+			}
+		case "expect":
+			if step == nil {
+				return nil, fmt.Errorf("Line %v: expect without tx", line)
+			} else if data, mask, err := parsePattern(arg); err != nil {
+				return nil, fmt.Errorf("Line %v: %v", line, err)
+			} else if len(data) > len(step.Tx) {
+				return nil, fmt.Errorf("Line %v: expect is longer than tx on line %v", line, step.Line)
+			} else {
+				step.Expect, step.Mask = data, mask
+			}
+		case "delay":
+			if delay, err := time.ParseDuration(arg); err != nil || delay < 0 {
+				return nil, fmt.Errorf("Line %v: Invalid delay: %v", line, arg)
+			} else if transaction == nil {
+				script = append(script, &Transaction{Pause: delay})
+			} else if delay > SCRIPT_DELAY_MAX {
+				return nil, fmt.Errorf("Line %v: delay after tx is longer than %v", line, SCRIPT_DELAY_MAX)
+			} else {
+				transaction.Steps[len(transaction.Steps)-1].Delay = delay
+			}
+		case "cs":
+			if arg != "" {
+				return nil, fmt.Errorf("Line %v: Unexpected arguments: %v", line, arg)
+			}
+			transaction, step = nil, nil
+		default:
+			return nil, fmt.Errorf("Line %v: Invalid command: %v", line, command)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return script, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// RUN
+
+// Run sends the steps of the transaction and returns the bytes received
+// for each step. Devices without multi-segment transfers send all steps
+// in a single transfer, and cannot delay between steps
+func (this *Transaction) Run(device gopi.SPI) ([][]byte, error) {
+	if len(this.Steps) == 0 {
+		time.Sleep(this.Pause)
+		return nil, nil
+	}
+	if device_, ok := device.(spi.SPIInterface); ok {
+		segments := make([]spi.SPISegment, len(this.Steps))
+		for i, step := range this.Steps {
+			segments[i] = spi.SPISegment{
+				Tx:    step.Tx,
+				Rx:    make([]byte, len(step.Tx)),
+				Delay: uint16(step.Delay / time.Microsecond),
+			}
+		}
+		if err := device_.TransferSegments(segments); err != nil {
+			return nil, err
+		}
+		recv := make([][]byte, len(segments))
+		for i := range segments {
+			recv[i] = segments[i].Rx
+		}
+		return recv, nil
+	}
+
+	// Concatenate the steps into a single transfer
+	send := []byte{}
+	for i, step := range this.Steps {
+		if step.Delay != 0 && i < len(this.Steps)-1 {
+			return nil, gopi.ErrNotImplemented
+		}
+		send = append(send, step.Tx...)
+	}
+	data, err := device.Transfer(send)
+	if err != nil {
+		return nil, err
+	} else if len(data) != len(send) {
+		return nil, gopi.ErrUnexpectedResponse
+	}
+	recv := make([][]byte, len(this.Steps))
+	for i, step := range this.Steps {
+		recv[i], data = data[:len(step.Tx)], data[len(step.Tx):]
+	}
+	if delay := this.Steps[len(this.Steps)-1].Delay; delay != 0 {
+		time.Sleep(delay)
+	}
+	return recv, nil
+}
+
+// Check returns an error if the received bytes do not match the
+// expected bytes of the step
+func (this *Step) Check(recv []byte) error {
+	if this.Expect == nil {
+		return nil
+	}
+	for i := range this.Expect {
+		if i >= len(recv) || recv[i]&this.Mask[i] != this.Expect[i]&this.Mask[i] {
+			return fmt.Errorf("Line %v: expected %v, received %v", this.Line, pattern(this.Expect, this.Mask), hex.EncodeToString(recv))
+		}
+	}
+	return nil
+}
+
+// pattern returns expected bytes as hex, with xx for any value
+func pattern(data, mask []byte) string {
+	values := ""
+	for i := range data {
+		if mask[i] == 0 {
+			values += "xx"
+		} else {
+			values += fmt.Sprintf("%02x", data[i])
+		}
+	}
+	return values
+}
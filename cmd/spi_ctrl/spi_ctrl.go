@@ -6,24 +6,134 @@
 	For Licensing and Usage information, please see LICENSE.md
 */
 
-// Reads bytes from the SPI interface
+// Reads, writes and transfers bytes on the SPI interface
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"strings"
 
 	// Frameworks
 	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi-hw/cmd/internal/output"
 	"github.com/olekukonko/tablewriter"
 
 	// Modules
-	_ "github.com/djthorpe/gopi-hw/sys/spi"
+	spi "github.com/djthorpe/gopi-hw/sys/spi"
 	_ "github.com/djthorpe/gopi/sys/logger"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
 
+var (
+	flag_names = map[string]spi.SPIModeFlag{
+		"cs_high":   spi.SPI_CS_HIGH,
+		"lsb_first": spi.SPI_LSB_FIRST,
+		"3wire":     spi.SPI_3WIRE,
+		"loop":      spi.SPI_LOOP,
+		"no_cs":     spi.SPI_NO_CS,
+		"ready":     spi.SPI_READY,
+		"tx_dual":   spi.SPI_TX_DUAL,
+		"tx_quad":   spi.SPI_TX_QUAD,
+		"rx_dual":   spi.SPI_RX_DUAL,
+		"rx_quad":   spi.SPI_RX_QUAD,
+	}
+)
+
+// parseFlags returns mode flags from comma-separated names
+func parseFlags(value string) (spi.SPIModeFlag, error) {
+	flags := spi.SPIModeFlag(0)
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.ToLower(strings.TrimSpace(name)); name == "" {
+			continue
+		} else if flag, exists := flag_names[name]; exists == false {
+			return 0, fmt.Errorf("Invalid -flags value: %v", name)
+		} else {
+			flags |= flag
+		}
+	}
+	return flags, nil
+}
+
+// loopback sends a pattern and checks the same pattern is received,
+// which requires MOSI connected to MISO or the loop flag
+func loopback(device gopi.SPI) error {
+	send := make([]byte, 0, 260)
+	for i := 0; i < 256; i++ {
+		send = append(send, byte(i))
+	}
+	send = append(send, 0xAA, 0x55, 0xFF, 0x00)
+	if recv, err := device.Transfer(send); err != nil {
+		return err
+	} else if bytes.Equal(send, recv) == false {
+		errors := 0
+		for i := range send {
+			if i >= len(recv) || send[i] != recv[i] {
+				errors++
+			}
+		}
+		return fmt.Errorf("Loopback failed: %v of %v bytes differ", errors, len(send))
+	} else {
+		fmt.Printf("Loopback passed: %v bytes\n", len(send))
+		return nil
+	}
+}
+
+// readTx returns bytes to send from a -tx value, or from stdin when the
+// value is -, as hex values or as raw bytes when binary is true
+func readTx(value string, stdin io.Reader, binary bool) ([]byte, error) {
+	if value != "-" {
+		return parseBytes(value)
+	} else if data, err := ioutil.ReadAll(stdin); err != nil {
+		return nil, err
+	} else if binary {
+		if len(data) == 0 {
+			return nil, fmt.Errorf("Missing bytes")
+		}
+		return data, nil
+	} else {
+		return parseBytes(string(data))
+	}
+}
+
+// printData writes received bytes, as a table for hex format
+func printData(data []byte, format output.Format) error {
+	if format == output.FORMAT_HEX {
+		return output.Table(os.Stdout, 0, data, nil)
+	} else {
+		fmt.Println(output.Bytes(data, format))
+		return nil
+	}
+}
+
+// transfer runs a script and outputs the received bytes. When single
+// is true there is one tx step and only the received bytes are written
+func transfer(device gopi.SPI, script Script, single bool, format output.Format) error {
+	for _, transaction := range script {
+		recv, err := transaction.Run(device)
+		if err != nil {
+			return err
+		}
+		for i, step := range transaction.Steps {
+			if single {
+				if err := printData(recv[i], format); err != nil {
+					return err
+				}
+			} else {
+				fmt.Printf("%3d: %v => %v\n", step.Line, output.Bytes(step.Tx, format), output.Bytes(recv[i], format))
+			}
+			if err := step.Check(recv[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func mainLoop(app *gopi.AppInstance, done chan<- struct{}) error {
 
 	if app.SPI == nil {
@@ -37,6 +147,17 @@ func mainLoop(app *gopi.AppInstance, done chan<- struct{}) error {
 		}
 	}
 
+	// Set extended mode flags
+	if value, exists := app.AppFlags.GetString("flags"); exists {
+		if device, ok := app.SPI.(spi.SPIInterface); ok == false {
+			return gopi.ErrNotImplemented
+		} else if flags, err := parseFlags(value); err != nil {
+			return err
+		} else if err := device.SetModeFlags(flags | spi.SPIModeFlag(app.SPI.Mode())); err != nil {
+			return err
+		}
+	}
+
 	// Set bits
 	if bits, exists := app.AppFlags.GetUint("bits"); exists {
 		if err := app.SPI.SetBitsPerWord(uint8(bits)); err != nil {
@@ -51,11 +172,77 @@ func mainLoop(app *gopi.AppInstance, done chan<- struct{}) error {
 		}
 	}
 
+	// Loopback self-test
+	if test, _ := app.AppFlags.GetBool("loopback"); test {
+		if err := loopback(app.SPI); err != nil {
+			return err
+		}
+		done <- gopi.DONE
+		return nil
+	}
+
+	// Output format
+	format, _ := app.AppFlags.GetString("format")
+	format_, err := output.ParseFormat(format)
+	if err != nil {
+		return err
+	}
+
+	// Read bytes and output them
+	if length, exists := app.AppFlags.GetUint("read"); exists && length > 0 {
+		if data, err := app.SPI.Read(uint32(length)); err != nil {
+			return err
+		} else if err := printData(data, format_); err != nil {
+			return err
+		}
+		done <- gopi.DONE
+		return nil
+	}
+
+	// Transfer bytes from -tx or a script file
+	script, single := Script(nil), false
+	if value, exists := app.AppFlags.GetString("tx"); exists {
+		binary, _ := app.AppFlags.GetBool("binary")
+		if data, err := readTx(value, os.Stdin, binary); err != nil {
+			return err
+		} else {
+			script, single = Script{&Transaction{Steps: []*Step{{Tx: data}}}}, true
+		}
+	} else if path, exists := app.AppFlags.GetString("script"); exists {
+		if fh, err := os.Open(path); err != nil {
+			return err
+		} else {
+			defer fh.Close()
+			if script, err = parseScript(fh); err != nil {
+				return fmt.Errorf("%v: %v", path, err)
+			}
+		}
+	}
+	if script != nil {
+		interval, _ := app.AppFlags.GetDuration("repeat")
+		count, _ := app.AppFlags.GetUint("count")
+		for i := uint(1); true; i++ {
+			if err := transfer(app.SPI, script, single, format_); err != nil {
+				return err
+			}
+			if interval == 0 || (count != 0 && i >= count) {
+				break
+			} else if app.WaitForSignalOrTimeout(interval) {
+				break
+			}
+		}
+		done <- gopi.DONE
+		return nil
+	}
+
 	// Read back values
 	table := tablewriter.NewWriter(os.Stdout)
 
 	table.SetHeader([]string{"Parameter", "Value"})
 	table.Append([]string{"mode", fmt.Sprint(app.SPI.Mode())})
+	if device, ok := app.SPI.(spi.SPIInterface); ok {
+		table.Append([]string{"flags", fmt.Sprint(device.ModeFlags())})
+	}
 	table.Append([]string{"bits_per_word", fmt.Sprint(app.SPI.BitsPerWord())})
 	table.Append([]string{"max_speed_hz", fmt.Sprint(app.SPI.MaxSpeedHz())})
 
@@ -75,6 +262,15 @@ func main() {
 	config.AppFlags.FlagUint("mode", 0, "Mode")
 	config.AppFlags.FlagUint("speed", 0, "Maximum speed, Hz")
 	config.AppFlags.FlagUint("bits", 8, "Bits per word")
+	config.AppFlags.FlagString("flags", "", "Mode flags (cs_high, lsb_first, 3wire, loop, no_cs, ready, tx_dual, tx_quad, rx_dual, rx_quad)")
+	config.AppFlags.FlagBool("loopback", false, "Transfer a pattern and check it is received")
+	config.AppFlags.FlagUint("read", 0, "Number of bytes to read")
+	config.AppFlags.FlagString("tx", "", "Bytes to send such as 0x9f,00,00,00, or - to read from stdin")
+	config.AppFlags.FlagBool("binary", false, "Read raw bytes from stdin rather than hex values")
+	config.AppFlags.FlagString("script", "", "Script file with cs, tx, delay and expect lines")
+	config.AppFlags.FlagDuration("repeat", 0, "Repeat the transfer at an interval")
+	config.AppFlags.FlagUint("count", 0, "Number of transfers when repeating, or zero to repeat until interrupted")
+	config.AppFlags.FlagString("format", "hex", "Output format (hex, dec, bin)")
 
 	// Run the command line tool
 	os.Exit(gopi.CommandLineTool(config, mainLoop))
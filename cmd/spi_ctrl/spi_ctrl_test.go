@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE SPI
+
+// echo returns the bytes sent, inverted
+type echo struct {
+	transfers int
+}
+
+func (this *echo) Close() error                { return nil }
+func (this *echo) Mode() gopi.SPIMode          { return gopi.SPI_MODE_0 }
+func (this *echo) MaxSpeedHz() uint32          { return 0 }
+func (this *echo) BitsPerWord() uint8          { return 8 }
+func (this *echo) SetMode(gopi.SPIMode) error  { return nil }
+func (this *echo) SetMaxSpeedHz(uint32) error  { return nil }
+func (this *echo) SetBitsPerWord(uint8) error  { return nil }
+func (this *echo) Read(uint32) ([]byte, error) { return nil, gopi.ErrNotImplemented }
+func (this *echo) Write([]byte) error          { return gopi.ErrNotImplemented }
+func (this *echo) Transfer(send []byte) ([]byte, error) {
+	this.transfers++
+	recv := make([]byte, len(send))
+	for i := range send {
+		recv[i] = ^send[i]
+	}
+	return recv, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestParse_000(t *testing.T) {
+	for value, expected := range map[string][]byte{
+		"0x9f,00,00,00":   {0x9F, 0x00, 0x00, 0x00},
+		"9f 0 0x1 ff":     {0x9F, 0x00, 0x01, 0xFF},
+		"9f000000\n":      {0x9F, 0x00, 0x00, 0x00},
+		"0xAB, 0xcd,\tEF": {0xAB, 0xCD, 0xEF},
+	} {
+		if data, err := parseBytes(value); err != nil {
+			t.Errorf("%q: %v", value, err)
+		} else if bytes.Equal(data, expected) == false {
+			t.Errorf("%q: unexpected bytes %v", value, data)
+		}
+	}
+	for _, value := range []string{"", "0x100", "9f0", "zz", "xx"} {
+		if _, err := parseBytes(value); err == nil {
+			t.Errorf("%q: expected error", value)
+		}
+	}
+	if data, err := readTx("-", strings.NewReader("\x9f\x00"), true); err != nil {
+		t.Error(err)
+	} else if bytes.Equal(data, []byte{0x9F, 0x00}) == false {
+		t.Error("Unexpected bytes", data)
+	}
+}
+
+func TestScript_000(t *testing.T) {
+	script, err := parseScript(strings.NewReader(`
+		# Read JEDEC ID
+		tx 9f
+		tx 00 00 00
+		expect ef xx 18
+		cs
+		delay 10ms
+		tx 0x05,0x00
+		delay 50us
+	`))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(script) != 3 {
+		t.Fatal("Unexpected transactions", len(script))
+	} else if len(script[0].Steps) != 2 || script[0].Steps[1].Line != 4 {
+		t.Error("Unexpected first transaction", script[0].Steps)
+	} else if len(script[1].Steps) != 0 || script[1].Pause.String() != "10ms" {
+		t.Error("Unexpected pause", script[1])
+	} else if len(script[2].Steps) != 1 || script[2].Steps[0].Delay.String() != "50µs" {
+		t.Error("Unexpected last transaction", script[2].Steps)
+	}
+
+	// Wildcards match any value
+	step := script[0].Steps[1]
+	if err := step.Check([]byte{0xEF, 0x40, 0x18}); err != nil {
+		t.Error(err)
+	} else if err := step.Check([]byte{0xEF, 0x40, 0x17}); err == nil {
+		t.Error("Expected mismatch")
+	}
+
+	// Steps are sent in a single transfer and split
+	device := &echo{}
+	if recv, err := script[0].Run(device); err != nil {
+		t.Error(err)
+	} else if device.transfers != 1 || len(recv) != 2 {
+		t.Error("Unexpected transfers", device.transfers, recv)
+	} else if bytes.Equal(recv[0], []byte{0x60}) == false || bytes.Equal(recv[1], []byte{0xFF, 0xFF, 0xFF}) == false {
+		t.Error("Unexpected received bytes", recv)
+	}
+}
+
+func TestScript_001(t *testing.T) {
+	for _, text := range []string{
+		"expect 00",
+		"tx 00\nexpect 00 00",
+		"tx 00\ndelay 1s",
+		"tx 00\ncs 1",
+		"tx 00\ncs\nexpect 00",
+		"rx 00",
+		"delay soon",
+	} {
+		if _, err := parseScript(strings.NewReader(text)); err == nil {
+			t.Errorf("%q: expected error", text)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package actuator
+
+import (
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
+// ServoDriver positions a servo by angle or pulse width
+type ServoDriver interface {
+	gopi.Driver
+
+	// Return and set the angle in degrees
+	Angle() (float32, error)
+	SetAngle(float32) error
+
+	// Return and set the pulse width in microseconds
+	Pulse() (uint, error)
+	SetPulse(uint) error
+
+	// Return and set the angles the servo is limited to
+	Limits() (float32, float32)
+	SetLimits(float32, float32) error
+
+	// Calibrate the pulse widths in microseconds for the minimum
+	// and maximum angles
+	Calibrate(uint, uint) error
+
+	// Return the duty cycle for an angle
+	DutyCycle(float32) float32
+}
+
+// LEDDriver sets the brightness of an LED and runs fades
+type LEDDriver interface {
+	gopi.Driver
+
+	// Return and set the brightness between 0.0 and 1.0, where
+	// setting the brightness stops any fade
+	Brightness() float32
+	SetBrightness(float32) error
+
+	// Fade to a brightness over a duration in the background
+	Fade(float32, time.Duration) error
+
+	// Breathe between minimum and maximum brightness with a period
+	// in the background, until stopped
+	Breathe(float32, float32, time.Duration) error
+
+	// Stop any fade or breathing
+	Stop()
+
+	// Wait for a fade to complete
+	Wait()
+}
@@ -0,0 +1,268 @@
+package actuator_test
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	actuator "github.com/djthorpe/gopi-hw/sys/actuator"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE PWM
+
+type fakepwm struct {
+	period     time.Duration
+	fixed      bool
+	duty_cycle map[gopi.GPIOPin]float32
+	writes     int
+	sync.Mutex
+}
+
+func newFakePWM(period time.Duration, fixed bool) *fakepwm {
+	return &fakepwm{period: period, fixed: fixed, duty_cycle: make(map[gopi.GPIOPin]float32)}
+}
+
+func (this *fakepwm) Close() error {
+	return nil
+}
+
+func (this *fakepwm) Pins() []gopi.GPIOPin {
+	return nil
+}
+
+func (this *fakepwm) Period(gopi.GPIOPin) (time.Duration, error) {
+	this.Lock()
+	defer this.Unlock()
+	return this.period, nil
+}
+
+func (this *fakepwm) SetPeriod(period time.Duration, pins ...gopi.GPIOPin) error {
+	this.Lock()
+	defer this.Unlock()
+	if this.fixed && period != this.period {
+		return gopi.ErrNotImplemented
+	}
+	this.period = period
+	return nil
+}
+
+func (this *fakepwm) DutyCycle(pin gopi.GPIOPin) (float32, error) {
+	this.Lock()
+	defer this.Unlock()
+	return this.duty_cycle[pin], nil
+}
+
+func (this *fakepwm) SetDutyCycle(duty_cycle float32, pins ...gopi.GPIOPin) error {
+	this.Lock()
+	defer this.Unlock()
+	if duty_cycle < 0 || duty_cycle > 1 {
+		return gopi.ErrBadParameter
+	}
+	for _, pin := range pins {
+		this.duty_cycle[pin] = duty_cycle
+	}
+	this.writes++
+	return nil
+}
+
+func (this *fakepwm) Writes() int {
+	this.Lock()
+	defer this.Unlock()
+	return this.writes
+}
+
+func openDriver(t *testing.T, config gopi.Config) gopi.Driver {
+	t.Helper()
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig()); err != nil {
+		t.Fatal(err)
+	} else if driver, err := gopi.Open(config, app.Logger); err != nil {
+		t.Fatal(err)
+	} else {
+		return driver
+	}
+	return nil
+}
+
+func approx(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-4
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SERVO
+
+func TestServo_000(t *testing.T) {
+	pwm := newFakePWM(0, false)
+	servo := openDriver(t, actuator.Servo{PWM: pwm, Pin: 18}).(actuator.ServoDriver)
+	defer servo.Close()
+
+	// Default period is 20ms, and 1000us to 2000us maps to 0 to 180 degrees
+	if pwm.period != 20*time.Millisecond {
+		t.Error("Unexpected period", pwm.period)
+	}
+	for angle, duty_cycle := range map[float32]float32{0: 0.05, 90: 0.075, 180: 0.1, -10: 0.05, 200: 0.1} {
+		if value := servo.DutyCycle(angle); approx(value, duty_cycle) == false {
+			t.Error("Unexpected duty cycle for", angle, value)
+		}
+	}
+	if err := servo.SetAngle(45); err != nil {
+		t.Error(err)
+	} else if pulse, err := servo.Pulse(); err != nil {
+		t.Error(err)
+	} else if pulse != 1250 {
+		t.Error("Unexpected pulse", pulse)
+	} else if angle, err := servo.Angle(); err != nil {
+		t.Error(err)
+	} else if approx(angle, 45) == false {
+		t.Error("Unexpected angle", angle)
+	}
+}
+
+func TestServo_001(t *testing.T) {
+	// The PWM cannot change period, so the existing period is used
+	pwm := newFakePWM(10*time.Millisecond, true)
+	servo := openDriver(t, actuator.Servo{PWM: pwm, Pin: 4, MinPulse: 500, MaxPulse: 2500, MinAngle: -90, MaxAngle: 90}).(actuator.ServoDriver)
+	defer servo.Close()
+
+	if value := servo.DutyCycle(0); approx(value, 0.15) == false {
+		t.Error("Unexpected duty cycle", value)
+	}
+
+	// Limit the angles
+	if err := servo.SetLimits(-45, 45); err != nil {
+		t.Error(err)
+	} else if value := servo.DutyCycle(-90); approx(value, 0.1) == false {
+		t.Error("Unexpected duty cycle", value)
+	} else if err := servo.SetLimits(-100, 0); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+
+	// Calibrate the pulse widths
+	if err := servo.Calibrate(600, 2400); err != nil {
+		t.Error(err)
+	} else if value := servo.DutyCycle(-45); approx(value, 0.105) == false {
+		t.Error("Unexpected duty cycle", value)
+	} else if err := servo.Calibrate(600, 10000); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+
+	// Set pulse directly
+	if err := servo.SetPulse(1000); err != nil {
+		t.Error(err)
+	} else if pulse, err := servo.Pulse(); err != nil {
+		t.Error(err)
+	} else if pulse != 1000 {
+		t.Error("Unexpected pulse", pulse)
+	}
+}
+
+func TestServo_002(t *testing.T) {
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig()); err != nil {
+		t.Fatal(err)
+	} else if _, err := gopi.Open(actuator.Servo{}, app.Logger); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if _, err := gopi.Open(actuator.Servo{PWM: newFakePWM(0, false), MinPulse: 2000, MaxPulse: 1000}, app.Logger); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if _, err := gopi.Open(actuator.Servo{PWM: newFakePWM(time.Millisecond, true)}, app.Logger); err == nil {
+		t.Error("Expected error when the pulse is longer than the period")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// LED
+
+func TestLED_000(t *testing.T) {
+	pwm := newFakePWM(time.Millisecond, false)
+	led := openDriver(t, actuator.LED{PWM: pwm, Pin: 18}).(actuator.LEDDriver)
+	defer led.Close()
+
+	// Gamma correction
+	if err := led.SetBrightness(0.5); err != nil {
+		t.Error(err)
+	} else if duty_cycle, _ := pwm.DutyCycle(18); approx(duty_cycle, float32(math.Pow(0.5, 2.2))) == false {
+		t.Error("Unexpected duty cycle", duty_cycle)
+	} else if brightness := led.Brightness(); brightness != 0.5 {
+		t.Error("Unexpected brightness", brightness)
+	} else if err := led.SetBrightness(1.5); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+
+	// Reopen reads the brightness back
+	led2 := openDriver(t, actuator.LED{PWM: pwm, Pin: 18, Gamma: 2.2}).(actuator.LEDDriver)
+	defer led2.Close()
+	if brightness := led2.Brightness(); approx(brightness, 0.5) == false {
+		t.Error("Unexpected brightness", brightness)
+	}
+}
+
+func TestLED_001(t *testing.T) {
+	pwm := newFakePWM(time.Millisecond, false)
+	led := openDriver(t, actuator.LED{PWM: pwm, Pin: 18, Gamma: 1, Interval: time.Millisecond}).(actuator.LEDDriver)
+	defer led.Close()
+
+	// Fade up and wait for it to complete
+	if err := led.Fade(1, 20*time.Millisecond); err != nil {
+		t.Error(err)
+	}
+	led.Wait()
+	if brightness := led.Brightness(); brightness != 1 {
+		t.Error("Unexpected brightness", brightness)
+	} else if duty_cycle, _ := pwm.DutyCycle(18); duty_cycle != 1 {
+		t.Error("Unexpected duty cycle", duty_cycle)
+	} else if writes := pwm.Writes(); writes < 2 {
+		t.Error("Expected fade in steps, got", writes, "writes")
+	}
+
+	// Fade down but cancel the fade by setting the brightness
+	if err := led.Fade(0, time.Hour); err != nil {
+		t.Error(err)
+	} else if err := led.SetBrightness(0.25); err != nil {
+		t.Error(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if brightness := led.Brightness(); brightness != 0.25 {
+		t.Error("Unexpected brightness after cancelling fade", brightness)
+	}
+}
+
+func TestLED_002(t *testing.T) {
+	pwm := newFakePWM(time.Millisecond, false)
+	led := openDriver(t, actuator.LED{PWM: pwm, Pin: 18, Gamma: 1, Interval: time.Millisecond}).(actuator.LEDDriver)
+
+	// Breathe until stopped, and check brightness stays within range
+	if err := led.Breathe(0.2, 0.8, 10*time.Millisecond); err != nil {
+		t.Error(err)
+	}
+	for i := 0; i < 20; i++ {
+		if brightness := led.Brightness(); brightness < 0.2-1e-4 || brightness > 0.8+1e-4 {
+			t.Error("Brightness out of range", brightness)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	led.Stop()
+	writes := pwm.Writes()
+	time.Sleep(5 * time.Millisecond)
+	if pwm.Writes() != writes {
+		t.Error("Expected no writes after stopping")
+	}
+
+	// Bad parameters
+	if err := led.Breathe(0.8, 0.2, time.Second); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if err := led.Fade(2, time.Second); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+
+	// Close stops breathing
+	if err := led.Breathe(0, 1, time.Second); err != nil {
+		t.Error(err)
+	} else if err := led.Close(); err != nil {
+		t.Error(err)
+	}
+}
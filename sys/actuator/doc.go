@@ -0,0 +1,26 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+// This package provides servo and LED helpers which work on top of any
+// PWM driver.
+//
+// A servo is positioned by the width of a pulse, usually between 1ms and
+// 2ms, repeated every 20ms. The Servo driver converts an angle into a
+// duty cycle, and the pulse widths and angle limits can be calibrated for
+// each servo:
+//
+//    servo, err := gopi.Open(actuator.Servo{ PWM: app.PWM, Pin: 18 }, app.Logger)
+//    servo.(actuator.ServoDriver).SetAngle(90)
+//
+// The LED driver sets gamma-corrected brightness, so that a brightness
+// of 0.5 looks half as bright as 1.0, and runs fades and "breathing" in
+// a background goroutine which is cancelled by setting the brightness,
+// starting another fade or closing the driver.
+//
+package actuator
@@ -0,0 +1,272 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package actuator
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// LED is the configuration for an LED on a PWM pin
+type LED struct {
+	// PWM driver and pin
+	PWM gopi.PWM
+	Pin gopi.GPIOPin
+
+	// Gamma used to convert brightness to duty cycle, defaults to 2.2
+	Gamma float64
+
+	// Period, which is left unchanged when zero
+	Period time.Duration
+
+	// Interval between steps of a fade, defaults to 20ms
+	Interval time.Duration
+}
+
+type led struct {
+	log        gopi.Logger
+	pwm        gopi.PWM
+	pin        gopi.GPIOPin
+	gamma      float64
+	interval   time.Duration
+	brightness float32
+	stop       chan struct{}
+	done       chan struct{}
+	sync.Mutex
+}
+
+// led_step returns the brightness for the time since the fade started,
+// and whether the fade has finished
+type led_step func(elapsed time.Duration) (float32, bool)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	LED_GAMMA    = 2.2
+	LED_INTERVAL = 20 * time.Millisecond
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open
+func (config LED) Open(logger gopi.Logger) (gopi.Driver, error) {
+	logger.Debug("<hw.actuator.led>Open{ pin=%v gamma=%v period=%v interval=%v }", config.Pin, config.Gamma, config.Period, config.Interval)
+
+	this := new(led)
+	this.log = logger
+
+	// PWM driver is required
+	if config.PWM == nil {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.pwm = config.PWM
+		this.pin = config.Pin
+	}
+
+	// Set gamma and interval
+	if config.Gamma < 0 || config.Interval < 0 {
+		return nil, gopi.ErrBadParameter
+	}
+	if this.gamma = config.Gamma; this.gamma == 0 {
+		this.gamma = LED_GAMMA
+	}
+	if this.interval = config.Interval; this.interval == 0 {
+		this.interval = LED_INTERVAL
+	}
+
+	// Set the period
+	if config.Period != 0 {
+		if err := this.pwm.SetPeriod(config.Period, this.pin); err != nil {
+			return nil, err
+		}
+	}
+
+	// Read the current brightness
+	if duty_cycle, err := this.pwm.DutyCycle(this.pin); err != nil {
+		return nil, err
+	} else {
+		this.brightness = float32(math.Pow(float64(duty_cycle), 1/this.gamma))
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close
+func (this *led) Close() error {
+	this.log.Debug("<hw.actuator.led>Close{ pin=%v }", this.pin)
+
+	// Stop any fade
+	this.Stop()
+
+	// Zero out member variables
+	this.pwm = nil
+
+	// Return success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *led) String() string {
+	return fmt.Sprintf("<hw.actuator.led>{ pin=%v gamma=%v brightness=%v }", this.pin, this.gamma, this.Brightness())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// BRIGHTNESS
+
+func (this *led) Brightness() float32 {
+	this.Lock()
+	defer this.Unlock()
+	return this.brightness
+}
+
+// SetBrightness stops any fade and sets the brightness
+func (this *led) SetBrightness(brightness float32) error {
+	this.log.Debug2("<hw.actuator.led>SetBrightness{ pin=%v brightness=%v }", this.pin, brightness)
+
+	if brightness < 0 || brightness > 1 {
+		return gopi.ErrBadParameter
+	} else {
+		this.Stop()
+		return this.setBrightness(brightness)
+	}
+}
+
+// Fade stops any existing fade and starts fading from the current
+// brightness to another brightness in the background
+func (this *led) Fade(brightness float32, duration time.Duration) error {
+	this.log.Debug2("<hw.actuator.led>Fade{ pin=%v brightness=%v duration=%v }", this.pin, brightness, duration)
+
+	if brightness < 0 || brightness > 1 || duration < 0 {
+		return gopi.ErrBadParameter
+	}
+
+	this.Stop()
+	from := this.Brightness()
+	return this.start(func(elapsed time.Duration) (float32, bool) {
+		if elapsed >= duration {
+			return brightness, true
+		} else {
+			fraction := float32(elapsed) / float32(duration)
+			return from + (brightness-from)*fraction, false
+		}
+	})
+}
+
+// Breathe stops any existing fade and starts breathing between a
+// minimum and maximum brightness in the background
+func (this *led) Breathe(min, max float32, period time.Duration) error {
+	this.log.Debug2("<hw.actuator.led>Breathe{ pin=%v min=%v max=%v period=%v }", this.pin, min, max, period)
+
+	if min < 0 || max > 1 || min > max || period <= 0 {
+		return gopi.ErrBadParameter
+	}
+
+	this.Stop()
+	return this.start(func(elapsed time.Duration) (float32, bool) {
+		phase := 2 * math.Pi * float64(elapsed) / float64(period)
+		return min + (max-min)*float32(1-math.Cos(phase))/2, false
+	})
+}
+
+// Stop cancels any fade or breathing and waits for the goroutine to end
+func (this *led) Stop() {
+	this.Lock()
+	stop, done := this.stop, this.done
+	this.stop, this.done = nil, nil
+	this.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// Wait blocks until a fade has completed or has been stopped. When
+// breathing, Wait blocks until Stop is called
+func (this *led) Wait() {
+	this.Lock()
+	done := this.done
+	this.Unlock()
+
+	if done != nil {
+		<-done
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// start sets the first step of a fade and then runs the fade in the
+// background, setting the brightness every interval until the fade has
+// finished or is stopped
+func (this *led) start(step led_step) error {
+	start := time.Now()
+	if brightness, finished := step(0); finished {
+		return this.setBrightness(brightness)
+	} else if err := this.setBrightness(brightness); err != nil {
+		return err
+	}
+
+	stop, done := make(chan struct{}), make(chan struct{})
+	this.Lock()
+	this.stop, this.done = stop, done
+	this.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(this.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				brightness, finished := step(time.Since(start))
+				if err := this.setBrightness(brightness); err != nil {
+					this.log.Warn("<hw.actuator.led>Fade: %v", err)
+					return
+				} else if finished {
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+
+	// Success
+	return nil
+}
+
+// setBrightness sets the gamma-corrected duty cycle for a brightness
+func (this *led) setBrightness(brightness float32) error {
+	duty_cycle := float32(math.Pow(float64(brightness), this.gamma))
+	if err := this.pwm.SetDutyCycle(duty_cycle, this.pin); err != nil {
+		return err
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	this.brightness = brightness
+
+	// Success
+	return nil
+}
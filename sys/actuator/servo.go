@@ -0,0 +1,261 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package actuator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Servo is the configuration for a servo on a PWM pin
+type Servo struct {
+	// PWM driver and pin
+	PWM gopi.PWM
+	Pin gopi.GPIOPin
+
+	// Period between pulses, defaults to 20ms. When the PWM driver
+	// cannot set the period, the existing period is used
+	Period time.Duration
+
+	// Pulse widths in microseconds at the minimum and maximum
+	// angles, which default to 1000 and 2000
+	MinPulse uint
+	MaxPulse uint
+
+	// Minimum and maximum angles in degrees, which default to
+	// 0 and 180
+	MinAngle float32
+	MaxAngle float32
+}
+
+type servo struct {
+	log       gopi.Logger
+	pwm       gopi.PWM
+	pin       gopi.GPIOPin
+	period    time.Duration
+	min_pulse uint
+	max_pulse uint
+	min_angle float32
+	max_angle float32
+	lower     float32
+	upper     float32
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	SERVO_PERIOD    = 20 * time.Millisecond
+	SERVO_MIN_PULSE = 1000
+	SERVO_MAX_PULSE = 2000
+	SERVO_MAX_ANGLE = 180
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open
+func (config Servo) Open(logger gopi.Logger) (gopi.Driver, error) {
+	logger.Debug("<hw.actuator.servo>Open{ pin=%v period=%v min_pulse=%v max_pulse=%v }", config.Pin, config.Period, config.MinPulse, config.MaxPulse)
+
+	this := new(servo)
+	this.log = logger
+
+	// PWM driver is required
+	if config.PWM == nil {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.pwm = config.PWM
+		this.pin = config.Pin
+	}
+
+	// Set pulse widths and angles
+	this.min_pulse, this.max_pulse = config.MinPulse, config.MaxPulse
+	if this.min_pulse == 0 && this.max_pulse == 0 {
+		this.min_pulse, this.max_pulse = SERVO_MIN_PULSE, SERVO_MAX_PULSE
+	}
+	this.min_angle, this.max_angle = config.MinAngle, config.MaxAngle
+	if this.min_angle == 0 && this.max_angle == 0 {
+		this.max_angle = SERVO_MAX_ANGLE
+	}
+	if this.min_pulse >= this.max_pulse || this.min_angle >= this.max_angle {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.lower, this.upper = this.min_angle, this.max_angle
+	}
+
+	// Set the period, or use the existing period if it cannot be set
+	period := config.Period
+	if period == 0 {
+		period = SERVO_PERIOD
+	}
+	if err := this.pwm.SetPeriod(period, this.pin); err != nil {
+		this.log.Warn("<hw.actuator.servo>Open: %v", err)
+		if period, err := this.pwm.Period(this.pin); err != nil {
+			return nil, err
+		} else {
+			this.period = period
+		}
+	} else {
+		this.period = period
+	}
+
+	// The longest pulse must fit within the period
+	if this.pulseDuration(this.max_pulse) >= this.period {
+		return nil, fmt.Errorf("<hw.actuator.servo>Open: Pulse of %vus is longer than period of %v", this.max_pulse, this.period)
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close
+func (this *servo) Close() error {
+	this.log.Debug("<hw.actuator.servo>Close{ pin=%v }", this.pin)
+
+	// Zero out member variables
+	this.pwm = nil
+
+	// Return success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *servo) String() string {
+	return fmt.Sprintf("<hw.actuator.servo>{ pin=%v period=%v pulse=%vus-%vus angle=%v-%v limits=%v-%v }", this.pin, this.period, this.min_pulse, this.max_pulse, this.min_angle, this.max_angle, this.lower, this.upper)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ANGLE AND PULSE
+
+// Angle returns the current angle, derived from the duty cycle
+func (this *servo) Angle() (float32, error) {
+	if pulse, err := this.Pulse(); err != nil {
+		return 0, err
+	} else {
+		this.Lock()
+		defer this.Unlock()
+		return this.angleForPulse(pulse), nil
+	}
+}
+
+// SetAngle moves the servo to an angle, which is clamped to the limits
+func (this *servo) SetAngle(angle float32) error {
+	this.log.Debug2("<hw.actuator.servo>SetAngle{ pin=%v angle=%v }", this.pin, angle)
+	return this.pwm.SetDutyCycle(this.DutyCycle(angle), this.pin)
+}
+
+// Pulse returns the current pulse width in microseconds
+func (this *servo) Pulse() (uint, error) {
+	if duty_cycle, err := this.pwm.DutyCycle(this.pin); err != nil {
+		return 0, err
+	} else {
+		this.Lock()
+		defer this.Unlock()
+		return uint(float64(duty_cycle)*float64(this.period/time.Microsecond) + 0.5), nil
+	}
+}
+
+// SetPulse sets the pulse width in microseconds, which can be used to
+// find the pulse widths for calibration
+func (this *servo) SetPulse(pulse uint) error {
+	this.log.Debug2("<hw.actuator.servo>SetPulse{ pin=%v pulse=%vus }", this.pin, pulse)
+	this.Lock()
+	defer this.Unlock()
+
+	if this.pulseDuration(pulse) >= this.period {
+		return gopi.ErrBadParameter
+	} else {
+		return this.pwm.SetDutyCycle(this.dutyCycleForPulse(float64(pulse)), this.pin)
+	}
+}
+
+// DutyCycle returns the duty cycle for an angle, which is clamped
+// to the limits
+func (this *servo) DutyCycle(angle float32) float32 {
+	this.Lock()
+	defer this.Unlock()
+
+	if angle < this.lower {
+		angle = this.lower
+	} else if angle > this.upper {
+		angle = this.upper
+	}
+	return this.dutyCycleForPulse(this.pulseForAngle(angle))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CALIBRATION
+
+// Limits returns the lower and upper angles the servo can be set to
+func (this *servo) Limits() (float32, float32) {
+	this.Lock()
+	defer this.Unlock()
+	return this.lower, this.upper
+}
+
+// SetLimits restricts the angles the servo can be set to, for example
+// where the servo is mechanically prevented from moving further
+func (this *servo) SetLimits(lower, upper float32) error {
+	this.Lock()
+	defer this.Unlock()
+
+	if lower > upper || lower < this.min_angle || upper > this.max_angle {
+		return gopi.ErrBadParameter
+	} else {
+		this.lower, this.upper = lower, upper
+		return nil
+	}
+}
+
+// Calibrate sets the pulse widths in microseconds for the minimum
+// and maximum angles
+func (this *servo) Calibrate(min_pulse, max_pulse uint) error {
+	this.Lock()
+	defer this.Unlock()
+
+	if min_pulse >= max_pulse || this.pulseDuration(max_pulse) >= this.period {
+		return gopi.ErrBadParameter
+	} else {
+		this.min_pulse, this.max_pulse = min_pulse, max_pulse
+		return nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *servo) pulseForAngle(angle float32) float64 {
+	fraction := float64(angle-this.min_angle) / float64(this.max_angle-this.min_angle)
+	return float64(this.min_pulse) + fraction*float64(this.max_pulse-this.min_pulse)
+}
+
+func (this *servo) angleForPulse(pulse uint) float32 {
+	fraction := (float64(pulse) - float64(this.min_pulse)) / float64(this.max_pulse-this.min_pulse)
+	return this.min_angle + float32(fraction)*(this.max_angle-this.min_angle)
+}
+
+func (this *servo) dutyCycleForPulse(pulse float64) float32 {
+	return float32(pulse * float64(time.Microsecond) / float64(this.period))
+}
+
+func (this *servo) pulseDuration(pulse uint) time.Duration {
+	return time.Duration(pulse) * time.Microsecond
+}
@@ -135,7 +135,14 @@ func (this *fsnotify) emit(evt hw.FSEvent) {
 	root := evt.Root()
 	if root == "" || evt.Flags() == hw.FS_FLAG_NONE {
 		// Ignore events with no flags or root
-	} else if _, exists := this.watches[root]; exists {
+	} else if this.is_watched(root) {
 		this.Emit(evt)
 	}
 }
+
+func (this *fsnotify) is_watched(root string) bool {
+	this.Lock()
+	defer this.Unlock()
+	_, exists := this.watches[root]
+	return exists
+}
@@ -0,0 +1,501 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package fsnotify
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	hw "github.com/djthorpe/gopi-hw"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type fsnotify_impl struct {
+	log      gopi.Logger
+	fd       int
+	file     *os.File
+	paths    map[int32]string // map of watch descriptors to folder paths
+	watches  []*inotify_watch
+	moves    map[uint32]*inotify_move // unmatched IN_MOVED_FROM events by cookie
+	callback func(hw.FSEvent)
+	done     chan struct{}
+	lock     sync.Mutex
+}
+
+// inotify_watch is returned from watch and represents a
+// recursive watch on a root folder
+type inotify_watch struct {
+	root string
+	wds  map[int32]bool
+}
+
+// inotify_event is a decoded inotify event
+type inotify_event struct {
+	wd     int32
+	mask   uint32
+	cookie uint32
+	name   string
+}
+
+// inotify_move is an IN_MOVED_FROM event which is waiting for the
+// IN_MOVED_TO event with the same cookie
+type inotify_move struct {
+	evt   *inotify_event
+	path  string
+	ts    time.Time
+	timer *time.Timer
+}
+
+type fsevent_impl struct {
+	ts    time.Time
+	root  string
+	path  string
+	from  string
+	flags hw.FSFlag
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Events which are watched on every folder
+	INOTIFY_MASK = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_DELETE_SELF |
+		syscall.IN_MODIFY | syscall.IN_ATTRIB | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+		syscall.IN_MOVE_SELF | syscall.IN_ONLYDIR
+	// Size of the read buffer, enough for 64 events with maximum length names
+	INOTIFY_BUFFER_SIZE = 64 * (syscall.SizeofInotifyEvent + syscall.NAME_MAX + 1)
+	// Time to wait for the IN_MOVED_TO event which pairs with an
+	// IN_MOVED_FROM event, which may be in a later read
+	INOTIFY_MOVE_EXPIRY = 50 * time.Millisecond
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// WATCH AND UNWATCH CONCRETE IMPLEMENTATION
+
+func (this *fsnotify_impl) init(callback func(hw.FSEvent), log gopi.Logger) error {
+	this.log = log
+	this.callback = callback
+	this.paths = make(map[int32]string)
+	this.watches = make([]*inotify_watch, 0)
+	this.moves = make(map[uint32]*inotify_move)
+	this.done = make(chan struct{})
+
+	// Create a non-blocking inotify handle so that closing the file
+	// will return from any pending read. Fd() is not called on the file
+	// since it would put the handle back into blocking mode
+	if fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK); err != nil {
+		return os.NewSyscallError("inotify_init1", err)
+	} else {
+		this.fd = fd
+		this.file = os.NewFile(uintptr(fd), "inotify")
+	}
+
+	// Read events in the background
+	go this.read()
+
+	return nil
+}
+
+func (this *fsnotify_impl) close() error {
+	// Closing the file ends the background read
+	err := this.file.Close()
+	<-this.done
+
+	// Release resources
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	for _, move := range this.moves {
+		move.timer.Stop()
+	}
+	this.paths = nil
+	this.watches = nil
+	this.moves = nil
+
+	return err
+}
+
+func (this *fsnotify_impl) watch(path string) (interface{}, error) {
+	if stat, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, gopi.ErrNotFound
+	} else if err != nil {
+		return nil, err
+	} else if stat.IsDir() == false {
+		return nil, gopi.ErrBadParameter
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	watch := &inotify_watch{path, make(map[int32]bool)}
+	if err := this.add_recursive(watch, path); err != nil {
+		this.remove_watch(watch)
+		return nil, err
+	} else {
+		this.watches = append(this.watches, watch)
+		return watch, nil
+	}
+}
+
+func (this *fsnotify_impl) unwatch(watch interface{}) error {
+	if watch == nil {
+		return gopi.ErrBadParameter
+	} else if watch_, ok := watch.(*inotify_watch); ok == false {
+		return gopi.ErrAppError
+	} else {
+		this.lock.Lock()
+		defer this.lock.Unlock()
+		for i, other := range this.watches {
+			if other == watch_ {
+				this.watches = append(this.watches[:i], this.watches[i+1:]...)
+				break
+			}
+		}
+		return this.remove_watch(watch_)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - WATCH DESCRIPTORS
+
+// add_recursive adds watch descriptors for a folder and all folders
+// underneath it
+func (this *fsnotify_impl) add_recursive(watch *inotify_watch, path string) error {
+	return filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// Folders can disappear whilst walking
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		} else if info.IsDir() == false {
+			return nil
+		} else if wd, err := syscall.InotifyAddWatch(this.fd, path, INOTIFY_MASK); err != nil {
+			return os.NewSyscallError("inotify_add_watch", err)
+		} else {
+			this.paths[int32(wd)] = path
+			watch.wds[int32(wd)] = true
+			return nil
+		}
+	})
+}
+
+// remove_watch removes the watch descriptors for a root, when no other root
+// shares the watch descriptor
+func (this *fsnotify_impl) remove_watch(watch *inotify_watch) error {
+	var result error
+	for wd := range watch.wds {
+		delete(watch.wds, wd)
+		if this.is_shared(wd) {
+			continue
+		}
+		delete(this.paths, wd)
+		if _, err := syscall.InotifyRmWatch(this.fd, uint32(wd)); err != nil && err != syscall.EINVAL {
+			result = os.NewSyscallError("inotify_rm_watch", err)
+		}
+	}
+	return result
+}
+
+// remove_path removes watch descriptors for a folder which no longer
+// exists (or has moved outside of the watched trees) and all its subfolders
+func (this *fsnotify_impl) remove_path(path string) {
+	for wd, other := range this.paths {
+		if other != path && strings.HasPrefix(other, path+string(filepath.Separator)) == false {
+			continue
+		}
+		delete(this.paths, wd)
+		for _, watch := range this.watches {
+			delete(watch.wds, wd)
+		}
+		syscall.InotifyRmWatch(this.fd, uint32(wd))
+	}
+}
+
+// rename_path changes the paths of a folder and all its subfolders
+// after a rename within a watched tree
+func (this *fsnotify_impl) rename_path(from, to string) {
+	for wd, other := range this.paths {
+		if other == from {
+			this.paths[wd] = to
+		} else if strings.HasPrefix(other, from+string(filepath.Separator)) {
+			this.paths[wd] = filepath.Join(to, strings.TrimPrefix(other, from))
+		}
+	}
+}
+
+// is_shared returns true if any root is watching a watch descriptor
+func (this *fsnotify_impl) is_shared(wd int32) bool {
+	for _, watch := range this.watches {
+		if _, exists := watch.wds[wd]; exists {
+			return true
+		}
+	}
+	return false
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - EVENTS
+
+func (this *fsnotify_impl) read() {
+	buf := make([]byte, INOTIFY_BUFFER_SIZE)
+	for {
+		if n, err := this.file.Read(buf); err != nil {
+			if pe, ok := err.(*os.PathError); ok && pe.Err == os.ErrClosed {
+				// File was closed
+			} else if err != os.ErrClosed {
+				this.log.Error("fsnotify: %v", err)
+			}
+			break
+		} else if events := inotify_decode(buf[:n]); len(events) > 0 {
+			// Determine events whilst locked and then emit them
+			this.lock.Lock()
+			emit := this.process(events)
+			this.lock.Unlock()
+			for _, evt := range emit {
+				this.callback(evt)
+			}
+		}
+	}
+	close(this.done)
+}
+
+// process converts inotify events into file system events, updating
+// the watched folders as needed
+func (this *fsnotify_impl) process(events []*inotify_event) []hw.FSEvent {
+	emit := make([]hw.FSEvent, 0, len(events))
+	ts := time.Now()
+	for _, evt := range events {
+		if evt.mask&syscall.IN_Q_OVERFLOW != 0 {
+			this.log.Warn("fsnotify: Event queue overflow")
+			continue
+		}
+		if evt.mask&syscall.IN_IGNORED != 0 {
+			// Watch has been removed by the kernel
+			this.remove_wd(evt.wd)
+			continue
+		}
+		folder, exists := this.paths[evt.wd]
+		if exists == false {
+			continue
+		}
+		path := folder
+		if evt.name != "" {
+			path = filepath.Join(folder, evt.name)
+		}
+
+		// Keep IN_MOVED_FROM until the IN_MOVED_TO with the same cookie,
+		// which is paired with it into a single rename event
+		from := ""
+		if evt.mask&syscall.IN_MOVED_FROM != 0 {
+			this.add_move(evt, path, ts)
+			continue
+		} else if evt.mask&syscall.IN_MOVED_TO != 0 {
+			if move, exists := this.moves[evt.cookie]; exists {
+				move.timer.Stop()
+				delete(this.moves, evt.cookie)
+				from = move.path
+			}
+		}
+
+		emit = append(emit, this.event(evt, path, from, ts)...)
+	}
+	return emit
+}
+
+// event updates the watched folders for an event, and returns a file
+// system event for each root watching the folder
+func (this *fsnotify_impl) event(evt *inotify_event, path, from string, ts time.Time) []hw.FSEvent {
+	// Update the watched folders
+	flags := inotify_flags(evt.mask, path)
+	if evt.mask&syscall.IN_ISDIR != 0 {
+		switch {
+		case evt.mask&syscall.IN_CREATE != 0:
+			this.add_folder(evt.wd, path)
+		case evt.mask&syscall.IN_MOVED_TO != 0 && from != "":
+			this.rename_path(from, path)
+		case evt.mask&syscall.IN_MOVED_TO != 0:
+			this.add_folder(evt.wd, path)
+		case evt.mask&syscall.IN_MOVED_FROM != 0:
+			this.remove_path(path)
+		}
+	}
+
+	// Create an event for each root watching the folder. Changes to
+	// subfolders themselves are reported through the parent folder
+	emit := make([]hw.FSEvent, 0, 1)
+	self := evt.mask&(syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0
+	for _, watch := range this.watches {
+		if self && watch.root != path {
+			continue
+		} else if _, exists := watch.wds[evt.wd]; exists {
+			emit = append(emit, &fsevent_impl{ts, watch.root, path, from, flags})
+		}
+	}
+	return emit
+}
+
+// add_move keeps an IN_MOVED_FROM event until the IN_MOVED_TO event
+// with the same cookie, or until it expires when the move was to
+// outside of the watched folders
+func (this *fsnotify_impl) add_move(evt *inotify_event, path string, ts time.Time) {
+	cookie := evt.cookie
+	if move, exists := this.moves[cookie]; exists {
+		move.timer.Stop()
+	}
+	move := &inotify_move{evt: evt, path: path, ts: ts}
+	move.timer = time.AfterFunc(INOTIFY_MOVE_EXPIRY, func() {
+		this.expire_move(cookie, move)
+	})
+	this.moves[cookie] = move
+}
+
+// expire_move emits an IN_MOVED_FROM event which has not been paired
+// with an IN_MOVED_TO event
+func (this *fsnotify_impl) expire_move(cookie uint32, move *inotify_move) {
+	this.lock.Lock()
+	if other, exists := this.moves[cookie]; exists == false || other != move {
+		this.lock.Unlock()
+		return
+	}
+	delete(this.moves, cookie)
+	emit := this.event(move.evt, move.path, "", move.ts)
+	this.lock.Unlock()
+	for _, evt := range emit {
+		this.callback(evt)
+	}
+}
+
+// add_folder adds a new folder to every root which is watching the parent
+func (this *fsnotify_impl) add_folder(parent int32, path string) {
+	for _, watch := range this.watches {
+		if _, exists := watch.wds[parent]; exists {
+			if err := this.add_recursive(watch, path); err != nil {
+				this.log.Warn("fsnotify: %v", err)
+			}
+		}
+	}
+}
+
+// remove_wd removes a watch descriptor which the kernel has removed
+func (this *fsnotify_impl) remove_wd(wd int32) {
+	delete(this.paths, wd)
+	for _, watch := range this.watches {
+		delete(watch.wds, wd)
+	}
+}
+
+func inotify_decode(buf []byte) []*inotify_event {
+	events := make([]*inotify_event, 0)
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buf); {
+		raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		name_offset := offset + syscall.SizeofInotifyEvent
+		name_end := name_offset + int(raw.Len)
+		if name_end > len(buf) {
+			break
+		}
+		events = append(events, &inotify_event{
+			wd:     raw.Wd,
+			mask:   raw.Mask,
+			cookie: raw.Cookie,
+			name:   strings.TrimRight(string(buf[name_offset:name_end]), "\x00"),
+		})
+		offset = name_end
+	}
+	return events
+}
+
+func inotify_flags(mask uint32, path string) hw.FSFlag {
+	f := hw.FS_FLAG_NONE
+
+	switch {
+	case mask&(syscall.IN_MOVED_FROM|syscall.IN_MOVED_TO|syscall.IN_MOVE_SELF) != 0:
+		f |= hw.FS_FLAG_RENAMED
+	case mask&syscall.IN_CREATE != 0:
+		f |= hw.FS_FLAG_CREATED
+	case mask&(syscall.IN_DELETE|syscall.IN_DELETE_SELF) != 0:
+		f |= hw.FS_FLAG_DELETED
+	case mask&syscall.IN_MODIFY != 0:
+		f |= hw.FS_FLAG_MODIFIED
+	case mask&syscall.IN_ATTRIB != 0:
+		f |= hw.FS_FLAG_CHMOD
+	}
+
+	if mask&syscall.IN_ISDIR != 0 {
+		f |= hw.FS_FLAG_ISFOLDER
+	} else if stat, err := os.Lstat(path); err == nil && stat.Mode()&os.ModeSymlink != 0 {
+		f |= hw.FS_FLAG_ISSYMLINK
+	} else if mask&(syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) == 0 {
+		f |= hw.FS_FLAG_ISFILE
+	} else {
+		f |= hw.FS_FLAG_ISFOLDER
+	}
+
+	return f
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// FSEvent implementation
+
+func (*fsevent_impl) Name() string {
+	return "FSEvent"
+}
+
+func (*fsevent_impl) Source() gopi.Driver {
+	return nil
+}
+
+func (this *fsevent_impl) Flags() hw.FSFlag {
+	return this.flags
+}
+
+func (this *fsevent_impl) Path() string {
+	return this.path
+}
+
+// OldPath returns the previous path for a rename within the
+// watched folders, or an empty string otherwise
+func (this *fsevent_impl) OldPath() string {
+	return this.from
+}
+
+func (this *fsevent_impl) RelPath() string {
+	if rel, err := filepath.Rel(this.root, this.path); err == nil {
+		return rel
+	} else {
+		return ""
+	}
+}
+
+func (this *fsevent_impl) Timestamp() time.Time {
+	return this.ts
+}
+
+func (this *fsevent_impl) Root() string {
+	return this.root
+}
+
+func (this *fsevent_impl) String() string {
+	if rel, err := filepath.Rel(this.root, this.path); err == nil {
+		return fmt.Sprintf("<fsevent>{ root=%v path=%v flags=%v ts=%v }", strconv.Quote(this.root), strconv.Quote(rel), this.Flags(), this.ts.Format(time.Kitchen))
+	} else {
+		return fmt.Sprintf("<fsevent>{ path=%v flags=%v ts=%v }", strconv.Quote(this.path), this.Flags(), this.ts.Format(time.Kitchen))
+	}
+}
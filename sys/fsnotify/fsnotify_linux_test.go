@@ -0,0 +1,163 @@
+package fsnotify_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	hw "github.com/djthorpe/gopi-hw"
+
+	// Modules
+	_ "github.com/djthorpe/gopi-hw/sys/fsnotify"
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CREATE MODULE
+
+func TestFSNotify_000(t *testing.T) {
+	config := gopi.NewAppConfig("hw/fsnotify")
+	if app, err := gopi.NewAppInstance(config); err != nil {
+		t.Fatal(err)
+	} else if fsnotify := app.ModuleInstance("hw/fsnotify").(hw.FSNotify); fsnotify == nil {
+		t.Fatal("Missing fsnotify module")
+	} else {
+		defer app.Close()
+		t.Log(fsnotify)
+	}
+}
+
+func TestFSNotify_001(t *testing.T) {
+	config := gopi.NewAppConfig("hw/fsnotify")
+	if app, err := gopi.NewAppInstance(config); err != nil {
+		t.Fatal(err)
+	} else {
+		defer app.Close()
+		fsnotify := app.ModuleInstance("hw/fsnotify").(hw.FSNotify)
+		if err := fsnotify.Watch("/nonexistent/path"); err != gopi.ErrNotFound {
+			t.Error("Expected ErrNotFound, got", err)
+		}
+		if file, err := ioutil.TempFile("", "fsnotify"); err != nil {
+			t.Fatal(err)
+		} else {
+			defer os.Remove(file.Name())
+			file.Close()
+			if err := fsnotify.Watch(file.Name()); err != gopi.ErrBadParameter {
+				t.Error("Expected ErrBadParameter, got", err)
+			}
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// WATCH FOR EVENTS
+
+func TestFSNotify_002(t *testing.T) {
+	root, err := ioutil.TempDir("", "fsnotify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	config := gopi.NewAppConfig("hw/fsnotify")
+	app, err := gopi.NewAppInstance(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+
+	fsnotify := app.ModuleInstance("hw/fsnotify").(hw.FSNotify)
+	events := fsnotify.Subscribe()
+	defer fsnotify.Unsubscribe(events)
+	if err := fsnotify.Watch(root); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a folder and wait for the event
+	if err := os.Mkdir(filepath.Join(root, "a"), 0755); err != nil {
+		t.Fatal(err)
+	} else if evt := waitForEvent(t, events, "a"); evt == nil {
+		t.Fatal("Missing create event for folder")
+	} else if evt.Flags() != hw.FS_FLAG_CREATED|hw.FS_FLAG_ISFOLDER {
+		t.Error("Unexpected flags", evt.Flags())
+	} else if evt.Root() != root {
+		t.Error("Unexpected root", evt.Root())
+	}
+
+	// Create a file in the new folder, which should be watched recursively
+	if err := ioutil.WriteFile(filepath.Join(root, "a", "b"), nil, 0644); err != nil {
+		t.Fatal(err)
+	} else if evt := waitForEvent(t, events, filepath.Join("a", "b")); evt == nil {
+		t.Fatal("Missing create event for file")
+	} else if evt.Flags() != hw.FS_FLAG_CREATED|hw.FS_FLAG_ISFILE {
+		t.Error("Unexpected flags", evt.Flags())
+	}
+
+	// Rename the file, which should result in a single event
+	if err := os.Rename(filepath.Join(root, "a", "b"), filepath.Join(root, "c")); err != nil {
+		t.Fatal(err)
+	} else if renamed := waitForRenames(t, events); len(renamed) != 1 {
+		t.Fatal("Expected a single rename event, got", len(renamed))
+	} else if evt := renamed[0]; evt.RelPath() != "c" {
+		t.Error("Unexpected path", evt.Path())
+	} else if evt.(interface{ OldPath() string }).OldPath() != filepath.Join(root, "a", "b") {
+		t.Error("Unexpected old path", evt)
+	} else if evt.Flags() != hw.FS_FLAG_RENAMED|hw.FS_FLAG_ISFILE {
+		t.Error("Unexpected flags", evt.Flags())
+	}
+
+	// Remove the file
+	if err := os.Remove(filepath.Join(root, "c")); err != nil {
+		t.Fatal(err)
+	} else if evt := waitForEvent(t, events, "c"); evt == nil {
+		t.Fatal("Missing delete event")
+	} else if evt.Flags() != hw.FS_FLAG_DELETED|hw.FS_FLAG_ISFILE {
+		t.Error("Unexpected flags", evt.Flags())
+	}
+
+	// Unwatch
+	if err := fsnotify.Unwatch(root); err != nil {
+		t.Error(err)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// waitForEvent returns the next event for a relative path, skipping
+// any other events, or returns nil on timeout
+func waitForEvent(t *testing.T, events <-chan gopi.Event, rel string) hw.FSEvent {
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case evt := <-events:
+			if evt_, ok := evt.(hw.FSEvent); ok && evt_.RelPath() == rel {
+				t.Log(evt_)
+				return evt_
+			}
+		case <-timeout:
+			return nil
+		}
+	}
+}
+
+// waitForRenames returns the rename events received until no events
+// have been received for half a second
+func waitForRenames(t *testing.T, events <-chan gopi.Event) []hw.FSEvent {
+	renamed := make([]hw.FSEvent, 0, 1)
+	for {
+		select {
+		case evt := <-events:
+			if evt_, ok := evt.(hw.FSEvent); ok && evt_.Flags()&hw.FS_FLAG_RENAMED != 0 {
+				t.Log(evt_)
+				renamed = append(renamed, evt_)
+			}
+		case <-time.After(500 * time.Millisecond):
+			return renamed
+		}
+	}
+}
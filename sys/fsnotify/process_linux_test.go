@@ -0,0 +1,70 @@
+package fsnotify
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	// Frameworks
+	gopi "github.com/djthorpe/gopi"
+	hw "github.com/djthorpe/gopi-hw"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PAIR MOVES ACROSS READS
+
+func TestProcess_000(t *testing.T) {
+	app, err := gopi.NewAppInstance(gopi.NewAppConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+
+	expired := make(chan hw.FSEvent, 1)
+	this := &fsnotify_impl{
+		log:      app.Logger,
+		paths:    map[int32]string{1: "/root"},
+		watches:  []*inotify_watch{{"/root", map[int32]bool{1: true}}},
+		moves:    make(map[uint32]*inotify_move),
+		callback: func(evt hw.FSEvent) { expired <- evt },
+	}
+
+	// IN_MOVED_FROM and IN_MOVED_TO in separate reads are a single rename
+	if emit := process(this, &inotify_event{1, syscall.IN_MOVED_FROM, 7, "a"}); len(emit) != 0 {
+		t.Error("Unexpected events", emit)
+	}
+	if emit := process(this, &inotify_event{1, syscall.IN_MOVED_TO, 7, "b"}); len(emit) != 1 {
+		t.Fatal("Expected a single rename event, got", emit)
+	} else if evt := emit[0].(*fsevent_impl); evt.path != "/root/b" || evt.from != "/root/a" || evt.flags != hw.FS_FLAG_RENAMED|hw.FS_FLAG_ISFILE {
+		t.Error("Unexpected rename event", evt)
+	}
+
+	// IN_MOVED_FROM without IN_MOVED_TO is emitted when it expires
+	process(this, &inotify_event{1, syscall.IN_MOVED_FROM, 8, "c"})
+	select {
+	case evt := <-expired:
+		if evt.Path() != "/root/c" || evt.(*fsevent_impl).from != "" {
+			t.Error("Unexpected event", evt)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timeout waiting for expired move")
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if len(this.moves) != 0 {
+		t.Error("Unexpected moves", this.moves)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// process processes the events of a single read whilst locked
+func process(this *fsnotify_impl, events ...*inotify_event) []hw.FSEvent {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.process(events)
+}
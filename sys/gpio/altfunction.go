@@ -0,0 +1,179 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// alt_functions names the ALT0 to ALT5 functions for each pin, where an
+// empty string means the function is reserved or not connected
+type alt_functions [][6]string
+
+////////////////////////////////////////////////////////////////////////////////
+// VARIABLES
+
+var (
+	// BCM2835 peripherals datasheet, section 6.2. GPIO46 and GPIO47 are
+	// internal, and GPIO48 to GPIO53 connect to the SD card
+	alt_bcm2835 = alt_functions{
+		{"SDA0", "SA5", "", "", "", ""},                              // GPIO0
+		{"SCL0", "SA4", "", "", "", ""},                              // GPIO1
+		{"SDA1", "SA3", "", "", "", ""},                              // GPIO2
+		{"SCL1", "SA2", "", "", "", ""},                              // GPIO3
+		{"GPCLK0", "SA1", "", "", "", "ARM_TDI"},                     // GPIO4
+		{"GPCLK1", "SA0", "", "", "", "ARM_TDO"},                     // GPIO5
+		{"GPCLK2", "SOE_N", "", "", "", "ARM_RTCK"},                  // GPIO6
+		{"SPI0_CE1_N", "SWE_N", "", "", "", ""},                      // GPIO7
+		{"SPI0_CE0_N", "SD0", "", "", "", ""},                        // GPIO8
+		{"SPI0_MISO", "SD1", "", "", "", ""},                         // GPIO9
+		{"SPI0_MOSI", "SD2", "", "", "", ""},                         // GPIO10
+		{"SPI0_SCLK", "SD3", "", "", "", ""},                         // GPIO11
+		{"PWM0", "SD4", "", "", "", "ARM_TMS"},                       // GPIO12
+		{"PWM1", "SD5", "", "", "", "ARM_TCK"},                       // GPIO13
+		{"TXD0", "SD6", "", "", "", "TXD1"},                          // GPIO14
+		{"RXD0", "SD7", "", "", "", "RXD1"},                          // GPIO15
+		{"", "SD8", "", "CTS0", "SPI1_CE2_N", "CTS1"},                // GPIO16
+		{"", "SD9", "", "RTS0", "SPI1_CE1_N", "RTS1"},                // GPIO17
+		{"PCM_CLK", "SD10", "", "BSCSL_SDA", "SPI1_CE0_N", "PWM0"},   // GPIO18
+		{"PCM_FS", "SD11", "", "BSCSL_SCL", "SPI1_MISO", "PWM1"},     // GPIO19
+		{"PCM_DIN", "SD12", "", "BSCSL_MISO", "SPI1_MOSI", "GPCLK0"}, // GPIO20
+		{"PCM_DOUT", "SD13", "", "BSCSL_CE", "SPI1_SCLK", "GPCLK1"},  // GPIO21
+		{"", "SD14", "", "SD1_CLK", "ARM_TRST", ""},                  // GPIO22
+		{"", "SD15", "", "SD1_CMD", "ARM_RTCK", ""},                  // GPIO23
+		{"", "SD16", "", "SD1_DAT0", "ARM_TDO", ""},                  // GPIO24
+		{"", "SD17", "", "SD1_DAT1", "ARM_TCK", ""},                  // GPIO25
+		{"", "", "", "SD1_DAT2", "ARM_TDI", ""},                      // GPIO26
+		{"", "", "", "SD1_DAT3", "ARM_TMS", ""},                      // GPIO27
+		{"SDA0", "SA5", "PCM_CLK", "", "", ""},                       // GPIO28
+		{"SCL0", "SA4", "PCM_FS", "", "", ""},                        // GPIO29
+		{"", "SA3", "PCM_DIN", "CTS0", "", "CTS1"},                   // GPIO30
+		{"", "SA2", "PCM_DOUT", "RTS0", "", "RTS1"},                  // GPIO31
+		{"GPCLK0", "SA1", "", "TXD0", "", "TXD1"},                    // GPIO32
+		{"", "SA0", "", "RXD0", "", "RXD1"},                          // GPIO33
+		{"GPCLK0", "SOE_N", "", "", "", ""},                          // GPIO34
+		{"SPI0_CE1_N", "SWE_N", "", "", "", ""},                      // GPIO35
+		{"SPI0_CE0_N", "SD0", "TXD0", "", "", ""},                    // GPIO36
+		{"SPI0_MISO", "SD1", "RXD0", "", "", ""},                     // GPIO37
+		{"SPI0_MOSI", "SD2", "RTS0", "", "", ""},                     // GPIO38
+		{"SPI0_SCLK", "SD3", "CTS0", "", "", ""},                     // GPIO39
+		{"PWM0", "SD4", "", "", "SPI2_MISO", "TXD1"},                 // GPIO40
+		{"PWM1", "SD5", "", "", "SPI2_MOSI", "RXD1"},                 // GPIO41
+		{"GPCLK1", "SD6", "", "", "SPI2_SCLK", "RTS1"},               // GPIO42
+		{"GPCLK2", "SD7", "", "", "SPI2_CE0_N", "CTS1"},              // GPIO43
+		{"GPCLK1", "SDA0", "SDA1", "", "SPI2_CE1_N", ""},             // GPIO44
+		{"PWM1", "SCL0", "SCL1", "", "SPI2_CE2_N", ""},               // GPIO45
+		{"", "", "", "", "", ""},                                     // GPIO46
+		{"", "", "", "", "", ""},                                     // GPIO47
+		{"SD0_CLK", "", "", "SD1_CLK", "", ""},                       // GPIO48
+		{"SD0_CMD", "", "", "SD1_CMD", "", ""},                       // GPIO49
+		{"SD0_DAT0", "", "", "SD1_DAT0", "", ""},                     // GPIO50
+		{"SD0_DAT1", "", "", "SD1_DAT1", "", ""},                     // GPIO51
+		{"SD0_DAT2", "", "", "SD1_DAT2", "", ""},                     // GPIO52
+		{"SD0_DAT3", "", "", "SD1_DAT3", "", ""},                     // GPIO53
+	}
+
+	// BCM2711 peripherals datasheet, section 5.3. The functions of GPIO54
+	// to GPIO57 are not named
+	alt_bcm2711 = alt_functions{
+		{"SDA0", "SA5", "PCLK", "SPI3_CE0_N", "TXD2", "SDA6"},                    // GPIO0
+		{"SCL0", "SA4", "DE", "SPI3_MISO", "RXD2", "SCL6"},                       // GPIO1
+		{"SDA1", "SA3", "LCD_VSYNC", "SPI3_MOSI", "CTS2", "SDA3"},                // GPIO2
+		{"SCL1", "SA2", "LCD_HSYNC", "SPI3_SCLK", "RTS2", "SCL3"},                // GPIO3
+		{"GPCLK0", "SA1", "DPI_D0", "SPI4_CE0_N", "TXD3", "SDA3"},                // GPIO4
+		{"GPCLK1", "SA0", "DPI_D1", "SPI4_MISO", "RXD3", "SCL3"},                 // GPIO5
+		{"GPCLK2", "SOE_N", "DPI_D2", "SPI4_MOSI", "CTS3", "SDA4"},               // GPIO6
+		{"SPI0_CE1_N", "SWE_N", "DPI_D3", "SPI4_SCLK", "RTS3", "SCL4"},           // GPIO7
+		{"SPI0_CE0_N", "SD0", "DPI_D4", "BSCSL_CE", "TXD4", "SDA4"},              // GPIO8
+		{"SPI0_MISO", "SD1", "DPI_D5", "BSCSL_MISO", "RXD4", "SCL4"},             // GPIO9
+		{"SPI0_MOSI", "SD2", "DPI_D6", "BSCSL_SDA", "CTS4", "SDA5"},              // GPIO10
+		{"SPI0_SCLK", "SD3", "DPI_D7", "BSCSL_SCL", "RTS4", "SCL5"},              // GPIO11
+		{"PWM0_0", "SD4", "DPI_D8", "SPI5_CE0_N", "TXD5", "SDA5"},                // GPIO12
+		{"PWM0_1", "SD5", "DPI_D9", "SPI5_MISO", "RXD5", "SCL5"},                 // GPIO13
+		{"TXD0", "SD6", "DPI_D10", "SPI5_MOSI", "CTS5", "TXD1"},                  // GPIO14
+		{"RXD0", "SD7", "DPI_D11", "SPI5_SCLK", "RTS5", "RXD1"},                  // GPIO15
+		{"", "SD8", "DPI_D12", "CTS0", "SPI1_CE2_N", "CTS1"},                     // GPIO16
+		{"", "SD9", "DPI_D13", "RTS0", "SPI1_CE1_N", "RTS1"},                     // GPIO17
+		{"PCM_CLK", "SD10", "DPI_D14", "SPI6_CE0_N", "SPI1_CE0_N", "PWM0_0"},     // GPIO18
+		{"PCM_FS", "SD11", "DPI_D15", "SPI6_MISO", "SPI1_MISO", "PWM0_1"},        // GPIO19
+		{"PCM_DIN", "SD12", "DPI_D16", "SPI6_MOSI", "SPI1_MOSI", "GPCLK0"},       // GPIO20
+		{"PCM_DOUT", "SD13", "DPI_D17", "SPI6_SCLK", "SPI1_SCLK", "GPCLK1"},      // GPIO21
+		{"SD0_CLK", "SD14", "DPI_D18", "SD1_CLK", "ARM_TRST", "SDA6"},            // GPIO22
+		{"SD0_CMD", "SD15", "DPI_D19", "SD1_CMD", "ARM_RTCK", "SCL6"},            // GPIO23
+		{"SD0_DAT0", "SD16", "DPI_D20", "SD1_DAT0", "ARM_TDO", "SPI3_CE1_N"},     // GPIO24
+		{"SD0_DAT1", "SD17", "DPI_D21", "SD1_DAT1", "ARM_TCK", "SPI4_CE1_N"},     // GPIO25
+		{"SD0_DAT2", "", "DPI_D22", "SD1_DAT2", "ARM_TDI", "SPI5_CE1_N"},         // GPIO26
+		{"SD0_DAT3", "", "DPI_D23", "SD1_DAT3", "ARM_TMS", "SPI6_CE1_N"},         // GPIO27
+		{"SDA0", "SA5", "PCM_CLK", "", "MII_A_RX_ERR", "RGMII_MDIO"},             // GPIO28
+		{"SCL0", "SA4", "PCM_FS", "", "MII_A_TX_ERR", "RGMII_MDC"},               // GPIO29
+		{"", "SA3", "PCM_DIN", "CTS0", "MII_A_CRS", "CTS1"},                      // GPIO30
+		{"", "SA2", "PCM_DOUT", "RTS0", "MII_A_COL", "RTS1"},                     // GPIO31
+		{"GPCLK0", "SA1", "", "TXD0", "SD_CARD_PRES", "TXD1"},                    // GPIO32
+		{"", "SA0", "", "RXD0", "SD_CARD_WRPROT", "RXD1"},                        // GPIO33
+		{"GPCLK0", "SOE_N", "", "SD1_CLK", "SD_CARD_LED", "RGMII_IRQ"},           // GPIO34
+		{"SPI0_CE1_N", "SWE_N", "", "SD1_CMD", "RGMII_START_STOP", ""},           // GPIO35
+		{"SPI0_CE0_N", "SD0", "TXD0", "SD1_DAT0", "RGMII_RX_OK", "MII_A_RX_ERR"}, // GPIO36
+		{"SPI0_MISO", "SD1", "RXD0", "SD1_DAT1", "RGMII_MDIO", "MII_A_TX_ERR"},   // GPIO37
+		{"SPI0_MOSI", "SD2", "RTS0", "SD1_DAT2", "RGMII_MDC", "MII_A_CRS"},       // GPIO38
+		{"SPI0_SCLK", "SD3", "CTS0", "SD1_DAT3", "RGMII_IRQ", "MII_A_COL"},       // GPIO39
+		{"PWM1_0", "SD4", "", "SD1_DAT4", "SPI0_MISO", "TXD1"},                   // GPIO40
+		{"PWM1_1", "SD5", "", "SD1_DAT5", "SPI0_MOSI", "RXD1"},                   // GPIO41
+		{"GPCLK1", "SD6", "", "SD1_DAT6", "SPI0_SCLK", "RTS1"},                   // GPIO42
+		{"GPCLK2", "SD7", "", "SD1_DAT7", "SPI0_CE0_N", "CTS1"},                  // GPIO43
+		{"GPCLK1", "SDA0", "SDA1", "", "SPI0_CE1_N", "SD_CARD_VOLT"},             // GPIO44
+		{"PWM0_1", "SCL0", "SCL1", "", "SPI0_CE2_N", "SD_CARD_PWR0"},             // GPIO45
+		{"SDA0", "SDA1", "SPI0_CE0_N", "", "", "SPI2_CE1_N"},                     // GPIO46
+		{"SCL0", "SCL1", "SPI0_MISO", "", "", "SPI2_CE0_N"},                      // GPIO47
+		{"SD0_CLK", "FL0", "SPI0_MOSI", "SD1_CLK", "ARM_TRST", "SPI2_SCLK"},      // GPIO48
+		{"SD0_CMD", "GPCLK0", "SPI0_SCLK", "SD1_CMD", "ARM_RTCK", "SPI2_MOSI"},   // GPIO49
+		{"SD0_DAT0", "GPCLK1", "PCM_CLK", "SD1_DAT0", "ARM_TDO", "SPI2_MISO"},    // GPIO50
+		{"SD0_DAT1", "GPCLK2", "PCM_FS", "SD1_DAT1", "ARM_TCK", "SD_CARD_LED"},   // GPIO51
+		{"SD0_DAT2", "PWM0_0", "PCM_DIN", "SD1_DAT2", "ARM_TDI", ""},             // GPIO52
+		{"SD0_DAT3", "PWM0_1", "PCM_DOUT", "SD1_DAT3", "ARM_TMS", ""},            // GPIO53
+		{"", "", "", "", "", ""},                                                 // GPIO54
+		{"", "", "", "", "", ""},                                                 // GPIO55
+		{"", "", "", "", "", ""},                                                 // GPIO56
+		{"", "", "", "", "", ""},                                                 // GPIO57
+	}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// altFunction returns the name of the function for a pin in an alternate
+// mode, or an empty string if the function is reserved or unknown
+func altFunction(table alt_functions, pin gopi.GPIOPin, mode gopi.GPIOMode) string {
+	index := -1
+	switch mode {
+	case gopi.GPIO_ALT0:
+		index = 0
+	case gopi.GPIO_ALT1:
+		index = 1
+	case gopi.GPIO_ALT2:
+		index = 2
+	case gopi.GPIO_ALT3:
+		index = 3
+	case gopi.GPIO_ALT4:
+		index = 4
+	case gopi.GPIO_ALT5:
+		index = 5
+	default:
+		return ""
+	}
+	if int(pin) < len(table) {
+		return table[pin][index]
+	} else {
+		return ""
+	}
+}
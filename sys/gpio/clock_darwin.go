@@ -0,0 +1,28 @@
+// +build darwin
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	"time"
+)
+
+var (
+	clock_epoch = time.Now()
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// monotonic returns the time elapsed since the process started
+func monotonic() time.Duration {
+	return time.Since(clock_epoch)
+}
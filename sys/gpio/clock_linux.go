@@ -0,0 +1,39 @@
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	CLOCK_MONOTONIC = 1
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// monotonic returns the current value of CLOCK_MONOTONIC, which is the
+// same clock the kernel uses to timestamp gpiochip line events
+func monotonic() time.Duration {
+	var ts syscall.Timespec
+	if _, _, err := syscall.Syscall(syscall.SYS_CLOCK_GETTIME, CLOCK_MONOTONIC, uintptr(unsafe.Pointer(&ts)), 0); err != 0 {
+		return 0
+	} else {
+		return time.Duration(ts.Nano())
+	}
+}
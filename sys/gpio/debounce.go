@@ -0,0 +1,185 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// debounce filters the raw level changes on a pin. After the first
+// change, further changes within the settle window are treated as bounce.
+// An edge is only emitted once the settle window has passed and the level
+// has been stable for the stable duration, and only when the level is
+// different from the level last emitted.
+type debounce struct {
+	settle, stable time.Duration
+	clock          clock
+	emit           func(gopi.GPIOEdge, time.Duration)
+
+	state gopi.GPIOState // Level last emitted
+	known bool           // True when state has been set
+	level gopi.GPIOState // Current raw level
+	start time.Duration  // Time of first change after stable
+	last  time.Duration  // Time of last change
+	timer timer
+	gen   uint
+
+	sync.Mutex
+}
+
+// clock returns the current time and schedules functions to be called
+// in the future, and can be replaced for testing
+type clock interface {
+	Now() time.Duration
+	AfterFunc(time.Duration, func()) timer
+}
+
+type timer interface {
+	Stop() bool
+}
+
+// sys_clock implements clock using the monotonic clock
+type sys_clock struct{}
+
+////////////////////////////////////////////////////////////////////////////////
+// NEW
+
+func newDebounce(settle, stable time.Duration, clock clock, emit func(gopi.GPIOEdge, time.Duration)) *debounce {
+	if clock == nil {
+		clock = sys_clock{}
+	}
+	return &debounce{settle: settle, stable: stable, clock: clock, emit: emit}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Set the stable level without emitting an edge
+func (this *debounce) Reset(level gopi.GPIOState) {
+	this.Lock()
+	defer this.Unlock()
+	this.stop()
+	this.state, this.level, this.known = level, level, true
+}
+
+// Level is called with the raw level of the pin and the time it changed.
+// When the stable level is not yet known, the first level sets it
+func (this *debounce) Level(level gopi.GPIOState, ts time.Duration) {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.known == false {
+		this.state, this.level, this.known = level, level, true
+		return
+	}
+
+	// Record the change
+	this.level = level
+	if this.timer == nil {
+		this.start = ts
+	} else {
+		this.stop()
+	}
+	this.last = ts
+
+	// Schedule the check for when the level could be stable
+	deadline := this.start + this.settle
+	if stable := this.last + this.stable; stable > deadline {
+		deadline = stable
+	}
+	delay := deadline - this.clock.Now()
+	if delay < 0 {
+		delay = 0
+	}
+	gen := this.gen
+	this.timer = this.clock.AfterFunc(delay, func() {
+		this.fire(gen)
+	})
+}
+
+// Close stops any pending check
+func (this *debounce) Close() {
+	this.Lock()
+	defer this.Unlock()
+	this.stop()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *debounce) String() string {
+	return fmt.Sprintf("<debounce>{ settle=%v stable=%v }", this.settle, this.stable)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *debounce) stop() {
+	if this.timer != nil {
+		this.timer.Stop()
+		this.timer = nil
+	}
+	// Invalidate any check which has already fired
+	this.gen++
+}
+
+// fire is called when the level has been stable, and emits an edge if
+// the level has changed
+func (this *debounce) fire(gen uint) {
+	this.Lock()
+	if gen != this.gen || this.timer == nil {
+		this.Unlock()
+		return
+	}
+	this.timer = nil
+	if this.level == this.state {
+		// Glitch, level has returned to the stable state
+		this.Unlock()
+		return
+	}
+	this.state = this.level
+	edge, ts := edgeForState(this.level), this.last
+	this.Unlock()
+
+	// Emit outside of the lock, as emitting can block
+	this.emit(edge, ts)
+}
+
+func edgeForState(state gopi.GPIOState) gopi.GPIOEdge {
+	if state == gopi.GPIO_HIGH {
+		return gopi.GPIO_EDGE_RISING
+	} else {
+		return gopi.GPIO_EDGE_FALLING
+	}
+}
+
+// matchEdge returns true if an edge should be emitted for a watch
+func matchEdge(watch, edge gopi.GPIOEdge) bool {
+	return watch == gopi.GPIO_EDGE_BOTH || watch == edge
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CLOCK
+
+func (sys_clock) Now() time.Duration {
+	return monotonic()
+}
+
+func (sys_clock) AfterFunc(d time.Duration, f func()) timer {
+	return time.AfterFunc(d, f)
+}
@@ -0,0 +1,180 @@
+package gpio
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE CLOCK
+
+type fakeclock struct {
+	sync.Mutex
+	now    time.Duration
+	timers []*faketimer
+}
+
+type faketimer struct {
+	clock   *fakeclock
+	at      time.Duration
+	f       func()
+	stopped bool
+}
+
+func (this *fakeclock) Now() time.Duration {
+	this.Lock()
+	defer this.Unlock()
+	return this.now
+}
+
+func (this *fakeclock) AfterFunc(d time.Duration, f func()) timer {
+	this.Lock()
+	defer this.Unlock()
+	t := &faketimer{clock: this, at: this.now + d, f: f}
+	this.timers = append(this.timers, t)
+	return t
+}
+
+// Advance moves the clock forward, calling any timers which
+// are due in order
+func (this *fakeclock) Advance(d time.Duration) {
+	this.Lock()
+	target := this.now + d
+	for {
+		var next *faketimer
+		for _, t := range this.timers {
+			if t.stopped == false && t.at <= target && (next == nil || t.at < next.at) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		next.stopped = true
+		this.now = next.at
+		this.Unlock()
+		next.f()
+		this.Lock()
+	}
+	this.now = target
+	this.Unlock()
+}
+
+func (this *faketimer) Stop() bool {
+	this.clock.Lock()
+	defer this.clock.Unlock()
+	active := this.stopped == false
+	this.stopped = true
+	return active
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+type edgeAt struct {
+	edge gopi.GPIOEdge
+	ts   time.Duration
+}
+
+func newTestDebounce(settle, stable time.Duration) (*debounce, *fakeclock, *[]edgeAt) {
+	clock := new(fakeclock)
+	edges := make([]edgeAt, 0)
+	debounce := newDebounce(settle, stable, clock, func(edge gopi.GPIOEdge, ts time.Duration) {
+		edges = append(edges, edgeAt{edge, ts})
+	})
+	debounce.Reset(gopi.GPIO_LOW)
+	return debounce, clock, &edges
+}
+
+func TestDebounce_000(t *testing.T) {
+	// A burst of edges results in a single edge after the settle window
+	debounce, clock, edges := newTestDebounce(5*time.Millisecond, 3*time.Millisecond)
+	debounce.Level(gopi.GPIO_HIGH, clock.Now())
+	clock.Advance(time.Millisecond)
+	debounce.Level(gopi.GPIO_LOW, clock.Now())
+	clock.Advance(time.Millisecond)
+	debounce.Level(gopi.GPIO_HIGH, clock.Now())
+
+	clock.Advance(2*time.Millisecond + 999*time.Microsecond)
+	if len(*edges) != 0 {
+		t.Fatal("Unexpected edges before settle window", *edges)
+	}
+	clock.Advance(time.Microsecond)
+	if len(*edges) != 1 {
+		t.Fatal("Expected one edge", *edges)
+	} else if (*edges)[0].edge != gopi.GPIO_EDGE_RISING {
+		t.Error("Unexpected edge", (*edges)[0].edge)
+	} else if (*edges)[0].ts != 2*time.Millisecond {
+		t.Error("Unexpected timestamp", (*edges)[0].ts)
+	}
+
+	// No further edges
+	clock.Advance(time.Second)
+	if len(*edges) != 1 {
+		t.Error("Unexpected edges", *edges)
+	}
+}
+
+func TestDebounce_001(t *testing.T) {
+	// A glitch which returns to the stable level emits nothing
+	debounce, clock, edges := newTestDebounce(2*time.Millisecond, 2*time.Millisecond)
+	debounce.Level(gopi.GPIO_HIGH, clock.Now())
+	clock.Advance(500 * time.Microsecond)
+	debounce.Level(gopi.GPIO_LOW, clock.Now())
+	clock.Advance(time.Second)
+	if len(*edges) != 0 {
+		t.Error("Unexpected edges for glitch", *edges)
+	}
+}
+
+func TestDebounce_002(t *testing.T) {
+	// The level has to be stable for the stable duration, which
+	// can extend beyond the settle window
+	debounce, clock, edges := newTestDebounce(time.Millisecond, 10*time.Millisecond)
+	debounce.Level(gopi.GPIO_HIGH, clock.Now())
+	clock.Advance(8 * time.Millisecond)
+	debounce.Level(gopi.GPIO_LOW, clock.Now())
+	clock.Advance(time.Millisecond)
+	debounce.Level(gopi.GPIO_HIGH, clock.Now())
+
+	clock.Advance(9 * time.Millisecond)
+	if len(*edges) != 0 {
+		t.Fatal("Unexpected edges before stable", *edges)
+	}
+	clock.Advance(time.Millisecond)
+	if len(*edges) != 1 || (*edges)[0].edge != gopi.GPIO_EDGE_RISING || (*edges)[0].ts != 9*time.Millisecond {
+		t.Fatal("Unexpected edges", *edges)
+	}
+
+	// Falling edge
+	debounce.Level(gopi.GPIO_LOW, clock.Now())
+	clock.Advance(10 * time.Millisecond)
+	if len(*edges) != 2 || (*edges)[1].edge != gopi.GPIO_EDGE_FALLING {
+		t.Error("Unexpected edges", *edges)
+	}
+}
+
+func TestDebounce_003(t *testing.T) {
+	// The first level sets the stable state when not reset, and
+	// close cancels any pending edge
+	clock := new(fakeclock)
+	count := 0
+	debounce := newDebounce(time.Millisecond, time.Millisecond, clock, func(gopi.GPIOEdge, time.Duration) {
+		count++
+	})
+	debounce.Level(gopi.GPIO_HIGH, clock.Now())
+	clock.Advance(time.Second)
+	if count != 0 {
+		t.Error("Unexpected edge for initial level")
+	}
+	debounce.Level(gopi.GPIO_LOW, clock.Now())
+	debounce.Close()
+	clock.Advance(time.Second)
+	if count != 0 {
+		t.Error("Unexpected edge after close")
+	}
+}
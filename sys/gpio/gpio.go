@@ -0,0 +1,62 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
+// GPIOTimestampedEvent is implemented by events which record when the
+// edge occurred. The timestamp is measured from the monotonic clock and
+// the sequence number increments for each edge on a pin, so a gap in the
+// sequence indicates that edges were dropped
+type GPIOTimestampedEvent interface {
+	gopi.GPIOEvent
+
+	Timestamp() time.Duration
+	Sequence() uint32
+}
+
+// GPIODroppedCounter is implemented by drivers which count the number
+// of edges which were not emitted
+type GPIODroppedCounter interface {
+	Dropped() uint64
+}
+
+// GPIODebounce is implemented by drivers which can filter edges in
+// software. After an edge, further edges within the settle window are
+// ignored, and an edge is emitted only once the level has been stable
+// for the stable duration. Setting both durations to zero disables
+// filtering for the pin
+type GPIODebounce interface {
+	Debounce(pin gopi.GPIOPin, settle, stable time.Duration) error
+}
+
+// GPIOAltFunction is implemented by drivers which can name the
+// alternate functions of a pin, for example SDA1 or TXD0
+type GPIOAltFunction interface {
+	// Return the name of the function for a pin in an alternate mode,
+	// or an empty string if the function is reserved or unknown
+	AltFunction(gopi.GPIOPin, gopi.GPIOMode) string
+}
+
+// GPIOHeader is implemented by drivers which know the layout of the
+// physical header, including the power and ground pins
+type GPIOHeader interface {
+	// Return the name of a physical pin, for example GPIO17, 3V3,
+	// 5V or GND, or an empty string if unknown
+	PhysicalPinName(uint) string
+}
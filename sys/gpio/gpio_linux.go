@@ -21,6 +21,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	// Frameworks
@@ -40,17 +41,30 @@ type GPIO struct {
 type gpio struct {
 	log      gopi.Logger
 	exported []gopi.GPIOPin
-	watched  map[gopi.GPIOPin]*os.File
+	watched  map[gopi.GPIOPin]*gpio_watch
+	debounce map[gopi.GPIOPin]*debounce
 	filepoll filepoll.FilePollInterface
+	dropped  uint64
+	clock    clock
 
 	sync.Mutex
 	event.Publisher
 }
 
+// gpio_watch is a pin which is being watched for edges
+type gpio_watch struct {
+	file  *os.File
+	edge  gopi.GPIOEdge
+	value string
+	seq   uint32
+}
+
 type gpio_event struct {
 	driver *gpio
 	pin    gopi.GPIOPin
 	edge   gopi.GPIOEdge
+	ts     time.Duration
+	seq    uint32
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -71,7 +85,8 @@ func (config GPIO) Open(logger gopi.Logger) (gopi.Driver, error) {
 
 	this := new(gpio)
 	this.log = logger
-	this.watched = make(map[gopi.GPIOPin]*os.File, 0)
+	this.watched = make(map[gopi.GPIOPin]*gpio_watch, 0)
+	this.debounce = make(map[gopi.GPIOPin]*debounce, 0)
 
 	// Make array of exported pins
 	if config.UnexportOnClose {
@@ -95,10 +110,15 @@ func (this *gpio) Close() error {
 	this.Lock()
 	defer this.Unlock()
 
+	// Stop debouncing
+	for _, debounce := range this.debounce {
+		debounce.Close()
+	}
+
 	// Unwatch pins
-	for pin, file := range this.watched {
-		this.filepoll.Unwatch(file)
-		if err := file.Close(); err != nil {
+	for pin, watch := range this.watched {
+		this.filepoll.Unwatch(watch.file)
+		if err := watch.file.Close(); err != nil {
 			this.log.Warn("<hw.gpio.linux>Close: %v: %v", pin, err)
 		}
 	}
@@ -120,6 +140,7 @@ func (this *gpio) Close() error {
 	// Zero out member variables
 	this.exported = nil
 	this.watched = nil
+	this.debounce = nil
 	this.filepoll = nil
 
 	// Return success
@@ -312,14 +333,17 @@ func (this *gpio) Watch(pin gopi.GPIOPin, edge gopi.GPIOEdge) error {
 	edge_write := ""
 	switch edge {
 	case gopi.GPIO_EDGE_NONE:
+		if debounce, exists := this.debounce[pin]; exists {
+			debounce.Close()
+		}
 		if err := writeEdge(pin, "none"); err != nil {
 			this.log.Error("Watch: Unable to write edge for %v: %v", pin, err)
-		} else if file, exists := this.watched[pin]; exists == false {
+		} else if watch, exists := this.watched[pin]; exists == false {
 			// IGNORE UNWATCHED PINS
-		} else if err := this.filepoll.Unwatch(file); err != nil {
+		} else if err := this.filepoll.Unwatch(watch.file); err != nil {
 			this.log.Error("%v: %v", pin, err)
-			file.Close()
-		} else if err := file.Close(); err != nil {
+			watch.file.Close()
+		} else if err := watch.file.Close(); err != nil {
 			return err
 		} else {
 			// Remove from list of watched pins
@@ -335,17 +359,28 @@ func (this *gpio) Watch(pin gopi.GPIOPin, edge gopi.GPIOEdge) error {
 		return errors.New("Watch: Invalid edge value")
 	}
 
+	// Debounced pins need to detect both edges, and filter afterwards
+	if debounce, exists := this.debounce[pin]; exists && edge_write != "" {
+		edge_write = "both"
+		if value, err := readPin(pin); err == nil {
+			debounce.Reset(stateForValue(value))
+		}
+	}
+
 	if edge_write != "" {
 		if err := writeEdge(pin, edge_write); err != nil {
 			this.log.Error("Watch: Unable to write edge for %v: %v", pin, err)
 			return err
-		} else if _, exists := this.watched[pin]; exists {
-			// IGNORE ALREADY WATCHED PINS
+		} else if watch, exists := this.watched[pin]; exists {
+			// Update the edge for already watched pins
+			watch.edge = edge
 		} else if file, err := watchValue(pin); err != nil {
 			this.log.Error("Watch: Unable to watch %v: %v", pin, err)
 			return err
 		} else if err := this.filepoll.Watch(file, filepoll.FILEPOLL_MODE_EDGE, func(handle *os.File, mode filepoll.FilePollMode) {
-			if err := this.handleEdge(handle, pin); err != nil {
+			// Capture the timestamp before reading the value
+			ts := monotonic()
+			if err := this.handleEdge(handle, pin, ts); err != nil {
 				this.log.Warn("Watch: %v: %v", pin, err)
 			}
 		}); err != nil {
@@ -353,16 +388,70 @@ func (this *gpio) Watch(pin gopi.GPIOPin, edge gopi.GPIOEdge) error {
 			file.Close()
 			return err
 		} else {
-			this.watched[pin] = file
+			this.watched[pin] = &gpio_watch{file: file, edge: edge}
+		}
+	}
+
+	return nil
+}
+
+// Debounce sets the settle window and minimum stable duration for
+// edges on a pin, or disables debouncing when both are zero
+func (this *gpio) Debounce(pin gopi.GPIOPin, settle, stable time.Duration) error {
+	this.log.Debug2("<hw.gpio.linux>Debounce{ pin=%v settle=%v stable=%v }", pin, settle, stable)
+	this.Lock()
+	defer this.Unlock()
+
+	if settle < 0 || stable < 0 {
+		return gopi.ErrBadParameter
+	}
+
+	// Remove existing debounce
+	if debounce, exists := this.debounce[pin]; exists {
+		debounce.Close()
+		delete(this.debounce, pin)
+	}
+
+	// Create a new debounce, which emits filtered edges
+	if settle != 0 || stable != 0 {
+		this.debounce[pin] = newDebounce(settle, stable, this.clock, func(edge gopi.GPIOEdge, ts time.Duration) {
+			this.emitDebounced(pin, edge, ts)
+		})
+	}
+
+	// Update the edge detection for watched pins
+	if watch, exists := this.watched[pin]; exists {
+		edge_write := edgeValue(watch.edge)
+		if debounce, exists := this.debounce[pin]; exists {
+			edge_write = "both"
+			if value, err := readPin(pin); err != nil {
+				return err
+			} else {
+				debounce.Reset(stateForValue(value))
+			}
+		}
+		if err := writeEdge(pin, edge_write); err != nil {
+			return err
 		}
 	}
 
+	// Success
 	return nil
 }
 
-// Emit an event
+// Emit an event, timestamped with the current time
 func (this *gpio) Emit(pin gopi.GPIOPin, edge gopi.GPIOEdge) {
-	this.Publisher.Emit(&gpio_event{driver: this, pin: pin, edge: edge})
+	ts := monotonic()
+	this.Lock()
+	seq := this.nextSequence(pin, 0)
+	this.Unlock()
+	this.Publisher.Emit(&gpio_event{driver: this, pin: pin, edge: edge, ts: ts, seq: seq})
+}
+
+// Dropped returns the number of edges which have been detected as
+// missing, because the value had changed back before it was read
+func (this *gpio) Dropped() uint64 {
+	return atomic.LoadUint64(&this.dropped)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -384,8 +473,19 @@ func (this *gpio_event) Edge() gopi.GPIOEdge {
 	return this.edge
 }
 
+// Timestamp returns the time the edge was detected, which is
+// measured from the monotonic clock
+func (this *gpio_event) Timestamp() time.Duration {
+	return this.ts
+}
+
+// Sequence returns the sequence number of the event for the pin
+func (this *gpio_event) Sequence() uint32 {
+	return this.seq
+}
+
 func (this *gpio_event) String() string {
-	return fmt.Sprintf("<sys.hw.linux.GPIO.Event>{ pin=%v edge=%v source=%v }", this.pin, this.edge, this.driver)
+	return fmt.Sprintf("<sys.hw.linux.GPIO.Event>{ pin=%v edge=%v ts=%v seq=%v source=%v }", this.pin, this.edge, this.ts, this.seq, this.driver)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -408,25 +508,101 @@ func (this *gpio) exportPin(pin gopi.GPIOPin) error {
 	return nil
 }
 
-func (this *gpio) handleEdge(handle *os.File, pin gopi.GPIOPin) error {
+// handleEdge reads the value of a pin after an edge, with the time
+// the edge was detected. When watching both edges and the value has not
+// changed since the last read, the opposite edge was missed and is
+// counted as dropped
+func (this *gpio) handleEdge(handle *os.File, pin gopi.GPIOPin, ts time.Duration) error {
 	if _, err := handle.Seek(0, io.SeekStart); err != nil {
 		return err
 	} else if buf, err := ioutil.ReadAll(handle); err != nil {
 		return err
 	} else {
 		value := strings.TrimSpace(string(buf))
+		edge := gopi.GPIO_EDGE_NONE
 		switch value {
 		case "0":
-			this.Emit(pin, gopi.GPIO_EDGE_FALLING)
+			edge = gopi.GPIO_EDGE_FALLING
 		case "1":
-			this.Emit(pin, gopi.GPIO_EDGE_RISING)
-		default:
-			this.Emit(pin, gopi.GPIO_EDGE_NONE)
+			edge = gopi.GPIO_EDGE_RISING
 		}
+
+		// Debounced pins emit edges once the level is stable
+		this.Lock()
+		if debounce, exists := this.debounce[pin]; exists {
+			if watch, exists := this.watched[pin]; exists {
+				watch.value = value
+			}
+			this.Unlock()
+			debounce.Level(stateForValue(value), ts)
+			return nil
+		}
+
+		// Determine sequence number and dropped edges
+		dropped := uint32(0)
+		if watch, exists := this.watched[pin]; exists {
+			if watch.edge == gopi.GPIO_EDGE_BOTH && watch.value != "" && watch.value == value {
+				dropped = 1
+			}
+			watch.value = value
+		}
+		seq := this.nextSequence(pin, dropped)
+		this.Unlock()
+
+		// Emit the event
+		this.Publisher.Emit(&gpio_event{driver: this, pin: pin, edge: edge, ts: ts, seq: seq})
 		return nil
 	}
 }
 
+// emitDebounced is called when the level of a debounced pin is stable,
+// and emits the edge if it matches the edge being watched
+func (this *gpio) emitDebounced(pin gopi.GPIOPin, edge gopi.GPIOEdge, ts time.Duration) {
+	this.Lock()
+	if watch, exists := this.watched[pin]; exists == false || matchEdge(watch.edge, edge) == false {
+		this.Unlock()
+		return
+	}
+	seq := this.nextSequence(pin, 0)
+	this.Unlock()
+	this.Publisher.Emit(&gpio_event{driver: this, pin: pin, edge: edge, ts: ts, seq: seq})
+}
+
+// nextSequence returns the next sequence number for a watched pin,
+// skipping over any dropped edges, or zero if the pin is not watched
+func (this *gpio) nextSequence(pin gopi.GPIOPin, dropped uint32) uint32 {
+	if watch, exists := this.watched[pin]; exists == false {
+		return 0
+	} else {
+		if dropped > 0 {
+			atomic.AddUint64(&this.dropped, uint64(dropped))
+		}
+		watch.seq += dropped + 1
+		return watch.seq
+	}
+}
+
+func stateForValue(value string) gopi.GPIOState {
+	if value == "0" {
+		return gopi.GPIO_LOW
+	} else {
+		return gopi.GPIO_HIGH
+	}
+}
+
+func edgeValue(edge gopi.GPIOEdge) string {
+	switch edge {
+	case gopi.GPIO_EDGE_RISING:
+		return "rising"
+	case gopi.GPIO_EDGE_FALLING:
+		return "falling"
+	case gopi.GPIO_EDGE_BOTH:
+		return "both"
+	default:
+		return "none"
+	}
+}
+
 func writeFile(filename string, value string) error {
 	return ioutil.WriteFile(filename, []byte(value), 777)
 }
@@ -0,0 +1,115 @@
+// +build linux,!rpi
+
+package gpio
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+func TestGPIOEvent_000(t *testing.T) {
+	file, err := ioutil.TempFile("", "gpio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	pin := gopi.GPIOPin(17)
+	this := &gpio{watched: map[gopi.GPIOPin]*gpio_watch{
+		pin: &gpio_watch{file: file, edge: gopi.GPIO_EDGE_BOTH},
+	}}
+	events := this.Subscribe()
+	defer this.Unsubscribe(events)
+
+	// Values read after each edge, where the repeated "0" indicates
+	// a rising edge was missed
+	values := []string{"1", "0", "0", "1"}
+	edges := []gopi.GPIOEdge{gopi.GPIO_EDGE_RISING, gopi.GPIO_EDGE_FALLING, gopi.GPIO_EDGE_FALLING, gopi.GPIO_EDGE_RISING}
+	sequences := []uint32{1, 2, 4, 5}
+	for i, value := range values {
+		ts := time.Duration(i+1) * time.Millisecond
+		go func(value string, ts time.Duration) {
+			if err := ioutil.WriteFile(file.Name(), []byte(value+"\n"), 0644); err != nil {
+				t.Error(err)
+			} else if err := this.handleEdge(file, pin, ts); err != nil {
+				t.Error(err)
+			}
+		}(value, ts)
+		select {
+		case evt := <-events:
+			if evt_, ok := evt.(GPIOTimestampedEvent); ok == false {
+				t.Error("Unexpected event", evt)
+			} else if evt_.Pin() != pin || evt_.Edge() != edges[i] {
+				t.Error("Unexpected edge", evt_.Edge(), "expected", edges[i])
+			} else if evt_.Timestamp() != ts {
+				t.Error("Unexpected timestamp", evt_.Timestamp())
+			} else if evt_.Sequence() != sequences[i] {
+				t.Error("Unexpected sequence", evt_.Sequence(), "expected", sequences[i])
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timeout waiting for event")
+		}
+	}
+
+	if dropped := this.Dropped(); dropped != 1 {
+		t.Error("Unexpected dropped count", dropped)
+	}
+}
+
+func TestGPIOEvent_001(t *testing.T) {
+	// Monotonic clock should always advance
+	a := monotonic()
+	time.Sleep(time.Millisecond)
+	if b := monotonic(); a == 0 || b <= a {
+		t.Error("Unexpected monotonic clock values", a, b)
+	}
+}
+
+func TestGPIOEvent_002(t *testing.T) {
+	file, err := ioutil.TempFile("", "gpio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	// Debounced pin, where edges are emitted from the fake clock
+	pin := gopi.GPIOPin(17)
+	clock := new(fakeclock)
+	this := &gpio{watched: map[gopi.GPIOPin]*gpio_watch{
+		pin: &gpio_watch{file: file, edge: gopi.GPIO_EDGE_RISING},
+	}, debounce: make(map[gopi.GPIOPin]*debounce)}
+	this.debounce[pin] = newDebounce(2*time.Millisecond, time.Millisecond, clock, func(edge gopi.GPIOEdge, ts time.Duration) {
+		this.emitDebounced(pin, edge, ts)
+	})
+	this.debounce[pin].Reset(gopi.GPIO_LOW)
+	events := this.Subscribe()
+	defer this.Unsubscribe(events)
+
+	for _, value := range []string{"1", "0", "1"} {
+		clock.Advance(100 * time.Microsecond)
+		if err := ioutil.WriteFile(file.Name(), []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		} else if err := this.handleEdge(file, pin, clock.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	go clock.Advance(2 * time.Millisecond)
+	select {
+	case evt := <-events:
+		if evt_ := evt.(GPIOTimestampedEvent); evt_.Edge() != gopi.GPIO_EDGE_RISING {
+			t.Error("Unexpected edge", evt_)
+		} else if evt_.Timestamp() != 300*time.Microsecond || evt_.Sequence() != 1 {
+			t.Error("Unexpected event", evt_)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timeout waiting for event")
+	}
+}
@@ -0,0 +1,363 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// GPIOPoll is the configuration for a driver which detects edges by
+// reading the state of watched pins at an interval. It wraps another GPIO
+// driver, for use when that driver cannot watch pins itself
+type GPIOPoll struct {
+	// GPIO driver to read pins from
+	GPIO gopi.GPIO
+
+	// Interval between reads, defaults to 10ms
+	Interval time.Duration
+
+	// Clock, which can be replaced for testing
+	clock clock
+}
+
+type gpio_poll struct {
+	log      gopi.Logger
+	gpio     gopi.GPIO
+	interval time.Duration
+	clock    clock
+	pins     map[gopi.GPIOPin]*gpio_poll_pin
+	debounce map[gopi.GPIOPin]*debounce
+	stop     chan struct{}
+	done     chan struct{}
+
+	sync.Mutex
+	event.Publisher
+}
+
+// gpio_poll_pin is a pin which is being watched
+type gpio_poll_pin struct {
+	edge  gopi.GPIOEdge
+	state gopi.GPIOState
+	seq   uint32
+}
+
+type gpio_poll_event struct {
+	driver *gpio_poll
+	pin    gopi.GPIOPin
+	edge   gopi.GPIOEdge
+	ts     time.Duration
+	seq    uint32
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	GPIO_POLL_INTERVAL = 10 * time.Millisecond
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open
+func (config GPIOPoll) Open(logger gopi.Logger) (gopi.Driver, error) {
+	logger.Debug("<hw.gpio.poll>Open{ interval=%v }", config.Interval)
+
+	this := new(gpio_poll)
+	this.log = logger
+	this.pins = make(map[gopi.GPIOPin]*gpio_poll_pin)
+	this.debounce = make(map[gopi.GPIOPin]*debounce)
+
+	// GPIO driver is required
+	if config.GPIO == nil {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.gpio = config.GPIO
+	}
+
+	// Set interval and clock
+	if config.Interval < 0 {
+		return nil, gopi.ErrBadParameter
+	} else if config.Interval == 0 {
+		this.interval = GPIO_POLL_INTERVAL
+	} else {
+		this.interval = config.Interval
+	}
+	if config.clock == nil {
+		this.clock = sys_clock{}
+	} else {
+		this.clock = config.clock
+	}
+
+	// Start background poller
+	this.stop = make(chan struct{})
+	this.done = make(chan struct{})
+	go this.poll()
+
+	// Success
+	return this, nil
+}
+
+// Close
+func (this *gpio_poll) Close() error {
+	this.log.Debug("<hw.gpio.poll>Close{ }")
+
+	// Stop the poller
+	close(this.stop)
+	<-this.done
+
+	this.Lock()
+	defer this.Unlock()
+
+	// Stop debouncing
+	for _, debounce := range this.debounce {
+		debounce.Close()
+	}
+
+	// Close subscriber channels
+	this.Publisher.Close()
+
+	// Zero out member variables
+	this.pins = nil
+	this.debounce = nil
+	this.gpio = nil
+
+	// Return success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - RETURN INFORMATION
+
+func (this *gpio_poll) NumberOfPhysicalPins() uint {
+	return this.gpio.NumberOfPhysicalPins()
+}
+
+func (this *gpio_poll) Pins() []gopi.GPIOPin {
+	return this.gpio.Pins()
+}
+
+func (this *gpio_poll) PhysicalPin(pin uint) gopi.GPIOPin {
+	return this.gpio.PhysicalPin(pin)
+}
+
+func (this *gpio_poll) PhysicalPinForPin(pin gopi.GPIOPin) uint {
+	return this.gpio.PhysicalPinForPin(pin)
+}
+
+// PhysicalPinName returns the name of a physical pin when the
+// wrapped driver knows the header layout
+func (this *gpio_poll) PhysicalPinName(pin uint) string {
+	if header, ok := this.gpio.(GPIOHeader); ok {
+		return header.PhysicalPinName(pin)
+	} else {
+		return ""
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - READ/WRITE
+
+func (this *gpio_poll) ReadPin(pin gopi.GPIOPin) gopi.GPIOState {
+	return this.gpio.ReadPin(pin)
+}
+
+func (this *gpio_poll) WritePin(pin gopi.GPIOPin, state gopi.GPIOState) {
+	this.gpio.WritePin(pin, state)
+}
+
+func (this *gpio_poll) GetPinMode(pin gopi.GPIOPin) gopi.GPIOMode {
+	return this.gpio.GetPinMode(pin)
+}
+
+func (this *gpio_poll) SetPinMode(pin gopi.GPIOPin, mode gopi.GPIOMode) {
+	this.gpio.SetPinMode(pin, mode)
+}
+
+func (this *gpio_poll) SetPullMode(pin gopi.GPIOPin, pull gopi.GPIOPull) error {
+	return this.gpio.SetPullMode(pin, pull)
+}
+
+// Watch will watch a pin for rising, falling or both edges. When
+// set to EDGE_NONE then watching is stopped
+func (this *gpio_poll) Watch(pin gopi.GPIOPin, edge gopi.GPIOEdge) error {
+	this.log.Debug2("<hw.gpio.poll>Watch{ pin=%v edge=%v }", pin, edge)
+	this.Lock()
+	defer this.Unlock()
+
+	switch edge {
+	case gopi.GPIO_EDGE_NONE:
+		if debounce, exists := this.debounce[pin]; exists {
+			debounce.Close()
+		}
+		delete(this.pins, pin)
+	case gopi.GPIO_EDGE_RISING, gopi.GPIO_EDGE_FALLING, gopi.GPIO_EDGE_BOTH:
+		if watch, exists := this.pins[pin]; exists {
+			watch.edge = edge
+		} else {
+			state := this.gpio.ReadPin(pin)
+			this.pins[pin] = &gpio_poll_pin{edge: edge, state: state}
+			if debounce, exists := this.debounce[pin]; exists {
+				debounce.Reset(state)
+			}
+		}
+	default:
+		return gopi.ErrBadParameter
+	}
+
+	// Success
+	return nil
+}
+
+// Debounce sets the settle window and minimum stable duration for
+// edges on a pin, or disables debouncing when both are zero
+func (this *gpio_poll) Debounce(pin gopi.GPIOPin, settle, stable time.Duration) error {
+	this.log.Debug2("<hw.gpio.poll>Debounce{ pin=%v settle=%v stable=%v }", pin, settle, stable)
+	this.Lock()
+	defer this.Unlock()
+
+	if settle < 0 || stable < 0 {
+		return gopi.ErrBadParameter
+	}
+
+	// Remove existing debounce
+	if debounce, exists := this.debounce[pin]; exists {
+		debounce.Close()
+		delete(this.debounce, pin)
+	}
+
+	// Create a new debounce, which emits filtered edges
+	if settle != 0 || stable != 0 {
+		debounce := newDebounce(settle, stable, this.clock, func(edge gopi.GPIOEdge, ts time.Duration) {
+			this.emit(pin, edge, ts)
+		})
+		if watch, exists := this.pins[pin]; exists {
+			debounce.Reset(watch.state)
+		}
+		this.debounce[pin] = debounce
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - EVENT
+
+func (this *gpio_poll_event) Name() string {
+	return "GPIOEvent"
+}
+
+func (this *gpio_poll_event) Source() gopi.Driver {
+	return this.driver
+}
+
+func (this *gpio_poll_event) Pin() gopi.GPIOPin {
+	return this.pin
+}
+
+func (this *gpio_poll_event) Edge() gopi.GPIOEdge {
+	return this.edge
+}
+
+// Timestamp returns the time the edge was detected, which is
+// measured from the monotonic clock
+func (this *gpio_poll_event) Timestamp() time.Duration {
+	return this.ts
+}
+
+// Sequence returns the sequence number of the event for the pin
+func (this *gpio_poll_event) Sequence() uint32 {
+	return this.seq
+}
+
+func (this *gpio_poll_event) String() string {
+	return fmt.Sprintf("<hw.gpio.poll.Event>{ pin=%v edge=%v ts=%v seq=%v }", this.pin, this.edge, this.ts, this.seq)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *gpio_poll) String() string {
+	return fmt.Sprintf("<hw.gpio.poll>{ interval=%v watched=%v gpio=%v }", this.interval, len(this.pins), this.gpio)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// poll reads the watched pins until the driver is closed
+func (this *gpio_poll) poll() {
+	ticker := time.NewTicker(this.interval)
+	defer ticker.Stop()
+FOR_LOOP:
+	for {
+		select {
+		case <-ticker.C:
+			this.sample()
+		case <-this.stop:
+			break FOR_LOOP
+		}
+	}
+	close(this.done)
+}
+
+// sample reads the watched pins once, and passes any changes in
+// state to the debounce for the pin or emits the edge directly
+func (this *gpio_poll) sample() {
+	type change struct {
+		pin      gopi.GPIOPin
+		state    gopi.GPIOState
+		debounce *debounce
+	}
+
+	// Read pins
+	this.Lock()
+	ts := this.clock.Now()
+	changes := make([]change, 0)
+	for pin, watch := range this.pins {
+		if state := this.gpio.ReadPin(pin); state != watch.state {
+			watch.state = state
+			changes = append(changes, change{pin, state, this.debounce[pin]})
+		}
+	}
+	this.Unlock()
+
+	// Process changes outside of the lock, as emitting can block
+	for _, change := range changes {
+		if change.debounce != nil {
+			change.debounce.Level(change.state, ts)
+		} else {
+			this.emit(change.pin, edgeForState(change.state), ts)
+		}
+	}
+}
+
+// emit an edge if it matches the edge being watched
+func (this *gpio_poll) emit(pin gopi.GPIOPin, edge gopi.GPIOEdge, ts time.Duration) {
+	this.Lock()
+	watch, exists := this.pins[pin]
+	if exists == false || matchEdge(watch.edge, edge) == false {
+		this.Unlock()
+		return
+	}
+	watch.seq += 1
+	seq := watch.seq
+	this.Unlock()
+	this.Publisher.Emit(&gpio_poll_event{driver: this, pin: pin, edge: edge, ts: ts, seq: seq})
+}
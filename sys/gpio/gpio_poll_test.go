@@ -0,0 +1,164 @@
+package gpio
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE GPIO
+
+type fakegpio struct {
+	sync.Mutex
+	event.Publisher
+	state map[gopi.GPIOPin]gopi.GPIOState
+}
+
+func (this *fakegpio) Close() error                           { return nil }
+func (this *fakegpio) NumberOfPhysicalPins() uint             { return 0 }
+func (this *fakegpio) Pins() []gopi.GPIOPin                   { return nil }
+func (this *fakegpio) PhysicalPin(uint) gopi.GPIOPin          { return gopi.GPIO_PIN_NONE }
+func (this *fakegpio) PhysicalPinForPin(gopi.GPIOPin) uint    { return 0 }
+func (this *fakegpio) GetPinMode(gopi.GPIOPin) gopi.GPIOMode  { return gopi.GPIO_INPUT }
+func (this *fakegpio) SetPinMode(gopi.GPIOPin, gopi.GPIOMode) {}
+func (this *fakegpio) SetPullMode(gopi.GPIOPin, gopi.GPIOPull) error {
+	return gopi.ErrNotImplemented
+}
+func (this *fakegpio) Watch(gopi.GPIOPin, gopi.GPIOEdge) error {
+	return gopi.ErrNotImplemented
+}
+
+func (this *fakegpio) ReadPin(pin gopi.GPIOPin) gopi.GPIOState {
+	this.Lock()
+	defer this.Unlock()
+	return this.state[pin]
+}
+
+func (this *fakegpio) WritePin(pin gopi.GPIOPin, state gopi.GPIOState) {
+	this.Lock()
+	defer this.Unlock()
+	this.state[pin] = state
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestGPIOPoll_000(t *testing.T) {
+	app, err := gopi.NewAppInstance(gopi.NewAppConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+
+	if _, err := gopi.Open(GPIOPoll{}, app.Logger); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter without GPIO driver")
+	} else if driver, err := gopi.Open(GPIOPoll{GPIO: &fakegpio{}}, app.Logger); err != nil {
+		t.Error(err)
+	} else {
+		defer driver.Close()
+		if _, ok := driver.(gopi.GPIO); ok == false {
+			t.Error("Expected gopi.GPIO interface")
+		} else if _, ok := driver.(GPIODebounce); ok == false {
+			t.Error("Expected GPIODebounce interface")
+		}
+		t.Log(driver)
+	}
+}
+
+func TestGPIOPoll_001(t *testing.T) {
+	app, err := gopi.NewAppInstance(gopi.NewAppConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer app.Close()
+
+	// The ticker interval is long, so that pins are only sampled
+	// when the test calls sample
+	gpio := &fakegpio{state: make(map[gopi.GPIOPin]gopi.GPIOState)}
+	clock := new(fakeclock)
+	driver_, err := gopi.Open(GPIOPoll{GPIO: gpio, Interval: time.Hour, clock: clock}, app.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer driver_.Close()
+	driver := driver_.(*gpio_poll)
+
+	// Forward events to a buffered channel so that sampling does not block
+	events := driver.Subscribe()
+	defer driver.Unsubscribe(events)
+	received := make(chan GPIOTimestampedEvent, 10)
+	go func() {
+		for evt := range events {
+			received <- evt.(GPIOTimestampedEvent)
+		}
+	}()
+
+	pin := gopi.GPIOPin(4)
+	if err := driver.Watch(pin, gopi.GPIO_EDGE_BOTH); err != nil {
+		t.Fatal(err)
+	}
+
+	// Edge without debounce
+	clock.Advance(time.Millisecond)
+	gpio.WritePin(pin, gopi.GPIO_HIGH)
+	driver.sample()
+	if evt := waitForGPIOEvent(received); evt == nil {
+		t.Fatal("Missing rising edge")
+	} else if evt.Edge() != gopi.GPIO_EDGE_RISING || evt.Timestamp() != time.Millisecond || evt.Sequence() != 1 {
+		t.Error("Unexpected event", evt)
+	}
+
+	// Bouncing edges with debounce
+	if err := driver.Debounce(pin, 5*time.Millisecond, 2*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	for i, state := range []gopi.GPIOState{gopi.GPIO_LOW, gopi.GPIO_HIGH, gopi.GPIO_LOW} {
+		clock.Advance(time.Millisecond)
+		gpio.WritePin(pin, state)
+		driver.sample()
+		if i < 2 {
+			if evt := waitForGPIOEvent(received); evt != nil {
+				t.Error("Unexpected event while bouncing", evt)
+			}
+		}
+	}
+	clock.Advance(5 * time.Millisecond)
+	if evt := waitForGPIOEvent(received); evt == nil {
+		t.Fatal("Missing falling edge")
+	} else if evt.Edge() != gopi.GPIO_EDGE_FALLING || evt.Timestamp() != 4*time.Millisecond || evt.Sequence() != 2 {
+		t.Error("Unexpected event", evt)
+	}
+
+	// Rising edges only, so falling edge is filtered
+	if err := driver.Watch(pin, gopi.GPIO_EDGE_RISING); err != nil {
+		t.Fatal(err)
+	} else if err := driver.Debounce(pin, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	gpio.WritePin(pin, gopi.GPIO_HIGH)
+	driver.sample()
+	gpio.WritePin(pin, gopi.GPIO_LOW)
+	driver.sample()
+	if evt := waitForGPIOEvent(received); evt == nil || evt.Edge() != gopi.GPIO_EDGE_RISING {
+		t.Error("Expected rising edge", evt)
+	} else if evt := waitForGPIOEvent(received); evt != nil {
+		t.Error("Unexpected event", evt)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func waitForGPIOEvent(events <-chan GPIOTimestampedEvent) GPIOTimestampedEvent {
+	select {
+	case evt := <-events:
+		return evt
+	case <-time.After(100 * time.Millisecond):
+		return nil
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"reflect"
 	"sync"
 	"syscall"
+	"time"
 	"unsafe"
 
 	// Frameworks
@@ -30,18 +31,40 @@ import (
 
 type GPIO struct {
 	Hardware gopi.Hardware
+
+	// Interval between reading levels of watched pins, defaults to 10ms
+	Poll time.Duration
 }
 
 type gpio struct {
 	log          gopi.Logger
-	pins         map[gopi.GPIOPin]uint // map of logical to physical pins
+	header       *header_layout
 	memlock      sync.Mutex
 	product_info *rpi.ProductInfo
-	mem8         []uint8  // access GPIO as bytes
-	mem32        []uint32 // access GPIO as uint32
+	mem8         []uint8   // access GPIO as bytes
+	regs         registers // access GPIO registers as uint32
+	poll         time.Duration
+	detect       *edge_detect
+	debounce     map[gopi.GPIOPin]*debounce
+	seq          map[gopi.GPIOPin]uint32
+	stop         chan struct{}
+	done         chan struct{}
 	event.Publisher
 }
 
+type header_key struct {
+	model    rpi.Model
+	revision rpi.Revision
+}
+
+type gpio_event struct {
+	driver *gpio
+	pin    gopi.GPIOPin
+	edge   gopi.GPIOEdge
+	ts     time.Duration
+	seq    uint32
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // CONSTANTS
 
@@ -53,57 +76,21 @@ const (
 	GPIO_MAXPINS            = 54 // GPIO0 to GPIO53
 )
 
-const (
-	// GPIO Registers
-	GPIO_GPLVL0    = 0x0034 // Register to read pins GPIO0-GPIO31
-	GPIO_GPLVL1    = 0x0038 // Register to read pins GPIO32-GPIO53
-	GPIO_GPSET0    = 0x001C // Register to write HIGH to pins GPIO0-GPIO31
-	GPIO_GPSET1    = 0x0020 // Register to write HIGH to pins GPIO32-GPIO53
-	GPIO_GPCLR0    = 0x0028 // Register to write LOW to pins GPIO0-GPIO31
-	GPIO_GPCLR1    = 0x002C // Register to write LOW to pins GPIO32-GPIO53
-	GPIO_GPFSEL0   = 0x0000 // Pin modes for GPIO0-GPIO9
-	GPIO_GPFSEL1   = 0x0004 // Pin modes for GPIO10-GPIO19
-	GPIO_GPFSEL2   = 0x0008 // Pin modes for GPIO20-GPIO29
-	GPIO_GPFSEL3   = 0x000C // Pin modes for GPIO30-GPIO39
-	GPIO_GPFSEL4   = 0x0010 // Pin modes for GPIO40-GPIO49
-	GPIO_GPFSEL5   = 0x0014 // Pin modes for GPIO50-GPIO53
-	GPIO_GPPUD     = 0x0094 // GPIO Pin Pull-up/down Enable
-	GPIO_GPPUDCLK0 = 0x0098 // GPIO Pin Pull-up/down Enable Clock 0
-	GPIO_GPPUDCLK1 = 0x009c // GPIO Pin Pull-up/down Enable Clock 1
-)
-
 ////////////////////////////////////////////////////////////////////////////////
 // VARIABLES
 
 var (
-	// Map logical pins to physical pins
-	pinmap = map[uint]gopi.GPIOPin{
-		3:  gopi.GPIOPin(2),  // I2C_SDA1      On Rpi1 A/B Rev1: GPIO0 otherwise GPIO2
-		5:  gopi.GPIOPin(3),  // I2C_SCL1      On Rpi1 A/B Rev1: GPIO1 otherwise GPIO3
-		7:  gopi.GPIOPin(4),  // GPIO_CLK
-		8:  gopi.GPIOPin(14), // TXD0
-		10: gopi.GPIOPin(15), // RXD0
-		11: gopi.GPIOPin(17), // GPIO_GEN0
-		12: gopi.GPIOPin(18), // GPIO_GEN1
-		13: gopi.GPIOPin(27), // GPIO_GEN2     On Rpi1 A/B Rev1: GPIO21 otherwise GPIO27
-		15: gopi.GPIOPin(22), // GPIO_GEN3
-		16: gopi.GPIOPin(23), // GPIO_GEN4
-		18: gopi.GPIOPin(24), // GPIO_GEN5
-		19: gopi.GPIOPin(10), // SPI_MOSI
-		21: gopi.GPIOPin(9),  // SPI_MOSO
-		22: gopi.GPIOPin(25), // GPIO_GEN6
-		23: gopi.GPIOPin(11), // SPI_CLK
-		24: gopi.GPIOPin(8),  // SPI_CE0_N
-		26: gopi.GPIOPin(7),  // SPI_CE1_N
-		29: gopi.GPIOPin(5),  // Not on Rpi1 (all below here)
-		31: gopi.GPIOPin(6),
-		32: gopi.GPIOPin(12),
-		33: gopi.GPIOPin(13),
-		35: gopi.GPIOPin(19),
-		36: gopi.GPIOPin(16),
-		37: gopi.GPIOPin(26),
-		38: gopi.GPIOPin(20),
-		40: gopi.GPIOPin(21),
+	// Header layouts for each model and revision, where a revision of
+	// RPI_REVISION_UNKNOWN matches any revision of the model. Models which
+	// are not listed use the 40 pin header
+	headers = map[header_key]*header_layout{
+		{rpi.RPI_MODEL_A, rpi.RPI_REVISION_UNKNOWN}:                    header_26_rev2,
+		{rpi.RPI_MODEL_B, rpi.Revision(1)}:                             header_26_rev1,
+		{rpi.RPI_MODEL_B, rpi.RPI_REVISION_UNKNOWN}:                    header_26_rev2,
+		{rpi.RPI_MODEL_COMPUTE_MODULE, rpi.RPI_REVISION_UNKNOWN}:       header_sodimm,
+		{rpi.RPI_MODEL_COMPUTE_MODULE_3, rpi.RPI_REVISION_UNKNOWN}:     header_sodimm,
+		{rpi.RPI_MODEL_COMPUTE_MODULE_3PLUS, rpi.RPI_REVISION_UNKNOWN}: header_sodimm,
+		{rpi.RPI_MODEL_B_4, rpi.RPI_REVISION_UNKNOWN}:                  header_40,
 	}
 )
 
@@ -116,6 +103,18 @@ func (config GPIO) Open(logger gopi.Logger) (gopi.Driver, error) {
 
 	this := new(gpio)
 	this.log = logger
+	this.detect = newEdgeDetect()
+	this.debounce = make(map[gopi.GPIOPin]*debounce)
+	this.seq = make(map[gopi.GPIOPin]uint32)
+
+	// Set poll interval for watched pins
+	if config.Poll < 0 {
+		return nil, gopi.ErrBadParameter
+	} else if config.Poll == 0 {
+		this.poll = GPIO_POLL_INTERVAL
+	} else {
+		this.poll = config.Poll
+	}
 
 	// Get product
 	if _, product, err := rpi.VCGetSerialRevision(); err != nil {
@@ -126,14 +125,8 @@ func (config GPIO) Open(logger gopi.Logger) (gopi.Driver, error) {
 		this.product_info = product_info
 	}
 
-	// Create pin mappings. Because there is some variation between the different
-	// models and revisions, we use some logic in a private method to fudge
-	this.pins = make(map[gopi.GPIOPin]uint)
-	for pin := range pinmap {
-		if logical := this.PhysicalPin(pin); logical != gopi.GPIO_PIN_NONE {
-			this.pins[logical] = pin
-		}
-	}
+	// Set the header layout for the model and revision
+	this.header = headerForProduct(this.product_info)
 
 	// Open the /dev/mem and provide offset & size for accessing memory
 	if file, base, size, err := gpioOpenDevice(); err != nil {
@@ -156,7 +149,7 @@ func (config GPIO) Open(logger gopi.Logger) (gopi.Driver, error) {
 		header := *(*reflect.SliceHeader)(unsafe.Pointer(&this.mem8))
 		header.Len /= (32 / 8)
 		header.Cap /= (32 / 8)
-		this.mem32 = *(*[]uint32)(unsafe.Pointer(&header))
+		this.regs = mem_registers(*(*[]uint32)(unsafe.Pointer(&header)))
 
 		// Success
 		return this, nil
@@ -167,12 +160,21 @@ func (config GPIO) Open(logger gopi.Logger) (gopi.Driver, error) {
 func (this *gpio) Close() error {
 	this.log.Debug("sys.hw.rpi.GPIO.Close{ }")
 
+	// Stop polling
+	this.stopPoll()
+
+	// Stop debouncing
+	for _, debounce := range this.debounce {
+		debounce.Close()
+	}
+
 	// close publisher
 	this.Publisher.Close()
 
 	// Unmap memory and return error
 	this.memlock.Lock()
 	defer this.memlock.Unlock()
+	this.regs = nil
 	return syscall.Munmap(this.mem8)
 }
 
@@ -180,7 +182,7 @@ func (this *gpio) Close() error {
 // STRINGIFY
 
 func (this *gpio) String() string {
-	return fmt.Sprintf("sys.hw.rpi.GPIO{ physical_pins=%v logical_pins=%v }", this.NumberOfPhysicalPins(), this.pins)
+	return fmt.Sprintf("sys.hw.rpi.GPIO{ header=%v physical_pins=%v }", this.header, this.NumberOfPhysicalPins())
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -188,11 +190,7 @@ func (this *gpio) String() string {
 
 // NumberOfPhysicalPins returns number of physical pins
 func (this *gpio) NumberOfPhysicalPins() uint {
-	if this.product_info.Model == rpi.RPI_MODEL_A || this.product_info.Model == rpi.RPI_MODEL_B {
-		return uint(26)
-	} else {
-		return uint(40)
-	}
+	return this.header.NumberOfPhysicalPins()
 }
 
 // Pins() returns array of available logical pins
@@ -207,40 +205,19 @@ func (this *gpio) Pins() []gopi.GPIOPin {
 // PhysicalPin returns logical pin for physical pin number. Returns
 // GPIO_PIN_NONE where there is no logical pin at that position
 func (this *gpio) PhysicalPin(pin uint) gopi.GPIOPin {
-
-	// Check for Raspberry Pi Version 1 and fudge things a little
-	if this.product_info.Model == rpi.RPI_MODEL_A || this.product_info.Model == rpi.RPI_MODEL_B {
-		// pin can be 1-28
-		if pin < 1 || pin > 28 {
-			return gopi.GPIO_PIN_NONE
-		}
-		if this.product_info.Revision == rpi.Revision(1) && pin == 3 {
-			return gopi.GPIOPin(0)
-		}
-		if this.product_info.Revision == rpi.Revision(1) && pin == 5 {
-			return gopi.GPIOPin(1)
-		}
-		if this.product_info.Revision == rpi.Revision(1) && pin == 13 {
-			return gopi.GPIOPin(21)
-		}
-	}
-
-	// now do things normally...
-	if logical_pin, ok := pinmap[pin]; ok == false {
-		return gopi.GPIO_PIN_NONE
-	} else {
-		return logical_pin
-	}
+	return this.header.PhysicalPin(pin)
 }
 
 // PhysicalPinForPin returns physical pin number for logical pin.
 // Returns 0 where there is no physical pin for this logical pin
 func (this *gpio) PhysicalPinForPin(logical gopi.GPIOPin) uint {
-	if physical, ok := this.pins[logical]; ok == false {
-		return 0
-	} else {
-		return physical
-	}
+	return this.header.PhysicalPinForPin(logical)
+}
+
+// PhysicalPinName returns the name of a physical pin, including
+// power and ground pins
+func (this *gpio) PhysicalPinName(pin uint) string {
+	return this.header.PhysicalPinName(pin)
 }
 
 // ReadPin reads pin state or returns LOW otherwise
@@ -252,10 +229,10 @@ func (this *gpio) ReadPin(logical gopi.GPIOPin) gopi.GPIOState {
 
 	if uint8(logical) <= uint8(31) {
 		// GPIO0 - GPIO31
-		register = this.mem32[GPIO_GPLVL0>>2]
+		register = this.regs.Read(GPIO_GPLVL0)
 	} else {
 		// GPIO32 - GPIO53
-		register = this.mem32[GPIO_GPLVL1>>2]
+		register = this.regs.Read(GPIO_GPLVL1)
 	}
 	if (register & (1 << (uint8(logical) & 31))) != 0 {
 		return gopi.GPIO_HIGH
@@ -273,15 +250,15 @@ func (this *gpio) WritePin(logical gopi.GPIOPin, state gopi.GPIOState) {
 	switch state {
 	case gopi.GPIO_LOW:
 		if uint8(logical) <= uint8(31) {
-			this.mem32[GPIO_GPCLR0>>2] = v
+			this.regs.Write(GPIO_GPCLR0, v)
 		} else {
-			this.mem32[GPIO_GPCLR1>>2] = v
+			this.regs.Write(GPIO_GPCLR1, v)
 		}
 	case gopi.GPIO_HIGH:
 		if uint8(logical) <= uint8(31) {
-			this.mem32[GPIO_GPSET0>>2] = v
+			this.regs.Write(GPIO_GPSET0, v)
 		} else {
-			this.mem32[GPIO_GPSET1>>2] = v
+			this.regs.Write(GPIO_GPSET1, v)
 		}
 	}
 }
@@ -296,7 +273,7 @@ func (this *gpio) GetPinMode(logical gopi.GPIOPin) gopi.GPIOMode {
 	defer this.memlock.Unlock()
 
 	// Retrieve register, shift to the right, and return last three bits
-	return gopi.GPIOMode((this.mem32[register>>2] >> shift) & 7)
+	return gopi.GPIOMode((this.regs.Read(register) >> shift) & 7)
 }
 
 // Set pin mode
@@ -308,22 +285,241 @@ func (this *gpio) SetPinMode(logical gopi.GPIOPin, mode gopi.GPIOMode) {
 	this.memlock.Lock()
 	defer this.memlock.Unlock()
 
-	this.mem32[register>>2] = (this.mem32[register>>2] &^ (7 << shift)) | (uint32(mode) << shift)
+	this.regs.Write(register, (this.regs.Read(register)&^(7<<shift))|(uint32(mode)<<shift))
 }
 
-// Set pull mode
+// SetPullMode sets the pull mode for a pin. The BCM2711 has registers
+// for each pin, and earlier processors use a clocked sequence
 func (this *gpio) SetPullMode(logical gopi.GPIOPin, pull gopi.GPIOPull) error {
-	return gopi.ErrNotImplemented
+	this.log.Debug2("sys.hw.rpi.GPIO.SetPullMode{ pin=%v pull=%v }", logical, pull)
+
+	if uint(logical) >= GPIO_MAXPINS {
+		return gopi.ErrBadParameter
+	}
+
+	this.memlock.Lock()
+	defer this.memlock.Unlock()
+
+	if this.product_info.Processor == rpi.RPI_PROCESSOR_BCM2838 {
+		return setPullBCM2711(this.regs, logical, pull)
+	} else {
+		return setPullBCM2835(this.regs, logical, pull, func() {
+			time.Sleep(GPIO_PULL_DELAY)
+		})
+	}
 }
 
-// Watch is not implemented
-func (this *gpio) Watch(gopi.GPIOPin, gopi.GPIOEdge) error {
-	return gopi.ErrNotImplemented
+// AltFunction returns the name of the function for a pin in an
+// alternate mode, or an empty string if reserved or unknown
+func (this *gpio) AltFunction(logical gopi.GPIOPin, mode gopi.GPIOMode) string {
+	if this.product_info.Processor == rpi.RPI_PROCESSOR_BCM2838 {
+		return altFunction(alt_bcm2711, logical, mode)
+	} else {
+		return altFunction(alt_bcm2835, logical, mode)
+	}
+}
+
+// Watch will watch a pin for rising, falling or both edges by reading
+// the level registers at the poll interval. When set to EDGE_NONE then
+// watching is stopped
+func (this *gpio) Watch(logical gopi.GPIOPin, edge gopi.GPIOEdge) error {
+	this.log.Debug2("sys.hw.rpi.GPIO.Watch{ pin=%v edge=%v }", logical, edge)
+
+	if uint(logical) >= GPIO_MAXPINS {
+		return gopi.ErrBadParameter
+	}
+
+	switch edge {
+	case gopi.GPIO_EDGE_NONE, gopi.GPIO_EDGE_RISING, gopi.GPIO_EDGE_FALLING, gopi.GPIO_EDGE_BOTH:
+		break
+	default:
+		return gopi.ErrBadParameter
+	}
+
+	this.memlock.Lock()
+	defer this.memlock.Unlock()
+
+	// Set edge and reset the debounce for the pin
+	this.detect.Watch(logical, edge, this.regs)
+	if debounce, exists := this.debounce[logical]; exists {
+		if edge == gopi.GPIO_EDGE_NONE {
+			debounce.Close()
+		} else {
+			debounce.Reset(this.readPin(logical))
+		}
+	}
+
+	// Start or stop polling
+	if this.detect.Watching() && this.stop == nil {
+		this.stop = make(chan struct{})
+		this.done = make(chan struct{})
+		go this.pollLevels(this.stop, this.done)
+	} else if this.detect.Watching() == false && this.stop != nil {
+		// Signal the poller to stop without waiting, as it needs the lock
+		close(this.stop)
+		this.stop, this.done = nil, nil
+	}
+
+	// Success
+	return nil
+}
+
+// Debounce sets the settle window and minimum stable duration for
+// edges on a pin, or disables debouncing when both are zero
+func (this *gpio) Debounce(logical gopi.GPIOPin, settle, stable time.Duration) error {
+	this.log.Debug2("sys.hw.rpi.GPIO.Debounce{ pin=%v settle=%v stable=%v }", logical, settle, stable)
+
+	if settle < 0 || stable < 0 {
+		return gopi.ErrBadParameter
+	}
+
+	this.memlock.Lock()
+	defer this.memlock.Unlock()
+
+	// Remove existing debounce
+	if debounce, exists := this.debounce[logical]; exists {
+		debounce.Close()
+		delete(this.debounce, logical)
+	}
+
+	// Create a new debounce, which emits filtered edges
+	if settle != 0 || stable != 0 {
+		debounce := newDebounce(settle, stable, nil, func(edge gopi.GPIOEdge, ts time.Duration) {
+			this.emit(logical, edge, ts)
+		})
+		debounce.Reset(this.readPin(logical))
+		this.debounce[logical] = debounce
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - EVENT
+
+func (this *gpio_event) Name() string {
+	return "GPIOEvent"
+}
+
+func (this *gpio_event) Source() gopi.Driver {
+	return this.driver
+}
+
+func (this *gpio_event) Pin() gopi.GPIOPin {
+	return this.pin
+}
+
+func (this *gpio_event) Edge() gopi.GPIOEdge {
+	return this.edge
+}
+
+// Timestamp returns the time the edge was detected, which is
+// measured from the monotonic clock
+func (this *gpio_event) Timestamp() time.Duration {
+	return this.ts
+}
+
+// Sequence returns the sequence number of the event for the pin
+func (this *gpio_event) Sequence() uint32 {
+	return this.seq
+}
+
+func (this *gpio_event) String() string {
+	return fmt.Sprintf("<sys.hw.rpi.GPIO.Event>{ pin=%v edge=%v ts=%v seq=%v }", this.pin, this.edge, this.ts, this.seq)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+// headerForProduct returns the header layout for a model and revision,
+// falling back to any revision of the model and then the 40 pin header
+func headerForProduct(product *rpi.ProductInfo) *header_layout {
+	if header, exists := headers[header_key{product.Model, product.Revision}]; exists {
+		return header
+	} else if header, exists := headers[header_key{product.Model, rpi.RPI_REVISION_UNKNOWN}]; exists {
+		return header
+	} else {
+		return header_40
+	}
+}
+
+// readPin returns the level of a pin, without locking
+func (this *gpio) readPin(logical gopi.GPIOPin) gopi.GPIOState {
+	if readLevels(this.regs)&(1<<uint(logical)) != 0 {
+		return gopi.GPIO_HIGH
+	} else {
+		return gopi.GPIO_LOW
+	}
+}
+
+// pollLevels reads the levels of watched pins until stopped
+func (this *gpio) pollLevels(stop, done chan struct{}) {
+	ticker := time.NewTicker(this.poll)
+	defer ticker.Stop()
+FOR_LOOP:
+	for {
+		select {
+		case <-ticker.C:
+			this.detectEdges()
+		case <-stop:
+			break FOR_LOOP
+		}
+	}
+	close(done)
+}
+
+// stopPoll stops polling and waits for the poller to end
+func (this *gpio) stopPoll() {
+	this.memlock.Lock()
+	stop, done := this.stop, this.done
+	this.stop, this.done = nil, nil
+	this.memlock.Unlock()
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// detectEdges reads the level registers once, and passes any changes
+// to the debounce for the pin or emits the edge directly
+func (this *gpio) detectEdges() {
+	this.memlock.Lock()
+	if this.regs == nil {
+		this.memlock.Unlock()
+		return
+	}
+	ts := monotonic()
+	changes := this.detect.Detect(this.regs)
+	debounce := make([]*debounce, len(changes))
+	for i, change := range changes {
+		debounce[i] = this.debounce[change.pin]
+	}
+	this.memlock.Unlock()
+
+	// Process changes outside of the lock, as emitting can block
+	for i, change := range changes {
+		if debounce[i] != nil {
+			debounce[i].Level(change.state, ts)
+		} else {
+			this.emit(change.pin, edgeForState(change.state), ts)
+		}
+	}
+}
+
+// emit an edge if it matches the edge being watched
+func (this *gpio) emit(logical gopi.GPIOPin, edge gopi.GPIOEdge, ts time.Duration) {
+	this.memlock.Lock()
+	if matchEdge(this.detect.Edge(logical), edge) == false {
+		this.memlock.Unlock()
+		return
+	}
+	this.seq[logical] += 1
+	seq := this.seq[logical]
+	this.memlock.Unlock()
+	this.Publisher.Emit(&gpio_event{driver: this, pin: logical, edge: edge, ts: ts, seq: seq})
+}
+
 func gpioOpenDevice() (*os.File, uint32, uint32, error) {
 	// open GPIO memory mapped file, or if that doesn't exist
 	// attempt /dev/mem which would only work for root user
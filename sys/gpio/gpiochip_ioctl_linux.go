@@ -0,0 +1,178 @@
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// gpiochip_io abstracts opening the character device and calling ioctl
+// so that the driver can be tested without hardware
+type gpiochip_io interface {
+	Open(path string) (*os.File, error)
+	Ioctl(fd uintptr, cmd uintptr, arg unsafe.Pointer) error
+}
+
+// gpiochip_sys implements gpiochip_io using system calls
+type gpiochip_sys struct{}
+
+type gpiochip_info struct {
+	name  [GPIO_MAX_NAME_SIZE]byte
+	label [GPIO_MAX_NAME_SIZE]byte
+	lines uint32
+}
+
+type gpio_v2_line_values struct {
+	bits uint64
+	mask uint64
+}
+
+type gpio_v2_line_attribute struct {
+	id      uint32
+	padding uint32
+	value   uint64 // flags, values or debounce period
+}
+
+type gpio_v2_line_config_attribute struct {
+	attr gpio_v2_line_attribute
+	mask uint64
+}
+
+type gpio_v2_line_config struct {
+	flags     uint64
+	num_attrs uint32
+	padding   [5]uint32
+	attrs     [GPIO_V2_LINE_NUM_ATTRS_MAX]gpio_v2_line_config_attribute
+}
+
+type gpio_v2_line_request struct {
+	offsets           [GPIO_V2_LINES_MAX]uint32
+	consumer          [GPIO_MAX_NAME_SIZE]byte
+	config            gpio_v2_line_config
+	num_lines         uint32
+	event_buffer_size uint32
+	padding           [5]uint32
+	fd                int32
+}
+
+type gpio_v2_line_info struct {
+	name      [GPIO_MAX_NAME_SIZE]byte
+	consumer  [GPIO_MAX_NAME_SIZE]byte
+	offset    uint32
+	num_attrs uint32
+	flags     uint64
+	attrs     [GPIO_V2_LINE_NUM_ATTRS_MAX]gpio_v2_line_attribute
+	padding   [4]uint32
+}
+
+type gpio_v2_line_event struct {
+	timestamp_ns uint64
+	id           uint32
+	offset       uint32
+	seqno        uint32
+	line_seqno   uint32
+	padding      [6]uint32
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	GPIO_MAX_NAME_SIZE         = 32
+	GPIO_V2_LINES_MAX          = 64
+	GPIO_V2_LINE_NUM_ATTRS_MAX = 10
+)
+
+const (
+	// gpiochip ioctl commands, from linux/gpio.h
+	GPIO_GET_CHIPINFO_IOCTL       = 0x8044B401 /* _IOR(0xB4, 0x01, struct gpiochip_info) */
+	GPIO_V2_GET_LINEINFO_IOCTL    = 0xC100B405 /* _IOWR(0xB4, 0x05, struct gpio_v2_line_info) */
+	GPIO_V2_GET_LINE_IOCTL        = 0xC250B407 /* _IOWR(0xB4, 0x07, struct gpio_v2_line_request) */
+	GPIO_V2_LINE_SET_CONFIG_IOCTL = 0xC110B40D /* _IOWR(0xB4, 0x0D, struct gpio_v2_line_config) */
+	GPIO_V2_LINE_GET_VALUES_IOCTL = 0xC010B40E /* _IOWR(0xB4, 0x0E, struct gpio_v2_line_values) */
+	GPIO_V2_LINE_SET_VALUES_IOCTL = 0xC010B40F /* _IOWR(0xB4, 0x0F, struct gpio_v2_line_values) */
+)
+
+const (
+	// gpio_v2_line_flag values
+	GPIO_V2_LINE_FLAG_USED                 uint64 = 1 << 0
+	GPIO_V2_LINE_FLAG_ACTIVE_LOW           uint64 = 1 << 1
+	GPIO_V2_LINE_FLAG_INPUT                uint64 = 1 << 2
+	GPIO_V2_LINE_FLAG_OUTPUT               uint64 = 1 << 3
+	GPIO_V2_LINE_FLAG_EDGE_RISING          uint64 = 1 << 4
+	GPIO_V2_LINE_FLAG_EDGE_FALLING         uint64 = 1 << 5
+	GPIO_V2_LINE_FLAG_OPEN_DRAIN           uint64 = 1 << 6
+	GPIO_V2_LINE_FLAG_OPEN_SOURCE          uint64 = 1 << 7
+	GPIO_V2_LINE_FLAG_BIAS_PULL_UP         uint64 = 1 << 8
+	GPIO_V2_LINE_FLAG_BIAS_PULL_DOWN       uint64 = 1 << 9
+	GPIO_V2_LINE_FLAG_BIAS_DISABLED        uint64 = 1 << 10
+	GPIO_V2_LINE_FLAG_EVENT_CLOCK_REALTIME uint64 = 1 << 11
+)
+
+const (
+	// Masks of flags which are mutually exclusive
+	GPIO_V2_LINE_FLAG_DIRECTION = GPIO_V2_LINE_FLAG_INPUT | GPIO_V2_LINE_FLAG_OUTPUT
+	GPIO_V2_LINE_FLAG_EDGE      = GPIO_V2_LINE_FLAG_EDGE_RISING | GPIO_V2_LINE_FLAG_EDGE_FALLING
+	GPIO_V2_LINE_FLAG_DRIVE     = GPIO_V2_LINE_FLAG_OPEN_DRAIN | GPIO_V2_LINE_FLAG_OPEN_SOURCE
+	GPIO_V2_LINE_FLAG_BIAS      = GPIO_V2_LINE_FLAG_BIAS_PULL_UP | GPIO_V2_LINE_FLAG_BIAS_PULL_DOWN | GPIO_V2_LINE_FLAG_BIAS_DISABLED
+)
+
+const (
+	// gpio_v2_line_attr_id values
+	GPIO_V2_LINE_ATTR_ID_FLAGS         uint32 = 1
+	GPIO_V2_LINE_ATTR_ID_OUTPUT_VALUES uint32 = 2
+	GPIO_V2_LINE_ATTR_ID_DEBOUNCE      uint32 = 3
+)
+
+const (
+	// gpio_v2_line_event_id values
+	GPIO_V2_LINE_EVENT_RISING_EDGE  uint32 = 1
+	GPIO_V2_LINE_EVENT_FALLING_EDGE uint32 = 2
+)
+
+const (
+	// Size of a line event in bytes
+	GPIO_V2_LINE_EVENT_SIZE = 48
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// SYSTEM CALLS
+
+func (gpiochip_sys) Open(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_SYNC, 0)
+}
+
+func (gpiochip_sys) Ioctl(fd uintptr, cmd uintptr, arg unsafe.Pointer) error {
+	if _, _, err := syscall.Syscall(syscall.SYS_IOCTL, fd, cmd, uintptr(arg)); err != 0 {
+		return os.NewSyscallError("ioctl", err)
+	} else {
+		return nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// cstring converts a NUL-terminated byte array into a string
+func cstring(buf []byte) string {
+	for i, c := range buf {
+		if c == 0 {
+			return string(buf[:i])
+		}
+	}
+	return string(buf)
+}
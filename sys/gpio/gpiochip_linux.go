@@ -0,0 +1,646 @@
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi-hw/sys/filepoll"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// GPIOChip is the configuration for the character device driver, which
+// uses line requests on /dev/gpiochipN rather than the sysfs interface
+type GPIOChip struct {
+	// Chip number, for /dev/gpiochipN
+	Chip uint
+
+	// Consumer label for requested lines, defaults to "gopi"
+	Consumer string
+
+	// Filepoller for edge events
+	FilePoll filepoll.FilePollInterface
+
+	// System calls, which can be replaced for testing
+	io gpiochip_io
+}
+
+// GPIODrive determines how an output pin is driven
+type GPIODrive uint8
+
+// GPIOChipInterface is implemented by the character device driver in
+// addition to the gopi.GPIO interface
+type GPIOChipInterface interface {
+	gopi.GPIO
+
+	// Set drive mode for an output pin
+	SetDriveMode(gopi.GPIOPin, GPIODrive) error
+}
+
+type gpiochip struct {
+	log      gopi.Logger
+	chip     uint
+	consumer string
+	name     string
+	label    string
+	lines    uint32
+	dev      *os.File
+	io       gpiochip_io
+	filepoll filepoll.FilePollInterface
+	requests map[gopi.GPIOPin]*gpiochip_line
+	dropped  uint64
+
+	sync.Mutex
+	event.Publisher
+}
+
+// gpiochip_line is a line which has been requested
+type gpiochip_line struct {
+	file    *os.File
+	flags   uint64
+	watched bool
+	seq     uint32
+}
+
+type gpiochip_event struct {
+	driver *gpiochip
+	pin    gopi.GPIOPin
+	edge   gopi.GPIOEdge
+	ts     time.Duration
+	seq    uint32
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	GPIO_DEV_GPIOCHIP = "/dev/gpiochip%v"
+	GPIO_CONSUMER     = "gopi"
+)
+
+const (
+	GPIO_DRIVE_PUSH_PULL GPIODrive = iota
+	GPIO_DRIVE_OPEN_DRAIN
+	GPIO_DRIVE_OPEN_SOURCE
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open
+func (config GPIOChip) Open(logger gopi.Logger) (gopi.Driver, error) {
+	logger.Debug("<hw.gpio.gpiochip>Open{ chip=%v consumer=%v }", config.Chip, strconv.Quote(config.Consumer))
+
+	this := new(gpiochip)
+	this.log = logger
+	this.chip = config.Chip
+	this.requests = make(map[gopi.GPIOPin]*gpiochip_line)
+
+	// Set consumer label
+	if config.Consumer == "" {
+		this.consumer = GPIO_CONSUMER
+	} else if len(config.Consumer) >= GPIO_MAX_NAME_SIZE {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.consumer = config.Consumer
+	}
+
+	// System calls
+	if config.io != nil {
+		this.io = config.io
+	} else {
+		this.io = gpiochip_sys{}
+	}
+
+	// File Poll module is required or else returns ErrBadParameter
+	if config.FilePoll != nil {
+		this.filepoll = config.FilePoll
+	} else {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Open the device
+	if dev, err := this.io.Open(fmt.Sprintf(GPIO_DEV_GPIOCHIP, config.Chip)); err != nil {
+		return nil, err
+	} else {
+		this.dev = dev
+	}
+
+	// Get chip information
+	var info gpiochip_info
+	if err := this.io.Ioctl(this.dev.Fd(), GPIO_GET_CHIPINFO_IOCTL, unsafe.Pointer(&info)); err != nil {
+		this.dev.Close()
+		return nil, err
+	} else {
+		this.name = cstring(info.name[:])
+		this.label = cstring(info.label[:])
+		this.lines = info.lines
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close
+func (this *gpiochip) Close() error {
+	this.log.Debug("<hw.gpio.gpiochip>Close{ chip=%v }", this.chip)
+	this.Lock()
+	defer this.Unlock()
+
+	// Release lines
+	for pin, line := range this.requests {
+		if line.watched {
+			if err := this.filepoll.Unwatch(line.file); err != nil {
+				this.log.Warn("<hw.gpio.gpiochip>Close: %v: %v", pin, err)
+			}
+		}
+		if err := line.file.Close(); err != nil {
+			this.log.Warn("<hw.gpio.gpiochip>Close: %v: %v", pin, err)
+		}
+	}
+
+	// Close subscriber channels
+	this.Publisher.Close()
+
+	// Close device
+	err := this.dev.Close()
+
+	// Zero out member variables
+	this.requests = nil
+	this.filepoll = nil
+	this.dev = nil
+
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - RETURN INFORMATION
+
+// Return number of physical pins, which is not known for a gpiochip
+func (this *gpiochip) NumberOfPhysicalPins() uint {
+	return 0
+}
+
+// Return array of lines for the chip
+func (this *gpiochip) Pins() []gopi.GPIOPin {
+	pins := make([]gopi.GPIOPin, 0, this.lines)
+	for i := uint32(0); i < this.lines && i < uint32(gopi.GPIO_PIN_NONE); i++ {
+		pins = append(pins, gopi.GPIOPin(i))
+	}
+	return pins
+}
+
+// Return logical pin for physical pin number, which is not known
+// for a gpiochip
+func (this *gpiochip) PhysicalPin(uint) gopi.GPIOPin {
+	return gopi.GPIO_PIN_NONE
+}
+
+// Return physical pin number for logical pin, which is not known
+// for a gpiochip
+func (this *gpiochip) PhysicalPinForPin(gopi.GPIOPin) uint {
+	return 0
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - READ/WRITE
+
+// Read pin state
+func (this *gpiochip) ReadPin(pin gopi.GPIOPin) gopi.GPIOState {
+	this.log.Debug2("<hw.gpio.gpiochip>ReadPin{ pin=%v }", pin)
+	this.Lock()
+	defer this.Unlock()
+
+	if line, err := this.request(pin); err != nil {
+		this.log.Error("Unable to request %v: %v", pin, err)
+		return gopi.GPIO_LOW
+	} else if value, err := this.getValue(line); err != nil {
+		this.log.Error("Unable to read %v: %v", pin, err)
+		return gopi.GPIO_LOW
+	} else if value {
+		return gopi.GPIO_HIGH
+	} else {
+		return gopi.GPIO_LOW
+	}
+}
+
+// WritePin writes pin state - either low or high
+func (this *gpiochip) WritePin(pin gopi.GPIOPin, state gopi.GPIOState) {
+	this.log.Debug2("<hw.gpio.gpiochip>WritePin{ pin=%v state=%v }", pin, state)
+	this.Lock()
+	defer this.Unlock()
+
+	if line, err := this.request(pin); err != nil {
+		this.log.Error("Unable to request %v: %v", pin, err)
+	} else if err := this.setValue(line, state == gopi.GPIO_HIGH); err != nil {
+		this.log.Error("Unable to write value to %v: %v", pin, err)
+	}
+}
+
+// GetPinMode gets pin mode, which is either in or out
+// or returns GPIO_NONE on error
+func (this *gpiochip) GetPinMode(pin gopi.GPIOPin) gopi.GPIOMode {
+	this.log.Debug2("<hw.gpio.gpiochip>GetPinMode{ pin=%v }", pin)
+	this.Lock()
+	defer this.Unlock()
+
+	if info, err := this.lineInfo(pin); err != nil {
+		this.log.Error("Unable to get line information for %v: %v", pin, err)
+		return gopi.GPIO_NONE
+	} else if info.flags&GPIO_V2_LINE_FLAG_OUTPUT != 0 {
+		return gopi.GPIO_OUTPUT
+	} else if info.flags&GPIO_V2_LINE_FLAG_INPUT != 0 {
+		return gopi.GPIO_INPUT
+	} else {
+		return gopi.GPIO_NONE
+	}
+}
+
+// SetPinMode set pin mode to either in or out. No other
+// modes are supported through this driver
+func (this *gpiochip) SetPinMode(pin gopi.GPIOPin, mode gopi.GPIOMode) {
+	this.log.Debug2("<hw.gpio.gpiochip>SetPinMode{ pin=%v mode=%v }", pin, mode)
+	this.Lock()
+	defer this.Unlock()
+
+	line, err := this.request(pin)
+	if err != nil {
+		this.log.Error("Unable to request %v: %v", pin, err)
+		return
+	}
+	switch mode {
+	case gopi.GPIO_INPUT:
+		// Drive flags are only valid for outputs
+		flags := line.flags&^(GPIO_V2_LINE_FLAG_DIRECTION|GPIO_V2_LINE_FLAG_DRIVE) | GPIO_V2_LINE_FLAG_INPUT
+		if err := this.setConfig(line, flags); err != nil {
+			this.log.Error("Unable to set input mode for %v: %v", pin, err)
+		}
+	case gopi.GPIO_OUTPUT:
+		// Edge detection is only valid for inputs
+		if err := this.unwatch(line); err != nil {
+			this.log.Error("Unable to unwatch %v: %v", pin, err)
+		}
+		flags := line.flags&^(GPIO_V2_LINE_FLAG_DIRECTION|GPIO_V2_LINE_FLAG_EDGE) | GPIO_V2_LINE_FLAG_OUTPUT
+		if err := this.setConfig(line, flags); err != nil {
+			this.log.Error("Unable to set output mode for %v: %v", pin, err)
+		}
+	default:
+		this.log.Error("Invalid pin mode %v: %v", pin, mode)
+	}
+}
+
+// SetPullMode sets the bias for a pin to pull up, pull down or
+// to disable the bias
+func (this *gpiochip) SetPullMode(pin gopi.GPIOPin, pull gopi.GPIOPull) error {
+	this.log.Debug2("<hw.gpio.gpiochip>SetPullMode{ pin=%v pull=%v }", pin, pull)
+	this.Lock()
+	defer this.Unlock()
+
+	var bias uint64
+	switch pull {
+	case gopi.GPIO_PULL_OFF:
+		bias = GPIO_V2_LINE_FLAG_BIAS_DISABLED
+	case gopi.GPIO_PULL_DOWN:
+		bias = GPIO_V2_LINE_FLAG_BIAS_PULL_DOWN
+	case gopi.GPIO_PULL_UP:
+		bias = GPIO_V2_LINE_FLAG_BIAS_PULL_UP
+	default:
+		return gopi.ErrBadParameter
+	}
+
+	if line, err := this.request(pin); err != nil {
+		return err
+	} else if line.flags&GPIO_V2_LINE_FLAG_DIRECTION == 0 {
+		// Bias requires the direction to be known
+		return fmt.Errorf("SetPullMode: %v has no direction", pin)
+	} else {
+		return this.setConfig(line, line.flags&^GPIO_V2_LINE_FLAG_BIAS|bias)
+	}
+}
+
+// SetDriveMode sets an output pin to push-pull, open-drain or
+// open-source
+func (this *gpiochip) SetDriveMode(pin gopi.GPIOPin, drive GPIODrive) error {
+	this.log.Debug2("<hw.gpio.gpiochip>SetDriveMode{ pin=%v drive=%v }", pin, drive)
+	this.Lock()
+	defer this.Unlock()
+
+	var flags uint64
+	switch drive {
+	case GPIO_DRIVE_PUSH_PULL:
+		flags = 0
+	case GPIO_DRIVE_OPEN_DRAIN:
+		flags = GPIO_V2_LINE_FLAG_OPEN_DRAIN
+	case GPIO_DRIVE_OPEN_SOURCE:
+		flags = GPIO_V2_LINE_FLAG_OPEN_SOURCE
+	default:
+		return gopi.ErrBadParameter
+	}
+
+	if line, err := this.request(pin); err != nil {
+		return err
+	} else if line.flags&GPIO_V2_LINE_FLAG_OUTPUT == 0 {
+		// Drive flags are only valid for outputs
+		return fmt.Errorf("SetDriveMode: %v is not an output", pin)
+	} else {
+		return this.setConfig(line, line.flags&^GPIO_V2_LINE_FLAG_DRIVE|flags)
+	}
+}
+
+// Watch will watch a pin for rising, falling or both edges. When
+// set to EDGE_NONE then watching is stopped
+func (this *gpiochip) Watch(pin gopi.GPIOPin, edge gopi.GPIOEdge) error {
+	this.log.Debug2("<hw.gpio.gpiochip>Watch{ pin=%v edge=%v }", pin, edge)
+	this.Lock()
+	defer this.Unlock()
+
+	var flags uint64
+	switch edge {
+	case gopi.GPIO_EDGE_NONE:
+		flags = 0
+	case gopi.GPIO_EDGE_RISING:
+		flags = GPIO_V2_LINE_FLAG_EDGE_RISING
+	case gopi.GPIO_EDGE_FALLING:
+		flags = GPIO_V2_LINE_FLAG_EDGE_FALLING
+	case gopi.GPIO_EDGE_BOTH:
+		flags = GPIO_V2_LINE_FLAG_EDGE_RISING | GPIO_V2_LINE_FLAG_EDGE_FALLING
+	default:
+		return gopi.ErrBadParameter
+	}
+
+	line, err := this.request(pin)
+	if err != nil {
+		return err
+	}
+
+	// Stop watching
+	if flags == 0 {
+		if line.flags&GPIO_V2_LINE_FLAG_EDGE != 0 {
+			if err := this.setConfig(line, line.flags&^GPIO_V2_LINE_FLAG_EDGE); err != nil {
+				return err
+			}
+		}
+		return this.unwatch(line)
+	}
+
+	// Edge detection requires an input
+	flags |= line.flags&^(GPIO_V2_LINE_FLAG_DIRECTION|GPIO_V2_LINE_FLAG_DRIVE|GPIO_V2_LINE_FLAG_EDGE) | GPIO_V2_LINE_FLAG_INPUT
+	if err := this.setConfig(line, flags); err != nil {
+		return err
+	} else if line.watched {
+		return nil
+	} else if err := this.filepoll.Watch(line.file, filepoll.FILEPOLL_MODE_READ, func(handle *os.File, mode filepoll.FilePollMode) {
+		if err := this.handleEvents(handle, pin); err != nil {
+			this.log.Warn("Watch: %v: %v", pin, err)
+		}
+	}); err != nil {
+		return err
+	} else {
+		line.watched = true
+	}
+
+	// Success
+	return nil
+}
+
+// Dropped returns the number of edge events which were discarded by
+// the kernel, determined from gaps in the line sequence numbers
+func (this *gpiochip) Dropped() uint64 {
+	return atomic.LoadUint64(&this.dropped)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - EVENT
+
+func (this *gpiochip_event) Name() string {
+	return "GPIOEvent"
+}
+
+func (this *gpiochip_event) Source() gopi.Driver {
+	return this.driver
+}
+
+func (this *gpiochip_event) Pin() gopi.GPIOPin {
+	return this.pin
+}
+
+func (this *gpiochip_event) Edge() gopi.GPIOEdge {
+	return this.edge
+}
+
+// Timestamp returns the kernel timestamp for the edge, which is
+// measured from the monotonic clock
+func (this *gpiochip_event) Timestamp() time.Duration {
+	return this.ts
+}
+
+// Sequence returns the sequence number of the event for the pin
+func (this *gpiochip_event) Sequence() uint32 {
+	return this.seq
+}
+
+func (this *gpiochip_event) String() string {
+	return fmt.Sprintf("<hw.gpio.gpiochip.Event>{ pin=%v edge=%v ts=%v seq=%v }", this.pin, this.edge, this.ts, this.seq)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// request returns a line, requesting the line from the chip if it
+// has not yet been requested. The direction of the line is preserved.
+func (this *gpiochip) request(pin gopi.GPIOPin) (*gpiochip_line, error) {
+	if line, exists := this.requests[pin]; exists {
+		return line, nil
+	}
+
+	// Get existing flags for the line
+	info, err := this.lineInfo(pin)
+	if err != nil {
+		return nil, err
+	}
+	flags := info.flags & (GPIO_V2_LINE_FLAG_DIRECTION | GPIO_V2_LINE_FLAG_ACTIVE_LOW | GPIO_V2_LINE_FLAG_DRIVE | GPIO_V2_LINE_FLAG_BIAS)
+
+	// Inputs are requested with their bias, outputs are requested "as-is"
+	// so that the output value is not changed
+	var req gpio_v2_line_request
+	req.offsets[0] = uint32(pin)
+	req.num_lines = 1
+	copy(req.consumer[:GPIO_MAX_NAME_SIZE-1], this.consumer)
+	if flags&GPIO_V2_LINE_FLAG_INPUT != 0 {
+		req.config.flags = flags &^ GPIO_V2_LINE_FLAG_DRIVE
+	} else {
+		req.config.flags = flags & GPIO_V2_LINE_FLAG_ACTIVE_LOW
+	}
+	if err := this.io.Ioctl(this.dev.Fd(), GPIO_V2_GET_LINE_IOCTL, unsafe.Pointer(&req)); err != nil {
+		return nil, err
+	} else if req.fd < 0 {
+		return nil, gopi.ErrUnexpectedResponse
+	}
+
+	line := &gpiochip_line{
+		file:  os.NewFile(uintptr(req.fd), fmt.Sprintf("%v:%v", this.name, uint(pin))),
+		flags: flags,
+	}
+	this.requests[pin] = line
+	return line, nil
+}
+
+func (this *gpiochip) lineInfo(pin gopi.GPIOPin) (*gpio_v2_line_info, error) {
+	if uint32(pin) >= this.lines {
+		return nil, gopi.ErrBadParameter
+	}
+	info := &gpio_v2_line_info{offset: uint32(pin)}
+	if err := this.io.Ioctl(this.dev.Fd(), GPIO_V2_GET_LINEINFO_IOCTL, unsafe.Pointer(info)); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// setConfig reconfigures a line, preserving the value of outputs
+func (this *gpiochip) setConfig(line *gpiochip_line, flags uint64) error {
+	var config gpio_v2_line_config
+	config.flags = flags
+	if flags&GPIO_V2_LINE_FLAG_OUTPUT != 0 && line.flags&GPIO_V2_LINE_FLAG_OUTPUT != 0 {
+		if value, err := this.getValue(line); err != nil {
+			return err
+		} else if value {
+			config.num_attrs = 1
+			config.attrs[0].attr.id = GPIO_V2_LINE_ATTR_ID_OUTPUT_VALUES
+			config.attrs[0].attr.value = 1
+			config.attrs[0].mask = 1
+		}
+	}
+	if err := this.io.Ioctl(line.file.Fd(), GPIO_V2_LINE_SET_CONFIG_IOCTL, unsafe.Pointer(&config)); err != nil {
+		return err
+	} else {
+		line.flags = flags
+		return nil
+	}
+}
+
+func (this *gpiochip) getValue(line *gpiochip_line) (bool, error) {
+	values := gpio_v2_line_values{mask: 1}
+	if err := this.io.Ioctl(line.file.Fd(), GPIO_V2_LINE_GET_VALUES_IOCTL, unsafe.Pointer(&values)); err != nil {
+		return false, err
+	} else {
+		return values.bits&1 != 0, nil
+	}
+}
+
+func (this *gpiochip) setValue(line *gpiochip_line, value bool) error {
+	values := gpio_v2_line_values{mask: 1}
+	if value {
+		values.bits = 1
+	}
+	return this.io.Ioctl(line.file.Fd(), GPIO_V2_LINE_SET_VALUES_IOCTL, unsafe.Pointer(&values))
+}
+
+func (this *gpiochip) unwatch(line *gpiochip_line) error {
+	if line.watched == false {
+		return nil
+	} else if err := this.filepoll.Unwatch(line.file); err != nil {
+		return err
+	} else {
+		line.watched = false
+		return nil
+	}
+}
+
+// handleEvents reads edge events from a line and emits them
+func (this *gpiochip) handleEvents(handle *os.File, pin gopi.GPIOPin) error {
+	buf := make([]byte, GPIO_V2_LINE_EVENT_SIZE*16)
+	if n, err := handle.Read(buf); err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	} else {
+		for i := 0; i+GPIO_V2_LINE_EVENT_SIZE <= n; i += GPIO_V2_LINE_EVENT_SIZE {
+			evt := decodeLineEvent(buf[i : i+GPIO_V2_LINE_EVENT_SIZE])
+			this.countDropped(pin, evt.line_seqno)
+			this.Emit(&gpiochip_event{
+				driver: this,
+				pin:    pin,
+				edge:   edgeForLineEvent(evt.id),
+				ts:     time.Duration(evt.timestamp_ns),
+				seq:    evt.line_seqno,
+			})
+		}
+		return nil
+	}
+}
+
+// countDropped records the sequence number of a line event, and counts
+// any gap in the sequence as events dropped by the kernel
+func (this *gpiochip) countDropped(pin gopi.GPIOPin, seq uint32) {
+	this.Lock()
+	defer this.Unlock()
+	if line, exists := this.requests[pin]; exists {
+		if line.seq != 0 && seq > line.seq+1 {
+			atomic.AddUint64(&this.dropped, uint64(seq-line.seq-1))
+		}
+		line.seq = seq
+	}
+}
+
+func decodeLineEvent(buf []byte) gpio_v2_line_event {
+	return gpio_v2_line_event{
+		timestamp_ns: binary.LittleEndian.Uint64(buf[0:]),
+		id:           binary.LittleEndian.Uint32(buf[8:]),
+		offset:       binary.LittleEndian.Uint32(buf[12:]),
+		seqno:        binary.LittleEndian.Uint32(buf[16:]),
+		line_seqno:   binary.LittleEndian.Uint32(buf[20:]),
+	}
+}
+
+func edgeForLineEvent(id uint32) gopi.GPIOEdge {
+	switch id {
+	case GPIO_V2_LINE_EVENT_RISING_EDGE:
+		return gopi.GPIO_EDGE_RISING
+	case GPIO_V2_LINE_EVENT_FALLING_EDGE:
+		return gopi.GPIO_EDGE_FALLING
+	default:
+		return gopi.GPIO_EDGE_NONE
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *gpiochip) String() string {
+	return fmt.Sprintf("<hw.gpio.gpiochip>{ chip=%v name=%v label=%v lines=%v consumer=%v requested=%v }", this.chip, strconv.Quote(this.name), strconv.Quote(this.label), this.lines, strconv.Quote(this.consumer), len(this.requests))
+}
+
+func (d GPIODrive) String() string {
+	switch d {
+	case GPIO_DRIVE_PUSH_PULL:
+		return "GPIO_DRIVE_PUSH_PULL"
+	case GPIO_DRIVE_OPEN_DRAIN:
+		return "GPIO_DRIVE_OPEN_DRAIN"
+	case GPIO_DRIVE_OPEN_SOURCE:
+		return "GPIO_DRIVE_OPEN_SOURCE"
+	default:
+		return "[??? Invalid GPIODrive value]"
+	}
+}
@@ -0,0 +1,357 @@
+package gpio
+
+import (
+	"encoding/binary"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi-hw/sys/filepoll"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE IOCTL LAYER
+
+type fakechip struct {
+	lines    []*fakeline
+	requests map[uintptr]*fakeline
+}
+
+type fakeline struct {
+	offset   uint32
+	flags    uint64
+	value    bool
+	consumer string
+	r, w     *os.File
+}
+
+func newFakeChip(lines int) *fakechip {
+	this := &fakechip{make([]*fakeline, lines), make(map[uintptr]*fakeline)}
+	for i := range this.lines {
+		this.lines[i] = &fakeline{offset: uint32(i), flags: GPIO_V2_LINE_FLAG_INPUT}
+	}
+	return this
+}
+
+func (this *fakechip) Open(path string) (*os.File, error) {
+	return os.Open(os.DevNull)
+}
+
+func (this *fakechip) Ioctl(fd uintptr, cmd uintptr, arg unsafe.Pointer) error {
+	switch cmd {
+	case GPIO_GET_CHIPINFO_IOCTL:
+		info := (*gpiochip_info)(arg)
+		copy(info.name[:], "gpiochip0")
+		copy(info.label[:], "fake")
+		info.lines = uint32(len(this.lines))
+	case GPIO_V2_GET_LINEINFO_IOCTL:
+		info := (*gpio_v2_line_info)(arg)
+		line := this.lines[info.offset]
+		info.flags = line.flags
+		if line.r != nil {
+			info.flags |= GPIO_V2_LINE_FLAG_USED
+		}
+		copy(info.consumer[:], line.consumer)
+	case GPIO_V2_GET_LINE_IOCTL:
+		req := (*gpio_v2_line_request)(arg)
+		line := this.lines[req.offsets[0]]
+		if line.r != nil {
+			return syscall.EBUSY
+		} else if err := this.setFlags(line, req.config.flags, &req.config); err != nil {
+			return err
+		} else if r, w, err := os.Pipe(); err != nil {
+			return err
+		} else if dup, err := syscall.Dup(int(r.Fd())); err != nil {
+			return err
+		} else {
+			line.r, line.w = r, w
+			line.consumer = cstring(req.consumer[:])
+			this.requests[uintptr(dup)] = line
+			req.fd = int32(dup)
+		}
+	case GPIO_V2_LINE_SET_CONFIG_IOCTL:
+		if line, exists := this.requests[fd]; exists == false {
+			return syscall.EBADF
+		} else {
+			config := (*gpio_v2_line_config)(arg)
+			return this.setFlags(line, config.flags, config)
+		}
+	case GPIO_V2_LINE_GET_VALUES_IOCTL:
+		if line, exists := this.requests[fd]; exists == false {
+			return syscall.EBADF
+		} else if line.value {
+			(*gpio_v2_line_values)(arg).bits = 1
+		} else {
+			(*gpio_v2_line_values)(arg).bits = 0
+		}
+	case GPIO_V2_LINE_SET_VALUES_IOCTL:
+		if line, exists := this.requests[fd]; exists == false {
+			return syscall.EBADF
+		} else if line.flags&GPIO_V2_LINE_FLAG_OUTPUT == 0 {
+			return syscall.EPERM
+		} else {
+			line.value = (*gpio_v2_line_values)(arg).bits&1 != 0
+		}
+	default:
+		return syscall.ENOTTY
+	}
+	return nil
+}
+
+// setFlags validates flags in the same way as the kernel
+func (this *fakechip) setFlags(line *fakeline, flags uint64, config *gpio_v2_line_config) error {
+	if flags&GPIO_V2_LINE_FLAG_DIRECTION == GPIO_V2_LINE_FLAG_DIRECTION {
+		return syscall.EINVAL
+	} else if flags&GPIO_V2_LINE_FLAG_EDGE != 0 && flags&GPIO_V2_LINE_FLAG_INPUT == 0 {
+		return syscall.EINVAL
+	} else if flags&GPIO_V2_LINE_FLAG_DRIVE != 0 && flags&GPIO_V2_LINE_FLAG_OUTPUT == 0 {
+		return syscall.EINVAL
+	} else if flags&GPIO_V2_LINE_FLAG_BIAS != 0 && flags&GPIO_V2_LINE_FLAG_DIRECTION == 0 {
+		return syscall.EINVAL
+	}
+	if flags&GPIO_V2_LINE_FLAG_DIRECTION == 0 {
+		// Requested as-is
+		line.flags = line.flags&GPIO_V2_LINE_FLAG_DIRECTION | flags
+		return nil
+	}
+	line.flags = flags
+	if flags&GPIO_V2_LINE_FLAG_OUTPUT != 0 {
+		line.value = false
+		for i := uint32(0); i < config.num_attrs; i++ {
+			if config.attrs[i].attr.id == GPIO_V2_LINE_ATTR_ID_OUTPUT_VALUES && config.attrs[i].mask&1 != 0 {
+				line.value = config.attrs[i].attr.value&1 != 0
+			}
+		}
+	}
+	return nil
+}
+
+// edge writes an edge event to a requested line
+func (this *fakechip) edge(pin gopi.GPIOPin, id uint32, ts time.Duration, seq uint32) error {
+	buf := make([]byte, GPIO_V2_LINE_EVENT_SIZE)
+	binary.LittleEndian.PutUint64(buf[0:], uint64(ts))
+	binary.LittleEndian.PutUint32(buf[8:], id)
+	binary.LittleEndian.PutUint32(buf[12:], uint32(pin))
+	binary.LittleEndian.PutUint32(buf[16:], seq)
+	binary.LittleEndian.PutUint32(buf[20:], seq)
+	_, err := this.lines[pin].w.Write(buf)
+	return err
+}
+
+func (this *fakechip) Close() {
+	for _, line := range this.lines {
+		if line.r != nil {
+			line.r.Close()
+			line.w.Close()
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestGPIOChip_000(t *testing.T) {
+	// Check structure sizes match linux/gpio.h
+	if size := unsafe.Sizeof(gpiochip_info{}); size != 68 {
+		t.Error("gpiochip_info: unexpected size", size)
+	}
+	if size := unsafe.Sizeof(gpio_v2_line_info{}); size != 256 {
+		t.Error("gpio_v2_line_info: unexpected size", size)
+	}
+	if size := unsafe.Sizeof(gpio_v2_line_config{}); size != 272 {
+		t.Error("gpio_v2_line_config: unexpected size", size)
+	}
+	if size := unsafe.Sizeof(gpio_v2_line_request{}); size != 592 {
+		t.Error("gpio_v2_line_request: unexpected size", size)
+	}
+	if size := unsafe.Sizeof(gpio_v2_line_values{}); size != 16 {
+		t.Error("gpio_v2_line_values: unexpected size", size)
+	}
+	if size := unsafe.Sizeof(gpio_v2_line_event{}); size != GPIO_V2_LINE_EVENT_SIZE {
+		t.Error("gpio_v2_line_event: unexpected size", size)
+	}
+}
+
+func TestGPIOChip_001(t *testing.T) {
+	app, chip, driver := openGPIOChip(t, 8)
+	defer app.Close()
+	defer chip.Close()
+	defer driver.Close()
+
+	if pins := driver.Pins(); len(pins) != 8 {
+		t.Error("Unexpected number of pins", pins)
+	} else if driver.NumberOfPhysicalPins() != 0 {
+		t.Error("Unexpected number of physical pins")
+	} else if driver.GetPinMode(gopi.GPIOPin(8)) != gopi.GPIO_NONE {
+		t.Error("Expected GPIO_NONE for invalid pin")
+	} else {
+		t.Log(driver)
+	}
+}
+
+func TestGPIOChip_002(t *testing.T) {
+	app, chip, driver := openGPIOChip(t, 8)
+	defer app.Close()
+	defer chip.Close()
+	defer driver.Close()
+
+	pin := gopi.GPIOPin(4)
+	if mode := driver.GetPinMode(pin); mode != gopi.GPIO_INPUT {
+		t.Error("Unexpected mode", mode)
+	}
+	driver.SetPinMode(pin, gopi.GPIO_OUTPUT)
+	if mode := driver.GetPinMode(pin); mode != gopi.GPIO_OUTPUT {
+		t.Error("Unexpected mode", mode)
+	}
+	driver.WritePin(pin, gopi.GPIO_HIGH)
+	if state := driver.ReadPin(pin); state != gopi.GPIO_HIGH {
+		t.Error("Unexpected state", state)
+	} else if chip.lines[pin].value != true {
+		t.Error("Unexpected line value")
+	} else if chip.lines[pin].consumer != "test" {
+		t.Error("Unexpected consumer", chip.lines[pin].consumer)
+	}
+
+	// Changing the bias on an output preserves the value
+	if err := driver.SetPullMode(pin, gopi.GPIO_PULL_UP); err != nil {
+		t.Error(err)
+	} else if chip.lines[pin].flags&GPIO_V2_LINE_FLAG_BIAS_PULL_UP == 0 {
+		t.Error("Expected pull up flag")
+	} else if driver.ReadPin(pin) != gopi.GPIO_HIGH {
+		t.Error("Expected value to be preserved")
+	}
+
+	driver.WritePin(pin, gopi.GPIO_LOW)
+	if state := driver.ReadPin(pin); state != gopi.GPIO_LOW {
+		t.Error("Unexpected state", state)
+	}
+}
+
+func TestGPIOChip_003(t *testing.T) {
+	app, chip, driver := openGPIOChip(t, 8)
+	defer app.Close()
+	defer chip.Close()
+	defer driver.Close()
+
+	pin := gopi.GPIOPin(2)
+	if err := driver.SetPullMode(pin, gopi.GPIO_PULL_DOWN); err != nil {
+		t.Error(err)
+	} else if chip.lines[pin].flags&GPIO_V2_LINE_FLAG_BIAS != GPIO_V2_LINE_FLAG_BIAS_PULL_DOWN {
+		t.Error("Expected pull down flag")
+	} else if err := driver.SetPullMode(pin, gopi.GPIO_PULL_OFF); err != nil {
+		t.Error(err)
+	} else if chip.lines[pin].flags&GPIO_V2_LINE_FLAG_BIAS != GPIO_V2_LINE_FLAG_BIAS_DISABLED {
+		t.Error("Expected bias disabled flag")
+	}
+
+	// Drive mode is only valid for outputs
+	if err := driver.SetDriveMode(pin, GPIO_DRIVE_OPEN_DRAIN); err == nil {
+		t.Error("Expected error setting drive mode on input")
+	}
+	driver.SetPinMode(pin, gopi.GPIO_OUTPUT)
+	if err := driver.SetDriveMode(pin, GPIO_DRIVE_OPEN_DRAIN); err != nil {
+		t.Error(err)
+	} else if chip.lines[pin].flags&GPIO_V2_LINE_FLAG_DRIVE != GPIO_V2_LINE_FLAG_OPEN_DRAIN {
+		t.Error("Expected open drain flag")
+	} else if err := driver.SetDriveMode(pin, GPIO_DRIVE_OPEN_SOURCE); err != nil {
+		t.Error(err)
+	} else if chip.lines[pin].flags&GPIO_V2_LINE_FLAG_DRIVE != GPIO_V2_LINE_FLAG_OPEN_SOURCE {
+		t.Error("Expected open source flag")
+	}
+
+	// Changing to an input clears the drive mode
+	driver.SetPinMode(pin, gopi.GPIO_INPUT)
+	if chip.lines[pin].flags&GPIO_V2_LINE_FLAG_DRIVE != 0 {
+		t.Error("Expected drive flags to be cleared")
+	} else if chip.lines[pin].flags&GPIO_V2_LINE_FLAG_BIAS != GPIO_V2_LINE_FLAG_BIAS_DISABLED {
+		t.Error("Expected bias to be preserved")
+	}
+}
+
+func TestGPIOChip_004(t *testing.T) {
+	app, chip, driver := openGPIOChip(t, 8)
+	defer app.Close()
+	defer chip.Close()
+	defer driver.Close()
+
+	pin := gopi.GPIOPin(5)
+	events := driver.Subscribe()
+	defer driver.Unsubscribe(events)
+
+	if err := driver.Watch(pin, gopi.GPIO_EDGE_BOTH); err != nil {
+		t.Fatal(err)
+	} else if chip.lines[pin].flags&GPIO_V2_LINE_FLAG_EDGE != GPIO_V2_LINE_FLAG_EDGE {
+		t.Error("Expected edge flags")
+	}
+
+	if err := chip.edge(pin, GPIO_V2_LINE_EVENT_RISING_EDGE, 1234*time.Microsecond, 1); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case evt := <-events:
+		if evt_, ok := evt.(*gpiochip_event); ok == false {
+			t.Error("Unexpected event", evt)
+		} else if evt_.Pin() != pin || evt_.Edge() != gopi.GPIO_EDGE_RISING {
+			t.Error("Unexpected event", evt_)
+		} else if evt_.Timestamp() != 1234*time.Microsecond || evt_.Sequence() != 1 {
+			t.Error("Unexpected timestamp or sequence", evt_)
+		} else {
+			t.Log(evt_)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timeout waiting for event")
+	}
+
+	// A gap in the sequence is counted as dropped
+	if err := chip.edge(pin, GPIO_V2_LINE_EVENT_FALLING_EDGE, 2345*time.Microsecond, 4); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case evt := <-events:
+		if evt_, ok := evt.(GPIOTimestampedEvent); ok == false {
+			t.Error("Unexpected event", evt)
+		} else if evt_.Edge() != gopi.GPIO_EDGE_FALLING || evt_.Sequence() != 4 {
+			t.Error("Unexpected event", evt_)
+		} else if dropped := GPIODroppedCounter(driver).Dropped(); dropped != 2 {
+			t.Error("Unexpected dropped count", dropped)
+		}
+	case <-time.After(time.Second):
+		t.Error("Timeout waiting for event")
+	}
+
+	// Stop watching
+	if err := driver.Watch(pin, gopi.GPIO_EDGE_NONE); err != nil {
+		t.Error(err)
+	} else if chip.lines[pin].flags&GPIO_V2_LINE_FLAG_EDGE != 0 {
+		t.Error("Expected edge flags to be cleared")
+	} else if driver.requests[pin].watched {
+		t.Error("Expected line to be unwatched")
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func openGPIOChip(t *testing.T, lines int) (*gopi.AppInstance, *fakechip, *gpiochip) {
+	t.Helper()
+	chip := newFakeChip(lines)
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig("hw/filepoll")); err != nil {
+		t.Fatal(err)
+	} else if driver, err := gopi.Open(GPIOChip{
+		Consumer: "test",
+		FilePoll: app.ModuleInstance("hw/filepoll").(filepoll.FilePollInterface),
+		io:       chip,
+	}, app.Logger); err != nil {
+		t.Fatal(err)
+	} else {
+		return app, chip, driver.(*gpiochip)
+	}
+	return nil, nil, nil
+}
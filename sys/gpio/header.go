@@ -0,0 +1,150 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	"strconv"
+	"strings"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// header_layout describes the physical pins on a header or connector,
+// where physical pins are numbered from one
+type header_layout struct {
+	name  string
+	names []string
+	pins  []gopi.GPIOPin
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// VARIABLES
+
+var (
+	// Raspberry Pi 1 Model B Revision 1, where the pins which later became
+	// power and ground were marked as do not connect
+	header_26_rev1 = newHeaderLayout("P1 (26 pins, revision 1)",
+		"3V3", "5V", "GPIO0", "DNC", "GPIO1", "GND", "GPIO4", "GPIO14", "DNC", "GPIO15",
+		"GPIO17", "GPIO18", "GPIO21", "DNC", "GPIO22", "GPIO23", "DNC", "GPIO24", "GPIO10", "DNC",
+		"GPIO9", "GPIO25", "GPIO11", "GPIO8", "DNC", "GPIO7",
+	)
+
+	// Raspberry Pi 1 Model A and Model B Revision 2
+	header_26_rev2 = newHeaderLayout("P1 (26 pins, revision 2)",
+		"3V3", "5V", "GPIO2", "5V", "GPIO3", "GND", "GPIO4", "GPIO14", "GND", "GPIO15",
+		"GPIO17", "GPIO18", "GPIO27", "GND", "GPIO22", "GPIO23", "3V3", "GPIO24", "GPIO10", "GND",
+		"GPIO9", "GPIO25", "GPIO11", "GPIO8", "GND", "GPIO7",
+	)
+
+	// Raspberry Pi Model A+, B+, 2, 3, 4 and Zero, where pins 27 and 28 are
+	// reserved for the HAT identification EEPROM
+	header_40 = newHeaderLayout("J8 (40 pins)",
+		"3V3", "5V", "GPIO2", "5V", "GPIO3", "GND", "GPIO4", "GPIO14", "GND", "GPIO15",
+		"GPIO17", "GPIO18", "GPIO27", "GND", "GPIO22", "GPIO23", "3V3", "GPIO24", "GPIO10", "GND",
+		"GPIO9", "GPIO25", "GPIO11", "GPIO8", "GND", "GPIO7", "GPIO0", "GPIO1", "GPIO5", "GND",
+		"GPIO6", "GPIO12", "GPIO13", "GND", "GPIO19", "GPIO16", "GPIO26", "GPIO20", "GND", "GPIO21",
+	)
+
+	// Compute Module SODIMM connector, where pins above 92 carry the
+	// display, camera, USB, HDMI and power signals
+	header_sodimm = newHeaderLayout("SODIMM (200 pins)",
+		"GND", "EMMC_DISABLE_N", "GPIO0", "NC", "GPIO1", "NC", "GND", "NC", "GPIO2", "NC",
+		"GPIO3", "NC", "GND", "NC", "GPIO4", "NC", "GPIO5", "NC", "GND", "NC",
+		"GPIO6", "NC", "GPIO7", "NC", "GND", "GND", "GPIO8", "GPIO28", "GPIO9", "GPIO29",
+		"GND", "GND", "GPIO10", "GPIO30", "GPIO11", "GPIO31", "GND", "GND", "GPIO0-27_VREF", "GPIO0-27_VREF",
+		"GPIO28-45_VREF", "GPIO28-45_VREF", "GND", "GND", "GPIO12", "GPIO32", "GPIO13", "GPIO33", "GND", "GND",
+		"GPIO14", "GPIO34", "GPIO15", "GPIO35", "GND", "GND", "GPIO16", "GPIO36", "GPIO17", "GPIO37",
+		"GND", "GND", "GPIO18", "GPIO38", "GPIO19", "GPIO39", "GND", "GND", "GPIO20", "GPIO40",
+		"GPIO21", "GPIO41", "GND", "GND", "GPIO22", "GPIO42", "GPIO23", "GPIO43", "GND", "GND",
+		"GPIO24", "GPIO44", "GPIO25", "GPIO45", "GND", "GND", "GPIO26", "HDMI_HPD_N_1V8", "GPIO27", "EMMC_EN_N_1V8",
+		"GND", "GND", "DSI0_DN1", "DSI1_DP0", "DSI0_DP1", "DSI1_DN0", "GND", "GND", "DSI0_DN0", "DSI1_CP",
+		"DSI0_DP0", "DSI1_CN", "GND", "GND", "DSI0_CN", "DSI1_DP3", "DSI0_CP", "DSI1_DN3", "GND", "GND",
+		"HDMI_CK_N", "DSI1_DP2", "HDMI_CK_P", "DSI1_DN2", "GND", "GND", "HDMI_D0_N", "DSI1_DP1", "HDMI_D0_P", "DSI1_DN1",
+		"GND", "GND", "HDMI_D1_N", "NC", "HDMI_D1_P", "NC", "GND", "NC", "HDMI_D2_N", "NC",
+		"HDMI_D2_P", "NC", "GND", "GND", "CAM1_DP3", "CAM0_DP0", "CAM1_DN3", "CAM0_DN0", "GND", "GND",
+		"CAM1_DP2", "CAM0_CP", "CAM1_DN2", "CAM0_CN", "GND", "GND", "CAM1_CP", "CAM0_DP1", "CAM1_CN", "CAM0_DN1",
+		"GND", "GND", "CAM1_DP1", "NC", "CAM1_DN1", "NC", "GND", "NC", "CAM1_DP0", "NC",
+		"CAM1_DN0", "NC", "GND", "GND", "USB_DP", "TVDAC", "USB_DM", "USB_OTGID", "GND", "GND",
+		"HDMI_CEC", "VC_TRST_N", "HDMI_SDA", "VC_TDI", "HDMI_SCL", "VC_TMS", "RUN", "VC_TDO", "VDD_CORE", "VC_TCK",
+		"GND", "GND", "1V8", "1V8", "1V8", "1V8", "GND", "GND", "VDAC", "VDAC",
+		"3V3", "3V3", "3V3", "3V3", "GND", "GND", "VBAT", "VBAT", "VBAT", "VBAT",
+	)
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// NEW
+
+// newHeaderLayout returns a layout from the names of the physical pins,
+// where names of the form GPIOn are GPIO pins
+func newHeaderLayout(name string, names ...string) *header_layout {
+	this := &header_layout{name, names, make([]gopi.GPIOPin, len(names))}
+	for i, name := range names {
+		this.pins[i] = gopi.GPIO_PIN_NONE
+		if strings.HasPrefix(name, "GPIO") {
+			if pin, err := strconv.ParseUint(strings.TrimPrefix(name, "GPIO"), 10, 8); err == nil {
+				this.pins[i] = gopi.GPIOPin(pin)
+			}
+		}
+	}
+	return this
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// NumberOfPhysicalPins returns the number of pins on the header
+func (this *header_layout) NumberOfPhysicalPins() uint {
+	return uint(len(this.pins))
+}
+
+// PhysicalPin returns the logical pin for a physical pin, or
+// GPIO_PIN_NONE if the physical pin is not a GPIO pin
+func (this *header_layout) PhysicalPin(pin uint) gopi.GPIOPin {
+	if pin < 1 || pin > uint(len(this.pins)) {
+		return gopi.GPIO_PIN_NONE
+	} else {
+		return this.pins[pin-1]
+	}
+}
+
+// PhysicalPinForPin returns the physical pin for a logical pin, or
+// zero if the logical pin is not on the header
+func (this *header_layout) PhysicalPinForPin(logical gopi.GPIOPin) uint {
+	if logical == gopi.GPIO_PIN_NONE {
+		return 0
+	}
+	for i, pin := range this.pins {
+		if pin == logical {
+			return uint(i + 1)
+		}
+	}
+	return 0
+}
+
+// PhysicalPinName returns the name of a physical pin, for example
+// GPIO17, 3V3, 5V or GND, or an empty string if unknown
+func (this *header_layout) PhysicalPinName(pin uint) string {
+	if pin < 1 || pin > uint(len(this.names)) {
+		return ""
+	} else {
+		return this.names[pin-1]
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *header_layout) String() string {
+	return this.name
+}
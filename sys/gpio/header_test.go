@@ -0,0 +1,110 @@
+package gpio
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestHeader_000(t *testing.T) {
+	for _, header := range []*header_layout{header_26_rev1, header_26_rev2, header_40} {
+		if header.NumberOfPhysicalPins() != uint(len(header.names)) {
+			t.Error("Unexpected number of pins for", header)
+		}
+		// Pin 1 is always 3V3 and pin 6 is always ground
+		if name := header.PhysicalPinName(1); name != "3V3" {
+			t.Error("Unexpected name for pin 1 on", header, name)
+		}
+		if name := header.PhysicalPinName(6); name != "GND" {
+			t.Error("Unexpected name for pin 6 on", header, name)
+		}
+		if pin := header.PhysicalPin(6); pin != gopi.GPIO_PIN_NONE {
+			t.Error("Expected no logical pin for ground on", header, pin)
+		}
+		// Out of range
+		if pin := header.PhysicalPin(0); pin != gopi.GPIO_PIN_NONE {
+			t.Error("Expected no logical pin for pin 0 on", header, pin)
+		}
+		if pin := header.PhysicalPin(header.NumberOfPhysicalPins() + 1); pin != gopi.GPIO_PIN_NONE {
+			t.Error("Expected no logical pin beyond the header on", header, pin)
+		}
+	}
+	if header_sodimm.NumberOfPhysicalPins() != 200 {
+		t.Error("Unexpected number of pins for", header_sodimm)
+	}
+	for physical := uint(1); physical <= header_sodimm.NumberOfPhysicalPins(); physical++ {
+		if name := header_sodimm.PhysicalPinName(physical); name == "" {
+			t.Error("Expected a name for pin", physical, "on", header_sodimm)
+		}
+	}
+	for physical, name := range map[uint]string{97: "GND", 177: "RUN", 183: "1V8", 191: "3V3", 200: "VBAT"} {
+		if pin_name := header_sodimm.PhysicalPinName(physical); pin_name != name {
+			t.Error("Unexpected name for pin", physical, "on", header_sodimm, pin_name)
+		}
+	}
+}
+
+func TestHeader_001(t *testing.T) {
+	// Revision 1 boards have GPIO0, GPIO1 and GPIO21 where later boards
+	// have GPIO2, GPIO3 and GPIO27
+	tests := []struct {
+		physical uint
+		rev1     gopi.GPIOPin
+		rev2     gopi.GPIOPin
+	}{
+		{3, 0, 2},
+		{5, 1, 3},
+		{13, 21, 27},
+		{11, 17, 17},
+	}
+	for _, test := range tests {
+		if pin := header_26_rev1.PhysicalPin(test.physical); pin != test.rev1 {
+			t.Error("Revision 1: unexpected pin", pin, "for physical pin", test.physical)
+		}
+		if pin := header_26_rev2.PhysicalPin(test.physical); pin != test.rev2 {
+			t.Error("Revision 2: unexpected pin", pin, "for physical pin", test.physical)
+		}
+		if pin := header_40.PhysicalPin(test.physical); pin != test.rev2 {
+			t.Error("40 pin: unexpected pin", pin, "for physical pin", test.physical)
+		}
+	}
+	if name := header_26_rev1.PhysicalPinName(4); name != "DNC" {
+		t.Error("Revision 1: unexpected name for pin 4", name)
+	}
+	if name := header_26_rev2.PhysicalPinName(4); name != "5V" {
+		t.Error("Revision 2: unexpected name for pin 4", name)
+	}
+}
+
+func TestHeader_002(t *testing.T) {
+	// Every logical pin on a header maps back to the same physical pin
+	for _, header := range []*header_layout{header_26_rev1, header_26_rev2, header_40, header_sodimm} {
+		seen := make(map[gopi.GPIOPin]bool)
+		for physical := uint(1); physical <= header.NumberOfPhysicalPins(); physical++ {
+			logical := header.PhysicalPin(physical)
+			if logical == gopi.GPIO_PIN_NONE {
+				continue
+			}
+			if seen[logical] {
+				t.Error("Duplicate logical pin", logical, "on", header)
+			}
+			seen[logical] = true
+			if pin := header.PhysicalPinForPin(logical); pin != physical {
+				t.Error("Unexpected physical pin", pin, "for", logical, "on", header)
+			}
+		}
+	}
+	if pin := header_40.PhysicalPinForPin(gopi.GPIOPin(40)); pin != 0 {
+		t.Error("Expected no physical pin for GPIO40 on", header_40, pin)
+	}
+	if pin := header_40.PhysicalPinForPin(gopi.GPIO_PIN_NONE); pin != 0 {
+		t.Error("Expected no physical pin for GPIO_PIN_NONE on", header_40, pin)
+	}
+	if pin := header_sodimm.PhysicalPinForPin(gopi.GPIOPin(45)); pin != 84 {
+		t.Error("Unexpected physical pin for GPIO45 on", header_sodimm, pin)
+	}
+}
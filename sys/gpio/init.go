@@ -0,0 +1,37 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register polled edge detection, which wraps the GPIO module
+	gopi.RegisterModule(gopi.Module{
+		Name:     "hw/gpio/poll",
+		Requires: []string{"gpio"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagDuration("gpio.sample", GPIO_POLL_INTERVAL, "Interval between samples of watched pins")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			interval, _ := app.AppFlags.GetDuration("gpio.sample")
+			return gopi.Open(GPIOPoll{
+				GPIO:     app.GPIO,
+				Interval: interval,
+			}, app.Logger)
+		},
+	})
+}
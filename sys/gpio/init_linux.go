@@ -36,4 +36,23 @@ func init() {
 			}, app.Logger)
 		},
 	})
+
+	gopi.RegisterModule(gopi.Module{
+		Name:     "hw/gpio/gpiochip",
+		Requires: []string{"hw/filepoll"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("gpio.chip", 0, "GPIO character device number")
+			config.AppFlags.FlagString("gpio.consumer", GPIO_CONSUMER, "Consumer label for requested lines")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			chip, _ := app.AppFlags.GetUint("gpio.chip")
+			consumer, _ := app.AppFlags.GetString("gpio.consumer")
+			return gopi.Open(GPIOChip{
+				Chip:     chip,
+				Consumer: consumer,
+				FilePoll: app.ModuleInstance("hw/filepoll").(filepoll.FilePollInterface),
+			}, app.Logger)
+		},
+	})
 }
@@ -12,7 +12,9 @@
 package gpio
 
 import (
+	// Frameworks
 	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi-hw/sys/filepoll"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -23,8 +25,31 @@ func init() {
 		Name:     "hw/gpio/rpi",
 		Type:     gopi.MODULE_TYPE_GPIO,
 		Requires: []string{"hw/rpi"},
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagDuration("gpio.poll", GPIO_POLL_INTERVAL, "Interval between reading levels of watched pins")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			poll, _ := app.AppFlags.GetDuration("gpio.poll")
+			return gopi.Open(GPIO{Hardware: app.Hardware, Poll: poll}, app.Logger)
+		},
+	})
+
+	gopi.RegisterModule(gopi.Module{
+		Name:     "hw/gpio/gpiochip",
+		Requires: []string{"hw/filepoll"},
+		Type:     gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("gpio.chip", 0, "GPIO character device number")
+			config.AppFlags.FlagString("gpio.consumer", GPIO_CONSUMER, "Consumer label for requested lines")
+		},
 		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
-			return gopi.Open(GPIO{Hardware: app.Hardware}, app.Logger)
+			chip, _ := app.AppFlags.GetUint("gpio.chip")
+			consumer, _ := app.AppFlags.GetString("gpio.consumer")
+			return gopi.Open(GPIOChip{
+				Chip:     chip,
+				Consumer: consumer,
+				FilePoll: app.ModuleInstance("hw/filepoll").(filepoll.FilePollInterface),
+			}, app.Logger)
 		},
 	})
 }
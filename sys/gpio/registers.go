@@ -0,0 +1,220 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2019
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package gpio
+
+import (
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// registers provides access to the GPIO register file by byte offset,
+// which is memory-mapped on the Raspberry Pi and can be replaced for testing
+type registers interface {
+	Read(offset uint) uint32
+	Write(offset uint, value uint32)
+}
+
+// mem_registers implements registers over mapped memory
+type mem_registers []uint32
+
+// edge_detect detects changes in level for watched pins by comparing
+// successive reads of the level registers
+type edge_detect struct {
+	edges  map[gopi.GPIOPin]gopi.GPIOEdge
+	mask   uint64 // Watched pins
+	levels uint64 // Levels at the last read
+}
+
+// edge_change is a change in level for a watched pin
+type edge_change struct {
+	pin   gopi.GPIOPin
+	state gopi.GPIOState
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// GPIO Registers
+	GPIO_GPLVL0    = 0x0034 // Register to read pins GPIO0-GPIO31
+	GPIO_GPLVL1    = 0x0038 // Register to read pins GPIO32-GPIO53
+	GPIO_GPSET0    = 0x001C // Register to write HIGH to pins GPIO0-GPIO31
+	GPIO_GPSET1    = 0x0020 // Register to write HIGH to pins GPIO32-GPIO53
+	GPIO_GPCLR0    = 0x0028 // Register to write LOW to pins GPIO0-GPIO31
+	GPIO_GPCLR1    = 0x002C // Register to write LOW to pins GPIO32-GPIO53
+	GPIO_GPFSEL0   = 0x0000 // Pin modes for GPIO0-GPIO9
+	GPIO_GPFSEL1   = 0x0004 // Pin modes for GPIO10-GPIO19
+	GPIO_GPFSEL2   = 0x0008 // Pin modes for GPIO20-GPIO29
+	GPIO_GPFSEL3   = 0x000C // Pin modes for GPIO30-GPIO39
+	GPIO_GPFSEL4   = 0x0010 // Pin modes for GPIO40-GPIO49
+	GPIO_GPFSEL5   = 0x0014 // Pin modes for GPIO50-GPIO53
+	GPIO_GPPUD     = 0x0094 // GPIO Pin Pull-up/down Enable
+	GPIO_GPPUDCLK0 = 0x0098 // GPIO Pin Pull-up/down Enable Clock 0
+	GPIO_GPPUDCLK1 = 0x009c // GPIO Pin Pull-up/down Enable Clock 1
+
+	// BCM2711 Registers
+	GPIO_PUP_PDN_CNTRL_REG0 = 0x00E4 // Pull-up/down for GPIO0-GPIO15
+	GPIO_PUP_PDN_CNTRL_REG1 = 0x00E8 // Pull-up/down for GPIO16-GPIO31
+	GPIO_PUP_PDN_CNTRL_REG2 = 0x00EC // Pull-up/down for GPIO32-GPIO47
+	GPIO_PUP_PDN_CNTRL_REG3 = 0x00F0 // Pull-up/down for GPIO48-GPIO57
+)
+
+const (
+	// BCM2835 GPPUD values
+	GPIO_GPPUD_OFF  uint32 = 0x00
+	GPIO_GPPUD_DOWN uint32 = 0x01
+	GPIO_GPPUD_UP   uint32 = 0x02
+
+	// BCM2711 GPIO_PUP_PDN_CNTRL values
+	GPIO_PUP_PDN_NONE uint32 = 0x00
+	GPIO_PUP_PDN_UP   uint32 = 0x01
+	GPIO_PUP_PDN_DOWN uint32 = 0x02
+)
+
+const (
+	// Time to wait for the BCM2835 pull control signals to set up and
+	// hold, which needs to be at least 150 cycles
+	GPIO_PULL_DELAY = 5 * time.Microsecond
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// REGISTERS
+
+func (this mem_registers) Read(offset uint) uint32 {
+	return this[offset>>2]
+}
+
+func (this mem_registers) Write(offset uint, value uint32) {
+	this[offset>>2] = value
+}
+
+// readLevels returns the levels of all pins, with GPIO0 as bit zero
+func readLevels(regs registers) uint64 {
+	return uint64(regs.Read(GPIO_GPLVL0)) | uint64(regs.Read(GPIO_GPLVL1))<<32
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PULL UP AND PULL DOWN
+
+// setPullBCM2835 sets the pull mode for a pin using the clocked sequence:
+// set the control signal, clock it into the pin, then remove the control
+// signal and the clock. The delay function waits for the signals to settle
+func setPullBCM2835(regs registers, pin gopi.GPIOPin, pull gopi.GPIOPull, delay func()) error {
+	var value uint32
+	switch pull {
+	case gopi.GPIO_PULL_OFF:
+		value = GPIO_GPPUD_OFF
+	case gopi.GPIO_PULL_DOWN:
+		value = GPIO_GPPUD_DOWN
+	case gopi.GPIO_PULL_UP:
+		value = GPIO_GPPUD_UP
+	default:
+		return gopi.ErrBadParameter
+	}
+	clk := uint(GPIO_GPPUDCLK0)
+	if pin > 31 {
+		clk = GPIO_GPPUDCLK1
+	}
+	regs.Write(GPIO_GPPUD, value)
+	delay()
+	regs.Write(clk, 1<<(uint(pin)&31))
+	delay()
+	regs.Write(GPIO_GPPUD, GPIO_GPPUD_OFF)
+	regs.Write(clk, 0)
+	return nil
+}
+
+// setPullBCM2711 sets the pull mode for a pin directly, with two bits
+// for each pin
+func setPullBCM2711(regs registers, pin gopi.GPIOPin, pull gopi.GPIOPull) error {
+	var value uint32
+	switch pull {
+	case gopi.GPIO_PULL_OFF:
+		value = GPIO_PUP_PDN_NONE
+	case gopi.GPIO_PULL_DOWN:
+		value = GPIO_PUP_PDN_DOWN
+	case gopi.GPIO_PULL_UP:
+		value = GPIO_PUP_PDN_UP
+	default:
+		return gopi.ErrBadParameter
+	}
+	register := GPIO_PUP_PDN_CNTRL_REG0 + uint(pin>>4)<<2
+	shift := (uint(pin) & 15) << 1
+	regs.Write(register, regs.Read(register)&^(3<<shift)|value<<shift)
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// EDGE DETECT
+
+func newEdgeDetect() *edge_detect {
+	return &edge_detect{edges: make(map[gopi.GPIOPin]gopi.GPIOEdge)}
+}
+
+// Watch sets the edge for a pin, or stops watching the pin when the edge
+// is GPIO_EDGE_NONE. The level of a newly watched pin is read so that
+// the current level is not reported as a change
+func (this *edge_detect) Watch(pin gopi.GPIOPin, edge gopi.GPIOEdge, regs registers) {
+	bit := uint64(1) << uint(pin)
+	if edge == gopi.GPIO_EDGE_NONE {
+		delete(this.edges, pin)
+		this.mask &^= bit
+	} else {
+		if this.mask&bit == 0 {
+			this.levels = this.levels&^bit | readLevels(regs)&bit
+		}
+		this.edges[pin] = edge
+		this.mask |= bit
+	}
+}
+
+// Edge returns the edge being watched for a pin
+func (this *edge_detect) Edge(pin gopi.GPIOPin) gopi.GPIOEdge {
+	if edge, exists := this.edges[pin]; exists {
+		return edge
+	} else {
+		return gopi.GPIO_EDGE_NONE
+	}
+}
+
+// Watching returns true if any pins are being watched
+func (this *edge_detect) Watching() bool {
+	return this.mask != 0
+}
+
+// Detect reads the level registers and returns the changes in level
+// for watched pins since the last read, in pin order
+func (this *edge_detect) Detect(regs registers) []edge_change {
+	levels := readLevels(regs)
+	changed := (levels ^ this.levels) & this.mask
+	this.levels = this.levels&^this.mask | levels&this.mask
+	if changed == 0 {
+		return nil
+	}
+	changes := make([]edge_change, 0, 1)
+	for pin := uint(0); changed != 0; pin++ {
+		bit := uint64(1) << pin
+		if changed&bit == 0 {
+			continue
+		}
+		changed &^= bit
+		state := gopi.GPIO_LOW
+		if levels&bit != 0 {
+			state = gopi.GPIO_HIGH
+		}
+		changes = append(changes, edge_change{gopi.GPIOPin(pin), state})
+	}
+	return changes
+}
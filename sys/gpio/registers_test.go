@@ -0,0 +1,238 @@
+package gpio
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE REGISTERS
+
+type fakeregisters map[uint]uint32
+
+func (this fakeregisters) Read(offset uint) uint32 {
+	return this[offset]
+}
+
+func (this fakeregisters) Write(offset uint, value uint32) {
+	this[offset] = value
+}
+
+func (this fakeregisters) SetLevel(pin gopi.GPIOPin, state gopi.GPIOState) {
+	offset, bit := uint(GPIO_GPLVL0), uint32(1)<<(uint(pin)&31)
+	if pin > 31 {
+		offset = GPIO_GPLVL1
+	}
+	if state == gopi.GPIO_HIGH {
+		this[offset] |= bit
+	} else {
+		this[offset] &^= bit
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestRegisters_000(t *testing.T) {
+	regs := mem_registers(make([]uint32, 64))
+	regs.Write(GPIO_GPLVL1, 0x12345678)
+	if regs[GPIO_GPLVL1>>2] != 0x12345678 {
+		t.Error("Unexpected register value")
+	} else if regs.Read(GPIO_GPLVL1) != 0x12345678 {
+		t.Error("Unexpected register read")
+	}
+	regs.Write(GPIO_GPLVL0, 1)
+	if levels := readLevels(regs); levels != 0x1234567800000001 {
+		t.Errorf("Unexpected levels %016X", levels)
+	}
+}
+
+func TestEdgeDetect_000(t *testing.T) {
+	regs := make(fakeregisters)
+	detect := newEdgeDetect()
+
+	// Pin which is high when watched does not report a change
+	regs.SetLevel(4, gopi.GPIO_HIGH)
+	detect.Watch(4, gopi.GPIO_EDGE_BOTH, regs)
+	detect.Watch(40, gopi.GPIO_EDGE_RISING, regs)
+	if detect.Watching() == false {
+		t.Error("Expected watching")
+	} else if changes := detect.Detect(regs); len(changes) != 0 {
+		t.Error("Unexpected changes", changes)
+	}
+
+	// Unwatched pins are ignored
+	regs.SetLevel(5, gopi.GPIO_HIGH)
+	if changes := detect.Detect(regs); len(changes) != 0 {
+		t.Error("Unexpected changes", changes)
+	}
+
+	// Changes in both banks, in pin order
+	regs.SetLevel(40, gopi.GPIO_HIGH)
+	regs.SetLevel(4, gopi.GPIO_LOW)
+	if changes := detect.Detect(regs); len(changes) != 2 {
+		t.Error("Unexpected changes", changes)
+	} else if changes[0] != (edge_change{4, gopi.GPIO_LOW}) || changes[1] != (edge_change{40, gopi.GPIO_HIGH}) {
+		t.Error("Unexpected changes", changes)
+	} else if detect.Edge(40) != gopi.GPIO_EDGE_RISING || detect.Edge(5) != gopi.GPIO_EDGE_NONE {
+		t.Error("Unexpected edges")
+	}
+
+	// No further changes
+	if changes := detect.Detect(regs); len(changes) != 0 {
+		t.Error("Unexpected changes", changes)
+	}
+
+	// Stop watching
+	detect.Watch(4, gopi.GPIO_EDGE_NONE, regs)
+	detect.Watch(40, gopi.GPIO_EDGE_NONE, regs)
+	regs.SetLevel(4, gopi.GPIO_HIGH)
+	if detect.Watching() {
+		t.Error("Expected not watching")
+	} else if changes := detect.Detect(regs); len(changes) != 0 {
+		t.Error("Unexpected changes", changes)
+	}
+}
+
+func TestEdgeDetect_001(t *testing.T) {
+	regs := make(fakeregisters)
+	detect := newEdgeDetect()
+
+	// Re-watching a pin with a different edge keeps the last level
+	detect.Watch(53, gopi.GPIO_EDGE_FALLING, regs)
+	regs.SetLevel(53, gopi.GPIO_HIGH)
+	detect.Watch(53, gopi.GPIO_EDGE_BOTH, regs)
+	if changes := detect.Detect(regs); len(changes) != 1 || changes[0] != (edge_change{53, gopi.GPIO_HIGH}) {
+		t.Error("Unexpected changes", changes)
+	}
+
+	// A pulse shorter than the poll interval is not detected
+	regs.SetLevel(53, gopi.GPIO_LOW)
+	regs.SetLevel(53, gopi.GPIO_HIGH)
+	if changes := detect.Detect(regs); len(changes) != 0 {
+		t.Error("Unexpected changes", changes)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PULL UP AND PULL DOWN
+
+type regwrite struct {
+	offset uint
+	value  uint32
+}
+
+type recordregisters struct {
+	fakeregisters
+	writes []regwrite
+}
+
+func (this *recordregisters) Write(offset uint, value uint32) {
+	this.writes = append(this.writes, regwrite{offset, value})
+	this.fakeregisters.Write(offset, value)
+}
+
+func TestPull_000(t *testing.T) {
+	// BCM2835 clocked sequence, with a delay after setting the control
+	// signal and after setting the clock
+	regs := &recordregisters{fakeregisters: make(fakeregisters)}
+	delays := make([]int, 0)
+	if err := setPullBCM2835(regs, 33, gopi.GPIO_PULL_UP, func() {
+		delays = append(delays, len(regs.writes))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	expected := []regwrite{
+		{GPIO_GPPUD, GPIO_GPPUD_UP},
+		{GPIO_GPPUDCLK1, 1 << 1},
+		{GPIO_GPPUD, GPIO_GPPUD_OFF},
+		{GPIO_GPPUDCLK1, 0},
+	}
+	if len(regs.writes) != len(expected) {
+		t.Fatal("Unexpected writes", regs.writes)
+	}
+	for i := range expected {
+		if regs.writes[i] != expected[i] {
+			t.Errorf("Write %v: expected %v, got %v", i, expected[i], regs.writes[i])
+		}
+	}
+	if len(delays) != 2 || delays[0] != 1 || delays[1] != 2 {
+		t.Error("Unexpected delays", delays)
+	}
+
+	regs.writes = nil
+	if err := setPullBCM2835(regs, 4, gopi.GPIO_PULL_DOWN, func() {}); err != nil {
+		t.Fatal(err)
+	} else if regs.writes[0] != (regwrite{GPIO_GPPUD, GPIO_GPPUD_DOWN}) || regs.writes[1] != (regwrite{GPIO_GPPUDCLK0, 1 << 4}) {
+		t.Error("Unexpected writes", regs.writes)
+	} else if err := setPullBCM2835(regs, 4, gopi.GPIOPull(99), func() {}); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter")
+	}
+}
+
+func TestPull_001(t *testing.T) {
+	// BCM2711 registers, two bits per pin, other pins unchanged
+	regs := make(fakeregisters)
+	regs[GPIO_PUP_PDN_CNTRL_REG1] = 0xFFFFFFFF
+	if err := setPullBCM2711(regs, 17, gopi.GPIO_PULL_DOWN); err != nil {
+		t.Fatal(err)
+	} else if value := regs[GPIO_PUP_PDN_CNTRL_REG1]; value != 0xFFFFFFFB {
+		t.Errorf("Unexpected register value %08X", value)
+	}
+	if err := setPullBCM2711(regs, 47, gopi.GPIO_PULL_UP); err != nil {
+		t.Fatal(err)
+	} else if value := regs[GPIO_PUP_PDN_CNTRL_REG2]; value != 0x40000000 {
+		t.Errorf("Unexpected register value %08X", value)
+	}
+	if err := setPullBCM2711(regs, 47, gopi.GPIO_PULL_OFF); err != nil {
+		t.Fatal(err)
+	} else if value := regs[GPIO_PUP_PDN_CNTRL_REG2]; value != 0 {
+		t.Errorf("Unexpected register value %08X", value)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ALT FUNCTIONS
+
+func TestAltFunction_000(t *testing.T) {
+	if len(alt_bcm2835) != 54 {
+		t.Error("Unexpected number of pins for BCM2835", len(alt_bcm2835))
+	}
+	if len(alt_bcm2711) != 58 {
+		t.Error("Unexpected number of pins for BCM2711", len(alt_bcm2711))
+	}
+	tests := []struct {
+		table alt_functions
+		pin   gopi.GPIOPin
+		mode  gopi.GPIOMode
+		name  string
+	}{
+		{alt_bcm2835, 2, gopi.GPIO_ALT0, "SDA1"},
+		{alt_bcm2835, 14, gopi.GPIO_ALT0, "TXD0"},
+		{alt_bcm2835, 14, gopi.GPIO_ALT5, "TXD1"},
+		{alt_bcm2835, 18, gopi.GPIO_ALT5, "PWM0"},
+		{alt_bcm2835, 40, gopi.GPIO_ALT0, "PWM0"},
+		{alt_bcm2835, 48, gopi.GPIO_ALT3, "SD1_CLK"},
+		{alt_bcm2835, 53, gopi.GPIO_ALT3, "SD1_DAT3"},
+		{alt_bcm2835, 46, gopi.GPIO_ALT0, ""},
+		{alt_bcm2835, 0, gopi.GPIO_ALT4, ""},
+		{alt_bcm2835, 2, gopi.GPIO_INPUT, ""},
+		{alt_bcm2835, 60, gopi.GPIO_ALT0, ""},
+		{alt_bcm2711, 0, gopi.GPIO_ALT4, "TXD2"},
+		{alt_bcm2711, 12, gopi.GPIO_ALT0, "PWM0_0"},
+		{alt_bcm2711, 40, gopi.GPIO_ALT0, "PWM1_0"},
+		{alt_bcm2711, 41, gopi.GPIO_ALT0, "PWM1_1"},
+		{alt_bcm2711, 45, gopi.GPIO_ALT0, "PWM0_1"},
+		{alt_bcm2711, 40, gopi.GPIO_ALT4, "SPI0_MISO"},
+		{alt_bcm2711, 49, gopi.GPIO_ALT5, "SPI2_MOSI"},
+		{alt_bcm2711, 57, gopi.GPIO_ALT0, ""},
+		{alt_bcm2711, 58, gopi.GPIO_ALT0, ""},
+	}
+	for _, test := range tests {
+		if name := altFunction(test.table, test.pin, test.mode); name != test.name {
+			t.Errorf("%v %v: expected %q, got %q", test.pin, test.mode, test.name, name)
+		}
+	}
+}
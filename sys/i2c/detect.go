@@ -0,0 +1,105 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package i2c
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	I2C_DEV_GLOB      = "/dev/i2c-*"
+	I2C_SYSFS_DEVICES = "/sys/bus/i2c/devices"
+	I2C_SLAVE_FIRST   = 0x08 /* First address which is not reserved */
+	I2C_SLAVE_LAST    = 0x77 /* Last address which is not reserved */
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC FUNCTIONS
+
+// I2CReservedSlave returns true if a seven-bit address is reserved by the
+// I2C specification for general call, CBUS, high-speed mode or ten-bit
+// addressing, and should not be probed
+func I2CReservedSlave(slave uint8) bool {
+	return slave < I2C_SLAVE_FIRST || slave > I2C_SLAVE_LAST
+}
+
+// I2CDetectModeForSlave returns the probe used for an address. In auto
+// mode addresses 0x30-0x37 and 0x50-0x5F are probed with a read byte,
+// since a quick write can corrupt EEPROMs or lock write-protect switches,
+// and other addresses are probed with a quick write
+func I2CDetectModeForSlave(slave uint8, mode I2CDetectMode) I2CDetectMode {
+	if mode != I2C_DETECT_AUTO {
+		return mode
+	} else if (slave >= 0x30 && slave <= 0x37) || (slave >= 0x50 && slave <= 0x5F) {
+		return I2C_DETECT_READ
+	} else {
+		return I2C_DETECT_QUICK
+	}
+}
+
+// I2CBuses returns the bus numbers for the /dev/i2c-* devices
+func I2CBuses() ([]uint, error) {
+	return i2c_buses(I2C_DEV_GLOB)
+}
+
+// I2CBoundSlaves returns the addresses on a bus which are bound to a
+// kernel driver, mapped to the name of the driver
+func I2CBoundSlaves(bus uint) (map[uint8]string, error) {
+	return i2c_bound_slaves(I2C_SYSFS_DEVICES, bus)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func i2c_buses(pattern string) ([]uint, error) {
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	buses := make([]uint, 0, len(paths))
+	for _, path := range paths {
+		suffix := path[strings.LastIndex(path, "-")+1:]
+		if bus, err := strconv.ParseUint(suffix, 10, 32); err == nil {
+			buses = append(buses, uint(bus))
+		}
+	}
+	sort.Slice(buses, func(i, j int) bool { return buses[i] < buses[j] })
+	return buses, nil
+}
+
+// i2c_bound_slaves reads the client devices for a bus, which are named
+// <bus>-<address> with the address as four hex digits. A client which
+// has a driver symlink is bound to that driver
+func i2c_bound_slaves(root string, bus uint) (map[uint8]string, error) {
+	slaves := make(map[uint8]string)
+	paths, err := filepath.Glob(filepath.Join(root, fmt.Sprintf("%v-*", bus)))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		suffix := strings.TrimPrefix(filepath.Base(path), fmt.Sprintf("%v-", bus))
+		if len(suffix) != 4 {
+			continue
+		} else if slave, err := strconv.ParseUint(suffix, 16, 16); err != nil || slave > 0x7F {
+			continue
+		} else if driver, err := os.Readlink(filepath.Join(path, "driver")); err == nil {
+			slaves[uint8(slave)] = filepath.Base(driver)
+		}
+	}
+	return slaves, nil
+}
@@ -0,0 +1,94 @@
+package i2c
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestDetect_000(t *testing.T) {
+	for slave, reserved := range map[uint8]bool{0x00: true, 0x07: true, 0x08: false, 0x50: false, 0x77: false, 0x78: true, 0x7F: true} {
+		if I2CReservedSlave(slave) != reserved {
+			t.Errorf("Unexpected reserved value for 0x%02X", slave)
+		}
+	}
+	tests := []struct {
+		slave    uint8
+		mode     I2CDetectMode
+		expected I2CDetectMode
+	}{
+		{0x20, I2C_DETECT_AUTO, I2C_DETECT_QUICK},
+		{0x30, I2C_DETECT_AUTO, I2C_DETECT_READ},
+		{0x37, I2C_DETECT_AUTO, I2C_DETECT_READ},
+		{0x38, I2C_DETECT_AUTO, I2C_DETECT_QUICK},
+		{0x50, I2C_DETECT_AUTO, I2C_DETECT_READ},
+		{0x5F, I2C_DETECT_AUTO, I2C_DETECT_READ},
+		{0x50, I2C_DETECT_QUICK, I2C_DETECT_QUICK},
+		{0x20, I2C_DETECT_READ, I2C_DETECT_READ},
+	}
+	for _, test := range tests {
+		if mode := I2CDetectModeForSlave(test.slave, test.mode); mode != test.expected {
+			t.Errorf("0x%02X %v: expected %v, got %v", test.slave, test.mode, test.expected, mode)
+		}
+	}
+}
+
+func TestDetect_001(t *testing.T) {
+	root, err := ioutil.TempDir("", "i2c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	// Bus 1 has a bound client at 0x50 and an unbound client at 0x68,
+	// bus 10 has a bound client at 0x20 and the adapter itself
+	for _, client := range []string{"1-0050", "1-0068", "10-0020", "i2c-1"} {
+		if err := os.MkdirAll(filepath.Join(root, client), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("../../drivers/at24", filepath.Join(root, "1-0050", "driver")); err != nil {
+		t.Fatal(err)
+	} else if err := os.Symlink("../../drivers/pcf857x", filepath.Join(root, "10-0020", "driver")); err != nil {
+		t.Fatal(err)
+	}
+
+	if slaves, err := i2c_bound_slaves(root, 1); err != nil {
+		t.Error(err)
+	} else if len(slaves) != 1 || slaves[0x50] != "at24" {
+		t.Error("Unexpected bound slaves", slaves)
+	}
+	if slaves, err := i2c_bound_slaves(root, 10); err != nil {
+		t.Error(err)
+	} else if len(slaves) != 1 || slaves[0x20] != "pcf857x" {
+		t.Error("Unexpected bound slaves", slaves)
+	}
+	if slaves, err := i2c_bound_slaves(root, 2); err != nil {
+		t.Error(err)
+	} else if len(slaves) != 0 {
+		t.Error("Unexpected bound slaves", slaves)
+	}
+}
+
+func TestDetect_002(t *testing.T) {
+	root, err := ioutil.TempDir("", "i2c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	for _, dev := range []string{"i2c-10", "i2c-1", "i2c-2", "i2c-x"} {
+		if err := ioutil.WriteFile(filepath.Join(root, dev), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if buses, err := i2c_buses(filepath.Join(root, "i2c-*")); err != nil {
+		t.Error(err)
+	} else if len(buses) != 3 || buses[0] != 1 || buses[1] != 2 || buses[2] != 10 {
+		t.Error("Unexpected buses", buses)
+	}
+}
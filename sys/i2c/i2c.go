@@ -0,0 +1,242 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package i2c
+
+import (
+	"fmt"
+	"strings"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// I2CFunction is a bitmask of the functionality of an adapter
+type I2CFunction uint32
+
+// I2CUnsupportedError is returned when an operation needs functionality
+// which the adapter does not have
+type I2CUnsupportedError struct {
+	Function I2CFunction
+}
+
+// I2CDetectMode is the probe used to detect a slave
+type I2CDetectMode uint
+
+// I2CMessageFlag modifies how a message in a combined transfer is sent
+type I2CMessageFlag uint16
+
+// I2CMessage is a single message in a combined transfer. Messages are
+// separated by a repeated start condition, with one stop condition at
+// the end of the transfer
+type I2CMessage struct {
+	// Slave address, which is a ten-bit address when I2C_M_TEN is set
+	Slave uint16
+
+	// Flags for the message
+	Flags I2CMessageFlag
+
+	// Data to write, or the buffer to read into when I2C_M_RD is set
+	Buf []byte
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
+// I2CInterface is implemented by drivers which support raw I2C messages
+// as well as SMBus transactions
+type I2CInterface interface {
+	gopi.I2C
+
+	// Transfer sends messages to one or more slaves in a single
+	// combined transaction, filling the buffers of read messages
+	Transfer([]I2CMessage) error
+
+	// Read bytes from the current slave
+	Read(uint) ([]byte, error)
+
+	// Write bytes to the current slave
+	Write([]byte) error
+
+	// DetectSlaveMode returns true if a slave acknowledges the probe
+	DetectSlaveMode(slave uint8, mode I2CDetectMode) (bool, error)
+
+	// WriteBlock writes an SMBus block of up to 32 bytes to a register
+	WriteBlock(reg uint8, data []byte) error
+
+	// ProcessCall writes a word to a register and reads a word back
+	ProcessCall(reg uint8, value uint16) (uint16, error)
+
+	// BlockProcessCall writes a block to a register and reads a block back
+	BlockProcessCall(reg uint8, data []byte) ([]byte, error)
+
+	// Enable or disable SMBus packet error checking
+	PEC() bool
+	SetPEC(bool) error
+
+	// Enable or disable ten-bit slave addresses
+	TenBitAddressing() bool
+	SetTenBitAddressing(bool) error
+
+	// Set and get a ten-bit slave address when ten-bit addressing is
+	// enabled, which is used instead of the seven-bit slave address
+	SetTenBitSlave(uint16) error
+	GetTenBitSlave() uint16
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	I2C_SLAVE_NONE        uint8  = 0xFF
+	I2C_TENBIT_SLAVE_NONE uint16 = 0xFFFF
+	I2C_SMBUS_BLOCK_MAX          = 32 /* As specified in SMBus standard */
+)
+
+const (
+	// i2c functions
+	I2C_FUNC_I2C                    I2CFunction = 0x00000001
+	I2C_FUNC_10BIT_ADDR             I2CFunction = 0x00000002
+	I2C_FUNC_PROTOCOL_MANGLING      I2CFunction = 0x00000004 /* I2C_M_IGNORE_NAK etc. */
+	I2C_FUNC_SMBUS_PEC              I2CFunction = 0x00000008
+	I2C_FUNC_NOSTART                I2CFunction = 0x00000010 /* I2C_M_NOSTART */
+	I2C_FUNC_SMBUS_BLOCK_PROC_CALL  I2CFunction = 0x00008000 /* SMBus 2.0 */
+	I2C_FUNC_SMBUS_QUICK            I2CFunction = 0x00010000
+	I2C_FUNC_SMBUS_READ_BYTE        I2CFunction = 0x00020000
+	I2C_FUNC_SMBUS_WRITE_BYTE       I2CFunction = 0x00040000
+	I2C_FUNC_SMBUS_READ_BYTE_DATA   I2CFunction = 0x00080000
+	I2C_FUNC_SMBUS_WRITE_BYTE_DATA  I2CFunction = 0x00100000
+	I2C_FUNC_SMBUS_READ_WORD_DATA   I2CFunction = 0x00200000
+	I2C_FUNC_SMBUS_WRITE_WORD_DATA  I2CFunction = 0x00400000
+	I2C_FUNC_SMBUS_PROC_CALL        I2CFunction = 0x00800000
+	I2C_FUNC_SMBUS_READ_BLOCK_DATA  I2CFunction = 0x01000000
+	I2C_FUNC_SMBUS_WRITE_BLOCK_DATA I2CFunction = 0x02000000
+	I2C_FUNC_SMBUS_READ_I2C_BLOCK   I2CFunction = 0x04000000 /* I2C-like block xfer  */
+	I2C_FUNC_SMBUS_WRITE_I2C_BLOCK  I2CFunction = 0x08000000 /* w/ 1-byte reg. addr. */
+)
+
+const (
+	// Probe with a quick write for most addresses, but with a read byte
+	// for addresses which are usually EEPROMs or write-protect switches
+	I2C_DETECT_AUTO I2CDetectMode = iota
+	I2C_DETECT_QUICK
+	I2C_DETECT_READ
+)
+
+const (
+	I2C_M_RD           I2CMessageFlag = 0x0001 /* read data, from slave to master */
+	I2C_M_TEN          I2CMessageFlag = 0x0010 /* this is a ten bit chip address */
+	I2C_M_NO_RD_ACK    I2CMessageFlag = 0x0800 /* if I2C_FUNC_PROTOCOL_MANGLING */
+	I2C_M_IGNORE_NAK   I2CMessageFlag = 0x1000 /* if I2C_FUNC_PROTOCOL_MANGLING */
+	I2C_M_REV_DIR_ADDR I2CMessageFlag = 0x2000 /* if I2C_FUNC_PROTOCOL_MANGLING */
+	I2C_M_NOSTART      I2CMessageFlag = 0x4000 /* if I2C_FUNC_NOSTART */
+	I2C_M_STOP         I2CMessageFlag = 0x8000 /* if I2C_FUNC_PROTOCOL_MANGLING */
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (e I2CUnsupportedError) Error() string {
+	return fmt.Sprintf("%v unsupported by adapter", e.Function)
+}
+
+// Stringify I2CFuncs
+func (f I2CFunction) String() string {
+	switch f {
+	case I2C_FUNC_I2C:
+		return "I2C_FUNC_I2C"
+	case I2C_FUNC_10BIT_ADDR:
+		return "I2C_FUNC_10BIT_ADDR"
+	case I2C_FUNC_PROTOCOL_MANGLING:
+		return "I2C_FUNC_PROTOCOL_MANGLING"
+	case I2C_FUNC_SMBUS_PEC:
+		return "I2C_FUNC_SMBUS_PEC"
+	case I2C_FUNC_NOSTART:
+		return "I2C_FUNC_NOSTART"
+	case I2C_FUNC_SMBUS_BLOCK_PROC_CALL:
+		return "I2C_FUNC_SMBUS_BLOCK_PROC_CALL"
+	case I2C_FUNC_SMBUS_QUICK:
+		return "I2C_FUNC_SMBUS_QUICK"
+	case I2C_FUNC_SMBUS_READ_BYTE:
+		return "I2C_FUNC_SMBUS_READ_BYTE"
+	case I2C_FUNC_SMBUS_WRITE_BYTE:
+		return "I2C_FUNC_SMBUS_WRITE_BYTE"
+	case I2C_FUNC_SMBUS_READ_BYTE_DATA:
+		return "I2C_FUNC_SMBUS_READ_BYTE_DATA"
+	case I2C_FUNC_SMBUS_WRITE_BYTE_DATA:
+		return "I2C_FUNC_SMBUS_WRITE_BYTE_DATA"
+	case I2C_FUNC_SMBUS_READ_WORD_DATA:
+		return "I2C_FUNC_SMBUS_READ_WORD_DATA"
+	case I2C_FUNC_SMBUS_WRITE_WORD_DATA:
+		return "I2C_FUNC_SMBUS_WRITE_WORD_DATA"
+	case I2C_FUNC_SMBUS_PROC_CALL:
+		return "I2C_FUNC_SMBUS_PROC_CALL"
+	case I2C_FUNC_SMBUS_READ_BLOCK_DATA:
+		return "I2C_FUNC_SMBUS_READ_BLOCK_DATA"
+	case I2C_FUNC_SMBUS_WRITE_BLOCK_DATA:
+		return "I2C_FUNC_SMBUS_WRITE_BLOCK_DATA"
+	case I2C_FUNC_SMBUS_READ_I2C_BLOCK:
+		return "I2C_FUNC_SMBUS_READ_I2C_BLOCK"
+	case I2C_FUNC_SMBUS_WRITE_I2C_BLOCK:
+		return "I2C_FUNC_SMBUS_WRITE_I2C_BLOCK"
+	default:
+		return "[?? Unknown I2CFunction value]"
+	}
+}
+
+func (m I2CDetectMode) String() string {
+	switch m {
+	case I2C_DETECT_AUTO:
+		return "I2C_DETECT_AUTO"
+	case I2C_DETECT_QUICK:
+		return "I2C_DETECT_QUICK"
+	case I2C_DETECT_READ:
+		return "I2C_DETECT_READ"
+	default:
+		return "[?? Invalid I2CDetectMode value]"
+	}
+}
+
+func (m I2CMessage) String() string {
+	return fmt.Sprintf("<hw.i2c.Message>{ slave=0x%02X flags=%v len=%v }", m.Slave, m.Flags, len(m.Buf))
+}
+
+func (f I2CMessageFlag) String() string {
+	if f == 0 {
+		return "I2C_M_NONE"
+	}
+	flags := ""
+	for flag := I2CMessageFlag(1); flag != 0; flag <<= 1 {
+		if f&flag == 0 {
+			continue
+		}
+		switch flag {
+		case I2C_M_RD:
+			flags += "I2C_M_RD|"
+		case I2C_M_TEN:
+			flags += "I2C_M_TEN|"
+		case I2C_M_NO_RD_ACK:
+			flags += "I2C_M_NO_RD_ACK|"
+		case I2C_M_IGNORE_NAK:
+			flags += "I2C_M_IGNORE_NAK|"
+		case I2C_M_REV_DIR_ADDR:
+			flags += "I2C_M_REV_DIR_ADDR|"
+		case I2C_M_NOSTART:
+			flags += "I2C_M_NOSTART|"
+		case I2C_M_STOP:
+			flags += "I2C_M_STOP|"
+		default:
+			flags += "[?? Unknown I2CMessageFlag value]|"
+		}
+	}
+	return strings.TrimSuffix(flags, "|")
+}
@@ -28,15 +28,28 @@ type I2C struct {
 	Bus uint
 }
 
-type I2CFunction uint32
-
 type i2c struct {
-	log   gopi.Logger
-	bus   uint
-	slave uint8
-	dev   *os.File
-	funcs I2CFunction
-	lock  sync.Mutex
+	log          gopi.Logger
+	bus          uint
+	slave        uint8
+	tenbit_slave uint16
+	dev          *os.File
+	funcs        I2CFunction
+	pec          bool
+	tenbit       bool
+	lock         sync.Mutex
+}
+
+type i2c_msg struct {
+	addr  uint16
+	flags uint16
+	len   uint16
+	buf   unsafe.Pointer
+}
+
+type i2c_rdwr_ioctl_data struct {
+	msgs  unsafe.Pointer
+	nmsgs uint32
 }
 
 type i2c_smbus_ioctl_data struct {
@@ -50,9 +63,9 @@ type i2c_smbus_ioctl_data struct {
 // CONSTANTS
 
 const (
-	I2C_DEV                   = "/dev/i2c"
-	I2C_SLAVE_NONE      uint8 = 0xFF
-	I2C_SMBUS_BLOCK_MAX       = 32 /* As specified in SMBus standard */
+	I2C_DEV                 = "/dev/i2c"
+	I2C_RDWR_IOCTL_MAX_MSGS = 42   /* Maximum number of messages in a combined transfer */
+	I2C_MSG_MAX             = 8192 /* Maximum length of a message in a combined transfer */
 )
 
 const (
@@ -68,28 +81,6 @@ const (
 	I2C_SMBUS       = 0x0720 /* SMBus transfer */
 )
 
-const (
-	// i2c functions
-	I2C_FUNC_I2C                    I2CFunction = 0x00000001
-	I2C_FUNC_10BIT_ADDR             I2CFunction = 0x00000002
-	I2C_FUNC_PROTOCOL_MANGLING      I2CFunction = 0x00000004 /* I2C_M_IGNORE_NAK etc. */
-	I2C_FUNC_SMBUS_PEC              I2CFunction = 0x00000008
-	I2C_FUNC_NOSTART                I2CFunction = 0x00000010 /* I2C_M_NOSTART */
-	I2C_FUNC_SMBUS_BLOCK_PROC_CALL  I2CFunction = 0x00008000 /* SMBus 2.0 */
-	I2C_FUNC_SMBUS_QUICK            I2CFunction = 0x00010000
-	I2C_FUNC_SMBUS_READ_BYTE        I2CFunction = 0x00020000
-	I2C_FUNC_SMBUS_WRITE_BYTE       I2CFunction = 0x00040000
-	I2C_FUNC_SMBUS_READ_BYTE_DATA   I2CFunction = 0x00080000
-	I2C_FUNC_SMBUS_WRITE_BYTE_DATA  I2CFunction = 0x00100000
-	I2C_FUNC_SMBUS_READ_WORD_DATA   I2CFunction = 0x00200000
-	I2C_FUNC_SMBUS_WRITE_WORD_DATA  I2CFunction = 0x00400000
-	I2C_FUNC_SMBUS_PROC_CALL        I2CFunction = 0x00800000
-	I2C_FUNC_SMBUS_READ_BLOCK_DATA  I2CFunction = 0x01000000
-	I2C_FUNC_SMBUS_WRITE_BLOCK_DATA I2CFunction = 0x02000000
-	I2C_FUNC_SMBUS_READ_I2C_BLOCK   I2CFunction = 0x04000000 /* I2C-like block xfer  */
-	I2C_FUNC_SMBUS_WRITE_I2C_BLOCK  I2CFunction = 0x08000000 /* w/ 1-byte reg. addr. */
-)
-
 const (
 	// i2c_smbus_xfer read or write markers
 	I2C_SMBUS_READ  uint8 = 0x01
@@ -123,6 +114,7 @@ func (config I2C) Open(log gopi.Logger) (gopi.Driver, error) {
 	this.log = log
 	this.bus = config.Bus
 	this.slave = I2C_SLAVE_NONE
+	this.tenbit_slave = I2C_TENBIT_SLAVE_NONE
 
 	// Open the device
 	if dev, err := i2c_open_device(config.Bus); err != nil {
@@ -150,6 +142,7 @@ func (this *i2c) Close() error {
 	err := this.dev.Close()
 	this.dev = nil
 	this.slave = I2C_SLAVE_NONE
+	this.tenbit_slave = I2C_TENBIT_SLAVE_NONE
 	return err
 }
 
@@ -171,54 +164,12 @@ func (this *i2c) String() string {
 		}
 	}
 	slave := fmt.Sprintf("%02X", this.slave)
-	if this.slave == I2C_SLAVE_NONE {
+	if this.tenbit_slave != I2C_TENBIT_SLAVE_NONE {
+		slave = fmt.Sprintf("%03X", this.tenbit_slave)
+	} else if this.slave == I2C_SLAVE_NONE {
 		slave = "I2C_SLAVE_NONE"
 	}
-	return fmt.Sprintf("<sys.hw.linux.I2C>{ bus=%v slave=%v funcs={ %v } }", this.bus, slave, strings.TrimSuffix(funcs, ","))
-}
-
-// Stringify I2CFuncs
-func (f I2CFunction) String() string {
-	switch f {
-	case I2C_FUNC_I2C:
-		return "I2C_FUNC_I2C"
-	case I2C_FUNC_10BIT_ADDR:
-		return "I2C_FUNC_10BIT_ADDR"
-	case I2C_FUNC_PROTOCOL_MANGLING:
-		return "I2C_FUNC_PROTOCOL_MANGLING"
-	case I2C_FUNC_SMBUS_PEC:
-		return "I2C_FUNC_SMBUS_PEC"
-	case I2C_FUNC_NOSTART:
-		return "I2C_FUNC_NOSTART"
-	case I2C_FUNC_SMBUS_BLOCK_PROC_CALL:
-		return "I2C_FUNC_SMBUS_BLOCK_PROC_CALL"
-	case I2C_FUNC_SMBUS_QUICK:
-		return "I2C_FUNC_SMBUS_QUICK"
-	case I2C_FUNC_SMBUS_READ_BYTE:
-		return "I2C_FUNC_SMBUS_READ_BYTE"
-	case I2C_FUNC_SMBUS_WRITE_BYTE:
-		return "I2C_FUNC_SMBUS_WRITE_BYTE"
-	case I2C_FUNC_SMBUS_READ_BYTE_DATA:
-		return "I2C_FUNC_SMBUS_READ_BYTE_DATA"
-	case I2C_FUNC_SMBUS_WRITE_BYTE_DATA:
-		return "I2C_FUNC_SMBUS_WRITE_BYTE_DATA"
-	case I2C_FUNC_SMBUS_READ_WORD_DATA:
-		return "I2C_FUNC_SMBUS_READ_WORD_DATA"
-	case I2C_FUNC_SMBUS_WRITE_WORD_DATA:
-		return "I2C_FUNC_SMBUS_WRITE_WORD_DATA"
-	case I2C_FUNC_SMBUS_PROC_CALL:
-		return "I2C_FUNC_SMBUS_PROC_CALL"
-	case I2C_FUNC_SMBUS_READ_BLOCK_DATA:
-		return "I2C_FUNC_SMBUS_READ_BLOCK_DATA"
-	case I2C_FUNC_SMBUS_WRITE_BLOCK_DATA:
-		return "I2C_FUNC_SMBUS_WRITE_BLOCK_DATA"
-	case I2C_FUNC_SMBUS_READ_I2C_BLOCK:
-		return "I2C_FUNC_SMBUS_READ_I2C_BLOCK"
-	case I2C_FUNC_SMBUS_WRITE_I2C_BLOCK:
-		return "I2C_FUNC_SMBUS_WRITE_I2C_BLOCK"
-	default:
-		return "[?? Unknown I2CFunction value]"
-	}
+	return fmt.Sprintf("<sys.hw.linux.I2C>{ bus=%v slave=%v pec=%v tenbit=%v funcs={ %v } }", this.bus, slave, this.pec, this.tenbit, strings.TrimSuffix(funcs, ","))
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -236,10 +187,37 @@ func (this *i2c) SetSlave(slave uint8) error {
 		return err
 	} else {
 		this.slave = slave
+		this.tenbit_slave = I2C_TENBIT_SLAVE_NONE
+		return nil
+	}
+}
+
+// SetTenBitSlave sets a slave address of up to 0x3FF, which requires
+// ten-bit addressing to be enabled first. The address is used by the
+// SMBus methods and by Read and Write
+func (this *i2c) SetTenBitSlave(slave uint16) error {
+	this.log.Debug2("<sys.hw.linux.I2C.SetTenBitSlave>{ slave=%v }", slave)
+	if this.tenbit == false {
+		return gopi.ErrOutOfOrder
+	} else if slave > 0x3FF {
+		return gopi.ErrBadParameter
+	} else if this.tenbit_slave == slave {
+		return nil
+	} else if err := i2c_ioctl(this.dev.Fd(), I2C_SLAVE, uintptr(slave)); err != nil {
+		return err
+	} else {
+		this.slave = I2C_SLAVE_NONE
+		this.tenbit_slave = slave
 		return nil
 	}
 }
 
+// GetTenBitSlave returns the ten-bit slave address, or
+// I2C_TENBIT_SLAVE_NONE if a ten-bit address has not been set
+func (this *i2c) GetTenBitSlave() uint16 {
+	return this.tenbit_slave
+}
+
 // GetSlave returns current slave address, or returns I2C_SLAVE_NONE if no slave
 // address has not yet been set
 func (this *i2c) GetSlave() uint8 {
@@ -249,37 +227,55 @@ func (this *i2c) GetSlave() uint8 {
 
 // DetectSlave checks to see if there is a device on a certain slave address
 func (this *i2c) DetectSlave(slave uint8) (bool, error) {
-	this.log.Debug2("<sys.hw.linux.I2C.DetectSlave>{ slave=%v }", slave)
+	return this.DetectSlaveMode(slave, I2C_DETECT_AUTO)
+}
+
+// DetectSlaveMode probes a slave with a quick write or read byte, and
+// returns true if the slave acknowledged
+func (this *i2c) DetectSlaveMode(slave uint8, mode I2CDetectMode) (bool, error) {
+	this.log.Debug2("<sys.hw.linux.I2C.DetectSlaveMode>{ slave=%v mode=%v }", slave, mode)
+
+	// Determine the probe, falling back to read byte when quick write
+	// is not supported in auto mode
+	probe := I2CDetectModeForSlave(slave, mode)
+	if probe == I2C_DETECT_QUICK && mode == I2C_DETECT_AUTO && this.funcs&I2C_FUNC_SMBUS_QUICK == 0 {
+		probe = I2C_DETECT_READ
+	}
+	switch probe {
+	case I2C_DETECT_QUICK:
+		if this.funcs&I2C_FUNC_SMBUS_QUICK == 0 {
+			return false, I2CUnsupportedError{I2C_FUNC_SMBUS_QUICK}
+		}
+	case I2C_DETECT_READ:
+		if this.funcs&I2C_FUNC_SMBUS_READ_BYTE == 0 {
+			return false, I2CUnsupportedError{I2C_FUNC_SMBUS_READ_BYTE}
+		}
+	default:
+		return false, gopi.ErrBadParameter
+	}
 
 	// Store old slave address and set this one
 	old_slave := this.slave
-	if slave != old_slave {
+	if slave != old_slave || this.tenbit_slave != I2C_TENBIT_SLAVE_NONE {
 		if err := i2c_ioctl(this.dev.Fd(), I2C_SLAVE, uintptr(slave)); err != nil {
 			return false, err
 		}
 	}
 
 	var detect bool
-	if this.funcs&I2C_FUNC_SMBUS_QUICK != 0 {
-		err := this.i2c_smbus_write_quick(0)
-		if err == nil {
-			detect = true
-		} else {
-			detect = false
-		}
-	} else if this.funcs&I2C_FUNC_SMBUS_READ_BYTE != 0 {
-		_, err := this.i2c_smbus_read_byte()
-		if err == nil {
-			detect = true
-		} else {
-			detect = false
-		}
+	if probe == I2C_DETECT_QUICK {
+		detect = this.i2c_smbus_write_quick(0) == nil
 	} else {
-		return false, gopi.ErrNotImplemented
+		_, err := this.i2c_smbus_read_byte()
+		detect = err == nil
 	}
 
 	// Restore slave address
-	if old_slave != I2C_SLAVE_NONE {
+	if this.tenbit_slave != I2C_TENBIT_SLAVE_NONE {
+		if err := i2c_ioctl(this.dev.Fd(), I2C_SLAVE, uintptr(this.tenbit_slave)); err != nil {
+			return false, err
+		}
+	} else if old_slave != I2C_SLAVE_NONE {
 		if err := i2c_ioctl(this.dev.Fd(), I2C_SLAVE, uintptr(old_slave)); err != nil {
 			return false, err
 		}
@@ -292,7 +288,7 @@ func (this *i2c) DetectSlave(slave uint8) (bool, error) {
 
 func (this *i2c) WriteQuick(value uint8) error {
 	this.log.Debug2("<sys.hw.linux.I2C.WriteQuick>{ value=%v }", value)
-	if this.slave == I2C_SLAVE_NONE {
+	if this.hasSlave() == false {
 		return gopi.ErrBadParameter
 	}
 	if this.funcs&I2C_FUNC_SMBUS_QUICK == 0 {
@@ -306,7 +302,7 @@ func (this *i2c) WriteQuick(value uint8) error {
 
 func (this *i2c) ReadUint8(reg uint8) (uint8, error) {
 	this.log.Debug2("<sys.hw.linux.I2C.ReadUint8>{ reg=0x%02X }", reg)
-	if this.slave == I2C_SLAVE_NONE {
+	if this.hasSlave() == false {
 		return uint8(0), gopi.ErrBadParameter
 	}
 	if this.funcs&I2C_FUNC_SMBUS_READ_BYTE_DATA == 0 {
@@ -322,7 +318,7 @@ func (this *i2c) ReadInt8(reg uint8) (int8, error) {
 
 func (this *i2c) ReadUint16(reg uint8) (uint16, error) {
 	this.log.Debug2("<sys.hw.linux.I2C.ReadUint16>{ reg=0x%02X }", reg)
-	if this.slave == I2C_SLAVE_NONE {
+	if this.hasSlave() == false {
 		return uint16(0), gopi.ErrBadParameter
 	}
 	if this.funcs&I2C_FUNC_SMBUS_READ_WORD_DATA == 0 {
@@ -338,7 +334,7 @@ func (this *i2c) ReadInt16(reg uint8) (int16, error) {
 
 func (this *i2c) ReadBlock(reg, length uint8) ([]byte, error) {
 	this.log.Debug2("<sys.hw.linux.I2C.ReadUint16>{ reg=0x%02X length=%v }", reg, length)
-	if this.slave == I2C_SLAVE_NONE {
+	if this.hasSlave() == false {
 		return nil, gopi.ErrBadParameter
 	}
 	if this.funcs&I2C_FUNC_SMBUS_READ_I2C_BLOCK == 0 {
@@ -352,7 +348,7 @@ func (this *i2c) ReadBlock(reg, length uint8) ([]byte, error) {
 
 func (this *i2c) WriteUint8(reg, value uint8) error {
 	this.log.Debug2("<sys.hw.linux.I2C.WriteUint8>{ reg=0x%02X value=%v }", reg, value)
-	if this.slave == I2C_SLAVE_NONE {
+	if this.hasSlave() == false {
 		return gopi.ErrBadParameter
 	}
 	if this.funcs&I2C_FUNC_SMBUS_WRITE_BYTE_DATA == 0 {
@@ -367,7 +363,7 @@ func (this *i2c) WriteInt8(reg uint8, value int8) error {
 
 func (this *i2c) WriteUint16(reg uint8, value uint16) error {
 	this.log.Debug2("<sys.hw.linux.I2C.WriteUint16>{ reg=0x%02X value=%v }", reg, value)
-	if this.slave == I2C_SLAVE_NONE {
+	if this.hasSlave() == false {
 		return gopi.ErrBadParameter
 	}
 	if this.funcs&I2C_FUNC_SMBUS_WRITE_WORD_DATA == 0 {
@@ -380,9 +376,220 @@ func (this *i2c) WriteInt16(reg uint8, value int16) error {
 	return this.WriteUint16(reg, uint16(value))
 }
 
+// WriteBlock writes up to 32 bytes to a register as an SMBus block
+// write, where the slave receives the number of bytes before the data
+func (this *i2c) WriteBlock(reg uint8, data []byte) error {
+	this.log.Debug2("<sys.hw.linux.I2C.WriteBlock>{ reg=0x%02X data=%v }", reg, strings.TrimSpace(fmt.Sprintf("% 02X", data)))
+	if this.hasSlave() == false {
+		return gopi.ErrBadParameter
+	}
+	if len(data) == 0 || len(data) > I2C_SMBUS_BLOCK_MAX {
+		return gopi.ErrBadParameter
+	}
+	if this.funcs&I2C_FUNC_SMBUS_WRITE_BLOCK_DATA == 0 {
+		return I2CUnsupportedError{I2C_FUNC_SMBUS_WRITE_BLOCK_DATA}
+	}
+	return this.i2c_smbus_write_block_data(reg, data)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROCESS CALL METHODS
+
+// ProcessCall writes a word to a register and reads a word back
+// in the same transaction
+func (this *i2c) ProcessCall(reg uint8, value uint16) (uint16, error) {
+	this.log.Debug2("<sys.hw.linux.I2C.ProcessCall>{ reg=0x%02X value=%v }", reg, value)
+	if this.hasSlave() == false {
+		return uint16(0), gopi.ErrBadParameter
+	}
+	if this.funcs&I2C_FUNC_SMBUS_PROC_CALL == 0 {
+		return uint16(0), I2CUnsupportedError{I2C_FUNC_SMBUS_PROC_CALL}
+	}
+	return this.i2c_smbus_process_call(reg, value)
+}
+
+// BlockProcessCall writes up to 32 bytes to a register and reads up
+// to 32 bytes back in the same transaction
+func (this *i2c) BlockProcessCall(reg uint8, data []byte) ([]byte, error) {
+	this.log.Debug2("<sys.hw.linux.I2C.BlockProcessCall>{ reg=0x%02X data=%v }", reg, strings.TrimSpace(fmt.Sprintf("% 02X", data)))
+	if this.hasSlave() == false {
+		return nil, gopi.ErrBadParameter
+	}
+	if len(data) == 0 || len(data) > I2C_SMBUS_BLOCK_MAX {
+		return nil, gopi.ErrBadParameter
+	}
+	if this.funcs&I2C_FUNC_SMBUS_BLOCK_PROC_CALL == 0 {
+		return nil, I2CUnsupportedError{I2C_FUNC_SMBUS_BLOCK_PROC_CALL}
+	}
+	return this.i2c_smbus_block_process_call(reg, data)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PEC AND TEN-BIT ADDRESSING
+
+// SetPEC enables or disables packet error checking for SMBus
+// transactions, where a checksum byte is appended to each message
+func (this *i2c) SetPEC(pec bool) error {
+	this.log.Debug2("<sys.hw.linux.I2C.SetPEC>{ pec=%v }", pec)
+	if this.funcs&I2C_FUNC_SMBUS_PEC == 0 {
+		return I2CUnsupportedError{I2C_FUNC_SMBUS_PEC}
+	}
+	if err := i2c_ioctl(this.dev.Fd(), I2C_PEC, boolToUintptr(pec)); err != nil {
+		return err
+	} else {
+		this.pec = pec
+		return nil
+	}
+}
+
+// PEC returns true if packet error checking is enabled
+func (this *i2c) PEC() bool {
+	return this.pec
+}
+
+// SetTenBitAddressing enables or disables ten-bit slave addresses.
+// The current slave address is set again, as it is interpreted
+// differently in each mode, and a ten-bit slave address is cleared
+// when ten-bit addressing is disabled
+func (this *i2c) SetTenBitAddressing(tenbit bool) error {
+	this.log.Debug2("<sys.hw.linux.I2C.SetTenBitAddressing>{ tenbit=%v }", tenbit)
+	if this.funcs&I2C_FUNC_10BIT_ADDR == 0 {
+		return I2CUnsupportedError{I2C_FUNC_10BIT_ADDR}
+	}
+	if err := i2c_ioctl(this.dev.Fd(), I2C_TENBIT, boolToUintptr(tenbit)); err != nil {
+		return err
+	} else {
+		this.tenbit = tenbit
+	}
+	if tenbit == false {
+		this.tenbit_slave = I2C_TENBIT_SLAVE_NONE
+	}
+	if this.slave != I2C_SLAVE_NONE {
+		if err := i2c_ioctl(this.dev.Fd(), I2C_SLAVE, uintptr(this.slave)); err != nil {
+			this.slave = I2C_SLAVE_NONE
+			return err
+		}
+	}
+	return nil
+}
+
+// TenBitAddressing returns true if ten-bit slave addresses are enabled
+func (this *i2c) TenBitAddressing() bool {
+	return this.tenbit
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TRANSFER METHODS
+
+// Transfer sends one or more messages in a single combined transaction,
+// filling the buffers of messages with the I2C_M_RD flag set. The flags
+// are checked against the functionality of the adapter
+func (this *i2c) Transfer(msgs []I2CMessage) error {
+	this.log.Debug2("<sys.hw.linux.I2C.Transfer>{ msgs=%v }", msgs)
+	if len(msgs) == 0 || len(msgs) > I2C_RDWR_IOCTL_MAX_MSGS {
+		return gopi.ErrBadParameter
+	}
+	if this.funcs&I2C_FUNC_I2C == 0 {
+		return I2CUnsupportedError{I2C_FUNC_I2C}
+	}
+	if data, err := i2c_messages(msgs, this.funcs); err != nil {
+		return err
+	} else {
+		return this.i2c_rdwr(data)
+	}
+}
+
+// Read bytes from the current slave
+func (this *i2c) Read(length uint) ([]byte, error) {
+	this.log.Debug2("<sys.hw.linux.I2C.Read>{ length=%v }", length)
+	if this.hasSlave() == false {
+		return nil, gopi.ErrBadParameter
+	}
+	buf := make([]byte, length)
+	if err := this.Transfer([]I2CMessage{this.message(I2C_M_RD, buf)}); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Write bytes to the current slave
+func (this *i2c) Write(buf []byte) error {
+	this.log.Debug2("<sys.hw.linux.I2C.Write>{ buf=%v }", strings.TrimSpace(fmt.Sprintf("% 02X", buf)))
+	if this.hasSlave() == false {
+		return gopi.ErrBadParameter
+	}
+	return this.Transfer([]I2CMessage{this.message(0, buf)})
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+// hasSlave returns true if a slave address or ten-bit slave address
+// has been set
+func (this *i2c) hasSlave() bool {
+	return this.slave != I2C_SLAVE_NONE || this.tenbit_slave != I2C_TENBIT_SLAVE_NONE
+}
+
+// message returns a message for the current slave
+func (this *i2c) message(flags I2CMessageFlag, buf []byte) I2CMessage {
+	if this.tenbit_slave != I2C_TENBIT_SLAVE_NONE {
+		return I2CMessage{Slave: this.tenbit_slave, Flags: flags | I2C_M_TEN, Buf: buf}
+	} else {
+		return I2CMessage{Slave: uint16(this.slave), Flags: flags, Buf: buf}
+	}
+}
+
+// i2c_messages converts messages to the kernel representation, checking
+// the flags against the functionality of the adapter
+func i2c_messages(msgs []I2CMessage, funcs I2CFunction) ([]i2c_msg, error) {
+	data := make([]i2c_msg, len(msgs))
+	for i, msg := range msgs {
+		// Check message length and slave address
+		if len(msg.Buf) > I2C_MSG_MAX {
+			return nil, gopi.ErrBadParameter
+		} else if msg.Flags&I2C_M_TEN != 0 && msg.Slave > 0x3FF {
+			return nil, gopi.ErrBadParameter
+		} else if msg.Flags&I2C_M_TEN == 0 && msg.Slave > 0x7F {
+			return nil, gopi.ErrBadParameter
+		}
+		// Check flags against adapter functionality
+		for flag := I2CMessageFlag(1); flag != 0; flag <<= 1 {
+			if msg.Flags&flag == 0 {
+				continue
+			}
+			switch flag {
+			case I2C_M_RD:
+				continue
+			case I2C_M_TEN:
+				if funcs&I2C_FUNC_10BIT_ADDR == 0 {
+					return nil, I2CUnsupportedError{I2C_FUNC_10BIT_ADDR}
+				}
+			case I2C_M_NOSTART:
+				if i == 0 {
+					return nil, gopi.ErrBadParameter
+				} else if funcs&I2C_FUNC_NOSTART == 0 {
+					return nil, I2CUnsupportedError{I2C_FUNC_NOSTART}
+				}
+			case I2C_M_IGNORE_NAK, I2C_M_NO_RD_ACK, I2C_M_REV_DIR_ADDR, I2C_M_STOP:
+				if funcs&I2C_FUNC_PROTOCOL_MANGLING == 0 {
+					return nil, I2CUnsupportedError{I2C_FUNC_PROTOCOL_MANGLING}
+				}
+			default:
+				return nil, gopi.ErrBadParameter
+			}
+		}
+		data[i] = i2c_msg{
+			addr:  msg.Slave,
+			flags: uint16(msg.Flags),
+			len:   uint16(len(msg.Buf)),
+		}
+		if len(msg.Buf) > 0 {
+			data[i].buf = unsafe.Pointer(&msg.Buf[0])
+		}
+	}
+	return data, nil
+}
+
 func (this *i2c) i2cFuncs() (I2CFunction, error) {
 	var funcs I2CFunction
 	this.lock.Lock()
@@ -401,6 +608,14 @@ func i2c_open_device(bus uint) (*os.File, error) {
 	}
 }
 
+func boolToUintptr(value bool) uintptr {
+	if value {
+		return 1
+	} else {
+		return 0
+	}
+}
+
 func i2c_ioctl(fd, cmd, arg uintptr) error {
 	if _, _, err := syscall.Syscall6(syscall.SYS_IOCTL, fd, cmd, arg, 0, 0, 0); err != 0 {
 		return err
@@ -424,6 +639,16 @@ func (this *i2c) i2c_smbus_access(rw uint8, command uint8, size uint32, data uin
 	return i2c_ioctl(this.dev.Fd(), I2C_SMBUS, uintptr(unsafe.Pointer(args)))
 }
 
+func (this *i2c) i2c_rdwr(msgs []i2c_msg) error {
+	args := &i2c_rdwr_ioctl_data{
+		msgs:  unsafe.Pointer(&msgs[0]),
+		nmsgs: uint32(len(msgs)),
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return i2c_ioctl(this.dev.Fd(), I2C_RDWR, uintptr(unsafe.Pointer(args)))
+}
+
 func (this *i2c) i2c_smbus_write_quick(value uint8) error {
 	return this.i2c_smbus_access(value, uint8(0), I2C_SMBUS_QUICK, 0)
 }
@@ -492,6 +717,26 @@ func (this *i2c) i2c_smbus_read_block_data(command uint8) ([]byte, error) {
 	return block, nil
 }
 
+func (this *i2c) i2c_smbus_write_block_data(command uint8, block []byte) error {
+	var data [I2C_SMBUS_BLOCK_MAX + 2]byte
+	data[0] = uint8(len(block))
+	copy(data[1:], block)
+	return this.i2c_smbus_access(I2C_SMBUS_WRITE, command, I2C_SMBUS_BLOCK_DATA, uintptr(unsafe.Pointer(&data)))
+}
+
+func (this *i2c) i2c_smbus_block_process_call(command uint8, block []byte) ([]byte, error) {
+	var data [I2C_SMBUS_BLOCK_MAX + 2]byte
+	data[0] = uint8(len(block))
+	copy(data[1:], block)
+	if err := this.i2c_smbus_access(I2C_SMBUS_WRITE, command, I2C_SMBUS_BLOCK_PROC_CALL, uintptr(unsafe.Pointer(&data))); err != nil {
+		return nil, err
+	}
+	if data[0] > I2C_SMBUS_BLOCK_MAX {
+		return nil, gopi.ErrUnexpectedResponse
+	}
+	return append([]byte{}, data[1:data[0]+1]...), nil
+}
+
 func (this *i2c) i2c_smbus_read_i2c_block_data(command uint8, length uint8) ([]byte, error) {
 	var data [I2C_SMBUS_BLOCK_MAX + 2]byte
 
@@ -0,0 +1,120 @@
+package i2c
+
+import (
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestMessages_000(t *testing.T) {
+	// Write register address, then read with a repeated start
+	buf := make([]byte, 64)
+	msgs := []I2CMessage{
+		{Slave: 0x50, Buf: []byte{0x00, 0x10}},
+		{Slave: 0x50, Flags: I2C_M_RD, Buf: buf},
+	}
+	if data, err := i2c_messages(msgs, I2C_FUNC_I2C); err != nil {
+		t.Fatal(err)
+	} else if len(data) != 2 {
+		t.Error("Unexpected number of messages", len(data))
+	} else if data[0].addr != 0x50 || data[0].flags != 0 || data[0].len != 2 {
+		t.Error("Unexpected write message", data[0])
+	} else if data[1].addr != 0x50 || data[1].flags != uint16(I2C_M_RD) || data[1].len != 64 {
+		t.Error("Unexpected read message", data[1])
+	} else if data[1].buf == nil {
+		t.Error("Expected buffer for read message")
+	}
+
+	// Empty message has no buffer
+	if data, err := i2c_messages([]I2CMessage{{Slave: 0x50}}, I2C_FUNC_I2C); err != nil {
+		t.Error(err)
+	} else if data[0].buf != nil || data[0].len != 0 {
+		t.Error("Unexpected empty message", data[0])
+	}
+}
+
+func TestMessages_001(t *testing.T) {
+	tests := []struct {
+		msgs  []I2CMessage
+		funcs I2CFunction
+		err   error
+	}{
+		// Slave addresses
+		{[]I2CMessage{{Slave: 0x80}}, I2C_FUNC_I2C, gopi.ErrBadParameter},
+		{[]I2CMessage{{Slave: 0x150, Flags: I2C_M_TEN}}, I2C_FUNC_I2C, I2CUnsupportedError{I2C_FUNC_10BIT_ADDR}},
+		{[]I2CMessage{{Slave: 0x150, Flags: I2C_M_TEN}}, I2C_FUNC_I2C | I2C_FUNC_10BIT_ADDR, nil},
+		{[]I2CMessage{{Slave: 0x400, Flags: I2C_M_TEN}}, I2C_FUNC_I2C | I2C_FUNC_10BIT_ADDR, gopi.ErrBadParameter},
+		// No start cannot be on the first message
+		{[]I2CMessage{{Slave: 0x50}, {Slave: 0x50, Flags: I2C_M_NOSTART}}, I2C_FUNC_I2C, I2CUnsupportedError{I2C_FUNC_NOSTART}},
+		{[]I2CMessage{{Slave: 0x50}, {Slave: 0x50, Flags: I2C_M_NOSTART}}, I2C_FUNC_I2C | I2C_FUNC_NOSTART, nil},
+		{[]I2CMessage{{Slave: 0x50, Flags: I2C_M_NOSTART}}, I2C_FUNC_I2C | I2C_FUNC_NOSTART, gopi.ErrBadParameter},
+		// Ignore NAK requires protocol mangling
+		{[]I2CMessage{{Slave: 0x50, Flags: I2C_M_IGNORE_NAK}}, I2C_FUNC_I2C, I2CUnsupportedError{I2C_FUNC_PROTOCOL_MANGLING}},
+		{[]I2CMessage{{Slave: 0x50, Flags: I2C_M_IGNORE_NAK | I2C_M_RD}}, I2C_FUNC_I2C | I2C_FUNC_PROTOCOL_MANGLING, nil},
+		// Unknown flags and long messages
+		{[]I2CMessage{{Slave: 0x50, Flags: 0x0400}}, I2C_FUNC_I2C, gopi.ErrBadParameter},
+		{[]I2CMessage{{Slave: 0x50, Buf: make([]byte, I2C_MSG_MAX+1)}}, I2C_FUNC_I2C, gopi.ErrBadParameter},
+	}
+	for i, test := range tests {
+		if _, err := i2c_messages(test.msgs, test.funcs); err != test.err {
+			t.Errorf("Test %v: expected %v, got %v", i, test.err, err)
+		}
+	}
+}
+
+func TestMessages_002(t *testing.T) {
+	if str := I2CMessageFlag(0).String(); str != "I2C_M_NONE" {
+		t.Error("Unexpected string", str)
+	}
+	if str := (I2C_M_RD | I2C_M_IGNORE_NAK).String(); str != "I2C_M_RD|I2C_M_IGNORE_NAK" {
+		t.Error("Unexpected string", str)
+	}
+}
+
+func TestUnsupported_000(t *testing.T) {
+	// Operations which the adapter cannot do return an error naming the function
+	app, err := gopi.NewAppInstance(gopi.NewAppConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	this := &i2c{log: app.Logger, funcs: I2C_FUNC_I2C, slave: 0x50, tenbit_slave: I2C_TENBIT_SLAVE_NONE}
+	if err := this.WriteBlock(0x00, []byte{0x01}); err != (I2CUnsupportedError{I2C_FUNC_SMBUS_WRITE_BLOCK_DATA}) {
+		t.Error("Unexpected error", err)
+	} else if err.Error() != "I2C_FUNC_SMBUS_WRITE_BLOCK_DATA unsupported by adapter" {
+		t.Error("Unexpected error message", err)
+	}
+	if _, err := this.ProcessCall(0x00, 0x1234); err != (I2CUnsupportedError{I2C_FUNC_SMBUS_PROC_CALL}) {
+		t.Error("Unexpected error", err)
+	}
+	if _, err := this.BlockProcessCall(0x00, []byte{0x01}); err != (I2CUnsupportedError{I2C_FUNC_SMBUS_BLOCK_PROC_CALL}) {
+		t.Error("Unexpected error", err)
+	}
+	if err := this.SetPEC(true); err != (I2CUnsupportedError{I2C_FUNC_SMBUS_PEC}) {
+		t.Error("Unexpected error", err)
+	} else if this.PEC() {
+		t.Error("Unexpected PEC value")
+	}
+	if err := this.SetTenBitAddressing(true); err != (I2CUnsupportedError{I2C_FUNC_10BIT_ADDR}) {
+		t.Error("Unexpected error", err)
+	} else if this.TenBitAddressing() {
+		t.Error("Unexpected ten-bit addressing value")
+	}
+	if err := this.SetTenBitSlave(0x150); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	} else if this.GetTenBitSlave() != I2C_TENBIT_SLAVE_NONE {
+		t.Error("Unexpected ten-bit slave", this.GetTenBitSlave())
+	}
+
+	// Block writes are limited to 32 bytes
+	this.funcs |= I2C_FUNC_SMBUS_WRITE_BLOCK_DATA
+	if err := this.WriteBlock(0x00, make([]byte, I2C_SMBUS_BLOCK_MAX+1)); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
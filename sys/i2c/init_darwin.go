@@ -1,4 +1,4 @@
-// +build darwin
+// +build darwin,!mock
 
 /*
   Go Language Raspberry Pi Interface
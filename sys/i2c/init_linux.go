@@ -1,4 +1,4 @@
-// +build linux
+// +build linux,!mock
 
 /*
   Go Language Raspberry Pi Interface
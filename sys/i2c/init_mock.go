@@ -0,0 +1,61 @@
+// +build mock
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package i2c
+
+import (
+	"strconv"
+	"strings"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register simulated I2C bus in place of the hardware
+	gopi.RegisterModule(gopi.Module{
+		Name: "hw/i2c",
+		Type: gopi.MODULE_TYPE_I2C,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("i2c.bus", 1, "I2C Bus")
+			config.AppFlags.FlagString("i2c.slaves", "", "Comma-separated virtual slave addresses")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			bus, _ := app.AppFlags.GetUint("i2c.bus")
+			slaves, _ := app.AppFlags.GetString("i2c.slaves")
+			if mock_slaves, err := parseMockSlaves(slaves); err != nil {
+				return nil, err
+			} else {
+				return gopi.Open(Mock{
+					Bus:    bus,
+					Slaves: mock_slaves,
+				}, app.Logger)
+			}
+		},
+	})
+}
+
+func parseMockSlaves(value string) ([]MockSlave, error) {
+	slaves := make([]MockSlave, 0)
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		} else if address, err := strconv.ParseUint(field, 0, 7); err != nil {
+			return nil, gopi.ErrBadParameter
+		} else {
+			slaves = append(slaves, MockSlave{Address: uint8(address)})
+		}
+	}
+	return slaves, nil
+}
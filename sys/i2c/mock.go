@@ -0,0 +1,655 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package i2c
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Mock is the configuration for a simulated I2C bus, which hosts virtual
+// slaves and records every transaction
+type Mock struct {
+	// Bus number, which is only used for display
+	Bus uint
+
+	// Virtual slaves on the bus
+	Slaves []MockSlave
+
+	// Functionality of the adapter, which is every function when zero
+	Funcs I2CFunction
+}
+
+// MockSlave is a virtual slave with 256 byte registers. A write sets the
+// register pointer from the first byte and writes the remaining bytes,
+// and a read returns bytes from the register pointer. The pointer is
+// incremented after each byte
+type MockSlave struct {
+	// Slave address
+	Address uint8
+
+	// Initial register contents, up to 256 bytes
+	Registers []byte
+
+	// Scripted responses for reads starting at a register, which are
+	// returned in order before the register contents are used
+	Responses map[uint8][][]byte
+}
+
+// MockOp is the type of a transaction on a simulated bus
+type MockOp uint
+
+// MockTransaction is a transaction recorded on a simulated bus
+type MockTransaction struct {
+	// Slave address
+	Slave uint16
+
+	// Quick write, read or write
+	Op MockOp
+
+	// Register pointer at the start of a read, or set by a write
+	Register uint8
+
+	// Bytes read, or bytes written after the register
+	Data []byte
+
+	// ErrNAK when the slave is absent
+	Err error
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
+// I2CMockInterface is implemented by the simulated bus
+type I2CMockInterface interface {
+	I2CInterface
+
+	// Return and clear the recorded transactions
+	Transactions() []MockTransaction
+	ClearTransactions()
+
+	// Add and remove virtual slaves
+	AddSlave(MockSlave) error
+	RemoveSlave(uint8) error
+
+	// Return the register contents of a virtual slave
+	Registers(uint8) ([]byte, error)
+}
+
+type mock struct {
+	log          gopi.Logger
+	bus          uint
+	funcs        I2CFunction
+	slave        uint8
+	tenbit_slave uint16
+	pec          bool
+	tenbit       bool
+	slaves       map[uint16]*mock_slave
+	transactions []MockTransaction
+	sync.Mutex
+}
+
+type mock_slave struct {
+	registers [256]byte
+	pointer   uint8
+	responses map[uint8][][]byte
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	I2C_MOCK_QUICK MockOp = iota
+	I2C_MOCK_READ
+	I2C_MOCK_WRITE
+)
+
+const (
+	// Every function an adapter can have
+	I2C_FUNC_ALL I2CFunction = I2C_FUNC_I2C | I2C_FUNC_10BIT_ADDR | I2C_FUNC_PROTOCOL_MANGLING |
+		I2C_FUNC_SMBUS_PEC | I2C_FUNC_NOSTART | I2C_FUNC_SMBUS_BLOCK_PROC_CALL | I2C_FUNC_SMBUS_QUICK |
+		I2C_FUNC_SMBUS_READ_BYTE | I2C_FUNC_SMBUS_WRITE_BYTE | I2C_FUNC_SMBUS_READ_BYTE_DATA |
+		I2C_FUNC_SMBUS_WRITE_BYTE_DATA | I2C_FUNC_SMBUS_READ_WORD_DATA | I2C_FUNC_SMBUS_WRITE_WORD_DATA |
+		I2C_FUNC_SMBUS_PROC_CALL | I2C_FUNC_SMBUS_READ_BLOCK_DATA | I2C_FUNC_SMBUS_WRITE_BLOCK_DATA |
+		I2C_FUNC_SMBUS_READ_I2C_BLOCK | I2C_FUNC_SMBUS_WRITE_I2C_BLOCK
+)
+
+var (
+	// ErrNAK is returned when no slave acknowledges its address
+	ErrNAK = errors.New("Slave did not acknowledge")
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open
+func (config Mock) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sys.hw.mock.I2C>Open{ bus=%v slaves=%v }", config.Bus, len(config.Slaves))
+
+	this := new(mock)
+	this.log = log
+	this.bus = config.Bus
+	this.slave = I2C_SLAVE_NONE
+	this.tenbit_slave = I2C_TENBIT_SLAVE_NONE
+	this.slaves = make(map[uint16]*mock_slave, len(config.Slaves))
+	this.transactions = make([]MockTransaction, 0)
+	if this.funcs = config.Funcs; this.funcs == 0 {
+		this.funcs = I2C_FUNC_ALL
+	}
+
+	// Add the slaves
+	for _, slave := range config.Slaves {
+		if err := this.AddSlave(slave); err != nil {
+			return nil, err
+		}
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close
+func (this *mock) Close() error {
+	this.log.Debug("<sys.hw.mock.I2C>Close")
+
+	this.Lock()
+	defer this.Unlock()
+	this.slaves = nil
+	this.slave = I2C_SLAVE_NONE
+	this.tenbit_slave = I2C_TENBIT_SLAVE_NONE
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *mock) String() string {
+	this.Lock()
+	defer this.Unlock()
+	slaves := ""
+	for addr := range this.slaves {
+		slaves += fmt.Sprintf("0x%02X,", addr)
+	}
+	return fmt.Sprintf("<sys.hw.mock.I2C>{ bus=%v slaves={ %v } transactions=%v }", this.bus, strings.TrimSuffix(slaves, ","), len(this.transactions))
+}
+
+func (o MockOp) String() string {
+	switch o {
+	case I2C_MOCK_QUICK:
+		return "I2C_MOCK_QUICK"
+	case I2C_MOCK_READ:
+		return "I2C_MOCK_READ"
+	case I2C_MOCK_WRITE:
+		return "I2C_MOCK_WRITE"
+	default:
+		return "[?? Invalid MockOp value]"
+	}
+}
+
+func (t MockTransaction) String() string {
+	if t.Err != nil {
+		return fmt.Sprintf("<hw.i2c.MockTransaction>{ slave=0x%02X op=%v err=%v }", t.Slave, t.Op, t.Err)
+	} else {
+		return fmt.Sprintf("<hw.i2c.MockTransaction>{ slave=0x%02X op=%v reg=0x%02X data=[%v] }", t.Slave, t.Op, t.Register, strings.TrimSpace(fmt.Sprintf("% 02X", t.Data)))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SLAVES AND TRANSACTIONS
+
+func (this *mock) AddSlave(slave MockSlave) error {
+	this.log.Debug2("<sys.hw.mock.I2C.AddSlave>{ address=0x%02X }", slave.Address)
+
+	this.Lock()
+	defer this.Unlock()
+	if slave.Address > 0x7F || len(slave.Registers) > 256 {
+		return gopi.ErrBadParameter
+	} else if _, exists := this.slaves[uint16(slave.Address)]; exists {
+		return gopi.ErrBadParameter
+	}
+	s := &mock_slave{responses: make(map[uint8][][]byte, len(slave.Responses))}
+	copy(s.registers[:], slave.Registers)
+	for reg, responses := range slave.Responses {
+		s.responses[reg] = append([][]byte{}, responses...)
+	}
+	this.slaves[uint16(slave.Address)] = s
+	return nil
+}
+
+func (this *mock) RemoveSlave(address uint8) error {
+	this.log.Debug2("<sys.hw.mock.I2C.RemoveSlave>{ address=0x%02X }", address)
+
+	this.Lock()
+	defer this.Unlock()
+	if _, exists := this.slaves[uint16(address)]; exists == false {
+		return gopi.ErrNotFound
+	} else {
+		delete(this.slaves, uint16(address))
+		return nil
+	}
+}
+
+func (this *mock) Registers(address uint8) ([]byte, error) {
+	this.Lock()
+	defer this.Unlock()
+	if slave, exists := this.slaves[uint16(address)]; exists == false {
+		return nil, gopi.ErrNotFound
+	} else {
+		return append([]byte{}, slave.registers[:]...), nil
+	}
+}
+
+func (this *mock) Transactions() []MockTransaction {
+	this.Lock()
+	defer this.Unlock()
+	return append([]MockTransaction{}, this.transactions...)
+}
+
+func (this *mock) ClearTransactions() {
+	this.Lock()
+	defer this.Unlock()
+	this.transactions = this.transactions[:0]
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SLAVE ADDRESS
+
+func (this *mock) SetSlave(slave uint8) error {
+	this.log.Debug2("<sys.hw.mock.I2C.SetSlave>{ slave=%v }", slave)
+	this.Lock()
+	defer this.Unlock()
+	if slave == I2C_SLAVE_NONE || slave > 0x7F {
+		return gopi.ErrBadParameter
+	}
+	this.slave = slave
+	this.tenbit_slave = I2C_TENBIT_SLAVE_NONE
+	return nil
+}
+
+func (this *mock) GetSlave() uint8 {
+	this.Lock()
+	defer this.Unlock()
+	return this.slave
+}
+
+func (this *mock) SetTenBitSlave(slave uint16) error {
+	this.log.Debug2("<sys.hw.mock.I2C.SetTenBitSlave>{ slave=%v }", slave)
+	this.Lock()
+	defer this.Unlock()
+	if this.tenbit == false {
+		return gopi.ErrOutOfOrder
+	} else if slave > 0x3FF {
+		return gopi.ErrBadParameter
+	}
+	this.slave = I2C_SLAVE_NONE
+	this.tenbit_slave = slave
+	return nil
+}
+
+func (this *mock) GetTenBitSlave() uint16 {
+	this.Lock()
+	defer this.Unlock()
+	return this.tenbit_slave
+}
+
+func (this *mock) DetectSlave(slave uint8) (bool, error) {
+	return this.DetectSlaveMode(slave, I2C_DETECT_AUTO)
+}
+
+func (this *mock) DetectSlaveMode(slave uint8, mode I2CDetectMode) (bool, error) {
+	this.log.Debug2("<sys.hw.mock.I2C.DetectSlaveMode>{ slave=%v mode=%v }", slave, mode)
+
+	probe := I2CDetectModeForSlave(slave, mode)
+	if probe == I2C_DETECT_QUICK && mode == I2C_DETECT_AUTO && this.funcs&I2C_FUNC_SMBUS_QUICK == 0 {
+		probe = I2C_DETECT_READ
+	}
+
+	this.Lock()
+	defer this.Unlock()
+	switch probe {
+	case I2C_DETECT_QUICK:
+		if err := this.check(I2C_FUNC_SMBUS_QUICK); err != nil {
+			return false, err
+		}
+		return this.quick(uint16(slave)) == nil, nil
+	case I2C_DETECT_READ:
+		if err := this.check(I2C_FUNC_SMBUS_READ_BYTE); err != nil {
+			return false, err
+		}
+		_, err := this.read(uint16(slave), 1)
+		return err == nil, nil
+	default:
+		return false, gopi.ErrBadParameter
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SMBUS METHODS
+
+func (this *mock) WriteQuick(value uint8) error {
+	this.Lock()
+	defer this.Unlock()
+	if err := this.checkSlave(I2C_FUNC_SMBUS_QUICK); err != nil {
+		return err
+	}
+	return this.quick(this.address())
+}
+
+func (this *mock) ReadUint8(reg uint8) (uint8, error) {
+	if data, err := this.readRegister(reg, 1, I2C_FUNC_SMBUS_READ_BYTE_DATA); err != nil {
+		return 0, err
+	} else {
+		return data[0], nil
+	}
+}
+
+func (this *mock) ReadInt8(reg uint8) (int8, error) {
+	value, err := this.ReadUint8(reg)
+	return int8(value), err
+}
+
+func (this *mock) ReadUint16(reg uint8) (uint16, error) {
+	if data, err := this.readRegister(reg, 2, I2C_FUNC_SMBUS_READ_WORD_DATA); err != nil {
+		return 0, err
+	} else {
+		return uint16(data[0]) | uint16(data[1])<<8, nil
+	}
+}
+
+func (this *mock) ReadInt16(reg uint8) (int16, error) {
+	value, err := this.ReadUint16(reg)
+	return int16(value), err
+}
+
+func (this *mock) ReadBlock(reg, length uint8) ([]byte, error) {
+	if length == 0 || length > I2C_SMBUS_BLOCK_MAX {
+		return nil, gopi.ErrBadParameter
+	}
+	return this.readRegister(reg, uint(length), I2C_FUNC_SMBUS_READ_I2C_BLOCK)
+}
+
+func (this *mock) WriteUint8(reg, value uint8) error {
+	return this.writeRegister(reg, []byte{value}, I2C_FUNC_SMBUS_WRITE_BYTE_DATA)
+}
+
+func (this *mock) WriteInt8(reg uint8, value int8) error {
+	return this.WriteUint8(reg, uint8(value))
+}
+
+func (this *mock) WriteUint16(reg uint8, value uint16) error {
+	return this.writeRegister(reg, []byte{byte(value), byte(value >> 8)}, I2C_FUNC_SMBUS_WRITE_WORD_DATA)
+}
+
+func (this *mock) WriteInt16(reg uint8, value int16) error {
+	return this.WriteUint16(reg, uint16(value))
+}
+
+// WriteBlock writes the data to the registers, without the count byte
+func (this *mock) WriteBlock(reg uint8, data []byte) error {
+	if len(data) == 0 || len(data) > I2C_SMBUS_BLOCK_MAX {
+		return gopi.ErrBadParameter
+	}
+	return this.writeRegister(reg, data, I2C_FUNC_SMBUS_WRITE_BLOCK_DATA)
+}
+
+// ProcessCall writes a word to a register and reads the word back
+func (this *mock) ProcessCall(reg uint8, value uint16) (uint16, error) {
+	this.Lock()
+	defer this.Unlock()
+	if err := this.checkSlave(I2C_FUNC_SMBUS_PROC_CALL); err != nil {
+		return 0, err
+	} else if err := this.write(this.address(), []byte{reg, byte(value), byte(value >> 8)}); err != nil {
+		return 0, err
+	} else if data, err := this.readFrom(this.address(), reg, 2); err != nil {
+		return 0, err
+	} else {
+		return uint16(data[0]) | uint16(data[1])<<8, nil
+	}
+}
+
+// BlockProcessCall writes a block to a register and reads the block back
+func (this *mock) BlockProcessCall(reg uint8, data []byte) ([]byte, error) {
+	if len(data) == 0 || len(data) > I2C_SMBUS_BLOCK_MAX {
+		return nil, gopi.ErrBadParameter
+	}
+	this.Lock()
+	defer this.Unlock()
+	if err := this.checkSlave(I2C_FUNC_SMBUS_BLOCK_PROC_CALL); err != nil {
+		return nil, err
+	} else if err := this.write(this.address(), append([]byte{reg}, data...)); err != nil {
+		return nil, err
+	} else {
+		return this.readFrom(this.address(), reg, uint(len(data)))
+	}
+}
+
+func (this *mock) PEC() bool {
+	this.Lock()
+	defer this.Unlock()
+	return this.pec
+}
+
+func (this *mock) SetPEC(pec bool) error {
+	this.Lock()
+	defer this.Unlock()
+	if err := this.check(I2C_FUNC_SMBUS_PEC); err != nil {
+		return err
+	}
+	this.pec = pec
+	return nil
+}
+
+func (this *mock) TenBitAddressing() bool {
+	this.Lock()
+	defer this.Unlock()
+	return this.tenbit
+}
+
+func (this *mock) SetTenBitAddressing(tenbit bool) error {
+	this.Lock()
+	defer this.Unlock()
+	if err := this.check(I2C_FUNC_10BIT_ADDR); err != nil {
+		return err
+	}
+	this.tenbit = tenbit
+	if tenbit == false {
+		this.tenbit_slave = I2C_TENBIT_SLAVE_NONE
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// RAW I2C METHODS
+
+// Transfer sends each message in turn, stopping at the first message
+// which is not acknowledged
+func (this *mock) Transfer(msgs []I2CMessage) error {
+	this.log.Debug2("<sys.hw.mock.I2C.Transfer>{ msgs=%v }", msgs)
+
+	this.Lock()
+	defer this.Unlock()
+	return this.transfer(msgs)
+}
+
+func (this *mock) Read(length uint) ([]byte, error) {
+	buf := make([]byte, length)
+	this.Lock()
+	defer this.Unlock()
+	if this.slave == I2C_SLAVE_NONE && this.tenbit_slave == I2C_TENBIT_SLAVE_NONE {
+		return nil, gopi.ErrBadParameter
+	} else if err := this.transfer([]I2CMessage{this.message(I2C_M_RD, buf)}); err != nil {
+		return nil, err
+	} else {
+		return buf, nil
+	}
+}
+
+func (this *mock) Write(buf []byte) error {
+	this.Lock()
+	defer this.Unlock()
+	if this.slave == I2C_SLAVE_NONE && this.tenbit_slave == I2C_TENBIT_SLAVE_NONE {
+		return gopi.ErrBadParameter
+	}
+	return this.transfer([]I2CMessage{this.message(0, buf)})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// transfer sends messages whilst locked
+func (this *mock) transfer(msgs []I2CMessage) error {
+	if len(msgs) == 0 {
+		return gopi.ErrBadParameter
+	} else if err := this.check(I2C_FUNC_I2C); err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		if msg.Flags&I2C_M_TEN != 0 && msg.Slave > 0x3FF {
+			return gopi.ErrBadParameter
+		} else if msg.Flags&I2C_M_TEN == 0 && msg.Slave > 0x7F {
+			return gopi.ErrBadParameter
+		}
+	}
+	for _, msg := range msgs {
+		if msg.Flags&I2C_M_RD != 0 {
+			if data, err := this.read(msg.Slave, uint(len(msg.Buf))); err != nil {
+				return err
+			} else {
+				copy(msg.Buf, data)
+			}
+		} else if err := this.write(msg.Slave, msg.Buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *mock) check(function I2CFunction) error {
+	if this.funcs&function == 0 {
+		return I2CUnsupportedError{function}
+	} else {
+		return nil
+	}
+}
+
+// address returns the ten-bit slave address when set, or else the slave
+// address
+func (this *mock) address() uint16 {
+	if this.tenbit_slave != I2C_TENBIT_SLAVE_NONE {
+		return this.tenbit_slave
+	} else {
+		return uint16(this.slave)
+	}
+}
+
+// message returns a message for the current slave
+func (this *mock) message(flags I2CMessageFlag, buf []byte) I2CMessage {
+	if this.tenbit_slave != I2C_TENBIT_SLAVE_NONE {
+		flags |= I2C_M_TEN
+	}
+	return I2CMessage{Slave: this.address(), Flags: flags, Buf: buf}
+}
+
+func (this *mock) checkSlave(function I2CFunction) error {
+	if this.slave == I2C_SLAVE_NONE && this.tenbit_slave == I2C_TENBIT_SLAVE_NONE {
+		return gopi.ErrBadParameter
+	} else {
+		return this.check(function)
+	}
+}
+
+func (this *mock) readRegister(reg uint8, length uint, function I2CFunction) ([]byte, error) {
+	this.Lock()
+	defer this.Unlock()
+	if err := this.checkSlave(function); err != nil {
+		return nil, err
+	} else {
+		return this.readFrom(this.address(), reg, length)
+	}
+}
+
+func (this *mock) writeRegister(reg uint8, data []byte, function I2CFunction) error {
+	this.Lock()
+	defer this.Unlock()
+	if err := this.checkSlave(function); err != nil {
+		return err
+	} else {
+		return this.write(this.address(), append([]byte{reg}, data...))
+	}
+}
+
+// readFrom sets the register pointer and then reads with a repeated start,
+// which is recorded as a single read
+func (this *mock) readFrom(addr uint16, reg uint8, length uint) ([]byte, error) {
+	if slave, exists := this.slaves[addr]; exists {
+		slave.pointer = reg
+	}
+	return this.read(addr, length)
+}
+
+func (this *mock) quick(addr uint16) error {
+	if _, exists := this.slaves[addr]; exists == false {
+		return this.nak(addr, I2C_MOCK_QUICK)
+	}
+	this.transactions = append(this.transactions, MockTransaction{Slave: addr, Op: I2C_MOCK_QUICK})
+	return nil
+}
+
+func (this *mock) read(addr uint16, length uint) ([]byte, error) {
+	slave, exists := this.slaves[addr]
+	if exists == false {
+		return nil, this.nak(addr, I2C_MOCK_READ)
+	}
+	reg := slave.pointer
+	data := make([]byte, length)
+	for i := range data {
+		data[i] = slave.registers[uint8(uint(reg)+uint(i))]
+	}
+	if responses := slave.responses[reg]; len(responses) > 0 {
+		copy(data, responses[0])
+		slave.responses[reg] = responses[1:]
+	}
+	slave.pointer = uint8(uint(reg) + length)
+	this.transactions = append(this.transactions, MockTransaction{Slave: addr, Op: I2C_MOCK_READ, Register: reg, Data: append([]byte{}, data...)})
+	return data, nil
+}
+
+func (this *mock) write(addr uint16, data []byte) error {
+	slave, exists := this.slaves[addr]
+	if exists == false {
+		return this.nak(addr, I2C_MOCK_WRITE)
+	}
+	if len(data) > 0 {
+		slave.pointer = data[0]
+		for i, value := range data[1:] {
+			slave.registers[uint8(uint(data[0])+uint(i))] = value
+		}
+	}
+	transaction := MockTransaction{Slave: addr, Op: I2C_MOCK_WRITE, Register: slave.pointer}
+	if len(data) > 1 {
+		transaction.Data = append([]byte{}, data[1:]...)
+		slave.pointer = uint8(uint(data[0]) + uint(len(data)-1))
+	}
+	this.transactions = append(this.transactions, transaction)
+	return nil
+}
+
+func (this *mock) nak(addr uint16, op MockOp) error {
+	this.transactions = append(this.transactions, MockTransaction{Slave: addr, Op: op, Err: ErrNAK})
+	return ErrNAK
+}
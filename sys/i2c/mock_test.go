@@ -0,0 +1,225 @@
+package i2c_test
+
+import (
+	"bytes"
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	i2c "github.com/djthorpe/gopi-hw/sys/i2c"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func openMock(t *testing.T, config i2c.Mock) i2c.I2CMockInterface {
+	t.Helper()
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig()); err != nil {
+		t.Fatal(err)
+	} else if driver, err := gopi.Open(config, app.Logger); err != nil {
+		t.Fatal(err)
+	} else {
+		return driver.(i2c.I2CMockInterface)
+	}
+	return nil
+}
+
+func TestMock_000(t *testing.T) {
+	bus := openMock(t, i2c.Mock{Slaves: []i2c.MockSlave{
+		{Address: 0x48, Registers: []byte{0x12, 0x34, 0x56}},
+		{Address: 0x50},
+	}})
+	defer bus.Close()
+
+	// Detect slaves, where 0x50 is probed with a read
+	for slave, expected := range map[uint8]bool{0x48: true, 0x49: false, 0x50: true} {
+		if detected, err := bus.DetectSlave(slave); err != nil {
+			t.Error(err)
+		} else if detected != expected {
+			t.Errorf("Unexpected detect value for 0x%02X: %v", slave, detected)
+		}
+	}
+	if txs := bus.Transactions(); len(txs) != 3 {
+		t.Error("Unexpected transactions", txs)
+	}
+	bus.ClearTransactions()
+
+	// Read registers
+	if err := bus.SetSlave(0x48); err != nil {
+		t.Fatal(err)
+	} else if value, err := bus.ReadUint8(0x01); err != nil {
+		t.Error(err)
+	} else if value != 0x34 {
+		t.Error("Unexpected value", value)
+	} else if value, err := bus.ReadUint16(0x00); err != nil {
+		t.Error(err)
+	} else if value != 0x3412 {
+		t.Error("Unexpected value", value)
+	}
+
+	// Write registers and read them back with a raw read
+	if err := bus.WriteUint16(0x10, 0xBEEF); err != nil {
+		t.Error(err)
+	} else if err := bus.Write([]byte{0x10}); err != nil {
+		t.Error(err)
+	} else if data, err := bus.Read(2); err != nil {
+		t.Error(err)
+	} else if bytes.Equal(data, []byte{0xEF, 0xBE}) == false {
+		t.Error("Unexpected data", data)
+	}
+
+	// Check the transaction log
+	txs := bus.Transactions()
+	expected := []i2c.MockTransaction{
+		{Slave: 0x48, Op: i2c.I2C_MOCK_READ, Register: 0x01, Data: []byte{0x34}},
+		{Slave: 0x48, Op: i2c.I2C_MOCK_READ, Register: 0x00, Data: []byte{0x12, 0x34}},
+		{Slave: 0x48, Op: i2c.I2C_MOCK_WRITE, Register: 0x10, Data: []byte{0xEF, 0xBE}},
+		{Slave: 0x48, Op: i2c.I2C_MOCK_WRITE, Register: 0x10},
+		{Slave: 0x48, Op: i2c.I2C_MOCK_READ, Register: 0x10, Data: []byte{0xEF, 0xBE}},
+	}
+	if len(txs) != len(expected) {
+		t.Fatal("Unexpected transactions", txs)
+	}
+	for i := range txs {
+		if txs[i].Slave != expected[i].Slave || txs[i].Op != expected[i].Op || txs[i].Register != expected[i].Register || bytes.Equal(txs[i].Data, expected[i].Data) == false {
+			t.Errorf("Transaction %v: expected %v, got %v", i, expected[i], txs[i])
+		}
+	}
+}
+
+func TestMock_001(t *testing.T) {
+	// Status register returns busy twice before ready
+	bus := openMock(t, i2c.Mock{Slaves: []i2c.MockSlave{
+		{Address: 0x76, Registers: []byte{0x00, 0x01}, Responses: map[uint8][][]byte{
+			0x00: {{0x80}, {0x80}},
+		}},
+	}})
+	defer bus.Close()
+
+	if err := bus.SetSlave(0x76); err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []uint8{0x80, 0x80, 0x00, 0x00} {
+		if value, err := bus.ReadUint8(0x00); err != nil {
+			t.Error(err)
+		} else if value != expected {
+			t.Errorf("Read %v: expected 0x%02X, got 0x%02X", i, expected, value)
+		}
+	}
+
+	// Absent slaves are not acknowledged
+	if err := bus.SetSlave(0x77); err != nil {
+		t.Fatal(err)
+	} else if _, err := bus.ReadUint8(0x00); err != i2c.ErrNAK {
+		t.Error("Expected ErrNAK, got", err)
+	} else if txs := bus.Transactions(); txs[len(txs)-1].Err != i2c.ErrNAK {
+		t.Error("Expected NAK in transaction log", txs[len(txs)-1])
+	}
+
+	// Slaves can be added and removed
+	if err := bus.AddSlave(i2c.MockSlave{Address: 0x77}); err != nil {
+		t.Error(err)
+	} else if _, err := bus.ReadUint8(0x00); err != nil {
+		t.Error(err)
+	} else if err := bus.RemoveSlave(0x77); err != nil {
+		t.Error(err)
+	} else if err := bus.RemoveSlave(0x77); err != gopi.ErrNotFound {
+		t.Error("Expected ErrNotFound, got", err)
+	}
+}
+
+func TestMock_002(t *testing.T) {
+	// Adapter without word or block functions
+	bus := openMock(t, i2c.Mock{
+		Slaves: []i2c.MockSlave{{Address: 0x20}},
+		Funcs:  i2c.I2C_FUNC_SMBUS_READ_BYTE_DATA | i2c.I2C_FUNC_SMBUS_WRITE_BYTE_DATA,
+	})
+	defer bus.Close()
+
+	if _, err := bus.ReadUint8(0x00); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter without slave, got", err)
+	} else if err := bus.SetSlave(0x20); err != nil {
+		t.Fatal(err)
+	} else if err := bus.WriteUint8(0x05, 0xAA); err != nil {
+		t.Error(err)
+	} else if _, err := bus.ReadUint16(0x05); err != (i2c.I2CUnsupportedError{i2c.I2C_FUNC_SMBUS_READ_WORD_DATA}) {
+		t.Error("Unexpected error", err)
+	} else if err := bus.Transfer([]i2c.I2CMessage{{Slave: 0x20}}); err != (i2c.I2CUnsupportedError{i2c.I2C_FUNC_I2C}) {
+		t.Error("Unexpected error", err)
+	} else if registers, err := bus.Registers(0x20); err != nil {
+		t.Error(err)
+	} else if registers[0x05] != 0xAA {
+		t.Error("Unexpected register value", registers[0x05])
+	}
+}
+
+func TestMock_003(t *testing.T) {
+	// Ten-bit slave addresses require ten-bit addressing
+	bus := openMock(t, i2c.Mock{Slaves: []i2c.MockSlave{{Address: 0x50}}})
+	defer bus.Close()
+
+	if err := bus.SetTenBitSlave(0x150); err != gopi.ErrOutOfOrder {
+		t.Error("Expected ErrOutOfOrder, got", err)
+	} else if err := bus.SetTenBitAddressing(true); err != nil {
+		t.Fatal(err)
+	} else if err := bus.SetTenBitSlave(0x400); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if err := bus.SetTenBitSlave(0x150); err != nil {
+		t.Fatal(err)
+	} else if bus.GetTenBitSlave() != 0x150 || bus.GetSlave() != i2c.I2C_SLAVE_NONE {
+		t.Error("Unexpected slaves", bus.GetTenBitSlave(), bus.GetSlave())
+	}
+
+	// Writes and SMBus transactions are addressed to the ten-bit slave
+	if err := bus.Write([]byte{0x00}); err != i2c.ErrNAK {
+		t.Error("Expected ErrNAK, got", err)
+	} else if _, err := bus.ReadUint8(0x00); err != i2c.ErrNAK {
+		t.Error("Expected ErrNAK, got", err)
+	} else if transactions := bus.Transactions(); len(transactions) != 2 || transactions[0].Slave != 0x150 || transactions[1].Slave != 0x150 {
+		t.Error("Unexpected transactions", transactions)
+	}
+
+	// Setting a seven-bit slave or disabling ten-bit addressing clears
+	// the ten-bit slave
+	if err := bus.SetSlave(0x50); err != nil {
+		t.Error(err)
+	} else if bus.GetTenBitSlave() != i2c.I2C_TENBIT_SLAVE_NONE {
+		t.Error("Unexpected ten-bit slave", bus.GetTenBitSlave())
+	} else if err := bus.SetTenBitSlave(0x150); err != nil {
+		t.Error(err)
+	} else if err := bus.SetTenBitAddressing(false); err != nil {
+		t.Error(err)
+	} else if bus.GetTenBitSlave() != i2c.I2C_TENBIT_SLAVE_NONE {
+		t.Error("Unexpected ten-bit slave", bus.GetTenBitSlave())
+	}
+}
+
+func TestMock_004(t *testing.T) {
+	bus := openMock(t, i2c.Mock{Slaves: []i2c.MockSlave{{Address: 0x48}, {Address: 0x49}}})
+	defer bus.Close()
+
+	// Reconfigure the bus whilst transfers are made, which is checked
+	// with the race detector
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			bus.SetSlave(0x48 + uint8(i&1))
+			bus.SetPEC(i&2 != 0)
+			bus.SetTenBitAddressing(false)
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		bus.WriteUint8(0x00, uint8(i))
+		bus.Read(1)
+	}
+	<-done
+	if slave := bus.GetSlave(); slave != 0x49 {
+		t.Error("Unexpected slave", slave)
+	} else if bus.PEC() == false || bus.TenBitAddressing() {
+		t.Error("Unexpected PEC or ten-bit addressing")
+	}
+}
@@ -0,0 +1,274 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package ir
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Decoder is the configuration for decoding remote control codes from
+// the mode2 events of a LIRC device
+type Decoder struct {
+	// Publisher of LIRC events, which is usually app.LIRC
+	LIRC gopi.Publisher
+
+	// Protocols to decode, or every protocol when empty
+	Protocols []Protocol
+
+	// Timing tolerance as a percentage, or IR_TOLERANCE when zero
+	Tolerance uint
+
+	// Remote definitions, which are matched before the protocols
+	Remotes []*Remote
+}
+
+type decoder struct {
+	log       gopi.Logger
+	lirc      gopi.Publisher
+	events    <-chan gopi.Event
+	done      chan struct{}
+	protocols map[Protocol]bool
+	tolerance uint
+	remotes   []*Remote
+
+	// Frame of pulses and spaces
+	frame []uint32
+
+	// Time in microseconds from the first event, and the time and
+	// code of the last frame decoded
+	clock   uint64
+	last_at uint64
+	last    *code
+
+	// Publisher of key events
+	event.Publisher
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Default timing tolerance as a percentage
+	IR_TOLERANCE = 25
+
+	// A space of at least this length in microseconds ends a frame
+	IR_FRAME_GAP = 6000
+
+	// A code received within this time in microseconds of the end of
+	// the last frame with the same code is a repeat
+	IR_REPEAT_WINDOW = 200000
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open
+func (config Decoder) Open(logger gopi.Logger) (gopi.Driver, error) {
+	logger.Debug("<hw.ir.Decoder>Open{ protocols=%v tolerance=%v remotes=%v }", config.Protocols, config.Tolerance, config.Remotes)
+
+	this := new(decoder)
+	this.log = logger
+	this.frame = make([]uint32, 0, 100)
+
+	// LIRC publisher is required
+	if config.LIRC == nil {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.lirc = config.LIRC
+	}
+
+	// Protocols
+	this.protocols = make(map[Protocol]bool)
+	if len(config.Protocols) == 0 {
+		for protocol := IR_PROTOCOL_NONE + 1; protocol <= IR_PROTOCOL_MAX; protocol++ {
+			this.protocols[protocol] = true
+		}
+	}
+	for _, protocol := range config.Protocols {
+		if protocol == IR_PROTOCOL_NONE || protocol > IR_PROTOCOL_MAX {
+			return nil, gopi.ErrBadParameter
+		}
+		this.protocols[protocol] = true
+	}
+
+	// Tolerance
+	if this.tolerance = config.Tolerance; this.tolerance == 0 {
+		this.tolerance = IR_TOLERANCE
+	} else if this.tolerance >= 100 {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Remote definitions
+	for _, remote := range config.Remotes {
+		if remote == nil {
+			return nil, gopi.ErrBadParameter
+		}
+	}
+	this.remotes = config.Remotes
+
+	// Receive events in the background
+	this.events = this.lirc.Subscribe()
+	this.done = make(chan struct{})
+	go this.receive()
+
+	// Success
+	return this, nil
+}
+
+// Close
+func (this *decoder) Close() error {
+	this.log.Debug("<hw.ir.Decoder>Close{ }")
+
+	// Unsubscribe, which ends the background task
+	this.lirc.Unsubscribe(this.events)
+	<-this.done
+
+	// Close subscriber channels
+	this.Publisher.Close()
+
+	// Blank out
+	this.lirc = nil
+	this.events = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *decoder) String() string {
+	return fmt.Sprintf("<hw.ir.Decoder>{ protocols=%v tolerance=%v%% remotes=%v }", this.Protocols(), this.tolerance, this.remotes)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES
+
+func (this *decoder) Protocols() []Protocol {
+	protocols := make([]Protocol, 0, len(this.protocols))
+	for protocol := IR_PROTOCOL_NONE + 1; protocol <= IR_PROTOCOL_MAX; protocol++ {
+		if this.protocols[protocol] {
+			protocols = append(protocols, protocol)
+		}
+	}
+	return protocols
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Decode returns the protocol, device and scancode of a frame of
+// alternating pulse and space durations, or IR_PROTOCOL_NONE when the
+// frame is not decoded or is a repeat frame
+func Decode(frame []uint32, tolerance uint) (Protocol, uint32, uint32) {
+	for _, decode := range decoders {
+		if code, ok := decode(frame, tolerance); ok && code.repeat == false {
+			return code.protocol, code.device, code.scancode
+		} else if ok {
+			break
+		}
+	}
+	return IR_PROTOCOL_NONE, 0, 0
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *decoder) receive() {
+	for evt := range this.events {
+		if lirc_event, ok := evt.(gopi.LIRCEvent); ok {
+			this.feed(lirc_event.Type(), lirc_event.Value())
+		}
+	}
+	close(this.done)
+}
+
+// feed adds a pulse or space to the frame, and decodes the frame when
+// a long space or timeout is received
+func (this *decoder) feed(t gopi.LIRCType, value uint32) {
+	switch t {
+	case gopi.LIRC_TYPE_PULSE:
+		if len(this.frame)%2 == 0 {
+			this.frame = append(this.frame, value)
+		} else {
+			this.frame[len(this.frame)-1] += value
+		}
+	case gopi.LIRC_TYPE_SPACE:
+		if len(this.frame) == 0 {
+			// Ignore spaces before the first pulse
+		} else if value >= IR_FRAME_GAP {
+			this.end()
+		} else if len(this.frame)%2 == 1 {
+			this.frame = append(this.frame, value)
+		} else {
+			this.frame[len(this.frame)-1] += value
+		}
+	case gopi.LIRC_TYPE_TIMEOUT:
+		this.end()
+	default:
+		return
+	}
+	this.clock += uint64(value)
+}
+
+// end decodes the frame and emits a key event
+func (this *decoder) end() {
+	frame := this.frame
+	this.frame = this.frame[:0]
+	if len(frame) == 0 {
+		return
+	}
+	for _, remote := range this.remotes {
+		if code, ok := remote.decode(frame); ok {
+			this.decoded(code)
+			return
+		}
+	}
+	for _, decode := range decoders {
+		if code, ok := decode(frame, this.tolerance); ok {
+			this.decoded(code)
+			return
+		}
+	}
+	this.log.Debug2("<hw.ir.Decoder>Unknown frame: %v", frame)
+}
+
+func (this *decoder) decoded(code code) {
+	if code.remote == nil && this.protocols[code.protocol] == false {
+		return
+	}
+
+	// Determine if the code is a repeat
+	within := this.last != nil && this.clock-this.last_at <= IR_REPEAT_WINDOW
+	repeat := false
+	if code.repeat {
+		// Repeat frames repeat the last code of the remote, or the last
+		// NEC code
+		if within == false || this.last.remote != code.remote {
+			return
+		} else if code.remote == nil && this.last.protocol != IR_PROTOCOL_NEC && this.last.protocol != IR_PROTOCOL_NEC_EXT {
+			return
+		}
+		code, repeat = *this.last, true
+	} else if within && code == *this.last {
+		repeat = true
+	}
+	this.last, this.last_at = &code, this.clock
+
+	// Emit the event
+	this.Emit(&key_event{driver: this, code: code, repeat: repeat, timestamp: time.Now()})
+}
@@ -0,0 +1,346 @@
+package ir_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	ir "github.com/djthorpe/gopi-hw/sys/ir"
+	"github.com/djthorpe/gopi/util/event"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// RECORDED PULSE TRAINS
+
+const (
+	// NEC address 0x00 command 0x45, then two repeat frames
+	TRAIN_NEC = `
+		space 16777215, pulse 9021, space 4399, pulse 590, space 543, pulse 546,
+		space 469, pulse 645, space 528, pulse 552, space 506, pulse 614,
+		space 467, pulse 656, space 524, pulse 567, space 464, pulse 551,
+		space 515, pulse 593, space 1598, pulse 570, space 1601, pulse 610,
+		space 1644, pulse 547, space 1695, pulse 612, space 1605, pulse 568,
+		space 1670, pulse 620, space 1664, pulse 547, space 1663, pulse 614,
+		space 1640, pulse 546, space 488, pulse 545, space 1661, pulse 649,
+		space 477, pulse 577, space 513, pulse 558, space 529, pulse 555,
+		space 1663, pulse 579, space 531, pulse 644, space 547, pulse 563,
+		space 1603, pulse 614, space 533, pulse 621, space 1614, pulse 587,
+		space 1602, pulse 610, space 1681, pulse 548, space 532, pulse 547,
+		space 1669, pulse 566, space 39708, pulse 9027, space 2208, pulse 594,
+		space 96295, pulse 8980, space 2199, pulse 614,
+	`
+
+	// Extended NEC address 0x1234 command 0x0B
+	TRAIN_NEC_EXT = `
+		pulse 9059, space 4465, pulse 594, space 545, pulse 601, space 588,
+		pulse 620, space 1737, pulse 594, space 583, pulse 618, space 1697,
+		pulse 503, space 1737, pulse 559, space 599, pulse 620, space 531,
+		pulse 583, space 506, pulse 615, space 1650, pulse 514, space 547,
+		pulse 560, space 611, pulse 531, space 1678, pulse 569, space 513,
+		pulse 573, space 531, pulse 501, space 593, pulse 527, space 1682,
+		pulse 535, space 1653, pulse 617, space 611, pulse 598, space 1679,
+		pulse 520, space 597, pulse 602, space 509, pulse 517, space 579,
+		pulse 579, space 556, pulse 516, space 516, pulse 500, space 611,
+		pulse 500, space 1656, pulse 599, space 527, pulse 521, space 1741,
+		pulse 521, space 1667, pulse 540, space 1655, pulse 569, space 1742,
+		pulse 586,
+	`
+
+	// RC5 address 0x05 command 0x35 twice with the same toggle bit,
+	// then RC5X command 0x45
+	TRAIN_RC5 = `
+		pulse 886, space 939, pulse 900, space 938, pulse 1836, space 928,
+		pulse 888, space 1775, pulse 1783, space 1827, pulse 904, space 853,
+		pulse 852, space 931, pulse 1783, space 1778, pulse 1798, space 1796,
+		pulse 930, space 88690, pulse 841, space 886, pulse 867, space 847,
+		pulse 1729, space 897, pulse 932, space 1831, pulse 1806, space 1799,
+		pulse 834, space 905, pulse 879, space 886, pulse 1801, space 1812,
+		pulse 1796, space 1801, pulse 849, space 89138, pulse 1719, space 935,
+		pulse 896, space 837, pulse 836, space 833, pulse 853, space 1830,
+		pulse 1748, space 1794, pulse 1721, space 928, pulse 888, space 870,
+		pulse 885, space 1793, pulse 1825, space 1743, pulse 895,
+	`
+
+	// RC6 mode 0 address 0x04 command 0x0C twice with different toggle bits
+	TRAIN_RC6 = `
+		pulse 2661, space 938, pulse 455, space 937, pulse 502, space 483,
+		pulse 443, space 441, pulse 449, space 937, pulse 903, space 408,
+		pulse 407, space 486, pulse 449, space 444, pulse 464, space 462,
+		pulse 485, space 407, pulse 840, space 885, pulse 422, space 402,
+		pulse 395, space 452, pulse 487, space 497, pulse 472, space 465,
+		pulse 389, space 460, pulse 878, space 441, pulse 467, space 922,
+		pulse 462, space 467, pulse 404, space 83138, pulse 2605, space 934,
+		pulse 451, space 836, pulse 391, space 388, pulse 408, space 496,
+		pulse 1302, space 1348, pulse 387, space 483, pulse 443, space 425,
+		pulse 440, space 459, pulse 491, space 409, pulse 894, space 857,
+		pulse 465, space 421, pulse 447, space 384, pulse 468, space 394,
+		pulse 442, space 467, pulse 419, space 436, pulse 898, space 503,
+		pulse 491, space 838, pulse 474, space 416, pulse 424,
+	`
+
+	// Sony 12-bit device 0x01 command 0x15 twice, then 15-bit device
+	// 0x97 command 0x15, then 20-bit device 0x1A extended 0xE9 command 0x2F
+	TRAIN_SONY = `
+		pulse 2397, space 650, pulse 1211, space 649, pulse 658, space 639,
+		pulse 1199, space 597, pulse 605, space 649, pulse 1215, space 564,
+		pulse 563, space 642, pulse 605, space 600, pulse 1220, space 618,
+		pulse 641, space 563, pulse 552, space 597, pulse 578, space 558,
+		pulse 551, space 25251, pulse 2443, space 653, pulse 1228, space 621,
+		pulse 545, space 616, pulse 1190, space 597, pulse 623, space 634,
+		pulse 1218, space 623, pulse 560, space 619, pulse 541, space 646,
+		pulse 1207, space 548, pulse 547, space 544, pulse 564, space 652,
+		pulse 570, space 616, pulse 543, space 300175, pulse 2381, space 596,
+		pulse 1215, space 647, pulse 565, space 606, pulse 1169, space 621,
+		pulse 577, space 603, pulse 1140, space 624, pulse 550, space 598,
+		pulse 623, space 575, pulse 1192, space 610, pulse 1259, space 647,
+		pulse 1150, space 630, pulse 572, space 580, pulse 1237, space 569,
+		pulse 605, space 576, pulse 543, space 548, pulse 1212, space 299810,
+		pulse 2391, space 553, pulse 1248, space 577, pulse 1189, space 548,
+		pulse 1142, space 648, pulse 1227, space 540, pulse 567, space 566,
+		pulse 1258, space 656, pulse 546, space 600, pulse 588, space 630,
+		pulse 1190, space 593, pulse 549, space 612, pulse 1220, space 565,
+		pulse 1239, space 626, pulse 1174, space 583, pulse 551, space 579,
+		pulse 582, space 541, pulse 1192, space 637, pulse 658, space 555,
+		pulse 1157, space 571, pulse 1230, space 552, pulse 1141,
+	`
+
+	// Panasonic device 0x0080 command 0x3D
+	TRAIN_PANASONIC = `
+		pulse 3453, space 1778, pulse 443, space 481, pulse 490, space 1335,
+		pulse 431, space 429, pulse 437, space 481, pulse 447, space 396,
+		pulse 395, space 474, pulse 437, space 432, pulse 452, space 450,
+		pulse 473, space 395, pulse 384, space 429, pulse 410, space 390,
+		pulse 383, space 440, pulse 475, space 485, pulse 460, space 1317,
+		pulse 377, space 448, pulse 422, space 429, pulse 455, space 466,
+		pulse 450, space 455, pulse 392, space 451, pulse 373, space 478,
+		pulse 439, space 380, pulse 379, space 376, pulse 396, space 484,
+		pulse 402, space 1312, pulse 375, space 471, pulse 431, space 413,
+		pulse 428, space 447, pulse 479, space 397, pulse 438, space 401,
+		pulse 453, space 409, pulse 435, space 372, pulse 456, space 382,
+		pulse 430, space 1319, pulse 407, space 424, pulse 442, space 1355,
+		pulse 479, space 1246, pulse 462, space 1268, pulse 412, space 1333,
+		pulse 401, space 437, pulse 408, space 375, pulse 380, space 1308,
+		pulse 470, space 385, pulse 423, space 1249, pulse 480, space 1273,
+		pulse 421, space 1244, pulse 374, space 1344, pulse 459, space 372,
+		pulse 399, space 1262, pulse 490,
+	`
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE LIRC DEVICE
+
+type lirc struct {
+	event.Publisher
+}
+
+type lirc_event struct {
+	t     gopi.LIRCType
+	value uint32
+}
+
+func (this *lirc) Close() error {
+	this.Publisher.Close()
+	return nil
+}
+
+func (this *lirc_event) Name() string        { return "LIRCEvent" }
+func (this *lirc_event) Source() gopi.Driver { return nil }
+func (this *lirc_event) Type() gopi.LIRCType { return this.t }
+func (this *lirc_event) Value() uint32       { return this.value }
+
+// parseTrain returns the events of a pulse train in mode2 format,
+// followed by a timeout
+func parseTrain(t *testing.T, train string) []gopi.LIRCEvent {
+	t.Helper()
+	events := make([]gopi.LIRCEvent, 0)
+	for _, field := range strings.Split(train, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		parts := strings.Fields(field)
+		if len(parts) != 2 {
+			t.Fatal("Invalid field:", field)
+		}
+		value, err := strconv.ParseUint(parts[1], 10, 32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch parts[0] {
+		case "pulse":
+			events = append(events, &lirc_event{gopi.LIRC_TYPE_PULSE, uint32(value)})
+		case "space":
+			events = append(events, &lirc_event{gopi.LIRC_TYPE_SPACE, uint32(value)})
+		default:
+			t.Fatal("Invalid field:", field)
+		}
+	}
+	return append(events, &lirc_event{gopi.LIRC_TYPE_TIMEOUT, 100000})
+}
+
+// decode opens a decoder, sends a pulse train and returns the key events
+func decode(t *testing.T, config ir.Decoder, train string) []ir.IRKeyEvent {
+	t.Helper()
+	device := &lirc{}
+	config.LIRC = device
+	app, err := gopi.NewAppInstance(gopi.NewAppConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	driver, err := gopi.Open(config, app.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := driver.(ir.IRDecoderInterface)
+	keys := decoder.Subscribe()
+
+	// Send the events in the background, since emitting blocks until
+	// the key events are received
+	events := parseTrain(t, train)
+	go func() {
+		for _, evt := range events {
+			device.Emit(evt)
+		}
+	}()
+
+	// Receive key events until none arrive
+	result := make([]ir.IRKeyEvent, 0)
+FOR_LOOP:
+	for {
+		select {
+		case evt := <-keys:
+			result = append(result, evt.(ir.IRKeyEvent))
+		case <-time.After(200 * time.Millisecond):
+			break FOR_LOOP
+		}
+	}
+
+	// Close the decoder, draining key events until the channel is closed
+	go func() {
+		for range keys {
+		}
+	}()
+	if err := decoder.Close(); err != nil {
+		t.Error(err)
+	}
+	device.Close()
+	return result
+}
+
+type key struct {
+	protocol ir.Protocol
+	device   uint32
+	scancode uint32
+	repeat   bool
+}
+
+func expect(t *testing.T, keys []ir.IRKeyEvent, expected []key) {
+	t.Helper()
+	if len(keys) != len(expected) {
+		t.Fatal("Expected", len(expected), "keys, got", keys)
+	}
+	for i, evt := range keys {
+		if evt.Protocol() != expected[i].protocol || evt.Device() != expected[i].device || evt.Scancode() != expected[i].scancode || evt.Repeat() != expected[i].repeat {
+			t.Error("Unexpected key", i, evt)
+		} else if evt.Timestamp().IsZero() {
+			t.Error("Unexpected timestamp", evt)
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestDecoder_000(t *testing.T) {
+	app, err := gopi.NewAppInstance(gopi.NewAppConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// LIRC is required, and protocols and tolerance must be valid
+	if _, err := gopi.Open(ir.Decoder{}, app.Logger); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if _, err := gopi.Open(ir.Decoder{LIRC: &lirc{}, Protocols: []ir.Protocol{ir.IR_PROTOCOL_NONE}}, app.Logger); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if _, err := gopi.Open(ir.Decoder{LIRC: &lirc{}, Tolerance: 100}, app.Logger); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+
+	// All protocols are decoded by default
+	if driver, err := gopi.Open(ir.Decoder{LIRC: &lirc{}}, app.Logger); err != nil {
+		t.Error(err)
+	} else {
+		defer driver.Close()
+		if protocols := driver.(ir.IRDecoderInterface).Protocols(); len(protocols) != int(ir.IR_PROTOCOL_MAX) {
+			t.Error("Unexpected protocols", protocols)
+		}
+	}
+
+	// Protocol names
+	for protocol := ir.IR_PROTOCOL_NONE; protocol <= ir.IR_PROTOCOL_MAX; protocol++ {
+		if strings.HasPrefix(protocol.String(), "IR_PROTOCOL_") == false {
+			t.Error("Unexpected name", protocol)
+		}
+	}
+}
+
+func TestDecoder_001(t *testing.T) {
+	expect(t, decode(t, ir.Decoder{}, TRAIN_NEC), []key{
+		{ir.IR_PROTOCOL_NEC, 0x00, 0x45, false},
+		{ir.IR_PROTOCOL_NEC, 0x00, 0x45, true},
+		{ir.IR_PROTOCOL_NEC, 0x00, 0x45, true},
+	})
+}
+
+func TestDecoder_002(t *testing.T) {
+	expect(t, decode(t, ir.Decoder{}, TRAIN_NEC_EXT), []key{
+		{ir.IR_PROTOCOL_NEC_EXT, 0x1234, 0x0B, false},
+	})
+}
+
+func TestDecoder_003(t *testing.T) {
+	expect(t, decode(t, ir.Decoder{}, TRAIN_RC5), []key{
+		{ir.IR_PROTOCOL_RC5, 0x05, 0x35, false},
+		{ir.IR_PROTOCOL_RC5, 0x05, 0x35, true},
+		{ir.IR_PROTOCOL_RC5, 0x05, 0x45, false},
+	})
+}
+
+func TestDecoder_004(t *testing.T) {
+	// A new toggle bit is a new key press
+	expect(t, decode(t, ir.Decoder{}, TRAIN_RC6), []key{
+		{ir.IR_PROTOCOL_RC6, 0x04, 0x0C, false},
+		{ir.IR_PROTOCOL_RC6, 0x04, 0x0C, false},
+	})
+}
+
+func TestDecoder_005(t *testing.T) {
+	expect(t, decode(t, ir.Decoder{}, TRAIN_SONY), []key{
+		{ir.IR_PROTOCOL_SONY12, 0x01, 0x15, false},
+		{ir.IR_PROTOCOL_SONY12, 0x01, 0x15, true},
+		{ir.IR_PROTOCOL_SONY15, 0x97, 0x15, false},
+		{ir.IR_PROTOCOL_SONY20, 0xE91A, 0x2F, false},
+	})
+}
+
+func TestDecoder_006(t *testing.T) {
+	expect(t, decode(t, ir.Decoder{}, TRAIN_PANASONIC), []key{
+		{ir.IR_PROTOCOL_PANASONIC, 0x0080, 0x3D, false},
+	})
+}
+
+func TestDecoder_007(t *testing.T) {
+	// Only the enabled protocols are emitted
+	expect(t, decode(t, ir.Decoder{Protocols: []ir.Protocol{ir.IR_PROTOCOL_SONY15}}, TRAIN_SONY), []key{
+		{ir.IR_PROTOCOL_SONY15, 0x97, 0x15, false},
+	})
+	expect(t, decode(t, ir.Decoder{Protocols: []ir.Protocol{ir.IR_PROTOCOL_RC5}}, TRAIN_NEC), []key{})
+
+	// Timings outside the tolerance are not decoded
+	expect(t, decode(t, ir.Decoder{Tolerance: 5}, TRAIN_PANASONIC), []key{})
+}
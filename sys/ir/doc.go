@@ -0,0 +1,55 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+// This package decodes infrared remote control codes from the pulses
+// and spaces (mode2 events) emitted by a gopi.LIRC device. The NEC and
+// extended NEC, Philips RC5 and RC6 mode 0, Sony SIRC 12, 15 and 20-bit
+// and Panasonic protocols are decoded:
+//
+//    driver, err := gopi.Open(ir.Decoder{ LIRC: app.LIRC }, app.Logger)
+//    decoder := driver.(ir.IRDecoderInterface)
+//    for evt := range decoder.Subscribe() {
+//      key := evt.(ir.IRKeyEvent)
+//      fmt.Println(key.Protocol(), key.Device(), key.Scancode(), key.Repeat())
+//    }
+//
+// A frame ends with a long space or a timeout event. Timings are matched
+// within a tolerance, which is IR_TOLERANCE percent by default. A code
+// received again soon after the last one is emitted as a repeat, as are
+// NEC repeat frames.
+//
+// The Encoder sends codes with a LIRC device, setting the carrier
+// frequency and duty cycle of the protocol. The toggle bit of RC5 and
+// RC6 codes changes on each key press, and Samsung codes are also
+// supported:
+//
+//    driver, err := gopi.Open(ir.Encoder{ LIRC: app.LIRC }, app.Logger)
+//    encoder := driver.(ir.IREncoderInterface)
+//    err = encoder.Send(ir.IR_PROTOCOL_NEC, 0x00, 0x45, 2, 0)
+//
+// Remote definitions are read from lircd.conf files with ParseRemotes.
+// The decoder matches frames against the keys of each remote before
+// trying the protocols, and emits key events with the remote and key
+// names. The encoder sends keys by name with SendKey:
+//
+//    remotes, err := ir.ParseRemotes(fh)
+//    driver, err := gopi.Open(ir.Decoder{ LIRC: app.LIRC, Remotes: remotes }, app.Logger)
+//
+// On linux, the RC driver receives codes decoded by the kernel instead,
+// from the input event device of an rc device under /sys/class/rc. The
+// protocols are enabled by writing the protocols file of the device, and
+// keymaps in the ir-keytable format name the keys for each scancode.
+// Input events do not report the protocol, so a scancode has the
+// protocol of the keymap which names it, or of the only enabled protocol
+// which decodes it. Key events are the same as those of the decoder:
+//
+//    keymap, err := ir.ParseKeymap(fh)
+//    driver, err := gopi.Open(ir.RC{ Keymaps: []*ir.Keymap{ keymap }, FilePoll: filepoll }, app.Logger)
+//
+package ir
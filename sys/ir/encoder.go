@@ -0,0 +1,210 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package ir
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Encoder is the configuration for sending remote control codes with
+// a LIRC device
+type Encoder struct {
+	// LIRC device, which is usually app.LIRC
+	LIRC gopi.LIRC
+}
+
+type encoder struct {
+	log  gopi.Logger
+	lirc gopi.LIRC
+
+	// Toggle bit for each protocol and remote, which changes on each
+	// key press
+	toggle        map[Protocol]int
+	remote_toggle map[*Remote]bool
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open
+func (config Encoder) Open(logger gopi.Logger) (gopi.Driver, error) {
+	logger.Debug("<hw.ir.Encoder>Open{ }")
+
+	this := new(encoder)
+	this.log = logger
+	this.toggle = make(map[Protocol]int)
+	this.remote_toggle = make(map[*Remote]bool)
+
+	// LIRC device is required
+	if config.LIRC == nil {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.lirc = config.LIRC
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close
+func (this *encoder) Close() error {
+	this.log.Debug("<hw.ir.Encoder>Close{ }")
+
+	// Blank out
+	this.lirc = nil
+	this.toggle = nil
+	this.remote_toggle = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *encoder) String() string {
+	return fmt.Sprintf("<hw.ir.Encoder>{ protocols=%v }", this.Protocols())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES
+
+func (this *encoder) Protocols() []Protocol {
+	protocols := make([]Protocol, 0, len(transmitters))
+	for protocol := IR_PROTOCOL_NONE + 1; protocol <= IR_PROTOCOL_MAX; protocol++ {
+		if _, exists := transmitters[protocol]; exists {
+			protocols = append(protocols, protocol)
+		}
+	}
+	return protocols
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ENCODE AND SEND
+
+func (this *encoder) Encode(protocol Protocol, device, scancode uint32, repeat bool) ([]uint32, error) {
+	if transmitter, exists := transmitters[protocol]; exists == false {
+		return nil, gopi.ErrBadParameter
+	} else {
+		return transmitter.encode(code{protocol: protocol, device: device, scancode: scancode, toggle: this.toggle[protocol], repeat: repeat})
+	}
+}
+
+func (this *encoder) Send(protocol Protocol, device, scancode uint32, repeats uint, gap time.Duration) error {
+	this.log.Debug("<hw.ir.Encoder>Send{ protocol=%v device=0x%X scancode=0x%X repeats=%v gap=%v }", protocol, device, scancode, repeats, gap)
+
+	transmitter, exists := transmitters[protocol]
+	if exists == false || gap < 0 {
+		return gopi.ErrBadParameter
+	}
+
+	// A new key press changes the toggle bit
+	this.toggle[protocol] ^= 1
+
+	// Encode the frame and repeat frame
+	frame, err := this.Encode(protocol, device, scancode, false)
+	if err != nil {
+		return err
+	}
+	repeat, err := this.Encode(protocol, device, scancode, true)
+	if err != nil {
+		return err
+	}
+
+	// Send the frames
+	return this.send(transmitter.carrier, transmitter.duty, frame, repeat, repeats, func(values []uint32) time.Duration {
+		if gap == 0 {
+			return frameGap(transmitter.period, values)
+		} else {
+			return gap
+		}
+	})
+}
+
+func (this *encoder) SendKey(remote *Remote, key string, repeats uint, gap time.Duration) error {
+	this.log.Debug("<hw.ir.Encoder>SendKey{ remote=%v key=%v repeats=%v gap=%v }", remote, key, repeats, gap)
+
+	if remote == nil || gap < 0 {
+		return gopi.ErrBadParameter
+	}
+
+	// A new key press changes the toggle bits
+	toggle := this.remote_toggle[remote] == false
+
+	// Encode the frame and repeat frame
+	frame, err := remote.Encode(key, toggle, false)
+	if err != nil {
+		return err
+	}
+	repeat, err := remote.Encode(key, toggle, true)
+	if err != nil {
+		return err
+	}
+	this.remote_toggle[remote] = toggle
+
+	// Send at least the minimum number of repeats
+	if repeats < remote.MinRepeat {
+		repeats = remote.MinRepeat
+	}
+	return this.send(remote.Frequency, remote.DutyCycle, frame, repeat, repeats, func(values []uint32) time.Duration {
+		if gap == 0 {
+			return remote.gap(values)
+		} else {
+			return gap
+		}
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// send sets the carrier and duty cycle, then sends a frame followed by
+// repeat frames. The carrier and duty cycle are ignored when the device
+// cannot set them
+func (this *encoder) send(carrier, duty uint32, frame, repeat []uint32, repeats uint, gap func([]uint32) time.Duration) error {
+	if err := this.lirc.SetSendCarrierHz(carrier); err != nil && err != gopi.ErrNotImplemented {
+		return err
+	} else if err := this.lirc.SetSendDutyCycle(duty); err != nil && err != gopi.ErrNotImplemented {
+		return err
+	}
+	values := frame
+	for i := uint(0); i <= repeats; i++ {
+		if i > 0 {
+			time.Sleep(gap(values))
+			values = repeat
+		}
+		if err := this.lirc.PulseSend(values); err != nil {
+			return err
+		}
+	}
+
+	// Success
+	return nil
+}
+
+// frameGap returns the gap after a frame which keeps to the frame
+// period, and which is at least the gap which ends a frame
+func frameGap(period uint32, frame []uint32) time.Duration {
+	duration := uint32(0)
+	for _, value := range frame {
+		duration += value
+	}
+	if duration+IR_FRAME_GAP > period {
+		return time.Duration(IR_FRAME_GAP) * time.Microsecond
+	} else {
+		return time.Duration(period-duration) * time.Microsecond
+	}
+}
@@ -0,0 +1,165 @@
+package ir_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	ir "github.com/djthorpe/gopi-hw/sys/ir"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE LIRC SEND DEVICE
+
+type sender struct {
+	lirc
+	carrier, duty uint32
+	frames        [][]uint32
+}
+
+func (this *sender) RcvMode() gopi.LIRCMode                    { return gopi.LIRC_MODE_MODE2 }
+func (this *sender) SendMode() gopi.LIRCMode                   { return gopi.LIRC_MODE_PULSE }
+func (this *sender) SetRcvMode(gopi.LIRCMode) error            { return gopi.ErrNotImplemented }
+func (this *sender) SetSendMode(gopi.LIRCMode) error           { return gopi.ErrNotImplemented }
+func (this *sender) GetRcvResolution() (uint32, error)         { return 0, gopi.ErrNotImplemented }
+func (this *sender) SetRcvTimeout(uint32) error                { return gopi.ErrNotImplemented }
+func (this *sender) SetRcvTimeoutReports(bool) error           { return gopi.ErrNotImplemented }
+func (this *sender) SetRcvCarrierHz(uint32) error              { return gopi.ErrNotImplemented }
+func (this *sender) SetRcvCarrierRangeHz(uint32, uint32) error { return gopi.ErrNotImplemented }
+func (this *sender) SetSendCarrierHz(value uint32) error       { this.carrier = value; return nil }
+func (this *sender) SetSendDutyCycle(value uint32) error       { this.duty = value; return nil }
+func (this *sender) PulseSend(values []uint32) error {
+	if len(values)%2 == 0 {
+		return gopi.ErrBadParameter
+	}
+	this.frames = append(this.frames, values)
+	return nil
+}
+
+// trainFrom returns frames in mode2 format, separated by long spaces
+func trainFrom(frames ...[]uint32) string {
+	fields := make([]string, 0)
+	for i, frame := range frames {
+		if i > 0 {
+			fields = append(fields, "space 50000")
+		}
+		for j, value := range frame {
+			if j%2 == 0 {
+				fields = append(fields, fmt.Sprint("pulse ", value))
+			} else {
+				fields = append(fields, fmt.Sprint("space ", value))
+			}
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+func openEncoder(t *testing.T, device gopi.LIRC) ir.IREncoderInterface {
+	t.Helper()
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig()); err != nil {
+		t.Fatal(err)
+	} else if driver, err := gopi.Open(ir.Encoder{LIRC: device}, app.Logger); err != nil {
+		t.Fatal(err)
+	} else {
+		return driver.(ir.IREncoderInterface)
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestEncoder_000(t *testing.T) {
+	encoder := openEncoder(t, &sender{})
+	defer encoder.Close()
+
+	// Every protocol is encoded, and decodes to the same code
+	for _, k := range []key{
+		{ir.IR_PROTOCOL_NEC, 0x00, 0x45, false},
+		{ir.IR_PROTOCOL_NEC_EXT, 0x1234, 0x0B, false},
+		{ir.IR_PROTOCOL_RC5, 0x05, 0x35, false},
+		{ir.IR_PROTOCOL_RC5, 0x1F, 0x7F, false},
+		{ir.IR_PROTOCOL_RC6, 0x04, 0x0C, false},
+		{ir.IR_PROTOCOL_RC6, 0xFF, 0x00, false},
+		{ir.IR_PROTOCOL_SONY12, 0x01, 0x15, false},
+		{ir.IR_PROTOCOL_SONY15, 0x97, 0x15, false},
+		{ir.IR_PROTOCOL_SONY20, 0xE91A, 0x2F, false},
+		{ir.IR_PROTOCOL_PANASONIC, 0x0080, 0x3D, false},
+		{ir.IR_PROTOCOL_SAMSUNG, 0x07, 0x02, false},
+	} {
+		if frame, err := encoder.Encode(k.protocol, k.device, k.scancode, false); err != nil {
+			t.Error(k.protocol, err)
+		} else if len(frame)%2 == 0 {
+			t.Error(k.protocol, "Unexpected frame length", len(frame))
+		} else {
+			expect(t, decode(t, ir.Decoder{}, trainFrom(frame)), []key{k})
+		}
+	}
+	if len(encoder.Protocols()) != int(ir.IR_PROTOCOL_MAX) {
+		t.Error("Unexpected protocols", encoder.Protocols())
+	}
+
+	// Out of range values
+	for _, k := range []key{
+		{ir.IR_PROTOCOL_NONE, 0x00, 0x00, false},
+		{ir.IR_PROTOCOL_NEC, 0x100, 0x00, false},
+		{ir.IR_PROTOCOL_NEC_EXT, 0x00, 0x100, false},
+		{ir.IR_PROTOCOL_RC5, 0x20, 0x00, false},
+		{ir.IR_PROTOCOL_RC5, 0x00, 0x80, false},
+		{ir.IR_PROTOCOL_SONY12, 0x20, 0x00, false},
+		{ir.IR_PROTOCOL_SONY20, 0x20, 0x00, false},
+		{ir.IR_PROTOCOL_SAMSUNG, 0x00, 0x100, false},
+	} {
+		if _, err := encoder.Encode(k.protocol, k.device, k.scancode, false); err != gopi.ErrBadParameter {
+			t.Error(k, "Expected ErrBadParameter, got", err)
+		}
+	}
+}
+
+func TestEncoder_001(t *testing.T) {
+	device := &sender{}
+	encoder := openEncoder(t, device)
+	defer encoder.Close()
+
+	// NEC sends repeat frames
+	if err := encoder.Send(ir.IR_PROTOCOL_NEC, 0x00, 0x45, 2, time.Millisecond); err != nil {
+		t.Fatal(err)
+	} else if device.carrier != 38000 || device.duty != 33 {
+		t.Error("Unexpected carrier", device.carrier, device.duty)
+	} else if len(device.frames) != 3 || len(device.frames[1]) != 3 {
+		t.Error("Unexpected frames", device.frames)
+	} else {
+		expect(t, decode(t, ir.Decoder{}, trainFrom(device.frames...)), []key{
+			{ir.IR_PROTOCOL_NEC, 0x00, 0x45, false},
+			{ir.IR_PROTOCOL_NEC, 0x00, 0x45, true},
+			{ir.IR_PROTOCOL_NEC, 0x00, 0x45, true},
+		})
+	}
+
+	// RC5 changes the toggle bit on each key press, so the second key
+	// press is not a repeat
+	device.frames = nil
+	if err := encoder.Send(ir.IR_PROTOCOL_RC5, 0x05, 0x35, 1, 0); err != nil {
+		t.Fatal(err)
+	} else if err := encoder.Send(ir.IR_PROTOCOL_RC5, 0x05, 0x35, 0, 0); err != nil {
+		t.Fatal(err)
+	} else if device.carrier != 36000 {
+		t.Error("Unexpected carrier", device.carrier)
+	} else {
+		expect(t, decode(t, ir.Decoder{}, trainFrom(device.frames...)), []key{
+			{ir.IR_PROTOCOL_RC5, 0x05, 0x35, false},
+			{ir.IR_PROTOCOL_RC5, 0x05, 0x35, true},
+			{ir.IR_PROTOCOL_RC5, 0x05, 0x35, false},
+		})
+	}
+
+	// Bad parameters
+	if err := encoder.Send(ir.IR_PROTOCOL_NONE, 0x00, 0x00, 0, 0); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if err := encoder.Send(ir.IR_PROTOCOL_NEC, 0x00, 0x00, 0, -time.Second); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
@@ -0,0 +1,191 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package ir
+
+import (
+	"fmt"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Protocol is a remote control protocol
+type Protocol uint
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
+// IRDecoderInterface decodes pulses and spaces from a LIRC device and
+// emits an IRKeyEvent for each code received
+type IRDecoderInterface interface {
+	gopi.Driver
+	gopi.Publisher
+
+	// Return the protocols which are decoded
+	Protocols() []Protocol
+}
+
+// IREncoderInterface encodes codes as pulses and spaces, and sends them
+// with a LIRC device
+type IREncoderInterface interface {
+	gopi.Driver
+
+	// Return the protocols which can be encoded
+	Protocols() []Protocol
+
+	// Encode returns the pulses and spaces of a code, or of the frame
+	// which repeats it
+	Encode(protocol Protocol, device, scancode uint32, repeat bool) ([]uint32, error)
+
+	// Send a code followed by a number of repeat frames, with a gap
+	// between the end of one frame and the start of the next. When
+	// the gap is zero the frame period of the protocol is used
+	Send(protocol Protocol, device, scancode uint32, repeats uint, gap time.Duration) error
+
+	// SendKey sends a key of a remote definition followed by at least
+	// the minimum number of repeat frames of the remote. When the gap is
+	// zero the gap of the remote is used
+	SendKey(remote *Remote, key string, repeats uint, gap time.Duration) error
+}
+
+// IRKeyEvent is emitted when a code is received
+type IRKeyEvent interface {
+	gopi.Event
+
+	// Protocol of the code
+	Protocol() Protocol
+
+	// Device address and scancode
+	Device() uint32
+	Scancode() uint32
+
+	// Name of the remote definition and key, which are empty when the
+	// code was not decoded with a remote definition
+	Remote() string
+	Key() string
+
+	// Repeat is true when the key is held down
+	Repeat() bool
+
+	// Timestamp is the time the code was received
+	Timestamp() time.Time
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	IR_PROTOCOL_NONE      Protocol = iota
+	IR_PROTOCOL_NEC                // NEC with 8-bit device address
+	IR_PROTOCOL_NEC_EXT            // Extended NEC with 16-bit device address
+	IR_PROTOCOL_RC5                // Philips RC5 and RC5X
+	IR_PROTOCOL_RC6                // Philips RC6 mode 0
+	IR_PROTOCOL_SONY12             // Sony SIRC 12-bit
+	IR_PROTOCOL_SONY15             // Sony SIRC 15-bit
+	IR_PROTOCOL_SONY20             // Sony SIRC 20-bit
+	IR_PROTOCOL_PANASONIC          // Panasonic 48-bit
+	IR_PROTOCOL_SAMSUNG            // Samsung 32-bit
+	IR_PROTOCOL_MAX       = IR_PROTOCOL_SAMSUNG
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (p Protocol) String() string {
+	switch p {
+	case IR_PROTOCOL_NONE:
+		return "IR_PROTOCOL_NONE"
+	case IR_PROTOCOL_NEC:
+		return "IR_PROTOCOL_NEC"
+	case IR_PROTOCOL_NEC_EXT:
+		return "IR_PROTOCOL_NEC_EXT"
+	case IR_PROTOCOL_RC5:
+		return "IR_PROTOCOL_RC5"
+	case IR_PROTOCOL_RC6:
+		return "IR_PROTOCOL_RC6"
+	case IR_PROTOCOL_SONY12:
+		return "IR_PROTOCOL_SONY12"
+	case IR_PROTOCOL_SONY15:
+		return "IR_PROTOCOL_SONY15"
+	case IR_PROTOCOL_SONY20:
+		return "IR_PROTOCOL_SONY20"
+	case IR_PROTOCOL_PANASONIC:
+		return "IR_PROTOCOL_PANASONIC"
+	case IR_PROTOCOL_SAMSUNG:
+		return "IR_PROTOCOL_SAMSUNG"
+	default:
+		return "[?? Invalid Protocol value]"
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// KEY EVENTS
+
+type key_event struct {
+	driver    gopi.Driver
+	code      code
+	repeat    bool
+	timestamp time.Time
+}
+
+func (this *key_event) Name() string {
+	return "IRKeyEvent"
+}
+
+func (this *key_event) Source() gopi.Driver {
+	return this.driver
+}
+
+func (this *key_event) Protocol() Protocol {
+	return this.code.protocol
+}
+
+func (this *key_event) Device() uint32 {
+	return this.code.device
+}
+
+func (this *key_event) Scancode() uint32 {
+	return this.code.scancode
+}
+
+func (this *key_event) Remote() string {
+	if this.code.remote == nil {
+		return ""
+	}
+	return this.code.remote.Name
+}
+
+func (this *key_event) Key() string {
+	if this.code.key == nil {
+		return ""
+	}
+	return this.code.key.Name
+}
+
+func (this *key_event) Repeat() bool {
+	return this.repeat
+}
+
+func (this *key_event) Timestamp() time.Time {
+	return this.timestamp
+}
+
+func (this *key_event) String() string {
+	if this.code.remote != nil {
+		return fmt.Sprintf("<hw.ir.KeyEvent>{ remote=%v key=%v device=0x%X scancode=0x%X repeat=%v }", this.Remote(), this.Key(), this.code.device, this.code.scancode, this.repeat)
+	} else if this.code.key != nil {
+		return fmt.Sprintf("<hw.ir.KeyEvent>{ protocol=%v key=%v device=0x%X scancode=0x%X repeat=%v }", this.code.protocol, this.Key(), this.code.device, this.code.scancode, this.repeat)
+	}
+	return fmt.Sprintf("<hw.ir.KeyEvent>{ protocol=%v device=0x%X scancode=0x%X repeat=%v }", this.code.protocol, this.code.device, this.code.scancode, this.repeat)
+}
@@ -0,0 +1,180 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package ir
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Keymap maps the scancodes of kernel rc-core devices to key names
+type Keymap struct {
+	Name string
+
+	// Protocols of the keymap
+	Protocols []Protocol
+
+	// Key names for each scancode
+	Keys map[uint32]string
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBAL VARIABLES
+
+var (
+	// Protocols decoded by each kernel rc-core protocol
+	rc_protocols = map[string][]Protocol{
+		"nec":  {IR_PROTOCOL_NEC, IR_PROTOCOL_NEC_EXT},
+		"rc-5": {IR_PROTOCOL_RC5},
+		"rc-6": {IR_PROTOCOL_RC6},
+		"sony": {IR_PROTOCOL_SONY12, IR_PROTOCOL_SONY15, IR_PROTOCOL_SONY20},
+	}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PARSE
+
+// ParseKeymap reads a keymap in the ir-keytable format, where the first
+// line is the table name and protocol, and each line after is a
+// scancode and key name:
+//
+//   # table rc5_tv, type: RC5
+//   0x1e0c KEY_POWER
+//
+func ParseKeymap(r io.Reader) (*Keymap, error) {
+	keymap := &Keymap{Protocols: make([]Protocol, 0), Keys: make(map[uint32]string)}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(text, "#") {
+			if line == 1 {
+				if err := keymap.header(text); err != nil {
+					return nil, fmt.Errorf("Line %v: %v", line, err)
+				}
+			}
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Line %v: Expected scancode and key name", line)
+		} else if scancode, err := strconv.ParseUint(fields[0], 0, 32); err != nil {
+			return nil, fmt.Errorf("Line %v: Invalid scancode: %v", line, fields[0])
+		} else {
+			keymap.Keys[uint32(scancode)] = fields[1]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Success
+	return keymap, nil
+}
+
+// header parses the table name and protocol types
+func (this *Keymap) header(text string) error {
+	text = strings.TrimSpace(strings.TrimPrefix(text, "#"))
+	if strings.HasPrefix(text, "table") == false {
+		return nil
+	}
+	for _, field := range strings.Split(strings.TrimPrefix(text, "table"), ",") {
+		field = strings.TrimSpace(field)
+		if strings.HasPrefix(field, "type:") {
+			for _, name := range strings.Fields(strings.TrimPrefix(field, "type:")) {
+				if protocols, exists := rc_protocols[rcProtocolName(name)]; exists {
+					this.Protocols = append(this.Protocols, protocols...)
+				} else {
+					return fmt.Errorf("Unsupported type: %v", name)
+				}
+			}
+		} else if field != "" {
+			this.Name = field
+		}
+	}
+	return nil
+}
+
+// rcProtocolName returns the kernel name of a protocol, such as rc-5
+func rcProtocolName(name string) string {
+	name = strings.Replace(strings.ToLower(name), "_", "-", -1)
+	if name == "rc5" || name == "rc6" {
+		name = name[:2] + "-" + name[2:]
+	}
+	return name
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SCANCODES
+
+// decodes returns true if the keymap is for a kernel protocol, or has
+// no protocols
+func (this *Keymap) decodes(name string) bool {
+	if len(this.Protocols) == 0 {
+		return true
+	}
+	for _, protocol := range this.Protocols {
+		for _, other := range rc_protocols[name] {
+			if protocol == other {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rcScancode returns the protocol, device and scancode of a scancode
+// decoded by a kernel protocol, or false if the protocol cannot have
+// decoded the scancode
+func rcScancode(name string, scancode uint32) (Protocol, uint32, uint32, bool) {
+	switch name {
+	case "nec":
+		// NEC scancodes are the address and command, and extended NEC
+		// scancodes are the address, inverted address and command in the
+		// order they are sent, so the address bytes are swapped to match
+		// the decoder
+		if scancode <= 0xFFFF {
+			return IR_PROTOCOL_NEC, scancode >> 8, scancode & 0xFF, true
+		} else if scancode <= 0xFFFFFF {
+			return IR_PROTOCOL_NEC_EXT, (scancode>>16)&0xFF | ((scancode>>8)&0xFF)<<8, scancode & 0xFF, true
+		}
+	case "rc-5":
+		if scancode <= 0x1F7F {
+			return IR_PROTOCOL_RC5, scancode >> 8, scancode & 0xFF, true
+		}
+	case "rc-6":
+		// Mode 0 scancodes are the address and command
+		if scancode <= 0xFFFF {
+			return IR_PROTOCOL_RC6, scancode >> 8, scancode & 0xFF, true
+		}
+	case "sony":
+		// Sony scancodes are the device, extended bits and command,
+		// and 12 and 15-bit codes cannot always be told apart
+		device, extended, command := scancode>>16, (scancode>>8)&0xFF, scancode&0x7F
+		if extended != 0 && device <= 0x1F {
+			return IR_PROTOCOL_SONY20, device | extended<<8, command, true
+		} else if extended == 0 && device <= 0x1F {
+			return IR_PROTOCOL_SONY12, device, command, true
+		} else if extended == 0 && device <= 0xFF {
+			return IR_PROTOCOL_SONY15, device, command, true
+		}
+	}
+	return IR_PROTOCOL_NONE, 0, scancode, false
+}
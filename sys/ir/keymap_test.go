@@ -0,0 +1,56 @@
+package ir_test
+
+import (
+	"strings"
+	"testing"
+
+	// Frameworks
+	ir "github.com/djthorpe/gopi-hw/sys/ir"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// KEYMAPS
+
+const (
+	KEYMAP_NEC = `# table car_mp3, type: NEC
+0x0045 KEY_CHANNELDOWN
+0x0046 KEY_CHANNEL
+
+# Extended address
+0x12340b KEY_POWER
+`
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestKeymap_000(t *testing.T) {
+	if keymap, err := ir.ParseKeymap(strings.NewReader(KEYMAP_NEC)); err != nil {
+		t.Fatal(err)
+	} else if keymap.Name != "car_mp3" {
+		t.Error("Unexpected name", keymap.Name)
+	} else if len(keymap.Protocols) != 2 || keymap.Protocols[0] != ir.IR_PROTOCOL_NEC || keymap.Protocols[1] != ir.IR_PROTOCOL_NEC_EXT {
+		t.Error("Unexpected protocols", keymap.Protocols)
+	} else if len(keymap.Keys) != 3 {
+		t.Error("Unexpected keys", keymap.Keys)
+	} else if keymap.Keys[0x45] != "KEY_CHANNELDOWN" || keymap.Keys[0x12340B] != "KEY_POWER" {
+		t.Error("Unexpected keys", keymap.Keys)
+	}
+}
+
+func TestKeymap_001(t *testing.T) {
+	for _, conf := range []string{
+		"# table tv, type: RC5 RC6\n0x1e0c KEY_POWER extra\n",
+		"# table tv, type: RC5\nKEY_POWER 0x1e0c\n",
+		"# table tv, type: PANASONIC\n",
+	} {
+		if _, err := ir.ParseKeymap(strings.NewReader(conf)); err == nil {
+			t.Error("Expected error parsing", strings.TrimSpace(conf))
+		}
+	}
+	if keymap, err := ir.ParseKeymap(strings.NewReader("# table tv, type: RC5 RC6\n")); err != nil {
+		t.Error(err)
+	} else if len(keymap.Protocols) != 2 || keymap.Protocols[0] != ir.IR_PROTOCOL_RC5 || keymap.Protocols[1] != ir.IR_PROTOCOL_RC6 {
+		t.Error("Unexpected protocols", keymap.Protocols)
+	}
+}
@@ -0,0 +1,531 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package ir
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// RemoteFlag are the flags of a remote definition
+type RemoteFlag uint
+
+// Remote is a remote definition from a lircd.conf file. Timings are in
+// microseconds, and a pulse and space of zero are not sent
+type Remote struct {
+	Name  string
+	Flags RemoteFlag
+
+	// Number of bits in each code, and the data before and after
+	Bits         uint
+	PreDataBits  uint
+	PreData      uint64
+	PostDataBits uint
+	PostData     uint64
+
+	// Timings of the header, bits, leading and trailing pulses, and
+	// repeat frames
+	Header [2]uint32
+	One    [2]uint32
+	Zero   [2]uint32
+	Plead  uint32
+	Ptrail uint32
+	Repeat [2]uint32
+
+	// Timing tolerance as a percentage and in microseconds
+	Eps  uint
+	Aeps uint32
+
+	// Gap after each frame, or the frame period when the remote has
+	// the CONST_LENGTH flag
+	Gap uint32
+
+	// Minimum number of repeat frames sent, bits which change on each key
+	// press, and double-length bits of RC6 codes
+	MinRepeat     uint
+	ToggleBitMask uint64
+	RC6Mask       uint64
+
+	// Carrier frequency in Hz and duty cycle as a percentage
+	Frequency uint32
+	DutyCycle uint32
+
+	// Codes, or raw codes when the remote has the RAW_CODES flag
+	Keys []*RemoteKey
+}
+
+// RemoteKey is a named code, or a raw pulse train of alternating pulse
+// and space durations when the remote has the RAW_CODES flag
+type RemoteKey struct {
+	Name string
+	Code uint64
+	Raw  []uint32
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	REMOTE_FLAG_RAW_CODES     RemoteFlag = (1 << iota) // Keys are raw pulse trains
+	REMOTE_FLAG_RC5                                    // Bi-phase, where a one is a space then a pulse
+	REMOTE_FLAG_RC6                                    // Bi-phase, where a one is a pulse then a space
+	REMOTE_FLAG_SPACE_ENC                              // Pulse distance, where bits differ in their space
+	REMOTE_FLAG_CONST_LENGTH                           // The gap is the frame period
+	REMOTE_FLAG_REVERSE                                // Bits are sent least significant bit first
+	REMOTE_FLAG_NO_HEAD_REP                            // Repeated codes are sent without a header
+	REMOTE_FLAG_REPEAT_HEADER                          // Repeat frames are sent with a header
+	REMOTE_FLAG_NONE          RemoteFlag = 0
+	REMOTE_FLAG_MAX                      = REMOTE_FLAG_REPEAT_HEADER
+)
+
+const (
+	// Default tolerance as a percentage and in microseconds
+	REMOTE_EPS  = 30
+	REMOTE_AEPS = 100
+
+	// Default carrier frequency in Hz and duty cycle as a percentage
+	REMOTE_FREQUENCY  = 38000
+	REMOTE_DUTY_CYCLE = 50
+)
+
+var (
+	remote_flag_names = map[string]RemoteFlag{
+		"RAW_CODES":     REMOTE_FLAG_RAW_CODES,
+		"RC5":           REMOTE_FLAG_RC5,
+		"SHIFT_ENC":     REMOTE_FLAG_RC5,
+		"RC6":           REMOTE_FLAG_RC6,
+		"SPACE_ENC":     REMOTE_FLAG_SPACE_ENC,
+		"CONST_LENGTH":  REMOTE_FLAG_CONST_LENGTH,
+		"REVERSE":       REMOTE_FLAG_REVERSE,
+		"NO_HEAD_REP":   REMOTE_FLAG_NO_HEAD_REP,
+		"REPEAT_HEADER": REMOTE_FLAG_REPEAT_HEADER,
+	}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PARSE
+
+// ParseRemotes reads remote definitions in lircd.conf format
+func ParseRemotes(r io.Reader) ([]*Remote, error) {
+	remotes := make([]*Remote, 0, 1)
+	scanner := bufio.NewScanner(r)
+	line := 0
+
+	var remote *Remote
+	var section string
+	var key *RemoteKey
+	for scanner.Scan() {
+		line++
+
+		// Remove comments and blank lines
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		keyword, args := strings.ToLower(fields[0]), fields[1:]
+
+		switch {
+		case keyword == "begin" && len(args) == 1 && args[0] == "remote":
+			if remote != nil {
+				return nil, fmt.Errorf("Line %v: Unexpected begin remote", line)
+			}
+			remote = &Remote{Eps: REMOTE_EPS, Aeps: REMOTE_AEPS, Frequency: REMOTE_FREQUENCY, DutyCycle: REMOTE_DUTY_CYCLE}
+		case keyword == "end" && len(args) == 1 && args[0] == "remote":
+			if remote == nil || section != "" {
+				return nil, fmt.Errorf("Line %v: Unexpected end remote", line)
+			} else if err := remote.validate(); err != nil {
+				return nil, fmt.Errorf("Line %v: %v", line, err)
+			}
+			remotes = append(remotes, remote)
+			remote = nil
+		case remote == nil:
+			return nil, fmt.Errorf("Line %v: Expected begin remote", line)
+		case keyword == "begin" && len(args) == 1 && (args[0] == "codes" || args[0] == "raw_codes"):
+			if section != "" {
+				return nil, fmt.Errorf("Line %v: Unexpected begin %v", line, args[0])
+			}
+			if section = args[0]; section == "raw_codes" {
+				remote.Flags |= REMOTE_FLAG_RAW_CODES
+			}
+		case keyword == "end" && len(args) == 1 && args[0] == section:
+			section, key = "", nil
+		case section == "codes":
+			if len(args) == 0 {
+				return nil, fmt.Errorf("Line %v: Missing code for %v", line, fields[0])
+			} else if code, err := strconv.ParseUint(args[0], 0, 64); err != nil {
+				return nil, fmt.Errorf("Line %v: Invalid code: %v", line, args[0])
+			} else {
+				remote.Keys = append(remote.Keys, &RemoteKey{Name: fields[0], Code: code})
+			}
+		case section == "raw_codes" && keyword == "name":
+			if len(args) != 1 {
+				return nil, fmt.Errorf("Line %v: Invalid name", line)
+			}
+			key = &RemoteKey{Name: args[0], Raw: make([]uint32, 0)}
+			remote.Keys = append(remote.Keys, key)
+		case section == "raw_codes":
+			if key == nil {
+				return nil, fmt.Errorf("Line %v: Raw code without a name", line)
+			}
+			for _, field := range fields {
+				if value, err := strconv.ParseUint(field, 10, 32); err != nil {
+					return nil, fmt.Errorf("Line %v: Invalid value: %v", line, field)
+				} else {
+					key.Raw = append(key.Raw, uint32(value))
+				}
+			}
+		default:
+			if err := remote.set(keyword, args); err != nil {
+				return nil, fmt.Errorf("Line %v: %v", line, err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	} else if remote != nil {
+		return nil, fmt.Errorf("Line %v: Missing end remote", line)
+	}
+
+	// Success
+	return remotes, nil
+}
+
+// set a parameter of the remote definition. Unknown parameters are ignored
+func (this *Remote) set(keyword string, args []string) error {
+	switch keyword {
+	case "name":
+		if len(args) != 1 {
+			return fmt.Errorf("Invalid name")
+		}
+		this.Name = args[0]
+	case "flags":
+		for _, name := range strings.Split(strings.Join(args, ""), "|") {
+			if flag, exists := remote_flag_names[strings.ToUpper(name)]; exists == false {
+				return fmt.Errorf("Invalid flag: %v", name)
+			} else {
+				this.Flags |= flag
+			}
+		}
+	case "bits", "pre_data_bits", "post_data_bits", "eps", "min_repeat":
+		if value, err := parseUint(args, 0); err != nil {
+			return err
+		} else {
+			switch keyword {
+			case "bits":
+				this.Bits = uint(value)
+			case "pre_data_bits":
+				this.PreDataBits = uint(value)
+			case "post_data_bits":
+				this.PostDataBits = uint(value)
+			case "eps":
+				this.Eps = uint(value)
+			case "min_repeat":
+				this.MinRepeat = uint(value)
+			}
+		}
+	case "pre_data", "post_data", "toggle_bit_mask", "rc6_mask":
+		if value, err := parseUint(args, 64); err != nil {
+			return err
+		} else {
+			switch keyword {
+			case "pre_data":
+				this.PreData = value
+			case "post_data":
+				this.PostData = value
+			case "toggle_bit_mask":
+				this.ToggleBitMask = value
+			case "rc6_mask":
+				this.RC6Mask = value
+			}
+		}
+	case "aeps", "plead", "ptrail", "gap", "frequency", "duty_cycle":
+		if value, err := parseUint(args, 32); err != nil {
+			return err
+		} else {
+			switch keyword {
+			case "aeps":
+				this.Aeps = uint32(value)
+			case "plead":
+				this.Plead = uint32(value)
+			case "ptrail":
+				this.Ptrail = uint32(value)
+			case "gap":
+				this.Gap = uint32(value)
+			case "frequency":
+				this.Frequency = uint32(value)
+			case "duty_cycle":
+				this.DutyCycle = uint32(value)
+			}
+		}
+	case "header", "one", "zero", "repeat":
+		if len(args) != 2 {
+			return fmt.Errorf("Expected pulse and space for %v", keyword)
+		}
+		var pair [2]uint32
+		for i := range pair {
+			if value, err := strconv.ParseUint(args[i], 10, 32); err != nil {
+				return fmt.Errorf("Invalid value: %v", args[i])
+			} else {
+				pair[i] = uint32(value)
+			}
+		}
+		switch keyword {
+		case "header":
+			this.Header = pair
+		case "one":
+			this.One = pair
+		case "zero":
+			this.Zero = pair
+		case "repeat":
+			this.Repeat = pair
+		}
+	}
+	return nil
+}
+
+// validate returns an error if the remote definition cannot be used
+func (this *Remote) validate() error {
+	if this.Name == "" {
+		return fmt.Errorf("Missing name")
+	} else if this.Eps >= 100 {
+		return fmt.Errorf("Invalid eps: %v", this.Eps)
+	} else if this.DutyCycle == 0 || this.DutyCycle >= 100 {
+		return fmt.Errorf("Invalid duty_cycle: %v", this.DutyCycle)
+	}
+	if this.Flags&REMOTE_FLAG_RAW_CODES != 0 {
+		for _, key := range this.Keys {
+			if len(key.Raw) == 0 || len(key.Raw)%2 == 0 {
+				return fmt.Errorf("%v: Raw codes must start and end with a pulse", key.Name)
+			}
+		}
+		return nil
+	}
+	if this.Bits == 0 || this.PreDataBits+this.Bits+this.PostDataBits > 64 {
+		return fmt.Errorf("Invalid bits: %v", this.Bits)
+	}
+	switch this.encoding() {
+	case REMOTE_FLAG_SPACE_ENC:
+		if this.One[0] == 0 || this.Zero[0] == 0 || this.One == this.Zero {
+			return fmt.Errorf("Invalid one and zero timings")
+		}
+	case REMOTE_FLAG_RC5, REMOTE_FLAG_RC6:
+		if this.One[0] == 0 || this.One[1] == 0 {
+			return fmt.Errorf("Invalid one timing")
+		}
+	default:
+		return fmt.Errorf("Unsupported flags: %v", this.Flags)
+	}
+	return nil
+}
+
+// parseUint returns a single unsigned integer argument
+func parseUint(args []string, bits int) (uint64, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("Expected a single value")
+	} else if value, err := strconv.ParseUint(args[0], 0, bits); err != nil {
+		return 0, fmt.Errorf("Invalid value: %v", args[0])
+	} else {
+		return value, nil
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// WRITE
+
+// WriteRemotes writes remote definitions in lircd.conf format
+func WriteRemotes(w io.Writer, remotes []*Remote) error {
+	for i, remote := range remotes {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if err := remote.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *Remote) write(w io.Writer) error {
+	if err := this.validate(); err != nil {
+		return err
+	}
+
+	// Parameters which are not zero
+	params := []struct {
+		name  string
+		value interface{}
+	}{
+		{"bits", this.Bits},
+		{"eps", this.Eps},
+		{"aeps", this.Aeps},
+		{"header", this.Header},
+		{"one", this.One},
+		{"zero", this.Zero},
+		{"plead", this.Plead},
+		{"ptrail", this.Ptrail},
+		{"repeat", this.Repeat},
+		{"pre_data_bits", this.PreDataBits},
+		{"pre_data", this.PreData},
+		{"post_data_bits", this.PostDataBits},
+		{"post_data", this.PostData},
+		{"gap", this.Gap},
+		{"min_repeat", this.MinRepeat},
+		{"toggle_bit_mask", this.ToggleBitMask},
+		{"rc6_mask", this.RC6Mask},
+		{"frequency", this.Frequency},
+		{"duty_cycle", this.DutyCycle},
+	}
+	fmt.Fprintf(w, "begin remote\n")
+	fmt.Fprintf(w, "  %-16s %v\n", "name", this.Name)
+	if flags := this.flags(); flags != "" {
+		fmt.Fprintf(w, "  %-16s %v\n", "flags", flags)
+	}
+	for _, param := range params {
+		switch value := param.value.(type) {
+		case uint:
+			if value != 0 {
+				fmt.Fprintf(w, "  %-16s %v\n", param.name, value)
+			}
+		case uint32:
+			if value != 0 {
+				fmt.Fprintf(w, "  %-16s %v\n", param.name, value)
+			}
+		case uint64:
+			if value != 0 {
+				fmt.Fprintf(w, "  %-16s 0x%X\n", param.name, value)
+			}
+		case [2]uint32:
+			if value[0] != 0 || value[1] != 0 {
+				fmt.Fprintf(w, "  %-16s %v %v\n", param.name, value[0], value[1])
+			}
+		}
+	}
+
+	// Keys
+	if this.Flags&REMOTE_FLAG_RAW_CODES != 0 {
+		fmt.Fprintf(w, "\n  begin raw_codes\n")
+		for _, key := range this.Keys {
+			fmt.Fprintf(w, "    name %v\n", key.Name)
+			for i := 0; i < len(key.Raw); i += 8 {
+				values := make([]string, 0, 8)
+				for _, value := range key.Raw[i:min(i+8, len(key.Raw))] {
+					values = append(values, fmt.Sprintf("%7v", value))
+				}
+				fmt.Fprintf(w, "    %v\n", strings.Join(values, " "))
+			}
+		}
+		fmt.Fprintf(w, "  end raw_codes\n")
+	} else {
+		digits := (this.Bits + 3) / 4
+		fmt.Fprintf(w, "\n  begin codes\n")
+		for _, key := range this.Keys {
+			fmt.Fprintf(w, "    %-24s 0x%0*X\n", key.Name, digits, key.Code)
+		}
+		fmt.Fprintf(w, "  end codes\n")
+	}
+	_, err := fmt.Fprintf(w, "end remote\n")
+	return err
+}
+
+// flags returns the flags in lircd.conf format
+func (this *Remote) flags() string {
+	flags := make([]string, 0)
+	for _, name := range []string{"RAW_CODES", "RC5", "RC6", "SPACE_ENC", "CONST_LENGTH", "REVERSE", "NO_HEAD_REP", "REPEAT_HEADER"} {
+		if this.Flags&remote_flag_names[name] != 0 {
+			flags = append(flags, name)
+		}
+	}
+	return strings.Join(flags, "|")
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES
+
+// Key returns a key by name, or nil if the key does not exist
+func (this *Remote) Key(name string) *RemoteKey {
+	for _, key := range this.Keys {
+		if key.Name == name {
+			return key
+		}
+	}
+	return nil
+}
+
+// encoding returns the flag for the bit encoding, which is SPACE_ENC
+// when no encoding is set
+func (this *Remote) encoding() RemoteFlag {
+	switch {
+	case this.Flags&REMOTE_FLAG_RC5 != 0 && this.Flags&REMOTE_FLAG_RC6 != 0:
+		return REMOTE_FLAG_NONE
+	case this.Flags&REMOTE_FLAG_RC5 != 0:
+		return REMOTE_FLAG_RC5
+	case this.Flags&REMOTE_FLAG_RC6 != 0:
+		return REMOTE_FLAG_RC6
+	default:
+		return REMOTE_FLAG_SPACE_ENC
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (f RemoteFlag) String() string {
+	if f == REMOTE_FLAG_NONE {
+		return "REMOTE_FLAG_NONE"
+	}
+	flags := ""
+	for flag := REMOTE_FLAG_RAW_CODES; flag != 0 && flag <= f; flag <<= 1 {
+		if f&flag == 0 {
+			continue
+		}
+		switch flag {
+		case REMOTE_FLAG_RAW_CODES:
+			flags += "REMOTE_FLAG_RAW_CODES|"
+		case REMOTE_FLAG_RC5:
+			flags += "REMOTE_FLAG_RC5|"
+		case REMOTE_FLAG_RC6:
+			flags += "REMOTE_FLAG_RC6|"
+		case REMOTE_FLAG_SPACE_ENC:
+			flags += "REMOTE_FLAG_SPACE_ENC|"
+		case REMOTE_FLAG_CONST_LENGTH:
+			flags += "REMOTE_FLAG_CONST_LENGTH|"
+		case REMOTE_FLAG_REVERSE:
+			flags += "REMOTE_FLAG_REVERSE|"
+		case REMOTE_FLAG_NO_HEAD_REP:
+			flags += "REMOTE_FLAG_NO_HEAD_REP|"
+		case REMOTE_FLAG_REPEAT_HEADER:
+			flags += "REMOTE_FLAG_REPEAT_HEADER|"
+		default:
+			flags += "[?? Invalid RemoteFlag value]|"
+		}
+	}
+	return strings.TrimSuffix(flags, "|")
+}
+
+func (this *Remote) String() string {
+	return fmt.Sprintf("<hw.ir.Remote>{ name=%v flags=%v bits=%v keys=%v }", this.Name, this.Flags, this.Bits, len(this.Keys))
+}
@@ -0,0 +1,303 @@
+package ir_test
+
+import (
+	"strings"
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	ir "github.com/djthorpe/gopi-hw/sys/ir"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// REMOTE DEFINITIONS
+
+const (
+	LIRCD_CONF = `
+# Remote definitions for testing
+begin remote
+  name  car_mp3
+  bits           16
+  flags SPACE_ENC|CONST_LENGTH
+  eps            30
+  aeps          100
+
+  header       9000  4500
+  one           560  1690
+  zero          560   560
+  ptrail        560
+  repeat       9000  2250
+  pre_data_bits   16
+  pre_data       0x00FF
+  gap          108000
+  toggle_bit_mask 0x0
+
+  begin codes
+      KEY_CHANNELDOWN          0xA25D    # Was: CH-
+      KEY_CHANNEL              0x629D
+  end codes
+end remote
+
+begin remote
+  name  philips_rc5
+  bits  13
+  flags RC5|CONST_LENGTH
+  one    889 889
+  zero   889 889
+  plead  889
+  gap    113792
+  min_repeat 1
+  toggle_bit_mask 0x800
+  frequency 36000
+
+  begin codes
+    KEY_VOLUMEUP   0x1175
+    KEY_VOLUMEDOWN 0x0145
+  end codes
+end remote
+
+begin remote
+  name  sony_raw
+  flags RAW_CODES
+  eps   30
+  aeps  100
+  gap   25000
+
+  begin raw_codes
+    name KEY_POWER
+      2400 600 1200 600 600 600 1200 600 600 600
+      1200 600 600 600 600 600 1200 600 600 600
+      600 600 600 600 600
+  end raw_codes
+end remote
+`
+)
+
+func parseRemotes(t *testing.T) []*ir.Remote {
+	t.Helper()
+	remotes, err := ir.ParseRemotes(strings.NewReader(LIRCD_CONF))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(remotes) != 3 {
+		t.Fatal("Unexpected remotes", remotes)
+	}
+	return remotes
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestRemote_000(t *testing.T) {
+	remotes := parseRemotes(t)
+	if remote := remotes[0]; remote.Name != "car_mp3" || remote.Flags != ir.REMOTE_FLAG_SPACE_ENC|ir.REMOTE_FLAG_CONST_LENGTH {
+		t.Error("Unexpected remote", remote)
+	} else if remote.Bits != 16 || remote.PreDataBits != 16 || remote.PreData != 0x00FF || remote.Header != [2]uint32{9000, 4500} || remote.Repeat != [2]uint32{9000, 2250} {
+		t.Error("Unexpected remote", remote)
+	} else if len(remote.Keys) != 2 || remote.Key("KEY_CHANNEL").Code != 0x629D || remote.Key("KEY_UNKNOWN") != nil {
+		t.Error("Unexpected keys", remote.Keys)
+	} else if remote.Frequency != ir.REMOTE_FREQUENCY || remote.DutyCycle != ir.REMOTE_DUTY_CYCLE {
+		t.Error("Unexpected carrier", remote)
+	}
+	if remote := remotes[1]; remote.Flags != ir.REMOTE_FLAG_RC5|ir.REMOTE_FLAG_CONST_LENGTH || remote.Plead != 889 || remote.ToggleBitMask != 0x800 || remote.MinRepeat != 1 || remote.Frequency != 36000 {
+		t.Error("Unexpected remote", remote)
+	} else if remote.Eps != ir.REMOTE_EPS || remote.Aeps != ir.REMOTE_AEPS {
+		t.Error("Unexpected tolerance", remote)
+	}
+	if remote := remotes[2]; remote.Flags != ir.REMOTE_FLAG_RAW_CODES || len(remote.Keys) != 1 || len(remote.Keys[0].Raw) != 25 {
+		t.Error("Unexpected remote", remote)
+	}
+
+	// Errors
+	for _, conf := range []string{
+		"begin remote\nname test\nbits 8\n",
+		"name test\n",
+		"begin remote\nname test\nbits 8\nflags SPACE_ENC|UNKNOWN\nend remote\n",
+		"begin remote\nname test\nbits 8\none 560 1690\nzero 560 560\nbegin codes\nKEY_1 0xZZ\nend codes\nend remote\n",
+		"begin remote\nname test\nbits 8\nend remote\n",
+		"begin remote\nname test\nbegin raw_codes\nname KEY_1\n100 200\nend raw_codes\nend remote\n",
+	} {
+		if _, err := ir.ParseRemotes(strings.NewReader(conf)); err == nil {
+			t.Error("Expected error for", conf)
+		}
+	}
+}
+
+func TestRemote_001(t *testing.T) {
+	encoder := openEncoder(t, &sender{})
+	defer encoder.Close()
+
+	// A remote definition encodes the same frames as the protocol
+	remote := parseRemotes(t)[0]
+	if frame, err := remote.Encode("KEY_CHANNELDOWN", false, false); err != nil {
+		t.Error(err)
+	} else if expected, err := encoder.Encode(ir.IR_PROTOCOL_NEC, 0x00, 0x45, false); err != nil {
+		t.Error(err)
+	} else if len(frame) != len(expected) {
+		t.Error("Unexpected frame", frame)
+	} else {
+		for i := range frame {
+			if frame[i] != expected[i] {
+				t.Error("Unexpected frame", frame)
+				break
+			}
+		}
+	}
+	if frame, err := remote.Encode("KEY_CHANNELDOWN", false, true); err != nil {
+		t.Error(err)
+	} else if len(frame) != 3 || frame[1] != 2250 {
+		t.Error("Unexpected repeat frame", frame)
+	} else if _, err := remote.Encode("KEY_UNKNOWN", false, false); err != gopi.ErrNotFound {
+		t.Error("Expected ErrNotFound, got", err)
+	}
+
+	// Bits are least significant bit first with the REVERSE flag
+	reversed := &ir.Remote{Flags: ir.REMOTE_FLAG_SPACE_ENC | ir.REMOTE_FLAG_REVERSE, Bits: 32, Header: remote.Header, One: remote.One, Zero: remote.Zero, Ptrail: remote.Ptrail, Keys: []*ir.RemoteKey{
+		{Name: "KEY_CHANNEL", Code: 0xB946FF00},
+	}}
+	if frame, err := reversed.Encode("KEY_CHANNEL", false, false); err != nil {
+		t.Error(err)
+	} else if expected, err := remote.Encode("KEY_CHANNEL", false, false); err != nil {
+		t.Error(err)
+	} else if trainFrom(frame) != trainFrom(expected) {
+		t.Error("Unexpected frame", frame)
+	}
+}
+
+func TestRemote_002(t *testing.T) {
+	remotes := parseRemotes(t)
+	config := ir.Decoder{Remotes: remotes}
+
+	// Named keys are decoded, including repeat frames
+	keys := decode(t, config, TRAIN_NEC)
+	expect(t, keys, []key{
+		{ir.IR_PROTOCOL_NONE, 0x00FF, 0xA25D, false},
+		{ir.IR_PROTOCOL_NONE, 0x00FF, 0xA25D, true},
+		{ir.IR_PROTOCOL_NONE, 0x00FF, 0xA25D, true},
+	})
+	for _, evt := range keys {
+		if evt.Remote() != "car_mp3" || evt.Key() != "KEY_CHANNELDOWN" {
+			t.Error("Unexpected key", evt)
+		}
+	}
+
+	// Codes with the toggle bit set
+	keys = decode(t, config, TRAIN_RC5)
+	expect(t, keys, []key{
+		{ir.IR_PROTOCOL_NONE, 0, 0x1175, false},
+		{ir.IR_PROTOCOL_NONE, 0, 0x1175, true},
+		{ir.IR_PROTOCOL_NONE, 0, 0x0145, false},
+	})
+	if len(keys) == 3 && (keys[0].Key() != "KEY_VOLUMEUP" || keys[2].Key() != "KEY_VOLUMEDOWN") {
+		t.Error("Unexpected keys", keys)
+	}
+
+	// Raw codes, and frames which do not match are decoded by protocol
+	keys = decode(t, config, TRAIN_SONY)
+	expect(t, keys, []key{
+		{ir.IR_PROTOCOL_NONE, 0, 0, false},
+		{ir.IR_PROTOCOL_NONE, 0, 0, true},
+		{ir.IR_PROTOCOL_SONY15, 0x97, 0x15, false},
+		{ir.IR_PROTOCOL_SONY20, 0xE91A, 0x2F, false},
+	})
+	if len(keys) == 4 && (keys[0].Remote() != "sony_raw" || keys[0].Key() != "KEY_POWER" || keys[2].Remote() != "" || keys[2].Key() != "") {
+		t.Error("Unexpected keys", keys)
+	}
+}
+
+func TestRemote_003(t *testing.T) {
+	device := &sender{}
+	encoder := openEncoder(t, device)
+	defer encoder.Close()
+	remote := parseRemotes(t)[1]
+
+	// Keys are sent with the minimum number of repeats, and the toggle
+	// bit changes on each key press
+	if err := encoder.SendKey(remote, "KEY_VOLUMEUP", 0, 0); err != nil {
+		t.Fatal(err)
+	} else if err := encoder.SendKey(remote, "KEY_VOLUMEUP", 0, 0); err != nil {
+		t.Fatal(err)
+	} else if device.carrier != 36000 || device.duty != ir.REMOTE_DUTY_CYCLE {
+		t.Error("Unexpected carrier", device.carrier, device.duty)
+	} else if len(device.frames) != 4 {
+		t.Error("Unexpected frames", device.frames)
+	} else {
+		expect(t, decode(t, ir.Decoder{Remotes: []*ir.Remote{remote}}, trainFrom(device.frames...)), []key{
+			{ir.IR_PROTOCOL_NONE, 0, 0x1175, false},
+			{ir.IR_PROTOCOL_NONE, 0, 0x1175, true},
+			{ir.IR_PROTOCOL_NONE, 0, 0x1175, false},
+			{ir.IR_PROTOCOL_NONE, 0, 0x1175, true},
+		})
+	}
+
+	// Unknown keys
+	if err := encoder.SendKey(remote, "KEY_UNKNOWN", 0, 0); err != gopi.ErrNotFound {
+		t.Error("Expected ErrNotFound, got", err)
+	} else if err := encoder.SendKey(nil, "KEY_VOLUMEUP", 0, 0); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func TestRemote_004(t *testing.T) {
+	encoder := openEncoder(t, &sender{})
+	defer encoder.Close()
+
+	// Remote definitions for protocols encode the same frames
+	for _, k := range []key{
+		{ir.IR_PROTOCOL_NEC, 0x00, 0x45, false},
+		{ir.IR_PROTOCOL_NEC_EXT, 0x1234, 0x0B, false},
+		{ir.IR_PROTOCOL_RC5, 0x05, 0x35, false},
+		{ir.IR_PROTOCOL_RC5, 0x1F, 0x7F, false},
+		{ir.IR_PROTOCOL_RC6, 0x04, 0x0C, false},
+		{ir.IR_PROTOCOL_SONY12, 0x01, 0x15, false},
+		{ir.IR_PROTOCOL_SONY15, 0x97, 0x15, false},
+		{ir.IR_PROTOCOL_SONY20, 0xE91A, 0x2F, false},
+		{ir.IR_PROTOCOL_PANASONIC, 0x0080, 0x3D, false},
+		{ir.IR_PROTOCOL_SAMSUNG, 0x07, 0x02, false},
+	} {
+		remote, err := ir.NewRemote("test", k.protocol)
+		if err != nil {
+			t.Error(k.protocol, err)
+			continue
+		}
+		if code, err := ir.RemoteCode(k.protocol, k.device, k.scancode); err != nil {
+			t.Error(k.protocol, err)
+		} else {
+			remote.Keys = append(remote.Keys, &ir.RemoteKey{Name: "KEY_TEST", Code: code})
+		}
+		if frame, err := remote.Encode("KEY_TEST", false, false); err != nil {
+			t.Error(k.protocol, err)
+		} else if expected, err := encoder.Encode(k.protocol, k.device, k.scancode, false); err != nil {
+			t.Error(k.protocol, err)
+		} else if trainFrom(frame) != trainFrom(expected) {
+			t.Error(k.protocol, "Unexpected frame", frame, expected)
+		} else if protocol, device, scancode := ir.Decode(frame, ir.IR_TOLERANCE); protocol != k.protocol || device != k.device || scancode != k.scancode {
+			t.Error(k.protocol, "Unexpected decode", protocol, device, scancode)
+		}
+	}
+	if _, err := ir.NewRemote("test", ir.IR_PROTOCOL_NONE); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if _, err := ir.RemoteCode(ir.IR_PROTOCOL_NEC, 0x100, 0x00); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func TestRemote_005(t *testing.T) {
+	// Remote definitions which are written can be read back
+	remotes := parseRemotes(t)
+	buf := new(strings.Builder)
+	if err := ir.WriteRemotes(buf, remotes); err != nil {
+		t.Fatal(err)
+	}
+	if remotes2, err := ir.ParseRemotes(strings.NewReader(buf.String())); err != nil {
+		t.Fatal(err, buf.String())
+	} else if len(remotes2) != len(remotes) {
+		t.Fatal("Unexpected remotes", remotes2)
+	} else {
+		buf2 := new(strings.Builder)
+		if err := ir.WriteRemotes(buf2, remotes2); err != nil {
+			t.Error(err)
+		} else if buf.String() != buf2.String() {
+			t.Error("Unexpected remotes", buf2.String())
+		}
+	}
+}
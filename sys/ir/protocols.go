@@ -0,0 +1,520 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package ir
+
+import (
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// code is a decoded frame. Frames without a toggle bit have a toggle
+// value of -1, and repeat frames have no device or scancode. Codes
+// decoded with a remote definition have no protocol
+type code struct {
+	protocol Protocol
+	device   uint32
+	scancode uint32
+	toggle   int
+	repeat   bool
+	remote   *Remote
+	key      *RemoteKey
+}
+
+// encodeFunc encodes a code as alternating pulse and space durations in
+// microseconds, which starts and ends with a pulse
+type encodeFunc func(code code) ([]uint32, error)
+
+// transmitter is the carrier frequency in Hz, duty cycle percentage,
+// frame period in microseconds and encoder for a protocol
+type transmitter struct {
+	carrier uint32
+	duty    uint32
+	period  uint32
+	encode  encodeFunc
+}
+
+// decodeFunc decodes a frame of alternating pulse and space durations
+// in microseconds, which starts and ends with a pulse
+type decodeFunc func(frame []uint32, tolerance uint) (code, bool)
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+// Timings in microseconds
+const (
+	NEC_HEADER_PULSE  = 9000
+	NEC_HEADER_SPACE  = 4500
+	NEC_REPEAT_SPACE  = 2250
+	NEC_BIT_PULSE     = 560
+	NEC_ZERO_SPACE    = 560
+	NEC_ONE_SPACE     = 1690
+	RC5_UNIT          = 889
+	RC6_UNIT          = 444
+	RC6_HEADER_PULSE  = 6 * RC6_UNIT
+	RC6_HEADER_SPACE  = 2 * RC6_UNIT
+	SONY_HEADER_PULSE = 2400
+	SONY_ZERO_PULSE   = 600
+	SONY_ONE_PULSE    = 1200
+	SONY_SPACE        = 600
+	PANASONIC_UNIT    = 432
+	PANASONIC_HEADER  = 8 * PANASONIC_UNIT
+	PANASONIC_SPACE   = 4 * PANASONIC_UNIT
+	PANASONIC_VENDOR  = 0x2002
+	SAMSUNG_HEADER    = 4500
+)
+
+var (
+	// Decoders in the order they are tried, where each decoder can
+	// return codes for several protocols
+	decoders = []decodeFunc{
+		decodeNEC, decodeRC5, decodeRC6, decodeSony, decodePanasonic, decodeSamsung,
+	}
+
+	// Transmitters for each protocol which can be encoded
+	transmitters = map[Protocol]transmitter{
+		IR_PROTOCOL_NEC:       {38000, 33, 108000, encodeNEC},
+		IR_PROTOCOL_NEC_EXT:   {38000, 33, 108000, encodeNEC},
+		IR_PROTOCOL_RC5:       {36000, 25, 113778, encodeRC5},
+		IR_PROTOCOL_RC6:       {36000, 25, 106667, encodeRC6},
+		IR_PROTOCOL_SONY12:    {40000, 33, 45000, encodeSony},
+		IR_PROTOCOL_SONY15:    {40000, 33, 45000, encodeSony},
+		IR_PROTOCOL_SONY20:    {40000, 33, 45000, encodeSony},
+		IR_PROTOCOL_PANASONIC: {36700, 33, 163296, encodePanasonic},
+		IR_PROTOCOL_SAMSUNG:   {38000, 33, 108000, encodeSamsung},
+	}
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TIMING
+
+// match returns true if a duration is within a percentage of the
+// expected duration
+func match(value, expected uint32, tolerance uint) bool {
+	margin := expected * uint32(tolerance) / 100
+	return value+margin >= expected && value <= expected+margin
+}
+
+// units returns the number of whole units in a duration, up to a maximum,
+// or zero if the duration is not a whole number of units
+func units(value, unit uint32, max uint32, tolerance uint) uint32 {
+	n := (value + unit/2) / unit
+	if n == 0 || n > max || match(value, n*unit, tolerance) == false {
+		return 0
+	}
+	return n
+}
+
+// levels returns the level of each unit of a bi-phase frame, where true
+// is a pulse. The first duration is a pulse
+func levels(frame []uint32, unit, max uint32, tolerance uint) ([]bool, bool) {
+	levels := make([]bool, 0, len(frame)*2)
+	for i, value := range frame {
+		n := units(value, unit, max, tolerance)
+		if n == 0 {
+			return nil, false
+		}
+		for j := uint32(0); j < n; j++ {
+			levels = append(levels, i%2 == 0)
+		}
+	}
+	return levels, true
+}
+
+// pulseDistance appends bits least significant bit first, where each
+// bit is a pulse then a longer space for a one
+func pulseDistance(frame []uint32, value uint64, bits uint, pulse, zero, one uint32) []uint32 {
+	for i := uint(0); i < bits; i++ {
+		if value&(1<<i) != 0 {
+			frame = append(frame, pulse, one)
+		} else {
+			frame = append(frame, pulse, zero)
+		}
+	}
+	return frame
+}
+
+// durations returns the pulse and space durations of bi-phase levels,
+// without the spaces at the start and end
+func durations(levels []bool, unit uint32) []uint32 {
+	frame := make([]uint32, 0, len(levels))
+	for i, level := range levels {
+		if len(frame) == 0 && level == false {
+			continue
+		} else if i > 0 && level == levels[i-1] && len(frame) > 0 {
+			frame[len(frame)-1] += unit
+		} else {
+			frame = append(frame, unit)
+		}
+	}
+	if len(frame)%2 == 0 && len(frame) > 0 {
+		frame = frame[:len(frame)-1]
+	}
+	return frame
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// NEC
+
+// decodeNEC decodes 32 bits least significant bit first, which are the
+// address, inverted address, command and inverted command. When the
+// address is not inverted the frame is extended NEC with a 16-bit
+// address
+func decodeNEC(frame []uint32, tolerance uint) (code, bool) {
+	if len(frame) < 3 || match(frame[0], NEC_HEADER_PULSE, tolerance) == false {
+		return code{}, false
+	}
+	if len(frame) == 3 && match(frame[1], NEC_REPEAT_SPACE, tolerance) && match(frame[2], NEC_BIT_PULSE, tolerance) {
+		return code{protocol: IR_PROTOCOL_NEC, toggle: -1, repeat: true}, true
+	}
+	if len(frame) != 67 || match(frame[1], NEC_HEADER_SPACE, tolerance) == false {
+		return code{}, false
+	}
+	value := uint32(0)
+	for i := uint(0); i < 32; i++ {
+		pulse, space := frame[2+2*i], frame[3+2*i]
+		if match(pulse, NEC_BIT_PULSE, tolerance) == false {
+			return code{}, false
+		} else if match(space, NEC_ONE_SPACE, tolerance) {
+			value |= 1 << i
+		} else if match(space, NEC_ZERO_SPACE, tolerance) == false {
+			return code{}, false
+		}
+	}
+	if match(frame[66], NEC_BIT_PULSE, tolerance) == false {
+		return code{}, false
+	}
+	address, naddress, command, ncommand := value&0xFF, (value>>8)&0xFF, (value>>16)&0xFF, value>>24
+	if command^ncommand != 0xFF {
+		return code{}, false
+	} else if address^naddress == 0xFF {
+		return code{protocol: IR_PROTOCOL_NEC, device: address, scancode: command, toggle: -1}, true
+	} else {
+		return code{protocol: IR_PROTOCOL_NEC_EXT, device: address | naddress<<8, scancode: command, toggle: -1}, true
+	}
+}
+
+// encodeNEC encodes a frame, or a repeat frame which has no address
+// or command
+func encodeNEC(code code) ([]uint32, error) {
+	if code.repeat {
+		return []uint32{NEC_HEADER_PULSE, NEC_REPEAT_SPACE, NEC_BIT_PULSE}, nil
+	}
+	value := uint64(code.scancode) | uint64(code.scancode^0xFF)<<8
+	if code.scancode > 0xFF {
+		return nil, gopi.ErrBadParameter
+	} else if code.protocol == IR_PROTOCOL_NEC && code.device <= 0xFF {
+		value = uint64(code.device|(code.device^0xFF)<<8) | value<<16
+	} else if code.protocol == IR_PROTOCOL_NEC_EXT && code.device <= 0xFFFF {
+		value = uint64(code.device) | value<<16
+	} else {
+		return nil, gopi.ErrBadParameter
+	}
+	frame := []uint32{NEC_HEADER_PULSE, NEC_HEADER_SPACE}
+	frame = pulseDistance(frame, value, 32, NEC_BIT_PULSE, NEC_ZERO_SPACE, NEC_ONE_SPACE)
+	return append(frame, NEC_BIT_PULSE), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// RC5
+
+// decodeRC5 decodes 14 bi-phase bits most significant bit first, where
+// a one is a space then a pulse. The bits are a start bit, a field bit
+// which is the inverted seventh command bit, a toggle bit, five address
+// bits and six command bits
+func decodeRC5(frame []uint32, tolerance uint) (code, bool) {
+	levels, ok := levels(frame, RC5_UNIT, 2, tolerance)
+	if ok == false {
+		return code{}, false
+	}
+
+	// The first half of the start bit is a space, and the second half of
+	// the last bit is a space when it is a zero
+	levels = append([]bool{false}, levels...)
+	if len(levels)%2 == 1 {
+		levels = append(levels, false)
+	}
+	if len(levels) != 28 {
+		return code{}, false
+	}
+	value := uint32(0)
+	for i := 0; i < len(levels); i += 2 {
+		if levels[i] == levels[i+1] {
+			return code{}, false
+		} else if levels[i+1] {
+			value |= 1 << uint(13-i/2)
+		}
+	}
+	if value&0x2000 == 0 {
+		return code{}, false
+	}
+	command := value & 0x3F
+	if value&0x1000 == 0 {
+		command |= 0x40
+	}
+	return code{protocol: IR_PROTOCOL_RC5, device: (value >> 6) & 0x1F, scancode: command, toggle: int(value>>11) & 1}, true
+}
+
+// encodeRC5 encodes a frame, where commands from 0x40 are RC5X codes
+// with the field bit cleared
+func encodeRC5(code code) ([]uint32, error) {
+	if code.device > 0x1F || code.scancode > 0x7F {
+		return nil, gopi.ErrBadParameter
+	}
+	value := uint32(0x2000) | uint32(code.toggle&1)<<11 | code.device<<6 | code.scancode&0x3F
+	if code.scancode&0x40 == 0 {
+		value |= 0x1000
+	}
+	levels := make([]bool, 0, 28)
+	for i := 13; i >= 0; i-- {
+		one := value&(1<<uint(i)) != 0
+		levels = append(levels, one == false, one)
+	}
+	return durations(levels, RC5_UNIT), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// RC6
+
+// decodeRC6 decodes mode 0 frames, which have a header, then bi-phase
+// bits where a one is a pulse then a space. The bits are a start bit,
+// three mode bits, a toggle bit of double length, eight address bits and
+// eight command bits
+func decodeRC6(frame []uint32, tolerance uint) (code, bool) {
+	if len(frame) < 3 || match(frame[0], RC6_HEADER_PULSE, tolerance) == false || match(frame[1], RC6_HEADER_SPACE, tolerance) == false {
+		return code{}, false
+	}
+	levels, ok := levels(frame[2:], RC6_UNIT, 3, tolerance)
+	if ok == false {
+		return code{}, false
+	}
+	if len(levels) == 43 {
+		levels = append(levels, false)
+	}
+	if len(levels) != 44 {
+		return code{}, false
+	}
+
+	// Start bit and mode
+	bit := func(i int) (uint32, bool) {
+		if levels[i] == levels[i+1] {
+			return 0, false
+		} else if levels[i] {
+			return 1, true
+		} else {
+			return 0, true
+		}
+	}
+	if start, ok := bit(0); ok == false || start != 1 {
+		return code{}, false
+	}
+	for i := 2; i < 8; i += 2 {
+		if mode, ok := bit(i); ok == false || mode != 0 {
+			return code{}, false
+		}
+	}
+
+	// Toggle bit
+	toggle := 0
+	if levels[8] != levels[9] || levels[10] != levels[11] || levels[8] == levels[10] {
+		return code{}, false
+	} else if levels[8] {
+		toggle = 1
+	}
+
+	// Address and command
+	value := uint32(0)
+	for i := 12; i < 44; i += 2 {
+		if b, ok := bit(i); ok == false {
+			return code{}, false
+		} else {
+			value = value<<1 | b
+		}
+	}
+	return code{protocol: IR_PROTOCOL_RC6, device: value >> 8, scancode: value & 0xFF, toggle: toggle}, true
+}
+
+// encodeRC6 encodes a mode 0 frame
+func encodeRC6(code code) ([]uint32, error) {
+	if code.device > 0xFF || code.scancode > 0xFF {
+		return nil, gopi.ErrBadParameter
+	}
+	levels := []bool{true, true, true, true, true, true, false, false}
+	bit := func(one bool) {
+		levels = append(levels, one, one == false)
+	}
+
+	// Start bit, mode and toggle bit
+	bit(true)
+	bit(false)
+	bit(false)
+	bit(false)
+	toggle := code.toggle&1 == 1
+	levels = append(levels, toggle, toggle, toggle == false, toggle == false)
+
+	// Address and command
+	value := code.device<<8 | code.scancode
+	for i := 15; i >= 0; i-- {
+		bit(value&(1<<uint(i)) != 0)
+	}
+	return durations(levels, RC6_UNIT), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SONY
+
+// decodeSony decodes 12, 15 or 20 bits least significant bit first,
+// where a one is a longer pulse. The bits are seven command bits, then
+// five address bits, eight address bits, or five address bits and eight
+// extended bits. The extended bits are the upper byte of the device
+func decodeSony(frame []uint32, tolerance uint) (code, bool) {
+	if len(frame) < 3 || match(frame[0], SONY_HEADER_PULSE, tolerance) == false || match(frame[1], SONY_SPACE, tolerance) == false {
+		return code{}, false
+	}
+	bits := uint(len(frame)-1) / 2
+	if len(frame)%2 == 0 || (bits != 12 && bits != 15 && bits != 20) {
+		return code{}, false
+	}
+	value := uint32(0)
+	for i := uint(0); i < bits; i++ {
+		if pulse := frame[2+2*i]; match(pulse, SONY_ONE_PULSE, tolerance) {
+			value |= 1 << i
+		} else if match(pulse, SONY_ZERO_PULSE, tolerance) == false {
+			return code{}, false
+		}
+		if i < bits-1 && match(frame[3+2*i], SONY_SPACE, tolerance) == false {
+			return code{}, false
+		}
+	}
+	command := value & 0x7F
+	switch bits {
+	case 12:
+		return code{protocol: IR_PROTOCOL_SONY12, device: value >> 7, scancode: command, toggle: -1}, true
+	case 15:
+		return code{protocol: IR_PROTOCOL_SONY15, device: value >> 7, scancode: command, toggle: -1}, true
+	default:
+		return code{protocol: IR_PROTOCOL_SONY20, device: (value>>7)&0x1F | (value>>12)<<8, scancode: command, toggle: -1}, true
+	}
+}
+
+// encodeSony encodes a frame of 12, 15 or 20 bits depending on the
+// protocol
+func encodeSony(code code) ([]uint32, error) {
+	var value uint32
+	var bits uint
+	switch {
+	case code.scancode > 0x7F:
+		return nil, gopi.ErrBadParameter
+	case code.protocol == IR_PROTOCOL_SONY12 && code.device <= 0x1F:
+		value, bits = code.device<<7|code.scancode, 12
+	case code.protocol == IR_PROTOCOL_SONY15 && code.device <= 0xFF:
+		value, bits = code.device<<7|code.scancode, 15
+	case code.protocol == IR_PROTOCOL_SONY20 && code.device <= 0xFF1F && code.device&0xE0 == 0:
+		value, bits = (code.device>>8)<<12|(code.device&0x1F)<<7|code.scancode, 20
+	default:
+		return nil, gopi.ErrBadParameter
+	}
+	frame := []uint32{SONY_HEADER_PULSE}
+	for i := uint(0); i < bits; i++ {
+		if value&(1<<i) != 0 {
+			frame = append(frame, SONY_SPACE, SONY_ONE_PULSE)
+		} else {
+			frame = append(frame, SONY_SPACE, SONY_ZERO_PULSE)
+		}
+	}
+	return frame, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PANASONIC
+
+// decodePanasonic decodes 48 bits least significant bit first, which are
+// six bytes: the vendor identifier, two device bytes, the command and a
+// checksum which is the exclusive or of the device and command bytes
+func decodePanasonic(frame []uint32, tolerance uint) (code, bool) {
+	if len(frame) != 99 || match(frame[0], PANASONIC_HEADER, tolerance) == false || match(frame[1], PANASONIC_SPACE, tolerance) == false {
+		return code{}, false
+	}
+	data := make([]byte, 6)
+	for i := uint(0); i < 48; i++ {
+		pulse, space := frame[2+2*i], frame[3+2*i]
+		if match(pulse, PANASONIC_UNIT, tolerance) == false {
+			return code{}, false
+		} else if match(space, 3*PANASONIC_UNIT, tolerance) {
+			data[i/8] |= 1 << (i % 8)
+		} else if match(space, PANASONIC_UNIT, tolerance) == false {
+			return code{}, false
+		}
+	}
+	if match(frame[98], PANASONIC_UNIT, tolerance) == false {
+		return code{}, false
+	} else if uint32(data[0])|uint32(data[1])<<8 != PANASONIC_VENDOR {
+		return code{}, false
+	} else if data[5] != data[2]^data[3]^data[4] {
+		return code{}, false
+	}
+	return code{protocol: IR_PROTOCOL_PANASONIC, device: uint32(data[2]) | uint32(data[3])<<8, scancode: uint32(data[4]), toggle: -1}, true
+}
+
+// encodePanasonic encodes a frame with the vendor identifier and checksum
+func encodePanasonic(code code) ([]uint32, error) {
+	if code.device > 0xFFFF || code.scancode > 0xFF {
+		return nil, gopi.ErrBadParameter
+	}
+	d2, d3, d4 := uint64(code.device&0xFF), uint64(code.device>>8), uint64(code.scancode)
+	value := uint64(PANASONIC_VENDOR) | d2<<16 | d3<<24 | d4<<32 | (d2^d3^d4)<<40
+	frame := []uint32{PANASONIC_HEADER, PANASONIC_SPACE}
+	frame = pulseDistance(frame, value, 48, PANASONIC_UNIT, PANASONIC_UNIT, 3*PANASONIC_UNIT)
+	return append(frame, PANASONIC_UNIT), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SAMSUNG
+
+// decodeSamsung decodes 32 bits least significant bit first, which are
+// the address twice, the command and the inverted command. The bits are
+// the same as NEC but the header is shorter
+func decodeSamsung(frame []uint32, tolerance uint) (code, bool) {
+	if len(frame) != 67 || match(frame[0], SAMSUNG_HEADER, tolerance) == false || match(frame[1], SAMSUNG_HEADER, tolerance) == false {
+		return code{}, false
+	}
+	value := uint32(0)
+	for i := uint(0); i < 32; i++ {
+		pulse, space := frame[2+2*i], frame[3+2*i]
+		if match(pulse, NEC_BIT_PULSE, tolerance) == false {
+			return code{}, false
+		} else if match(space, NEC_ONE_SPACE, tolerance) {
+			value |= 1 << i
+		} else if match(space, NEC_ZERO_SPACE, tolerance) == false {
+			return code{}, false
+		}
+	}
+	if match(frame[66], NEC_BIT_PULSE, tolerance) == false {
+		return code{}, false
+	}
+	address, address2, command, ncommand := value&0xFF, (value>>8)&0xFF, (value>>16)&0xFF, value>>24
+	if address != address2 || command^ncommand != 0xFF {
+		return code{}, false
+	}
+	return code{protocol: IR_PROTOCOL_SAMSUNG, device: address, scancode: command, toggle: -1}, true
+}
+
+// encodeSamsung encodes a frame. Samsung remotes repeat the whole frame
+func encodeSamsung(code code) ([]uint32, error) {
+	if code.device > 0xFF || code.scancode > 0xFF {
+		return nil, gopi.ErrBadParameter
+	}
+	value := uint64(code.device) | uint64(code.device)<<8 | uint64(code.scancode)<<16 | uint64(code.scancode^0xFF)<<24
+	frame := []uint32{SAMSUNG_HEADER, SAMSUNG_HEADER}
+	frame = pulseDistance(frame, value, 32, NEC_BIT_PULSE, NEC_ZERO_SPACE, NEC_ONE_SPACE)
+	return append(frame, NEC_BIT_PULSE), nil
+}
@@ -0,0 +1,423 @@
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package ir
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi-hw/sys/filepoll"
+	"github.com/djthorpe/gopi/util/event"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// RC is the configuration for receiving codes decoded by a kernel
+// rc-core device, which are read from its input event device
+type RC struct {
+	// Name of the rc device, such as rc0, or the first device when empty
+	Device string
+
+	// Protocols to enable, or the protocols of the keymaps when empty.
+	// When neither are set the enabled protocols are not changed
+	Protocols []Protocol
+
+	// Keymaps which name the keys for each scancode
+	Keymaps []*Keymap
+
+	// Filepoller
+	FilePoll filepoll.FilePollInterface
+}
+
+type rc struct {
+	log      gopi.Logger
+	name     string
+	dev      *os.File
+	filepoll filepoll.FilePollInterface
+	lock     sync.Mutex
+
+	// Kernel protocols which are enabled, and the keymaps
+	enabled []string
+	keymaps []*Keymap
+
+	// Scancode of the current report, and the time and code of the
+	// last report
+	scan    *uint32
+	last_at time.Time
+	last    *code
+
+	// Publisher of key events
+	event.Publisher
+}
+
+// rc_input_event is the kernel struct input_event
+type rc_input_event struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Input event types and codes
+	RC_EV_SYN      = 0x00
+	RC_EV_KEY      = 0x01
+	RC_EV_MSC      = 0x04
+	RC_SYN_REPORT  = 0x00
+	RC_MSC_SCAN    = 0x04
+	RC_KEY_RELEASE = 0
+)
+
+var (
+	// Paths of the rc device classes and input devices
+	rc_class = "/sys/class/rc"
+	rc_input = "/dev/input"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open
+func (config RC) Open(logger gopi.Logger) (gopi.Driver, error) {
+	logger.Debug("<sys.hw.linux.RC.Open>{ device=%v protocols=%v keymaps=%v }", strconv.Quote(config.Device), config.Protocols, len(config.Keymaps))
+
+	this := new(rc)
+	this.log = logger
+
+	// File Poll module is required or else returns ErrBadParameter
+	if config.FilePoll == nil {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.filepoll = config.FilePoll
+	}
+
+	// Keymaps, which also provide the protocols when none are set
+	protocols := config.Protocols
+	for _, keymap := range config.Keymaps {
+		if keymap == nil {
+			return nil, gopi.ErrBadParameter
+		}
+		if len(config.Protocols) == 0 {
+			protocols = append(protocols, keymap.Protocols...)
+		}
+	}
+
+	// Find the rc device
+	if name, err := rcDevice(config.Device); err != nil {
+		return nil, err
+	} else {
+		this.name = name
+	}
+	this.keymaps = config.Keymaps
+
+	// Enable protocols when they are not already enabled. A name without
+	// a prefix replaces the enabled protocols, so all are disabled and
+	// then each is added
+	if enabled, err := rcEnabled(this.name); err != nil {
+		return nil, err
+	} else {
+		this.enabled = enabled
+	}
+	if len(protocols) > 0 {
+		if names, err := rcProtocolNames(protocols); err != nil {
+			return nil, err
+		} else if rcEqual(names, this.enabled) == false {
+			if err := ioutil.WriteFile(filepath.Join(rc_class, this.name, "protocols"), []byte("none +"+strings.Join(names, " +")+"\n"), 0); err != nil {
+				return nil, err
+			} else if enabled, err := rcEnabled(this.name); err != nil {
+				return nil, err
+			} else {
+				this.enabled = enabled
+			}
+		}
+	}
+
+	// Open the input device
+	if path, err := rcEventDevice(this.name); err != nil {
+		return nil, err
+	} else if dev, err := os.OpenFile(path, os.O_RDONLY, 0); err != nil {
+		return nil, err
+	} else {
+		this.dev = dev
+	}
+
+	// Start watching
+	if err := this.filepoll.Watch(this.dev, filepoll.FILEPOLL_MODE_READ, this.rcReceive); err != nil {
+		this.dev.Close()
+		return nil, err
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close
+func (this *rc) Close() error {
+	this.log.Debug("<sys.hw.linux.RC.Close>{ }")
+
+	// Unwatch device
+	if err := this.filepoll.Unwatch(this.dev); err != nil {
+		this.log.Warn("Unwatch: %v", err)
+	}
+
+	// Mutex
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	// Close subscriber channels
+	this.Publisher.Close()
+
+	// Close device
+	if err := this.dev.Close(); err != nil {
+		return err
+	} else {
+		this.dev = nil
+	}
+
+	// Blank out
+	this.filepoll = nil
+
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *rc) String() string {
+	return fmt.Sprintf("<sys.hw.linux.RC>{ device=%v protocols=%v keymaps=%v }", this.name, this.Protocols(), len(this.keymaps))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES
+
+func (this *rc) Protocols() []Protocol {
+	protocols := make([]Protocol, 0, len(this.enabled))
+	for _, name := range this.enabled {
+		protocols = append(protocols, rc_protocols[name]...)
+	}
+	sort.Slice(protocols, func(i, j int) bool { return protocols[i] < protocols[j] })
+	return protocols
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CALLBACK
+
+func (this *rc) rcReceive(dev *os.File, mode filepoll.FilePollMode) {
+	var evt rc_input_event
+	if err := binary.Read(dev, binary.LittleEndian, &evt); err == io.EOF {
+		return
+	} else if err != nil {
+		this.log.Error("rcReceive: %v", err)
+		return
+	}
+
+	this.lock.Lock()
+	defer this.lock.Unlock()
+
+	switch evt.Type {
+	case RC_EV_MSC:
+		if evt.Code == RC_MSC_SCAN {
+			scan := uint32(evt.Value)
+			this.scan = &scan
+		}
+	case RC_EV_KEY:
+		// A key release ends the repeats of the last code
+		if evt.Value == RC_KEY_RELEASE {
+			this.last = nil
+		}
+	case RC_EV_SYN:
+		if evt.Code == RC_SYN_REPORT && this.scan != nil {
+			this.decoded(*this.scan, time.Unix(int64(evt.Time.Sec), int64(evt.Time.Usec)*1000))
+			this.scan = nil
+		}
+	}
+}
+
+// decoded emits a key event for a scancode
+func (this *rc) decoded(scancode uint32, timestamp time.Time) {
+	code := this.code(scancode)
+
+	// Determine if the code is a repeat
+	within := this.last != nil && timestamp.Sub(this.last_at) <= IR_REPEAT_WINDOW*time.Microsecond
+	repeat := within && code.protocol == this.last.protocol && code.device == this.last.device && code.scancode == this.last.scancode
+	this.last, this.last_at = &code, timestamp
+
+	// Emit the event
+	this.Emit(&key_event{driver: this, code: code, repeat: repeat, timestamp: timestamp})
+}
+
+// code returns the code for a scancode. The input events do not report
+// the kernel protocol, so the keymaps of the enabled protocols are tried
+// first, then the scancode is decoded when only one enabled protocol can
+// have sent it
+func (this *rc) code(scancode uint32) code {
+	for _, keymap := range this.keymaps {
+		name, exists := keymap.Keys[scancode]
+		if exists == false {
+			continue
+		}
+		key := &RemoteKey{Name: name, Code: uint64(scancode)}
+		for _, enabled := range this.enabled {
+			if keymap.decodes(enabled) == false {
+				continue
+			} else if protocol, device, value, ok := rcScancode(enabled, scancode); ok {
+				return code{protocol: protocol, device: device, scancode: value, key: key}
+			}
+		}
+		if len(keymap.Protocols) == 0 {
+			code := this.decode(scancode)
+			code.key = key
+			return code
+		}
+	}
+	return this.decode(scancode)
+}
+
+// decode returns the code for a scancode when only one enabled protocol
+// decodes it, or else the scancode with IR_PROTOCOL_NONE
+func (this *rc) decode(scancode uint32) code {
+	decoded := make([]code, 0, 1)
+	for _, enabled := range this.enabled {
+		if protocol, device, value, ok := rcScancode(enabled, scancode); ok {
+			decoded = append(decoded, code{protocol: protocol, device: device, scancode: value})
+		}
+	}
+	if len(decoded) == 1 {
+		return decoded[0]
+	}
+	return code{protocol: IR_PROTOCOL_NONE, scancode: scancode}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// rcDevice returns the name of the rc device, or the first rc device
+// when the name is empty
+func rcDevice(name string) (string, error) {
+	if name != "" {
+		if _, err := os.Stat(filepath.Join(rc_class, name)); os.IsNotExist(err) {
+			return "", gopi.ErrNotFound
+		} else if err != nil {
+			return "", err
+		} else {
+			return name, nil
+		}
+	}
+	if paths, err := filepath.Glob(filepath.Join(rc_class, "rc*")); err != nil {
+		return "", err
+	} else if len(paths) == 0 {
+		return "", gopi.ErrNotFound
+	} else {
+		sort.Strings(paths)
+		return filepath.Base(paths[0]), nil
+	}
+}
+
+// rcEventDevice returns the path of the input event device for an rc device
+func rcEventDevice(name string) (string, error) {
+	if paths, err := filepath.Glob(filepath.Join(rc_class, name, "input*", "event*")); err != nil {
+		return "", err
+	} else if len(paths) == 0 {
+		return "", gopi.ErrNotFound
+	} else {
+		return filepath.Join(rc_input, filepath.Base(paths[0])), nil
+	}
+}
+
+// rcEnabled returns the kernel protocols which are enabled for an rc
+// device, which are in brackets in the protocols file
+func rcEnabled(name string) ([]string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(rc_class, name, "protocols"))
+	if err != nil {
+		return nil, err
+	}
+	enabled := make([]string, 0)
+	for _, field := range strings.Fields(string(data)) {
+		if strings.HasPrefix(field, "[") && strings.HasSuffix(field, "]") {
+			enabled = append(enabled, strings.Trim(field, "[]"))
+		}
+	}
+	return enabled, nil
+}
+
+// rcProtocolNames returns the kernel protocol names for protocols, or
+// ErrNotImplemented when the kernel does not decode a protocol
+func rcProtocolNames(protocols []Protocol) ([]string, error) {
+	names := make([]string, 0, len(protocols))
+	for _, protocol := range protocols {
+		found := false
+		for name, decoded := range rc_protocols {
+			for _, other := range decoded {
+				if other == protocol {
+					found = true
+				}
+			}
+			if found {
+				names = appendName(names, name)
+				break
+			}
+		}
+		if found == false {
+			return nil, gopi.ErrNotImplemented
+		}
+	}
+	return names, nil
+}
+
+// rcEqual returns true if the enabled protocols are the same as names,
+// ignoring protocols the driver does not decode such as lirc
+func rcEqual(names, enabled []string) bool {
+	count := 0
+	for _, name := range enabled {
+		if _, exists := rc_protocols[name]; exists == false {
+			continue
+		} else if containsName(names, name) == false {
+			return false
+		}
+		count++
+	}
+	return count == len(names)
+}
+
+// appendName appends a name which is not already in names
+func appendName(names []string, name string) []string {
+	if containsName(names, name) {
+		return names
+	}
+	return append(names, name)
+}
+
+// containsName returns true if name is in names
+func containsName(names []string, name string) bool {
+	for _, other := range names {
+		if other == name {
+			return true
+		}
+	}
+	return false
+}
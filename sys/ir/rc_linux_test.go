@@ -0,0 +1,240 @@
+// +build linux
+
+package ir
+
+import (
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi-hw/sys/filepoll"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE DEVICE
+
+// rcFake creates an rc device class and input device in a temporary
+// folder, and returns the input device opened for writing events
+func rcFake(t *testing.T, protocols string) (string, *os.File) {
+	t.Helper()
+	root, err := ioutil.TempDir("", "rc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc_class, rc_input = filepath.Join(root, "class"), filepath.Join(root, "input")
+	if err := os.MkdirAll(filepath.Join(rc_class, "rc0", "input5", "event3"), 0755); err != nil {
+		t.Fatal(err)
+	} else if err := ioutil.WriteFile(filepath.Join(rc_class, "rc0", "protocols"), []byte(protocols), 0644); err != nil {
+		t.Fatal(err)
+	} else if err := os.MkdirAll(rc_input, 0755); err != nil {
+		t.Fatal(err)
+	} else if err := syscall.Mkfifo(filepath.Join(rc_input, "event3"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Opening for reading and writing does not block
+	if dev, err := os.OpenFile(filepath.Join(rc_input, "event3"), os.O_RDWR, 0); err != nil {
+		t.Fatal(err)
+		return "", nil
+	} else {
+		return root, dev
+	}
+}
+
+func rcOpen(t *testing.T, config RC) (IRDecoderInterface, func()) {
+	t.Helper()
+	app, err := gopi.NewAppInstance(gopi.NewAppConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	poll, err := gopi.Open(filepoll.FilePoll{}, app.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.FilePoll = poll.(filepoll.FilePollInterface)
+	driver, err := gopi.Open(config, app.Logger)
+	if err != nil {
+		poll.Close()
+		t.Fatal(err)
+	}
+	return driver.(IRDecoderInterface), func() {
+		driver.Close()
+		poll.Close()
+	}
+}
+
+func rcWrite(t *testing.T, dev *os.File, at time.Duration, events ...uint32) {
+	t.Helper()
+	for _, scancode := range events {
+		tv := syscall.NsecToTimeval(int64(at))
+		for _, evt := range []rc_input_event{
+			{Time: tv, Type: RC_EV_MSC, Code: RC_MSC_SCAN, Value: int32(scancode)},
+			{Time: tv, Type: RC_EV_KEY, Code: 0x74, Value: 1},
+			{Time: tv, Type: RC_EV_SYN, Code: RC_SYN_REPORT},
+		} {
+			if err := binary.Write(dev, binary.LittleEndian, evt); err != nil {
+				t.Fatal(err)
+			}
+		}
+		at += 100 * time.Millisecond
+	}
+}
+
+type rcKey struct {
+	protocol         Protocol
+	device, scancode uint32
+	key              string
+	repeat           bool
+}
+
+// rcExpect receives key events, which are 100ms apart from one second
+func rcExpect(t *testing.T, keys <-chan gopi.Event, expected []rcKey) {
+	t.Helper()
+	for i, expected := range expected {
+		select {
+		case evt := <-keys:
+			key := evt.(IRKeyEvent)
+			if key.Protocol() != expected.protocol || key.Device() != expected.device || key.Scancode() != expected.scancode || key.Key() != expected.key || key.Repeat() != expected.repeat {
+				t.Error(i, "Unexpected key event", key)
+			} else if key.Timestamp().Equal(time.Unix(1, 0).Add(time.Duration(i)*100*time.Millisecond)) == false {
+				t.Error(i, "Unexpected timestamp", key.Timestamp())
+			}
+		case <-time.After(time.Second):
+			t.Fatal(i, "Timeout waiting for key event")
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestRC_000(t *testing.T) {
+	root, dev := rcFake(t, "rc-5 [nec] rc-6 sony\n")
+	defer os.RemoveAll(root)
+	defer dev.Close()
+
+	keymap := &Keymap{Name: "car_mp3", Keys: map[uint32]string{0x45: "KEY_CHANNELDOWN"}}
+	decoder, close := rcOpen(t, RC{Keymaps: []*Keymap{keymap}})
+	defer close()
+	if protocols := decoder.Protocols(); len(protocols) != 2 || protocols[0] != IR_PROTOCOL_NEC || protocols[1] != IR_PROTOCOL_NEC_EXT {
+		t.Error("Unexpected protocols", protocols)
+	}
+
+	// A press of 0x45 repeated, then an extended code for device 0x1234,
+	// where the kernel scancode has the address bytes in the order sent
+	keys := decoder.Subscribe()
+	defer decoder.Unsubscribe(keys)
+	rcWrite(t, dev, time.Second, 0x45, 0x45, 0x34120B)
+	rcExpect(t, keys, []rcKey{
+		{IR_PROTOCOL_NEC, 0x00, 0x45, "KEY_CHANNELDOWN", false},
+		{IR_PROTOCOL_NEC, 0x00, 0x45, "KEY_CHANNELDOWN", true},
+		{IR_PROTOCOL_NEC_EXT, 0x1234, 0x0B, "", false},
+	})
+}
+
+func TestRC_001(t *testing.T) {
+	root, dev := rcFake(t, "[rc-5] nec rc-6 sony\n")
+	defer os.RemoveAll(root)
+	defer dev.Close()
+
+	// Protocols are written to the protocols file
+	_, close := rcOpen(t, RC{Device: "rc0", Protocols: []Protocol{IR_PROTOCOL_RC6, IR_PROTOCOL_SONY12, IR_PROTOCOL_SONY20}})
+	close()
+	if data, err := ioutil.ReadFile(filepath.Join(rc_class, "rc0", "protocols")); err != nil {
+		t.Error(err)
+	} else if string(data) != "none +rc-6 +sony\n" {
+		t.Errorf("Unexpected protocols: %q", data)
+	}
+
+	// Unknown devices and protocols the kernel does not decode
+	app, _ := gopi.NewAppInstance(gopi.NewAppConfig())
+	poll, err := gopi.Open(filepoll.FilePoll{}, app.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer poll.Close()
+	if _, err := gopi.Open(RC{Device: "rc1", FilePoll: poll.(filepoll.FilePollInterface)}, app.Logger); err != gopi.ErrNotFound {
+		t.Error("Expected ErrNotFound, got", err)
+	}
+	if _, err := gopi.Open(RC{Protocols: []Protocol{IR_PROTOCOL_PANASONIC}, FilePoll: poll.(filepoll.FilePollInterface)}, app.Logger); err != gopi.ErrNotImplemented {
+		t.Error("Expected ErrNotImplemented, got", err)
+	}
+}
+
+func TestRC_002(t *testing.T) {
+	for _, test := range []struct {
+		name             string
+		value            uint32
+		protocol         Protocol
+		device, scancode uint32
+	}{
+		{"nec", 0x0045, IR_PROTOCOL_NEC, 0x00, 0x45},
+		{"nec", 0x34120B, IR_PROTOCOL_NEC_EXT, 0x1234, 0x0B},
+		{"rc-5", 0x1E0C, IR_PROTOCOL_RC5, 0x1E, 0x0C},
+		{"rc-6", 0x040C, IR_PROTOCOL_RC6, 0x04, 0x0C},
+		{"sony", 0x010015, IR_PROTOCOL_SONY12, 0x01, 0x15},
+		{"sony", 0x970015, IR_PROTOCOL_SONY15, 0x97, 0x15},
+		{"sony", 0x1A0A15, IR_PROTOCOL_SONY20, 0x0A1A, 0x15},
+		{"rc-5", 0xFFFF, IR_PROTOCOL_NONE, 0x00, 0xFFFF},
+	} {
+		protocol, device, scancode, ok := rcScancode(test.name, test.value)
+		if protocol != test.protocol || device != test.device || scancode != test.scancode || ok != (test.protocol != IR_PROTOCOL_NONE) {
+			t.Errorf("%v 0x%X: Unexpected %v 0x%X 0x%X", test.name, test.value, protocol, device, scancode)
+		}
+	}
+
+	// The kernel scancode of an extended NEC frame is the first address
+	// byte sent, the second address byte and the command, and the device
+	// is the same as the decoder
+	if frame, err := encodeNEC(code{protocol: IR_PROTOCOL_NEC_EXT, device: 0x1234, scancode: 0x0B}); err != nil {
+		t.Fatal(err)
+	} else if protocol, device, scancode := Decode(frame, IR_TOLERANCE); protocol != IR_PROTOCOL_NEC_EXT || device != 0x1234 || scancode != 0x0B {
+		t.Errorf("Unexpected decode %v 0x%X 0x%X", protocol, device, scancode)
+	} else if _, device2, scancode2, _ := rcScancode("nec", uint32(necByte(frame, 0))<<16|uint32(necByte(frame, 1))<<8|scancode); device2 != device || scancode2 != scancode {
+		t.Errorf("Unexpected scancode 0x%X 0x%X", device2, scancode2)
+	}
+}
+
+// necByte returns a byte of an NEC frame, which is sent least
+// significant bit first after the header
+func necByte(frame []uint32, index int) byte {
+	value := byte(0)
+	for i := 0; i < 8; i++ {
+		if space := frame[2+(index*8+i)*2+1]; space > NEC_ZERO_SPACE*2 {
+			value |= 1 << uint(i)
+		}
+	}
+	return value
+}
+
+func TestRC_003(t *testing.T) {
+	root, dev := rcFake(t, "[rc-5] [nec] rc-6 sony\n")
+	defer os.RemoveAll(root)
+	defer dev.Close()
+
+	// Keymaps for two protocols which are both enabled
+	tv := &Keymap{Name: "tv", Protocols: []Protocol{IR_PROTOCOL_RC5}, Keys: map[uint32]string{0x1E0C: "KEY_POWER"}}
+	car := &Keymap{Name: "car_mp3", Protocols: []Protocol{IR_PROTOCOL_NEC, IR_PROTOCOL_NEC_EXT}, Keys: map[uint32]string{0x45: "KEY_CHANNELDOWN"}}
+	decoder, close := rcOpen(t, RC{Protocols: []Protocol{IR_PROTOCOL_RC5, IR_PROTOCOL_NEC}, Keymaps: []*Keymap{tv, car}})
+	defer close()
+
+	// Codes in a keymap have its protocol, and other codes are decoded
+	// when only one enabled protocol decodes them
+	keys := decoder.Subscribe()
+	defer decoder.Unsubscribe(keys)
+	rcWrite(t, dev, time.Second, 0x1E0C, 0x45, 0x34120B, 0x1E0D)
+	rcExpect(t, keys, []rcKey{
+		{IR_PROTOCOL_RC5, 0x1E, 0x0C, "KEY_POWER", false},
+		{IR_PROTOCOL_NEC, 0x00, 0x45, "KEY_CHANNELDOWN", false},
+		{IR_PROTOCOL_NEC_EXT, 0x1234, 0x0B, "", false},
+		{IR_PROTOCOL_NONE, 0x00, 0x1E0D, "", false},
+	})
+}
@@ -0,0 +1,301 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package ir
+
+import (
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// pulses is a frame of alternating pulse and space durations, which
+// starts with a pulse
+type pulses []uint32
+
+////////////////////////////////////////////////////////////////////////////////
+// NEW
+
+// NewRemote returns a remote definition without keys which encodes the
+// codes of a protocol. The codes of keys are returned by RemoteCode
+func NewRemote(name string, protocol Protocol) (*Remote, error) {
+	transmitter, exists := transmitters[protocol]
+	if exists == false {
+		return nil, gopi.ErrBadParameter
+	}
+	remote := &Remote{
+		Name:      name,
+		Flags:     REMOTE_FLAG_SPACE_ENC | REMOTE_FLAG_CONST_LENGTH,
+		Eps:       REMOTE_EPS,
+		Aeps:      REMOTE_AEPS,
+		Gap:       transmitter.period,
+		Frequency: transmitter.carrier,
+		DutyCycle: transmitter.duty,
+		Keys:      make([]*RemoteKey, 0),
+	}
+	switch protocol {
+	case IR_PROTOCOL_NEC, IR_PROTOCOL_NEC_EXT, IR_PROTOCOL_SAMSUNG:
+		remote.Flags |= REMOTE_FLAG_REVERSE
+		remote.Bits = 32
+		remote.Header = [2]uint32{NEC_HEADER_PULSE, NEC_HEADER_SPACE}
+		remote.One = [2]uint32{NEC_BIT_PULSE, NEC_ONE_SPACE}
+		remote.Zero = [2]uint32{NEC_BIT_PULSE, NEC_ZERO_SPACE}
+		remote.Ptrail = NEC_BIT_PULSE
+		if protocol == IR_PROTOCOL_SAMSUNG {
+			remote.Header = [2]uint32{SAMSUNG_HEADER, SAMSUNG_HEADER}
+		} else {
+			remote.Repeat = [2]uint32{NEC_HEADER_PULSE, NEC_REPEAT_SPACE}
+		}
+	case IR_PROTOCOL_SONY12, IR_PROTOCOL_SONY15, IR_PROTOCOL_SONY20:
+		remote.Flags |= REMOTE_FLAG_REVERSE
+		remote.Bits = map[Protocol]uint{IR_PROTOCOL_SONY12: 12, IR_PROTOCOL_SONY15: 15, IR_PROTOCOL_SONY20: 20}[protocol]
+		remote.Header = [2]uint32{SONY_HEADER_PULSE, SONY_SPACE}
+		remote.One = [2]uint32{SONY_ONE_PULSE, SONY_SPACE}
+		remote.Zero = [2]uint32{SONY_ZERO_PULSE, SONY_SPACE}
+	case IR_PROTOCOL_PANASONIC:
+		remote.Flags |= REMOTE_FLAG_REVERSE
+		remote.Bits = 48
+		remote.Header = [2]uint32{PANASONIC_HEADER, PANASONIC_SPACE}
+		remote.One = [2]uint32{PANASONIC_UNIT, 3 * PANASONIC_UNIT}
+		remote.Zero = [2]uint32{PANASONIC_UNIT, PANASONIC_UNIT}
+		remote.Ptrail = PANASONIC_UNIT
+	case IR_PROTOCOL_RC5:
+		// The first half of the start bit is the leading pulse, and the
+		// codes are the second start bit, toggle bit, address and command
+		remote.Flags = REMOTE_FLAG_RC5 | REMOTE_FLAG_CONST_LENGTH
+		remote.Bits = 13
+		remote.One = [2]uint32{RC5_UNIT, RC5_UNIT}
+		remote.Zero = [2]uint32{RC5_UNIT, RC5_UNIT}
+		remote.Plead = RC5_UNIT
+		remote.ToggleBitMask = 0x800
+	case IR_PROTOCOL_RC6:
+		// The codes are the start bit, mode, double-length toggle bit,
+		// address and command
+		remote.Flags = REMOTE_FLAG_RC6 | REMOTE_FLAG_CONST_LENGTH
+		remote.Bits = 21
+		remote.Header = [2]uint32{RC6_HEADER_PULSE, RC6_HEADER_SPACE}
+		remote.One = [2]uint32{RC6_UNIT, RC6_UNIT}
+		remote.Zero = [2]uint32{RC6_UNIT, RC6_UNIT}
+		remote.ToggleBitMask = 0x10000
+		remote.RC6Mask = 0x10000
+	}
+	return remote, nil
+}
+
+// RemoteCode returns the code of a key for a remote definition returned
+// by NewRemote
+func RemoteCode(protocol Protocol, device, scancode uint32) (uint64, error) {
+	// Check the device and scancode can be encoded
+	if transmitter, exists := transmitters[protocol]; exists == false {
+		return 0, gopi.ErrBadParameter
+	} else if _, err := transmitter.encode(code{protocol: protocol, device: device, scancode: scancode}); err != nil {
+		return 0, err
+	}
+
+	d, s := uint64(device), uint64(scancode)
+	switch protocol {
+	case IR_PROTOCOL_NEC:
+		return d | (d^0xFF)<<8 | s<<16 | (s^0xFF)<<24, nil
+	case IR_PROTOCOL_NEC_EXT:
+		return d | s<<16 | (s^0xFF)<<24, nil
+	case IR_PROTOCOL_SAMSUNG:
+		return d | d<<8 | s<<16 | (s^0xFF)<<24, nil
+	case IR_PROTOCOL_SONY12, IR_PROTOCOL_SONY15:
+		return d<<7 | s, nil
+	case IR_PROTOCOL_SONY20:
+		return (d>>8)<<12 | (d&0x1F)<<7 | s, nil
+	case IR_PROTOCOL_PANASONIC:
+		d2, d3 := d&0xFF, d>>8
+		return PANASONIC_VENDOR | d2<<16 | d3<<24 | s<<32 | (d2^d3^s)<<40, nil
+	case IR_PROTOCOL_RC5:
+		if s&0x40 == 0 {
+			return 0x1000 | d<<6 | s, nil
+		} else {
+			return d<<6 | s&0x3F, nil
+		}
+	case IR_PROTOCOL_RC6:
+		return 0x100000 | d<<8 | s, nil
+	default:
+		return 0, gopi.ErrBadParameter
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ENCODE
+
+// Encode returns the pulses and spaces of a key, or of the frame which
+// repeats it. When toggle is true the bits of the toggle mask are
+// inverted. Returns ErrNotFound if the key does not exist
+func (this *Remote) Encode(name string, toggle, repeat bool) ([]uint32, error) {
+	if key := this.Key(name); key == nil {
+		return nil, gopi.ErrNotFound
+	} else {
+		return this.encode(key, toggle, repeat), nil
+	}
+}
+
+func (this *Remote) encode(key *RemoteKey, toggle, repeat bool) []uint32 {
+	// Raw codes are the same for each frame
+	if this.Flags&REMOTE_FLAG_RAW_CODES != 0 {
+		return append([]uint32{}, key.Raw...)
+	}
+
+	frame := make(pulses, 0, 2*(this.PreDataBits+this.Bits+this.PostDataBits)+5)
+
+	// Repeat frames
+	if repeat && this.Repeat[0] != 0 {
+		if this.Flags&REMOTE_FLAG_REPEAT_HEADER != 0 {
+			frame = frame.add(true, this.Header[0]).add(false, this.Header[1])
+		}
+		frame = frame.add(true, this.Repeat[0]).add(false, this.Repeat[1])
+		return frame.add(true, this.Ptrail).trim()
+	}
+
+	// Header, which is omitted for repeated codes with NO_HEAD_REP
+	if repeat == false || this.Flags&REMOTE_FLAG_NO_HEAD_REP == 0 {
+		frame = frame.add(true, this.Header[0]).add(false, this.Header[1])
+	}
+	frame = frame.add(true, this.Plead)
+
+	// Data bits, most significant bit first
+	bits := this.PreDataBits + this.Bits + this.PostDataBits
+	data := this.data(key.Code)
+	if toggle {
+		data ^= this.ToggleBitMask
+	}
+	for i := int(bits) - 1; i >= 0; i-- {
+		mask := uint64(1) << uint(i)
+		one, double := data&mask != 0, this.RC6Mask&mask != 0
+		pulse, space := this.Zero[0], this.Zero[1]
+		if one {
+			pulse, space = this.One[0], this.One[1]
+		}
+		if double {
+			pulse, space = pulse*2, space*2
+		}
+		switch this.encoding() {
+		case REMOTE_FLAG_RC5:
+			// A one is a space then a pulse
+			frame = frame.add(one == false, pulse).add(one, space)
+		case REMOTE_FLAG_RC6:
+			// A one is a pulse then a space
+			frame = frame.add(one, pulse).add(one == false, space)
+		default:
+			frame = frame.add(true, pulse).add(false, space)
+		}
+	}
+	return frame.add(true, this.Ptrail).trim()
+}
+
+// data returns the pre-data, code and post-data as a single value, with
+// the bits of each part reversed when the remote has the REVERSE flag
+func (this *Remote) data(code uint64) uint64 {
+	pre, post := this.PreData, this.PostData
+	if this.Flags&REMOTE_FLAG_REVERSE != 0 {
+		pre, code, post = reverse(pre, this.PreDataBits), reverse(code, this.Bits), reverse(post, this.PostDataBits)
+	}
+	return pre<<(this.Bits+this.PostDataBits) | code<<this.PostDataBits | post
+}
+
+// gap returns the gap after a frame
+func (this *Remote) gap(frame []uint32) time.Duration {
+	if this.Flags&REMOTE_FLAG_CONST_LENGTH != 0 {
+		return frameGap(this.Gap, frame)
+	} else if this.Gap < IR_FRAME_GAP {
+		return time.Duration(IR_FRAME_GAP) * time.Microsecond
+	} else {
+		return time.Duration(this.Gap) * time.Microsecond
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DECODE
+
+// decode returns the code of the key which matches a frame, by comparing
+// the frame with the encoded frames of each key
+func (this *Remote) decode(frame []uint32) (code, bool) {
+	// Repeat frames
+	if this.Flags&REMOTE_FLAG_RAW_CODES == 0 && this.Repeat[0] != 0 && len(this.Keys) > 0 {
+		if this.matches(frame, this.encode(this.Keys[0], false, true)) {
+			return code{remote: this, toggle: -1, repeat: true}, true
+		}
+	}
+
+	// Codes, with and without the toggle bits, and without the header
+	// when repeated codes have no header
+	for _, key := range this.Keys {
+		for toggle := 0; toggle <= 1; toggle++ {
+			if toggle == 1 && this.ToggleBitMask == 0 {
+				break
+			}
+			if this.matches(frame, this.encode(key, toggle == 1, false)) || (this.Flags&REMOTE_FLAG_NO_HEAD_REP != 0 && this.matches(frame, this.encode(key, toggle == 1, true))) {
+				return code{remote: this, key: key, device: uint32(this.PreData), scancode: uint32(key.Code), toggle: toggle}, true
+			}
+		}
+	}
+
+	// No match
+	return code{}, false
+}
+
+// matches returns true when each duration of a frame is within the
+// tolerance of the remote
+func (this *Remote) matches(frame, expected []uint32) bool {
+	if len(frame) != len(expected) {
+		return false
+	}
+	for i, value := range expected {
+		margin := value * uint32(this.Eps) / 100
+		if margin < this.Aeps {
+			margin = this.Aeps
+		}
+		if frame[i]+margin < value || frame[i] > value+margin {
+			return false
+		}
+	}
+	return true
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PULSES
+
+// add a pulse or space to the frame, merging it with the last duration
+// when it is of the same type. Spaces at the start and durations of
+// zero are not added
+func (frame pulses) add(pulse bool, value uint32) pulses {
+	if value == 0 || (len(frame) == 0 && pulse == false) {
+		return frame
+	} else if len(frame) > 0 && (len(frame)%2 == 1) == pulse {
+		frame[len(frame)-1] += value
+		return frame
+	} else {
+		return append(frame, value)
+	}
+}
+
+// trim returns the frame without a space at the end
+func (frame pulses) trim() []uint32 {
+	if len(frame)%2 == 0 && len(frame) > 0 {
+		return frame[:len(frame)-1]
+	}
+	return frame
+}
+
+// reverse returns the bits of a value in reverse order
+func reverse(value uint64, bits uint) uint64 {
+	result := uint64(0)
+	for i := uint(0); i < bits; i++ {
+		result = result<<1 | (value>>i)&1
+	}
+	return result
+}
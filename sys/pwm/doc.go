@@ -32,4 +32,17 @@
 //
 //    go run -tags rpi ./cmd/pwm_ctrl/...
 //
+// LINUX IMPLEMENTATION
+//
+// The Linux implementation uses the sysfs interface at
+// /sys/class/pwm/pwmchipN, which on the Raspberry Pi is enabled with
+// the pwm or pwm-2chan device tree overlays. The -pwm.chip flag selects
+// the chip, and the -pwm.pins flag maps each channel to a pin. For
+// example, with the pwm-2chan overlay:
+//
+//    go run ./cmd/pwm_ctrl/... -pwm.pins 18,19
+//
+// On the Raspberry Pi the pi-blaster driver remains the default, and
+// the sysfs driver is available as the "hw/pwm/pwmchip" module.
+//
 package pwm
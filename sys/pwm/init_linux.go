@@ -0,0 +1,40 @@
+// +build linux,!rpi
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package pwm
+
+import (
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	gopi.RegisterModule(gopi.Module{
+		Name: "hw/pwm/pwmchip",
+		Type: gopi.MODULE_TYPE_PWM,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("pwm.chip", 0, "PWM chip number")
+			config.AppFlags.FlagString("pwm.pins", "", "Comma-separated list of pins for each PWM channel")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			chip, _ := app.AppFlags.GetUint("pwm.chip")
+			value, _ := app.AppFlags.GetString("pwm.pins")
+			if pins, err := parsePins(value); err != nil {
+				return nil, err
+			} else {
+				return gopi.Open(PWMChip{Chip: chip, Pins: pins}, app.Logger)
+			}
+		},
+	})
+}
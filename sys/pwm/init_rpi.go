@@ -36,4 +36,22 @@ func init() {
 			}, app.Logger)
 		},
 	})
+
+	gopi.RegisterModule(gopi.Module{
+		Name: "hw/pwm/pwmchip",
+		Type: gopi.MODULE_TYPE_OTHER,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("pwm.chip", 0, "PWM chip number")
+			config.AppFlags.FlagString("pwm.pins", "", "Comma-separated list of pins for each PWM channel")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			chip, _ := app.AppFlags.GetUint("pwm.chip")
+			value, _ := app.AppFlags.GetString("pwm.pins")
+			if pins, err := parsePins(value); err != nil {
+				return nil, err
+			} else {
+				return gopi.Open(PWMChip{Chip: chip, Pins: pins}, app.Logger)
+			}
+		},
+	})
 }
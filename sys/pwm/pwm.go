@@ -0,0 +1,63 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package pwm
+
+import (
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// PWMPolarity is the level of the output during the duty cycle
+type PWMPolarity uint
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
+// PWMChipInterface is implemented by drivers which can set the polarity
+// and enable or disable channels independently of the duty cycle
+type PWMChipInterface interface {
+	gopi.PWM
+
+	// Polarity
+	Polarity(gopi.GPIOPin) (PWMPolarity, error)
+	SetPolarity(PWMPolarity, ...gopi.GPIOPin) error
+
+	// Enable and disable output
+	Enabled(gopi.GPIOPin) (bool, error)
+	SetEnabled(bool, ...gopi.GPIOPin) error
+
+	// Release a pin so it can be used by other processes
+	Release(gopi.GPIOPin) error
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	PWM_POLARITY_NORMAL PWMPolarity = iota
+	PWM_POLARITY_INVERSED
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (p PWMPolarity) String() string {
+	switch p {
+	case PWM_POLARITY_NORMAL:
+		return "PWM_POLARITY_NORMAL"
+	case PWM_POLARITY_INVERSED:
+		return "PWM_POLARITY_INVERSED"
+	default:
+		return "[?? Invalid PWMPolarity value]"
+	}
+}
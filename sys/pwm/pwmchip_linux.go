@@ -0,0 +1,462 @@
+// +build linux
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package pwm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	"github.com/djthorpe/gopi/util/errors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// PWMChip is the configuration for a driver which uses the sysfs
+// interface at /sys/class/pwm/pwmchipN
+type PWMChip struct {
+	// Chip number
+	Chip uint
+
+	// Pin for each channel, where the index is the channel. When empty,
+	// each channel is mapped to the pin with the same number
+	Pins []gopi.GPIOPin
+
+	// Root of the PWM class, which can be replaced for testing
+	root string
+}
+
+type pwmchip struct {
+	log      gopi.Logger
+	chip     uint
+	path     string
+	npwm     uint
+	channels map[gopi.GPIOPin]uint
+	used     map[gopi.GPIOPin]bool
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	PWM_SYSFS_ROOT     = "/sys/class/pwm"
+	PWM_EXPORT_TIMEOUT = time.Second
+	PWM_EXPORT_DELAY   = 10 * time.Millisecond
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open
+func (config PWMChip) Open(logger gopi.Logger) (gopi.Driver, error) {
+	logger.Debug("<hw.pwm.pwmchip>Open{ chip=%v pins=%v }", config.Chip, config.Pins)
+
+	this := new(pwmchip)
+	this.log = logger
+	this.chip = config.Chip
+	this.channels = make(map[gopi.GPIOPin]uint)
+	this.used = make(map[gopi.GPIOPin]bool)
+
+	// Set path to the chip
+	if config.root == "" {
+		this.path = filepath.Join(PWM_SYSFS_ROOT, fmt.Sprintf("pwmchip%v", config.Chip))
+	} else {
+		this.path = filepath.Join(config.root, fmt.Sprintf("pwmchip%v", config.Chip))
+	}
+
+	// Read the number of channels
+	if npwm, err := readUint(filepath.Join(this.path, "npwm")); err != nil {
+		return nil, err
+	} else {
+		this.npwm = uint(npwm)
+	}
+
+	// Map pins to channels
+	if len(config.Pins) == 0 {
+		for channel := uint(0); channel < this.npwm; channel++ {
+			this.channels[gopi.GPIOPin(channel)] = channel
+		}
+	} else if uint(len(config.Pins)) > this.npwm {
+		return nil, gopi.ErrBadParameter
+	} else {
+		for channel, pin := range config.Pins {
+			if _, exists := this.channels[pin]; exists || pin == gopi.GPIO_PIN_NONE {
+				return nil, gopi.ErrBadParameter
+			}
+			this.channels[pin] = uint(channel)
+		}
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close
+func (this *pwmchip) Close() error {
+	this.log.Debug("<hw.pwm.pwmchip>Close{ chip=%v }", this.chip)
+
+	// Release pins which have been used, continuing when a pin
+	// cannot be released
+	var errs errors.CompoundError
+	for pin := range this.used {
+		if this.isExported(this.channels[pin]) {
+			errs.Add(this.Release(pin))
+		}
+	}
+
+	// Zero out member variables
+	this.channels = nil
+	this.used = nil
+
+	// Return any errors
+	return errs.ErrorOrSelf()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *pwmchip) String() string {
+	return fmt.Sprintf("<hw.pwm.pwmchip>{ chip=%v npwm=%v pins=%v }", this.chip, this.npwm, this.Pins())
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - PINS
+
+// Pins returns the pins which are mapped to channels, in channel order
+func (this *pwmchip) Pins() []gopi.GPIOPin {
+	pins := make([]gopi.GPIOPin, 0, len(this.channels))
+	for pin := range this.channels {
+		pins = append(pins, pin)
+	}
+	sort.Slice(pins, func(i, j int) bool {
+		return this.channels[pins[i]] < this.channels[pins[j]]
+	})
+	return pins
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - PERIOD AND DUTY CYCLE
+
+func (this *pwmchip) Period(pin gopi.GPIOPin) (time.Duration, error) {
+	this.log.Debug2("<hw.pwm.pwmchip>Period{ pin=%v }", pin)
+	this.Lock()
+	defer this.Unlock()
+
+	if channel, err := this.export(pin); err != nil {
+		return 0, err
+	} else if period, err := readUint(this.filename(channel, "period")); err != nil {
+		return 0, err
+	} else {
+		return time.Duration(period) * time.Nanosecond, nil
+	}
+}
+
+// SetPeriod sets the period for one or more pins, keeping the
+// duty cycle as the same fraction of the period
+func (this *pwmchip) SetPeriod(period time.Duration, pins ...gopi.GPIOPin) error {
+	this.log.Debug2("<hw.pwm.pwmchip>SetPeriod{ period=%v pins=%v }", period, pins)
+	this.Lock()
+	defer this.Unlock()
+
+	if period <= 0 || len(pins) == 0 {
+		return gopi.ErrBadParameter
+	}
+	for _, pin := range pins {
+		if channel, err := this.export(pin); err != nil {
+			return err
+		} else if old_period, err := readUint(this.filename(channel, "period")); err != nil {
+			return err
+		} else if old_duty, err := readUint(this.filename(channel, "duty_cycle")); err != nil {
+			return err
+		} else {
+			new_period := uint64(period.Nanoseconds())
+			new_duty := uint64(0)
+			if old_period != 0 {
+				new_duty = uint64(float64(old_duty) * float64(new_period) / float64(old_period))
+			}
+			// The duty cycle can never be longer than the period, so the
+			// order of writes depends on whether the period is shrinking
+			if new_period >= old_period {
+				if err := writeUint(this.filename(channel, "period"), new_period); err != nil {
+					return err
+				} else if err := writeUint(this.filename(channel, "duty_cycle"), new_duty); err != nil {
+					return err
+				}
+			} else {
+				if err := writeUint(this.filename(channel, "duty_cycle"), new_duty); err != nil {
+					return err
+				} else if err := writeUint(this.filename(channel, "period"), new_period); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// Success
+	return nil
+}
+
+func (this *pwmchip) DutyCycle(pin gopi.GPIOPin) (float32, error) {
+	this.log.Debug2("<hw.pwm.pwmchip>DutyCycle{ pin=%v }", pin)
+	this.Lock()
+	defer this.Unlock()
+
+	if channel, err := this.export(pin); err != nil {
+		return 0, err
+	} else if period, err := readUint(this.filename(channel, "period")); err != nil {
+		return 0, err
+	} else if duty, err := readUint(this.filename(channel, "duty_cycle")); err != nil {
+		return 0, err
+	} else if period == 0 {
+		return 0, nil
+	} else {
+		return float32(float64(duty) / float64(period)), nil
+	}
+}
+
+// SetDutyCycle sets the duty cycle for one or more pins as a fraction
+// of the period, and enables output on the pins
+func (this *pwmchip) SetDutyCycle(duty_cycle float32, pins ...gopi.GPIOPin) error {
+	this.log.Debug2("<hw.pwm.pwmchip>SetDutyCycle{ duty_cycle=%v pins=%v }", duty_cycle, pins)
+	this.Lock()
+	defer this.Unlock()
+
+	if duty_cycle < 0.0 || duty_cycle > 1.0 {
+		return gopi.ErrBadParameter
+	} else if len(pins) == 0 {
+		return gopi.ErrBadParameter
+	}
+	for _, pin := range pins {
+		if channel, err := this.export(pin); err != nil {
+			return err
+		} else if period, err := readUint(this.filename(channel, "period")); err != nil {
+			return err
+		} else if period == 0 {
+			return fmt.Errorf("<hw.pwm.pwmchip>SetDutyCycle: Period not set on pin %v", pin)
+		} else if err := writeUint(this.filename(channel, "duty_cycle"), uint64(float64(duty_cycle)*float64(period))); err != nil {
+			return err
+		} else if err := this.setEnabled(channel, true); err != nil {
+			return err
+		}
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACE - POLARITY AND ENABLE
+
+func (this *pwmchip) Polarity(pin gopi.GPIOPin) (PWMPolarity, error) {
+	this.log.Debug2("<hw.pwm.pwmchip>Polarity{ pin=%v }", pin)
+	this.Lock()
+	defer this.Unlock()
+
+	if channel, err := this.export(pin); err != nil {
+		return PWM_POLARITY_NORMAL, err
+	} else if value, err := readString(this.filename(channel, "polarity")); err != nil {
+		return PWM_POLARITY_NORMAL, err
+	} else {
+		switch value {
+		case "normal":
+			return PWM_POLARITY_NORMAL, nil
+		case "inversed":
+			return PWM_POLARITY_INVERSED, nil
+		default:
+			return PWM_POLARITY_NORMAL, fmt.Errorf("<hw.pwm.pwmchip>Polarity: Unexpected value '%v'", value)
+		}
+	}
+}
+
+// SetPolarity sets the polarity for one or more pins. Output is disabled
+// while the polarity is changed, as most hardware requires this
+func (this *pwmchip) SetPolarity(polarity PWMPolarity, pins ...gopi.GPIOPin) error {
+	this.log.Debug2("<hw.pwm.pwmchip>SetPolarity{ polarity=%v pins=%v }", polarity, pins)
+	this.Lock()
+	defer this.Unlock()
+
+	var value string
+	switch polarity {
+	case PWM_POLARITY_NORMAL:
+		value = "normal"
+	case PWM_POLARITY_INVERSED:
+		value = "inversed"
+	default:
+		return gopi.ErrBadParameter
+	}
+	if len(pins) == 0 {
+		return gopi.ErrBadParameter
+	}
+	for _, pin := range pins {
+		if channel, err := this.export(pin); err != nil {
+			return err
+		} else if enabled, err := this.enabled(channel); err != nil {
+			return err
+		} else if err := this.setEnabled(channel, false); err != nil {
+			return err
+		} else if err := writeString(this.filename(channel, "polarity"), value); err != nil {
+			return err
+		} else if err := this.setEnabled(channel, enabled); err != nil {
+			return err
+		}
+	}
+
+	// Success
+	return nil
+}
+
+func (this *pwmchip) Enabled(pin gopi.GPIOPin) (bool, error) {
+	this.log.Debug2("<hw.pwm.pwmchip>Enabled{ pin=%v }", pin)
+	this.Lock()
+	defer this.Unlock()
+
+	if channel, err := this.export(pin); err != nil {
+		return false, err
+	} else {
+		return this.enabled(channel)
+	}
+}
+
+func (this *pwmchip) SetEnabled(enabled bool, pins ...gopi.GPIOPin) error {
+	this.log.Debug2("<hw.pwm.pwmchip>SetEnabled{ enabled=%v pins=%v }", enabled, pins)
+	this.Lock()
+	defer this.Unlock()
+
+	if len(pins) == 0 {
+		return gopi.ErrBadParameter
+	}
+	for _, pin := range pins {
+		if channel, err := this.export(pin); err != nil {
+			return err
+		} else if err := this.setEnabled(channel, enabled); err != nil {
+			return err
+		}
+	}
+
+	// Success
+	return nil
+}
+
+// Release disables output on a pin and unexports the channel
+func (this *pwmchip) Release(pin gopi.GPIOPin) error {
+	this.log.Debug2("<hw.pwm.pwmchip>Release{ pin=%v }", pin)
+	this.Lock()
+	defer this.Unlock()
+
+	if channel, exists := this.channels[pin]; exists == false {
+		return gopi.ErrBadParameter
+	} else if this.isExported(channel) == false {
+		return gopi.ErrNotFound
+	} else if err := this.setEnabled(channel, false); err != nil {
+		return err
+	} else if err := writeUint(filepath.Join(this.path, "unexport"), uint64(channel)); err != nil {
+		return err
+	} else {
+		delete(this.used, pin)
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// export returns the channel for a pin, exporting the channel if
+// necessary and waiting for the channel directory to appear. Pins
+// which are used are released when the driver is closed
+func (this *pwmchip) export(pin gopi.GPIOPin) (uint, error) {
+	channel, exists := this.channels[pin]
+	if exists == false {
+		return 0, gopi.ErrBadParameter
+	} else {
+		this.used[pin] = true
+	}
+	if this.isExported(channel) {
+		return channel, nil
+	} else if err := writeUint(filepath.Join(this.path, "export"), uint64(channel)); err != nil {
+		return 0, err
+	}
+	for timeout := time.Now().Add(PWM_EXPORT_TIMEOUT); time.Now().Before(timeout); {
+		if this.isExported(channel) {
+			return channel, nil
+		}
+		time.Sleep(PWM_EXPORT_DELAY)
+	}
+	return 0, fmt.Errorf("<hw.pwm.pwmchip>Export: Timeout exporting pin %v", pin)
+}
+
+func (this *pwmchip) isExported(channel uint) bool {
+	if stat, err := os.Stat(this.filename(channel, "")); err != nil {
+		return false
+	} else {
+		return stat.IsDir()
+	}
+}
+
+func (this *pwmchip) enabled(channel uint) (bool, error) {
+	if value, err := readUint(this.filename(channel, "enable")); err != nil {
+		return false, err
+	} else {
+		return value != 0, nil
+	}
+}
+
+func (this *pwmchip) setEnabled(channel uint, enabled bool) error {
+	if value, err := this.enabled(channel); err != nil {
+		return err
+	} else if value == enabled {
+		return nil
+	} else if enabled {
+		return writeUint(this.filename(channel, "enable"), 1)
+	} else {
+		return writeUint(this.filename(channel, "enable"), 0)
+	}
+}
+
+func (this *pwmchip) filename(channel uint, filename string) string {
+	return filepath.Join(this.path, fmt.Sprintf("pwm%v", channel), filename)
+}
+
+func readString(filename string) (string, error) {
+	if bytes, err := ioutil.ReadFile(filename); err != nil {
+		return "", err
+	} else {
+		return strings.TrimSpace(string(bytes)), nil
+	}
+}
+
+func readUint(filename string) (uint64, error) {
+	if value, err := readString(filename); err != nil {
+		return 0, err
+	} else {
+		return strconv.ParseUint(value, 10, 64)
+	}
+}
+
+func writeString(filename, value string) error {
+	return ioutil.WriteFile(filename, []byte(value+"\n"), 0644)
+}
+
+func writeUint(filename string, value uint64) error {
+	return writeString(filename, strconv.FormatUint(value, 10))
+}
@@ -0,0 +1,258 @@
+// +build linux
+
+package pwm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE SYSFS
+
+// fakesysfs is a temporary directory standing in for /sys/class/pwm
+type fakesysfs struct {
+	t    *testing.T
+	root string
+}
+
+func newFakeSysfs(t *testing.T, npwm uint) *fakesysfs {
+	t.Helper()
+	if root, err := ioutil.TempDir("", "pwm"); err != nil {
+		t.Fatal(err)
+		return nil
+	} else {
+		this := &fakesysfs{t, root}
+		this.Write("pwmchip0/npwm", fmt.Sprint(npwm))
+		this.Write("pwmchip0/export", "")
+		this.Write("pwmchip0/unexport", "")
+		return this
+	}
+}
+
+// Export creates the directory for a channel, as the kernel does
+// when the channel number is written to the export file
+func (this *fakesysfs) Export(channel string) {
+	this.Write("pwmchip0/pwm"+channel+"/period", "0")
+	this.Write("pwmchip0/pwm"+channel+"/duty_cycle", "0")
+	this.Write("pwmchip0/pwm"+channel+"/polarity", "normal")
+	this.Write("pwmchip0/pwm"+channel+"/enable", "0")
+}
+
+func (this *fakesysfs) Write(filename, value string) {
+	this.t.Helper()
+	path := filepath.Join(this.root, filename)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		this.t.Fatal(err)
+	} else if err := ioutil.WriteFile(path, []byte(value+"\n"), 0644); err != nil {
+		this.t.Fatal(err)
+	}
+}
+
+func (this *fakesysfs) Read(filename string) string {
+	this.t.Helper()
+	if value, err := ioutil.ReadFile(filepath.Join(this.root, filename)); err != nil {
+		this.t.Fatal(err)
+		return ""
+	} else {
+		return strings.TrimSpace(string(value))
+	}
+}
+
+func (this *fakesysfs) Close() {
+	os.RemoveAll(this.root)
+}
+
+func openPWMChip(t *testing.T, sysfs *fakesysfs, pins ...gopi.GPIOPin) *pwmchip {
+	t.Helper()
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig()); err != nil {
+		t.Fatal(err)
+	} else if driver, err := gopi.Open(PWMChip{Pins: pins, root: sysfs.root}, app.Logger); err != nil {
+		t.Fatal(err)
+	} else {
+		return driver.(*pwmchip)
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestPWMChip_000(t *testing.T) {
+	sysfs := newFakeSysfs(t, 2)
+	defer sysfs.Close()
+
+	// Default mapping is channel number to pin number
+	driver := openPWMChip(t, sysfs)
+	defer driver.Close()
+	if pins := driver.Pins(); len(pins) != 2 || pins[0] != 0 || pins[1] != 1 {
+		t.Error("Unexpected pins", pins)
+	}
+
+	// Mapping to pins
+	driver2 := openPWMChip(t, sysfs, 18, 19)
+	defer driver2.Close()
+	if pins := driver2.Pins(); len(pins) != 2 || pins[0] != 18 || pins[1] != 19 {
+		t.Error("Unexpected pins", pins)
+	}
+
+	// Too many pins or duplicate pins
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig()); err != nil {
+		t.Fatal(err)
+	} else if _, err := gopi.Open(PWMChip{Pins: []gopi.GPIOPin{12, 13, 18}, root: sysfs.root}, app.Logger); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter for too many pins, got", err)
+	} else if _, err := gopi.Open(PWMChip{Pins: []gopi.GPIOPin{12, 12}, root: sysfs.root}, app.Logger); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter for duplicate pins, got", err)
+	} else if _, err := gopi.Open(PWMChip{Chip: 1, root: sysfs.root}, app.Logger); err == nil {
+		t.Error("Expected error for missing chip")
+	}
+}
+
+func TestPWMChip_001(t *testing.T) {
+	sysfs := newFakeSysfs(t, 2)
+	defer sysfs.Close()
+	sysfs.Export("1")
+
+	driver := openPWMChip(t, sysfs, 18, 19)
+	defer driver.Close()
+
+	// Duty cycle cannot be set until the period is set
+	if err := driver.SetDutyCycle(0.5, 19); err == nil {
+		t.Error("Expected error setting duty cycle without period")
+	}
+	if err := driver.SetPeriod(time.Millisecond, 19); err != nil {
+		t.Error(err)
+	} else if value := sysfs.Read("pwmchip0/pwm1/period"); value != "1000000" {
+		t.Error("Unexpected period", value)
+	}
+	if err := driver.SetDutyCycle(0.25, 19); err != nil {
+		t.Error(err)
+	} else if value := sysfs.Read("pwmchip0/pwm1/duty_cycle"); value != "250000" {
+		t.Error("Unexpected duty_cycle", value)
+	} else if value := sysfs.Read("pwmchip0/pwm1/enable"); value != "1" {
+		t.Error("Expected channel to be enabled", value)
+	}
+
+	// Changing period keeps the duty cycle as a fraction of the period
+	if err := driver.SetPeriod(2*time.Millisecond, 19); err != nil {
+		t.Error(err)
+	} else if value := sysfs.Read("pwmchip0/pwm1/duty_cycle"); value != "500000" {
+		t.Error("Unexpected duty_cycle", value)
+	} else if period, err := driver.Period(19); err != nil {
+		t.Error(err)
+	} else if period != 2*time.Millisecond {
+		t.Error("Unexpected period", period)
+	} else if duty_cycle, err := driver.DutyCycle(19); err != nil {
+		t.Error(err)
+	} else if duty_cycle != 0.25 {
+		t.Error("Unexpected duty cycle", duty_cycle)
+	}
+
+	// Bad parameters
+	if err := driver.SetDutyCycle(1.5, 19); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+	if err := driver.SetPeriod(0, 19); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+	if _, err := driver.Period(4); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter for unmapped pin, got", err)
+	}
+}
+
+func TestPWMChip_002(t *testing.T) {
+	sysfs := newFakeSysfs(t, 2)
+	defer sysfs.Close()
+	sysfs.Export("0")
+
+	driver := openPWMChip(t, sysfs, 18)
+	defer driver.Close()
+
+	if polarity, err := driver.Polarity(18); err != nil {
+		t.Error(err)
+	} else if polarity != PWM_POLARITY_NORMAL {
+		t.Error("Unexpected polarity", polarity)
+	}
+	if err := driver.SetEnabled(true, 18); err != nil {
+		t.Error(err)
+	} else if err := driver.SetPolarity(PWM_POLARITY_INVERSED, 18); err != nil {
+		t.Error(err)
+	} else if value := sysfs.Read("pwmchip0/pwm0/polarity"); value != "inversed" {
+		t.Error("Unexpected polarity", value)
+	} else if enabled, err := driver.Enabled(18); err != nil {
+		t.Error(err)
+	} else if enabled == false {
+		t.Error("Expected channel to be enabled after setting polarity")
+	}
+
+	// Release disables and unexports
+	if err := driver.Release(18); err != nil {
+		t.Error(err)
+	} else if value := sysfs.Read("pwmchip0/pwm0/enable"); value != "0" {
+		t.Error("Expected channel to be disabled", value)
+	} else if value := sysfs.Read("pwmchip0/unexport"); value != "0" {
+		t.Error("Unexpected unexport", value)
+	}
+}
+
+func TestPWMChip_003(t *testing.T) {
+	sysfs := newFakeSysfs(t, 2)
+	defer sysfs.Close()
+
+	driver := openPWMChip(t, sysfs, 18, 19)
+	defer driver.Close()
+
+	// Export channel 1 when the number is written to the export file
+	go func() {
+		for {
+			if value, _ := ioutil.ReadFile(filepath.Join(sysfs.root, "pwmchip0/export")); strings.TrimSpace(string(value)) == "1" {
+				sysfs.Export("1")
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	if period, err := driver.Period(19); err != nil {
+		t.Error(err)
+	} else if period != 0 {
+		t.Error("Unexpected period", period)
+	}
+
+	// Releasing a pin which is not exported
+	if err := driver.Release(18); err != gopi.ErrNotFound {
+		t.Error("Expected ErrNotFound, got", err)
+	}
+}
+
+func TestPWMChip_004(t *testing.T) {
+	sysfs := newFakeSysfs(t, 2)
+	defer sysfs.Close()
+	sysfs.Export("0")
+	sysfs.Export("1")
+
+	driver := openPWMChip(t, sysfs, 18, 19)
+	if err := driver.SetEnabled(true, 18); err != nil {
+		t.Error(err)
+	} else if err := driver.SetEnabled(true, 19); err != nil {
+		t.Error(err)
+	}
+
+	// Close releases every channel when one channel cannot be released
+	sysfs.Write("pwmchip0/pwm0/enable", "?")
+	if err := driver.Close(); err == nil {
+		t.Error("Expected error releasing channel 0")
+	} else if value := sysfs.Read("pwmchip0/pwm1/enable"); value != "0" {
+		t.Error("Expected channel 1 to be disabled", value)
+	}
+}
@@ -49,3 +49,22 @@ func ParseFrequency(value string) (time.Duration, error) {
 		}
 	}
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// parsePins converts a comma-separated list of pins into an array of
+// pins, or returns an empty array for an empty string
+func parsePins(value string) ([]gopi.GPIOPin, error) {
+	pins := make([]gopi.GPIOPin, 0)
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		} else if pin, err := strconv.ParseUint(field, 10, 8); err != nil {
+			return nil, gopi.ErrBadParameter
+		} else {
+			pins = append(pins, gopi.GPIOPin(pin))
+		}
+	}
+	return pins, nil
+}
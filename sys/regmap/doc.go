@@ -0,0 +1,35 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+// This package provides a register map for peripheral drivers, so that
+// a driver declares its registers and bit fields once and then reads
+// and writes them by name.
+//
+// Each register has an address, a width in bits, an access mode and a
+// reset value, and can be divided into fields with a bit offset and
+// width. Writing a field reads the register, changes the field bits and
+// writes the register back:
+//
+//    registers := []regmap.Register{
+//      { Name: "CTRL", Address: 0x20, Width: 8, Access: regmap.ACCESS_RW, Fields: []regmap.Field{
+//        { Name: "ODR", Offset: 4, Width: 4 },
+//        { Name: "EN", Offset: 0, Width: 1 },
+//      }},
+//    }
+//    transport := regmap.I2CTransport{ I2C: app.I2C, Slave: 0x19 }
+//    driver, err := gopi.Open(regmap.RegisterMap{ Transport: transport, Registers: registers }, app.Logger)
+//    driver.(regmap.RegisterMapDriver).WriteField("CTRL", "ODR", 5)
+//
+// Transports are provided for I2C, using the SMBus register methods, and
+// for SPI, where the register address is sent before the data. The
+// values of write-only registers can be cached so that their fields can
+// also be changed. A FakeTransport stores registers in memory for testing
+// drivers without hardware.
+//
+package regmap
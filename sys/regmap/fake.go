@@ -0,0 +1,96 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package regmap
+
+import (
+	"fmt"
+	"sync"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// FakeTransport stores 256 bytes of registers in memory, where multi-byte
+// accesses continue at the next address. It counts reads and writes, and
+// can be set to fail so error handling can be tested
+type FakeTransport struct {
+	memory        [256]byte
+	reads, writes uint
+	err           error
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (this *FakeTransport) ReadRegister(address uint8, length uint) ([]byte, error) {
+	this.Lock()
+	defer this.Unlock()
+	if this.err != nil {
+		return nil, this.err
+	} else if uint(address)+length > uint(len(this.memory)) {
+		return nil, gopi.ErrBadParameter
+	}
+	this.reads++
+	return append([]byte{}, this.memory[address:uint(address)+length]...), nil
+}
+
+func (this *FakeTransport) WriteRegister(address uint8, data []byte) error {
+	this.Lock()
+	defer this.Unlock()
+	if this.err != nil {
+		return this.err
+	} else if int(address)+len(data) > len(this.memory) {
+		return gopi.ErrBadParameter
+	}
+	this.writes++
+	copy(this.memory[address:], data)
+	return nil
+}
+
+// Peek returns bytes from memory without counting a read
+func (this *FakeTransport) Peek(address uint8, length uint) []byte {
+	this.Lock()
+	defer this.Unlock()
+	return append([]byte{}, this.memory[address:uint(address)+length]...)
+}
+
+// Poke sets bytes in memory without counting a write
+func (this *FakeTransport) Poke(address uint8, data []byte) {
+	this.Lock()
+	defer this.Unlock()
+	copy(this.memory[address:], data)
+}
+
+// Counts returns the number of reads and writes
+func (this *FakeTransport) Counts() (uint, uint) {
+	this.Lock()
+	defer this.Unlock()
+	return this.reads, this.writes
+}
+
+// SetError makes every subsequent access fail with an error, or
+// succeed when the error is nil
+func (this *FakeTransport) SetError(err error) {
+	this.Lock()
+	defer this.Unlock()
+	this.err = err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *FakeTransport) String() string {
+	reads, writes := this.Counts()
+	return fmt.Sprintf("<hw.regmap.FakeTransport>{ reads=%v writes=%v }", reads, writes)
+}
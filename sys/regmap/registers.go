@@ -0,0 +1,321 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package regmap
+
+import (
+	"fmt"
+	"sync"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// RegisterMap is the configuration for a set of registers on a transport
+type RegisterMap struct {
+	// Transport for reading and writing registers
+	Transport Transport
+
+	// Registers of the peripheral
+	Registers []Register
+
+	// Multi-byte registers are sent most significant byte first
+	// when true, or least significant byte first when false
+	BigEndian bool
+
+	// Cache the last value written to write-only registers, starting
+	// with the reset value, so they can be read and their fields written
+	Cache bool
+}
+
+type regmap struct {
+	log       gopi.Logger
+	transport Transport
+	registers map[string]*Register
+	order     []*Register
+	bigendian bool
+	cache     map[string]uint32
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open
+func (config RegisterMap) Open(logger gopi.Logger) (gopi.Driver, error) {
+	logger.Debug("<hw.regmap>Open{ registers=%v big_endian=%v cache=%v }", len(config.Registers), config.BigEndian, config.Cache)
+
+	this := new(regmap)
+	this.log = logger
+	this.bigendian = config.BigEndian
+
+	// Transport is required
+	if config.Transport == nil {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.transport = config.Transport
+	}
+
+	// Check and index the registers
+	this.registers = make(map[string]*Register, len(config.Registers))
+	for i := range config.Registers {
+		register := config.Registers[i]
+		if err := checkRegister(register); err != nil {
+			logger.Error("<hw.regmap>Open: %v: %v", register.Name, err)
+			return nil, err
+		} else if _, exists := this.registers[register.Name]; exists {
+			logger.Error("<hw.regmap>Open: Duplicate register %v", register.Name)
+			return nil, gopi.ErrBadParameter
+		} else {
+			this.registers[register.Name] = &register
+			this.order = append(this.order, &register)
+		}
+	}
+
+	// Set up the cache of write-only registers
+	if config.Cache {
+		this.cache = make(map[string]uint32)
+		for name, register := range this.registers {
+			if register.Access == ACCESS_WO {
+				this.cache[name] = register.Reset
+			}
+		}
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close
+func (this *regmap) Close() error {
+	this.log.Debug("<hw.regmap>Close{ }")
+
+	// Zero out member variables
+	this.transport = nil
+	this.registers = nil
+	this.order = nil
+	this.cache = nil
+
+	// Return success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *regmap) String() string {
+	return fmt.Sprintf("<hw.regmap>{ registers=%v big_endian=%v cached=%v }", len(this.registers), this.bigendian, len(this.cache))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// REGISTERS
+
+// Read returns the value of a register, or the cached value of a
+// write-only register
+func (this *regmap) Read(name string) (uint32, error) {
+	this.log.Debug2("<hw.regmap>Read{ register=%v }", name)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if register, exists := this.registers[name]; exists == false {
+		return 0, gopi.ErrNotFound
+	} else {
+		return this.read(register)
+	}
+}
+
+// Write sets the value of a register
+func (this *regmap) Write(name string, value uint32) error {
+	this.log.Debug2("<hw.regmap>Write{ register=%v value=0x%X }", name, value)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if register, exists := this.registers[name]; exists == false {
+		return gopi.ErrNotFound
+	} else if register.Access.writable() == false {
+		return gopi.ErrBadParameter
+	} else {
+		return this.write(register, value)
+	}
+}
+
+// Reset writes the reset value to every writable register, in the
+// order the registers were declared
+func (this *regmap) Reset() error {
+	this.log.Debug2("<hw.regmap>Reset{ }")
+
+	this.Lock()
+	defer this.Unlock()
+
+	for _, register := range this.order {
+		if register.Access.writable() {
+			if err := this.write(register, register.Reset); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// FIELDS
+
+// ReadField returns the value of a field, shifted so the least
+// significant bit of the field is bit zero
+func (this *regmap) ReadField(name, field string) (uint32, error) {
+	this.log.Debug2("<hw.regmap>ReadField{ register=%v field=%v }", name, field)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if register, f, err := this.field(name, field); err != nil {
+		return 0, err
+	} else if f.Access.readable() == false && this.cached(register) == false {
+		return 0, gopi.ErrBadParameter
+	} else if value, err := this.read(register); err != nil {
+		return 0, err
+	} else {
+		return (value & f.mask()) >> f.Offset, nil
+	}
+}
+
+// WriteField reads a register, sets the value of a field and writes the
+// register back. For write-only registers the cached value is used
+func (this *regmap) WriteField(name, field string, value uint32) error {
+	this.log.Debug2("<hw.regmap>WriteField{ register=%v field=%v value=0x%X }", name, field, value)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if register, f, err := this.field(name, field); err != nil {
+		return err
+	} else if f.Access.writable() == false || register.Access.writable() == false {
+		return gopi.ErrBadParameter
+	} else if value > f.mask()>>f.Offset {
+		return gopi.ErrBadParameter
+	} else if current, err := this.read(register); err != nil {
+		return err
+	} else {
+		return this.write(register, current&^f.mask()|value<<f.Offset)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func checkRegister(register Register) error {
+	if register.Name == "" {
+		return gopi.ErrBadParameter
+	}
+	if register.Width == 0 || register.Width > 32 || register.Width%8 != 0 {
+		return gopi.ErrBadParameter
+	}
+	if register.Access == ACCESS_NONE || register.Access > ACCESS_WO {
+		return gopi.ErrBadParameter
+	}
+	if register.Width < 32 && register.Reset>>register.Width != 0 {
+		return gopi.ErrBadParameter
+	}
+	names := make(map[string]bool, len(register.Fields))
+	for _, field := range register.Fields {
+		if field.Name == "" || names[field.Name] {
+			return gopi.ErrBadParameter
+		}
+		if field.Width == 0 || field.Offset+field.Width > register.Width {
+			return gopi.ErrBadParameter
+		}
+		if field.Access > ACCESS_WO {
+			return gopi.ErrBadParameter
+		}
+		names[field.Name] = true
+	}
+	return nil
+}
+
+// field returns a register and field, where the access mode of the
+// field is inherited from the register when not set
+func (this *regmap) field(name, field string) (*Register, Field, error) {
+	if register, exists := this.registers[name]; exists == false {
+		return nil, Field{}, gopi.ErrNotFound
+	} else {
+		for _, f := range register.Fields {
+			if f.Name != field {
+				continue
+			}
+			if f.Access == ACCESS_NONE {
+				f.Access = register.Access
+			}
+			return register, f, nil
+		}
+		return nil, Field{}, gopi.ErrNotFound
+	}
+}
+
+func (this *regmap) cached(register *Register) bool {
+	_, exists := this.cache[register.Name]
+	return exists
+}
+
+func (this *regmap) read(register *Register) (uint32, error) {
+	if value, exists := this.cache[register.Name]; exists {
+		return value, nil
+	} else if register.Access.readable() == false {
+		return 0, gopi.ErrBadParameter
+	} else if data, err := this.transport.ReadRegister(register.Address, register.Width/8); err != nil {
+		return 0, err
+	} else if uint(len(data)) != register.Width/8 {
+		return 0, gopi.ErrUnexpectedResponse
+	} else {
+		return this.decode(data), nil
+	}
+}
+
+func (this *regmap) write(register *Register, value uint32) error {
+	if register.Width < 32 && value>>register.Width != 0 {
+		return gopi.ErrBadParameter
+	} else if err := this.transport.WriteRegister(register.Address, this.encode(value, register.Width/8)); err != nil {
+		return err
+	} else if this.cached(register) {
+		this.cache[register.Name] = value
+	}
+
+	// Success
+	return nil
+}
+
+func (this *regmap) decode(data []byte) uint32 {
+	value := uint32(0)
+	for i := range data {
+		if this.bigendian {
+			value = value<<8 | uint32(data[i])
+		} else {
+			value |= uint32(data[i]) << (8 * uint(i))
+		}
+	}
+	return value
+}
+
+func (this *regmap) encode(value uint32, length uint) []byte {
+	data := make([]byte, length)
+	for i := uint(0); i < length; i++ {
+		if this.bigendian {
+			data[length-i-1] = byte(value >> (8 * i))
+		} else {
+			data[i] = byte(value >> (8 * i))
+		}
+	}
+	return data
+}
@@ -0,0 +1,140 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package regmap
+
+import (
+	"fmt"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Access determines whether a register or field can be read or written
+type Access uint
+
+// Register is a register of a peripheral
+type Register struct {
+	// Name of the register, which is unique within the register map
+	Name string
+
+	// Address of the register
+	Address uint8
+
+	// Width of the register in bits, which is 8, 16, 24 or 32
+	Width uint
+
+	// Access mode of the register
+	Access Access
+
+	// Reset value of the register
+	Reset uint32
+
+	// Bit fields within the register
+	Fields []Field
+}
+
+// Field is a range of bits within a register
+type Field struct {
+	// Name of the field, which is unique within the register
+	Name string
+
+	// Offset of the least significant bit of the field
+	Offset uint
+
+	// Width of the field in bits
+	Width uint
+
+	// Access mode of the field, which defaults to the register
+	// access mode when zero
+	Access Access
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
+// Transport reads and writes registers on a peripheral
+type Transport interface {
+	// Read a number of bytes starting at a register address
+	ReadRegister(address uint8, length uint) ([]byte, error)
+
+	// Write bytes starting at a register address
+	WriteRegister(address uint8, data []byte) error
+}
+
+// RegisterMapDriver reads and writes registers and fields by name
+type RegisterMapDriver interface {
+	gopi.Driver
+
+	// Return and set register values
+	Read(register string) (uint32, error)
+	Write(register string, value uint32) error
+
+	// Return and set field values, where setting a field reads the
+	// register, changes the field and writes the register back
+	ReadField(register, field string) (uint32, error)
+	WriteField(register, field string, value uint32) error
+
+	// Write the reset value to every writable register
+	Reset() error
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	ACCESS_NONE Access = iota
+	ACCESS_RW
+	ACCESS_RO
+	ACCESS_WO
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (a Access) String() string {
+	switch a {
+	case ACCESS_NONE:
+		return "ACCESS_NONE"
+	case ACCESS_RW:
+		return "ACCESS_RW"
+	case ACCESS_RO:
+		return "ACCESS_RO"
+	case ACCESS_WO:
+		return "ACCESS_WO"
+	default:
+		return "[?? Invalid Access value]"
+	}
+}
+
+func (r Register) String() string {
+	return fmt.Sprintf("<hw.regmap.Register>{ name=%v address=0x%02X width=%v access=%v reset=0x%X fields=%v }", r.Name, r.Address, r.Width, r.Access, r.Reset, r.Fields)
+}
+
+func (f Field) String() string {
+	return fmt.Sprintf("<hw.regmap.Field>{ name=%v offset=%v width=%v access=%v }", f.Name, f.Offset, f.Width, f.Access)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (a Access) readable() bool {
+	return a == ACCESS_RW || a == ACCESS_RO
+}
+
+func (a Access) writable() bool {
+	return a == ACCESS_RW || a == ACCESS_WO
+}
+
+func (f Field) mask() uint32 {
+	return uint32((uint64(1)<<f.Width)-1) << f.Offset
+}
@@ -0,0 +1,254 @@
+package regmap_test
+
+import (
+	"bytes"
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	i2c "github.com/djthorpe/gopi-hw/sys/i2c"
+	regmap "github.com/djthorpe/gopi-hw/sys/regmap"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// FAKE SPI
+
+type fakespi struct {
+	sent []byte
+	recv []byte
+}
+
+func (this *fakespi) Close() error                { return nil }
+func (this *fakespi) Mode() gopi.SPIMode          { return gopi.SPI_MODE_0 }
+func (this *fakespi) MaxSpeedHz() uint32          { return 0 }
+func (this *fakespi) BitsPerWord() uint8          { return 8 }
+func (this *fakespi) SetMode(gopi.SPIMode) error  { return nil }
+func (this *fakespi) SetMaxSpeedHz(uint32) error  { return nil }
+func (this *fakespi) SetBitsPerWord(uint8) error  { return nil }
+func (this *fakespi) Read(uint32) ([]byte, error) { return nil, gopi.ErrNotImplemented }
+func (this *fakespi) Write(send []byte) error     { this.sent = send; return nil }
+func (this *fakespi) Transfer(send []byte) ([]byte, error) {
+	this.sent = send
+	return this.recv[:len(send)], nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// REGISTERS
+
+var registers = []regmap.Register{
+	{Name: "WHO_AM_I", Address: 0x0F, Width: 8, Access: regmap.ACCESS_RO, Reset: 0x33},
+	{Name: "CTRL", Address: 0x20, Width: 8, Access: regmap.ACCESS_RW, Reset: 0x07, Fields: []regmap.Field{
+		{Name: "ODR", Offset: 4, Width: 4},
+		{Name: "LP", Offset: 3, Width: 1},
+		{Name: "EN", Offset: 0, Width: 3},
+	}},
+	{Name: "STATUS", Address: 0x27, Width: 8, Access: regmap.ACCESS_RW, Fields: []regmap.Field{
+		{Name: "READY", Offset: 3, Width: 1, Access: regmap.ACCESS_RO},
+	}},
+	{Name: "OUT", Address: 0x28, Width: 16, Access: regmap.ACCESS_RO},
+	{Name: "CMD", Address: 0x40, Width: 24, Access: regmap.ACCESS_WO, Reset: 0x010203, Fields: []regmap.Field{
+		{Name: "OP", Offset: 16, Width: 8},
+		{Name: "ARG", Offset: 0, Width: 16},
+	}},
+}
+
+func openRegisterMap(t *testing.T, config regmap.RegisterMap) regmap.RegisterMapDriver {
+	t.Helper()
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig()); err != nil {
+		t.Fatal(err)
+	} else if driver, err := gopi.Open(config, app.Logger); err != nil {
+		t.Fatal(err)
+	} else {
+		return driver.(regmap.RegisterMapDriver)
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestRegisterMap_000(t *testing.T) {
+	transport := &regmap.FakeTransport{}
+	transport.Poke(0x0F, []byte{0x33})
+	transport.Poke(0x28, []byte{0x34, 0x12})
+	driver := openRegisterMap(t, regmap.RegisterMap{Transport: transport, Registers: registers})
+	defer driver.Close()
+
+	// Read registers, with words least significant byte first
+	if value, err := driver.Read("WHO_AM_I"); err != nil {
+		t.Error(err)
+	} else if value != 0x33 {
+		t.Error("Unexpected WHO_AM_I value", value)
+	}
+	if value, err := driver.Read("OUT"); err != nil {
+		t.Error(err)
+	} else if value != 0x1234 {
+		t.Error("Unexpected OUT value", value)
+	}
+
+	// Access modes and names
+	if err := driver.Write("WHO_AM_I", 0); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if _, err := driver.Read("CMD"); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if _, err := driver.Read("MISSING"); err != gopi.ErrNotFound {
+		t.Error("Expected ErrNotFound, got", err)
+	} else if err := driver.WriteField("STATUS", "READY", 1); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if err := driver.Write("CTRL", 0x100); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func TestRegisterMap_001(t *testing.T) {
+	transport := &regmap.FakeTransport{}
+	driver := openRegisterMap(t, regmap.RegisterMap{Transport: transport, Registers: registers})
+	defer driver.Close()
+
+	// Reset writes CTRL, STATUS and CMD
+	if err := driver.Reset(); err != nil {
+		t.Fatal(err)
+	} else if value := transport.Peek(0x20, 1); value[0] != 0x07 {
+		t.Error("Unexpected CTRL value", value)
+	} else if value := transport.Peek(0x40, 3); bytes.Equal(value, []byte{0x03, 0x02, 0x01}) == false {
+		t.Error("Unexpected CMD value", value)
+	}
+
+	// Read-modify-write of fields keeps the other bits
+	if err := driver.WriteField("CTRL", "ODR", 0x9); err != nil {
+		t.Error(err)
+	} else if err := driver.WriteField("CTRL", "LP", 1); err != nil {
+		t.Error(err)
+	} else if value, err := driver.Read("CTRL"); err != nil {
+		t.Error(err)
+	} else if value != 0x9F {
+		t.Errorf("Unexpected CTRL value 0x%02X", value)
+	} else if value, err := driver.ReadField("CTRL", "EN"); err != nil {
+		t.Error(err)
+	} else if value != 0x7 {
+		t.Error("Unexpected EN value", value)
+	} else if err := driver.WriteField("CTRL", "LP", 2); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+
+	// Fields of write-only registers cannot be written without a cache
+	if err := driver.WriteField("CMD", "OP", 0xAA); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+}
+
+func TestRegisterMap_002(t *testing.T) {
+	// Big endian with write-only registers cached
+	transport := &regmap.FakeTransport{}
+	driver := openRegisterMap(t, regmap.RegisterMap{Transport: transport, Registers: registers, BigEndian: true, Cache: true})
+	defer driver.Close()
+
+	// The cache starts with the reset value
+	if value, err := driver.Read("CMD"); err != nil {
+		t.Error(err)
+	} else if value != 0x010203 {
+		t.Errorf("Unexpected CMD value 0x%06X", value)
+	}
+
+	// Field writes use the cache rather than reading
+	reads, _ := transport.Counts()
+	if err := driver.WriteField("CMD", "ARG", 0xBEEF); err != nil {
+		t.Error(err)
+	} else if value := transport.Peek(0x40, 3); bytes.Equal(value, []byte{0x01, 0xBE, 0xEF}) == false {
+		t.Error("Unexpected CMD value", value)
+	} else if value, err := driver.ReadField("CMD", "OP"); err != nil {
+		t.Error(err)
+	} else if value != 0x01 {
+		t.Error("Unexpected OP value", value)
+	} else if reads_, _ := transport.Counts(); reads_ != reads {
+		t.Error("Unexpected reads of write-only register")
+	}
+
+	// The cache is not changed when a write fails
+	transport.SetError(gopi.ErrUnexpectedResponse)
+	if err := driver.Write("CMD", 0); err != gopi.ErrUnexpectedResponse {
+		t.Error("Expected ErrUnexpectedResponse, got", err)
+	}
+	transport.SetError(nil)
+	if value, err := driver.Read("CMD"); err != nil {
+		t.Error(err)
+	} else if value != 0x01BEEF {
+		t.Errorf("Unexpected CMD value 0x%06X", value)
+	}
+}
+
+func TestRegisterMap_003(t *testing.T) {
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig()); err != nil {
+		t.Fatal(err)
+	} else {
+		bad := [][]regmap.Register{
+			{{Name: "A", Width: 12, Access: regmap.ACCESS_RW}},
+			{{Name: "A", Width: 8}},
+			{{Name: "A", Width: 8, Access: regmap.ACCESS_RW, Reset: 0x100}},
+			{{Name: "A", Width: 8, Access: regmap.ACCESS_RW, Fields: []regmap.Field{{Name: "F", Offset: 6, Width: 3}}}},
+			{{Name: "A", Width: 8, Access: regmap.ACCESS_RW}, {Name: "A", Width: 8, Access: regmap.ACCESS_RW}},
+		}
+		for i, registers := range bad {
+			if _, err := gopi.Open(regmap.RegisterMap{Transport: &regmap.FakeTransport{}, Registers: registers}, app.Logger); err != gopi.ErrBadParameter {
+				t.Errorf("Test %v: expected ErrBadParameter, got %v", i, err)
+			}
+		}
+		if _, err := gopi.Open(regmap.RegisterMap{Registers: registers}, app.Logger); err != gopi.ErrBadParameter {
+			t.Error("Expected ErrBadParameter, got", err)
+		}
+	}
+}
+
+func TestSPITransport_000(t *testing.T) {
+	spi := &fakespi{recv: []byte{0xFF, 0x34, 0x12}}
+	transport := regmap.SPITransport{SPI: spi, ReadFlag: 0x80}
+	if data, err := transport.ReadRegister(0x28, 2); err != nil {
+		t.Error(err)
+	} else if bytes.Equal(data, []byte{0x34, 0x12}) == false {
+		t.Error("Unexpected data", data)
+	} else if bytes.Equal(spi.sent, []byte{0xA8, 0x00, 0x00}) == false {
+		t.Error("Unexpected transfer", spi.sent)
+	}
+	if err := transport.WriteRegister(0x20, []byte{0x97}); err != nil {
+		t.Error(err)
+	} else if bytes.Equal(spi.sent, []byte{0x20, 0x97}) == false {
+		t.Error("Unexpected write", spi.sent)
+	}
+}
+
+func TestI2CTransport_000(t *testing.T) {
+	app, err := gopi.NewAppInstance(gopi.NewAppConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus, err := gopi.Open(i2c.Mock{Slaves: []i2c.MockSlave{{Address: 0x19, Registers: []byte{0x0F: 0x33, 0x28: 0x34, 0x29: 0x12}}}}, app.Logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	// The slave is set before the first access
+	driver := openRegisterMap(t, regmap.RegisterMap{Transport: regmap.I2CTransport{I2C: bus.(gopi.I2C), Slave: 0x19}, Registers: registers})
+	defer driver.Close()
+	if value, err := driver.Read("WHO_AM_I"); err != nil {
+		t.Error(err)
+	} else if value != 0x33 {
+		t.Error("Unexpected WHO_AM_I value", value)
+	} else if value, err := driver.Read("OUT"); err != nil {
+		t.Error(err)
+	} else if value != 0x1234 {
+		t.Error("Unexpected OUT value", value)
+	}
+
+	// Multi-byte writes which are not words use a raw write
+	if err := driver.Write("CMD", 0x0A0B0C); err != nil {
+		t.Error(err)
+	} else if registers, err := bus.(i2c.I2CMockInterface).Registers(0x19); err != nil {
+		t.Error(err)
+	} else if bytes.Equal(registers[0x40:0x43], []byte{0x0C, 0x0B, 0x0A}) == false {
+		t.Error("Unexpected CMD registers", registers[0x40:0x43])
+	}
+}
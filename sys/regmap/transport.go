@@ -0,0 +1,139 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package regmap
+
+import (
+	"fmt"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	i2c "github.com/djthorpe/gopi-hw/sys/i2c"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// I2CTransport reads and writes registers using SMBus transactions.
+// Multi-byte registers are read with a block read, and written with a
+// word write or a raw write when the driver supports it
+type I2CTransport struct {
+	I2C gopi.I2C
+
+	// Slave address, which is set before each transaction when it
+	// differs from the current slave
+	Slave uint8
+}
+
+// SPITransport reads and writes registers by sending the register address
+// followed by the data in a single transfer
+type SPITransport struct {
+	SPI gopi.SPI
+
+	// Bits set in the address byte for reads and writes, which for
+	// many peripherals is 0x80 for reads
+	ReadFlag, WriteFlag uint8
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Maximum number of bytes in an SMBus block read
+	I2C_BLOCK_MAX = 32
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// I2C TRANSPORT
+
+func (this I2CTransport) ReadRegister(address uint8, length uint) ([]byte, error) {
+	if err := this.setSlave(); err != nil {
+		return nil, err
+	}
+	switch {
+	case length == 1:
+		if value, err := this.I2C.ReadUint8(address); err != nil {
+			return nil, err
+		} else {
+			return []byte{value}, nil
+		}
+	case length == 2:
+		if value, err := this.I2C.ReadUint16(address); err != nil {
+			return nil, err
+		} else {
+			return []byte{byte(value), byte(value >> 8)}, nil
+		}
+	case length > 0 && length <= I2C_BLOCK_MAX:
+		return this.I2C.ReadBlock(address, uint8(length))
+	default:
+		return nil, gopi.ErrBadParameter
+	}
+}
+
+func (this I2CTransport) WriteRegister(address uint8, data []byte) error {
+	if err := this.setSlave(); err != nil {
+		return err
+	}
+	switch {
+	case len(data) == 1:
+		return this.I2C.WriteUint8(address, data[0])
+	case len(data) == 2:
+		return this.I2C.WriteUint16(address, uint16(data[0])|uint16(data[1])<<8)
+	case len(data) == 0:
+		return gopi.ErrBadParameter
+	}
+	if driver, ok := this.I2C.(i2c.I2CInterface); ok {
+		return driver.Write(append([]byte{address}, data...))
+	} else {
+		return gopi.ErrNotImplemented
+	}
+}
+
+func (this I2CTransport) setSlave() error {
+	if this.I2C == nil {
+		return gopi.ErrBadParameter
+	} else if this.I2C.GetSlave() == this.Slave {
+		return nil
+	} else {
+		return this.I2C.SetSlave(this.Slave)
+	}
+}
+
+func (this I2CTransport) String() string {
+	return fmt.Sprintf("<hw.regmap.I2CTransport>{ slave=0x%02X }", this.Slave)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// SPI TRANSPORT
+
+func (this SPITransport) ReadRegister(address uint8, length uint) ([]byte, error) {
+	if this.SPI == nil || length == 0 {
+		return nil, gopi.ErrBadParameter
+	}
+	send := make([]byte, length+1)
+	send[0] = address | this.ReadFlag
+	if recv, err := this.SPI.Transfer(send); err != nil {
+		return nil, err
+	} else if uint(len(recv)) != length+1 {
+		return nil, gopi.ErrUnexpectedResponse
+	} else {
+		return recv[1:], nil
+	}
+}
+
+func (this SPITransport) WriteRegister(address uint8, data []byte) error {
+	if this.SPI == nil || len(data) == 0 {
+		return gopi.ErrBadParameter
+	}
+	return this.SPI.Write(append([]byte{address | this.WriteFlag}, data...))
+}
+
+func (this SPITransport) String() string {
+	return fmt.Sprintf("<hw.regmap.SPITransport>{ read_flag=0x%02X write_flag=0x%02X }", this.ReadFlag, this.WriteFlag)
+}
@@ -1,3 +1,5 @@
+// +build linux,!mock
+
 /*
   Go Language Raspberry Pi Interface
   (c) Copyright David Thorpe 2016-2017
@@ -0,0 +1,49 @@
+// +build mock
+
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package spi
+
+import (
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// INIT
+
+func init() {
+	// Register simulated SPI device in place of the hardware
+	gopi.RegisterModule(gopi.Module{
+		Name: "hw/spi",
+		Type: gopi.MODULE_TYPE_SPI,
+		Config: func(config *gopi.AppConfig) {
+			config.AppFlags.FlagUint("spi.bus", 0, "SPI Bus")
+			config.AppFlags.FlagUint("spi.slave", 0, "SPI Slave")
+			config.AppFlags.FlagUint("spi.delay", 0, "SPI Transfer delay in microseconds")
+			config.AppFlags.FlagBool("spi.loopback", false, "Return the bytes sent")
+		},
+		New: func(app *gopi.AppInstance) (gopi.Driver, error) {
+			bus, _ := app.AppFlags.GetUint("spi.bus")
+			slave, _ := app.AppFlags.GetUint("spi.slave")
+			delay, _ := app.AppFlags.GetUint16("spi.delay")
+			mode := SPIModeFlag(0)
+			if loopback, _ := app.AppFlags.GetBool("spi.loopback"); loopback {
+				mode |= SPI_LOOP
+			}
+			return gopi.Open(Mock{
+				Bus:   bus,
+				Slave: slave,
+				Delay: delay,
+				Mode:  mode,
+			}, app.Logger)
+		},
+	})
+}
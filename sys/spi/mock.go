@@ -0,0 +1,392 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package spi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Mock is the configuration for a simulated SPI device, which sends
+// transfers to a device callback and records every transfer
+type Mock struct {
+	// Bus and slave numbers, which are only used for display
+	Bus   uint
+	Slave uint
+
+	// Transfer delay between blocks, in microseconds
+	Delay uint16
+
+	// Initial mode flags, where SPI_LOOP returns the bytes sent
+	Mode SPIModeFlag
+
+	// Initial speed, or SPI_MOCK_SPEED_HZ when zero
+	MaxSpeedHz uint32
+
+	// Initial bits per word, or eight when zero
+	BitsPerWord uint8
+
+	// Device which responds to transfers, or nil to receive zeros
+	Device MockDevice
+}
+
+// MockDevice is called with the bytes sent while the chip select is
+// asserted, and returns the same number of bytes received. The Transfer
+// method of a gopi.SPI can be used as a device
+type MockDevice func(send []byte) ([]byte, error)
+
+// MockTransfer is a transfer recorded on a simulated device. Each segment
+// of a multi-segment transfer is recorded separately
+type MockTransfer struct {
+	// Bytes sent, which are zeros for a read
+	Tx []byte
+
+	// Bytes received
+	Rx []byte
+
+	// Mode, speed and bits per word of the transfer
+	Mode        SPIModeFlag
+	SpeedHz     uint32
+	BitsPerWord uint8
+
+	// Delay after the transfer in microseconds
+	Delay uint16
+
+	// Chip select deasserted after the transfer
+	CSChange bool
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
+// SPIMockInterface is implemented by the simulated device
+type SPIMockInterface interface {
+	SPIInterface
+
+	// Set the device which responds to transfers
+	SetDevice(MockDevice)
+
+	// Return and clear the recorded transfers
+	Transfers() []MockTransfer
+	ClearTransfers()
+}
+
+type mock struct {
+	log           gopi.Logger
+	bus           uint
+	slave         uint
+	mode          SPIModeFlag
+	speed_hz      uint32
+	bits_per_word uint8
+	delay_usec    uint16
+	device        MockDevice
+	transfers     []MockTransfer
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Default speed of a simulated device
+	SPI_MOCK_SPEED_HZ = 500000
+
+	// Maximum bits per word of a simulated device
+	SPI_MOCK_BITS_MAX = 32
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open
+func (config Mock) Open(log gopi.Logger) (gopi.Driver, error) {
+	log.Debug("<sys.hw.mock.SPI>Open{ bus=%v slave=%v delay=%vus mode=%v }", config.Bus, config.Slave, config.Delay, config.Mode)
+
+	this := new(mock)
+	this.log = log
+	this.bus = config.Bus
+	this.slave = config.Slave
+	this.delay_usec = config.Delay
+	this.device = config.Device
+	this.transfers = make([]MockTransfer, 0)
+	if err := checkModeFlags(config.Mode); err != nil {
+		return nil, err
+	} else {
+		this.mode = config.Mode
+	}
+	if this.speed_hz = config.MaxSpeedHz; this.speed_hz == 0 {
+		this.speed_hz = SPI_MOCK_SPEED_HZ
+	}
+	if this.bits_per_word = config.BitsPerWord; this.bits_per_word == 0 {
+		this.bits_per_word = 8
+	} else if this.bits_per_word > SPI_MOCK_BITS_MAX {
+		return nil, gopi.ErrBadParameter
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close
+func (this *mock) Close() error {
+	this.log.Debug("<sys.hw.mock.SPI>Close")
+
+	this.Lock()
+	defer this.Unlock()
+	this.device = nil
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *mock) String() string {
+	this.Lock()
+	defer this.Unlock()
+	return fmt.Sprintf("<sys.hw.mock.SPI>{ bus=%v slave=%v mode=%v delay=%vus max_speed=%vHz bits_per_word=%v transfers=%v }", this.bus, this.slave, this.mode, this.delay_usec, this.speed_hz, this.bits_per_word, len(this.transfers))
+}
+
+func (t MockTransfer) String() string {
+	return fmt.Sprintf("<hw.spi.MockTransfer>{ tx=%v rx=%v mode=%v speed_hz=%v bits_per_word=%v delay=%vus cs_change=%v }", strings.ToUpper(hex.EncodeToString(t.Tx)), strings.ToUpper(hex.EncodeToString(t.Rx)), t.Mode, t.SpeedHz, t.BitsPerWord, t.Delay, t.CSChange)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// DEVICE AND TRANSFERS
+
+func (this *mock) SetDevice(device MockDevice) {
+	this.Lock()
+	defer this.Unlock()
+	this.device = device
+}
+
+func (this *mock) Transfers() []MockTransfer {
+	this.Lock()
+	defer this.Unlock()
+	return append([]MockTransfer{}, this.transfers...)
+}
+
+func (this *mock) ClearTransfers() {
+	this.Lock()
+	defer this.Unlock()
+	this.transfers = this.transfers[:0]
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GET AND SET PARAMETERS
+
+func (this *mock) Mode() gopi.SPIMode {
+	this.Lock()
+	defer this.Unlock()
+	return this.mode.Mode()
+}
+
+func (this *mock) ModeFlags() SPIModeFlag {
+	this.Lock()
+	defer this.Unlock()
+	return this.mode
+}
+
+func (this *mock) MaxSpeedHz() uint32 {
+	this.Lock()
+	defer this.Unlock()
+	return this.speed_hz
+}
+
+func (this *mock) BitsPerWord() uint8 {
+	this.Lock()
+	defer this.Unlock()
+	return this.bits_per_word
+}
+
+// SetMode sets the clock polarity and phase, keeping the other mode flags
+func (this *mock) SetMode(mode gopi.SPIMode) error {
+	this.log.Debug2("<sys.hw.mock.SPI.SetMode>{ mode=%v }", mode)
+	if SPIModeFlag(mode)&^SPI_MODE_MASK != 0 {
+		return gopi.ErrBadParameter
+	}
+	this.Lock()
+	defer this.Unlock()
+	this.mode = this.mode&^SPI_MODE_MASK | SPIModeFlag(mode)
+	return nil
+}
+
+func (this *mock) SetModeFlags(flags SPIModeFlag) error {
+	this.log.Debug2("<sys.hw.mock.SPI.SetModeFlags>{ flags=%v }", flags)
+	if err := checkModeFlags(flags); err != nil {
+		return err
+	}
+	this.Lock()
+	defer this.Unlock()
+	this.mode = flags
+	return nil
+}
+
+func (this *mock) SetMaxSpeedHz(speed uint32) error {
+	this.log.Debug2("<sys.hw.mock.SPI.SetMaxSpeedHz>{ speed=%v }", speed)
+	if speed == 0 {
+		return gopi.ErrBadParameter
+	}
+	this.Lock()
+	defer this.Unlock()
+	this.speed_hz = speed
+	return nil
+}
+
+func (this *mock) SetBitsPerWord(bits uint8) error {
+	this.log.Debug2("<sys.hw.mock.SPI.SetBitsPerWord>{ bits=%v }", bits)
+	if bits == 0 || bits > SPI_MOCK_BITS_MAX {
+		return gopi.ErrBadParameter
+	}
+	this.Lock()
+	defer this.Unlock()
+	this.bits_per_word = bits
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// TRANSFER
+
+func (this *mock) Transfer(send []byte) ([]byte, error) {
+	this.log.Debug2("<sys.hw.mock.SPI.Transfer>{ send=%v }", strings.ToUpper(hex.EncodeToString(send)))
+	if len(send) == 0 {
+		return []byte{}, nil
+	}
+	segment := SPISegment{Tx: send, Rx: make([]byte, len(send)), Delay: this.delay_usec}
+	if err := this.transfer([]SPISegment{segment}); err != nil {
+		return nil, err
+	} else {
+		return segment.Rx, nil
+	}
+}
+
+func (this *mock) Read(buffer_size uint32) ([]byte, error) {
+	this.log.Debug2("<sys.hw.mock.SPI.Read>{ buffer_size=%v }", buffer_size)
+	if buffer_size == 0 {
+		return []byte{}, nil
+	}
+	segment := SPISegment{Rx: make([]byte, buffer_size), Delay: this.delay_usec}
+	if err := this.transfer([]SPISegment{segment}); err != nil {
+		return nil, err
+	} else {
+		return segment.Rx, nil
+	}
+}
+
+func (this *mock) Write(send []byte) error {
+	this.log.Debug2("<sys.hw.mock.SPI.Write>{ send=%v }", strings.ToUpper(hex.EncodeToString(send)))
+	if len(send) == 0 {
+		return nil
+	}
+	return this.transfer([]SPISegment{{Tx: send, Delay: this.delay_usec}})
+}
+
+// TransferSegments calls the device once for each group of segments
+// sent while the chip select is asserted
+func (this *mock) TransferSegments(segments []SPISegment) error {
+	this.log.Debug2("<sys.hw.mock.SPI.TransferSegments>{ segments=%v }", segments)
+	if len(segments) == 0 || len(segments) > SPI_MESSAGE_MAX {
+		return gopi.ErrBadParameter
+	}
+	for _, segment := range segments {
+		if segment.Tx != nil && segment.Rx != nil && len(segment.Tx) != len(segment.Rx) {
+			return gopi.ErrBadParameter
+		} else if segment.TxWidth.valid() == false || segment.RxWidth.valid() == false {
+			return gopi.ErrBadParameter
+		} else if segment.BitsPerWord > SPI_MOCK_BITS_MAX {
+			return gopi.ErrBadParameter
+		}
+	}
+	return this.transfer(segments)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func checkModeFlags(flags SPIModeFlag) error {
+	if flags > SPI_FLAG_MAX<<1-1 {
+		return gopi.ErrBadParameter
+	} else if flags&(SPI_TX_DUAL|SPI_TX_QUAD) == SPI_TX_DUAL|SPI_TX_QUAD || flags&(SPI_RX_DUAL|SPI_RX_QUAD) == SPI_RX_DUAL|SPI_RX_QUAD {
+		return gopi.ErrBadParameter
+	} else {
+		return nil
+	}
+}
+
+// transfer sends the segments to the device, or back to the receive
+// buffers in loopback mode, and records them
+func (this *mock) transfer(segments []SPISegment) error {
+	this.Lock()
+	defer this.Unlock()
+
+	start := 0
+	for i, segment := range segments {
+		if segment.CSChange == false && i < len(segments)-1 {
+			continue
+		}
+
+		// Send the bytes for the segments with the chip select asserted
+		send := []byte{}
+		for _, segment := range segments[start : i+1] {
+			if segment.Tx != nil {
+				send = append(send, segment.Tx...)
+			} else {
+				send = append(send, make([]byte, segment.Len())...)
+			}
+		}
+		recv := make([]byte, len(send))
+		if this.mode&SPI_LOOP != 0 {
+			copy(recv, send)
+		} else if this.device != nil {
+			if data, err := this.device(send); err != nil {
+				return err
+			} else if len(data) != len(send) {
+				return gopi.ErrUnexpectedResponse
+			} else {
+				copy(recv, data)
+			}
+		}
+
+		// Fill the receive buffers and record the segments
+		for _, segment := range segments[start : i+1] {
+			length := segment.Len()
+			tx, rx := send[:length], recv[:length]
+			send, recv = send[length:], recv[length:]
+			copy(segment.Rx, rx)
+			transfer := MockTransfer{
+				Tx:          append([]byte{}, tx...),
+				Rx:          append([]byte{}, rx...),
+				Mode:        this.mode,
+				SpeedHz:     segment.SpeedHz,
+				BitsPerWord: segment.BitsPerWord,
+				Delay:       segment.Delay,
+				CSChange:    segment.CSChange,
+			}
+			if transfer.SpeedHz == 0 {
+				transfer.SpeedHz = this.speed_hz
+			}
+			if transfer.BitsPerWord == 0 {
+				transfer.BitsPerWord = this.bits_per_word
+			}
+			this.transfers = append(this.transfers, transfer)
+		}
+		start = i + 1
+	}
+
+	// Success
+	return nil
+}
@@ -0,0 +1,123 @@
+package spi_test
+
+import (
+	"bytes"
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	spi "github.com/djthorpe/gopi-hw/sys/spi"
+	spiflash "github.com/djthorpe/gopi-hw/sys/spiflash"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func openMock(t *testing.T, config spi.Mock) spi.SPIMockInterface {
+	t.Helper()
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig()); err != nil {
+		t.Fatal(err)
+	} else if driver, err := gopi.Open(config, app.Logger); err != nil {
+		t.Fatal(err)
+	} else {
+		return driver.(spi.SPIMockInterface)
+	}
+	return nil
+}
+
+func TestMock_000(t *testing.T) {
+	device := openMock(t, spi.Mock{})
+	defer device.Close()
+
+	// Parameters
+	if device.Mode() != gopi.SPI_MODE_0 || device.BitsPerWord() != 8 || device.MaxSpeedHz() != spi.SPI_MOCK_SPEED_HZ {
+		t.Error("Unexpected parameters", device)
+	} else if err := device.SetModeFlags(spi.SPI_CS_HIGH); err != nil {
+		t.Error(err)
+	} else if err := device.SetMode(gopi.SPI_MODE_3); err != nil {
+		t.Error(err)
+	} else if device.ModeFlags() != spi.SPI_CS_HIGH|spi.SPI_CPOL|spi.SPI_CPHA {
+		t.Error("Unexpected mode flags", device.ModeFlags())
+	} else if err := device.SetBitsPerWord(0); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	} else if err := device.SetModeFlags(spi.SPI_TX_DUAL | spi.SPI_TX_QUAD); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+
+	// Without a device zeros are received, and in loopback mode the bytes
+	// sent are received
+	if recv, err := device.Transfer([]byte{0x9F, 0x01}); err != nil {
+		t.Error(err)
+	} else if bytes.Equal(recv, []byte{0x00, 0x00}) == false {
+		t.Error("Unexpected bytes", recv)
+	} else if err := device.SetModeFlags(spi.SPI_LOOP); err != nil {
+		t.Error(err)
+	} else if recv, err := device.Transfer([]byte{0x9F, 0x01}); err != nil {
+		t.Error(err)
+	} else if bytes.Equal(recv, []byte{0x9F, 0x01}) == false {
+		t.Error("Unexpected bytes", recv)
+	}
+
+	// Transfers are recorded with the settings
+	if transfers := device.Transfers(); len(transfers) != 2 {
+		t.Error("Unexpected transfers", transfers)
+	} else if transfers[0].Mode != spi.SPI_CS_HIGH|spi.SPI_MODE_MASK || transfers[1].Mode != spi.SPI_LOOP {
+		t.Error("Unexpected modes", transfers)
+	}
+}
+
+func TestMock_001(t *testing.T) {
+	// The device receives the bytes of each chip select
+	sent := [][]byte{}
+	device := openMock(t, spi.Mock{Device: func(send []byte) ([]byte, error) {
+		sent = append(sent, append([]byte{}, send...))
+		recv := make([]byte, len(send))
+		for i := range recv {
+			recv[i] = byte(len(sent))
+		}
+		return recv, nil
+	}})
+	defer device.Close()
+
+	rx := make([]byte, 2)
+	if err := device.TransferSegments([]spi.SPISegment{
+		{Tx: []byte{0x03, 0x00}, CSChange: true},
+		{Tx: []byte{0x0B}},
+		{Rx: rx, SpeedHz: 1000, Delay: 10},
+	}); err != nil {
+		t.Fatal(err)
+	} else if len(sent) != 2 || bytes.Equal(sent[0], []byte{0x03, 0x00}) == false || bytes.Equal(sent[1], []byte{0x0B, 0x00, 0x00}) == false {
+		t.Error("Unexpected bytes sent", sent)
+	} else if bytes.Equal(rx, []byte{0x02, 0x02}) == false {
+		t.Error("Unexpected bytes received", rx)
+	}
+
+	// Each segment is recorded
+	if transfers := device.Transfers(); len(transfers) != 3 {
+		t.Error("Unexpected transfers", transfers)
+	} else if transfers[0].CSChange == false || transfers[2].SpeedHz != 1000 || transfers[2].Delay != 10 || transfers[1].SpeedHz != spi.SPI_MOCK_SPEED_HZ {
+		t.Error("Unexpected transfers", transfers)
+	} else if bytes.Equal(transfers[2].Tx, []byte{0x00, 0x00}) == false {
+		t.Error("Unexpected transfer", transfers[2])
+	}
+	device.ClearTransfers()
+	if transfers := device.Transfers(); len(transfers) != 0 {
+		t.Error("Unexpected transfers", transfers)
+	}
+}
+
+func TestMock_002(t *testing.T) {
+	// A simulated flash chip as the device
+	chip := &spiflash.FakeFlash{ID: spiflash.JEDECID{Manufacturer: 0xEF, Device: 0x4014}, Size: 65536}
+	device := openMock(t, spi.Mock{Device: chip.Transfer})
+	defer device.Close()
+
+	if recv, err := device.Transfer([]byte{spiflash.FLASH_CMD_JEDEC_ID, 0, 0, 0}); err != nil {
+		t.Error(err)
+	} else if bytes.Equal(recv[1:], []byte{0xEF, 0x40, 0x14}) == false {
+		t.Error("Unexpected JEDEC ID", recv)
+	}
+}
@@ -0,0 +1,199 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2016-2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package spi
+
+import (
+	"fmt"
+	"strings"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// SPIModeFlag is the 32-bit mode of a device, which includes the clock
+// polarity and phase of gopi.SPIMode in the lowest two bits
+type SPIModeFlag uint32
+
+// SPIWidth is the number of data lines used to transfer a segment
+type SPIWidth uint8
+
+// SPISegment is a single segment of a transfer. All segments of a
+// transfer are sent while the chip select is asserted, unless CSChange
+// is set on a segment
+type SPISegment struct {
+	// Data to send, or nil to send zeros
+	Tx []byte
+
+	// Buffer to receive into, or nil to discard received data. When
+	// both Tx and Rx are set they must be the same length
+	Rx []byte
+
+	// Speed and bits per word for the segment, which are the driver
+	// settings when zero
+	SpeedHz     uint32
+	BitsPerWord uint8
+
+	// Delay after the segment in microseconds, before the chip select
+	// changes or the next segment starts
+	Delay uint16
+
+	// Deassert the chip select after this segment, or leave it asserted
+	// after the last segment
+	CSChange bool
+
+	// Number of data lines for sending and receiving, which are single
+	// when zero
+	TxWidth, RxWidth SPIWidth
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
+// SPIInterface is implemented by drivers which support multi-segment
+// transfers
+type SPIInterface interface {
+	gopi.SPI
+
+	// TransferSegments sends the segments as a single message, filling
+	// the receive buffers
+	TransferSegments([]SPISegment) error
+
+	// Return and set the 32-bit mode, including chip select, bit order,
+	// loopback and dual or quad transfers
+	ModeFlags() SPIModeFlag
+	SetModeFlags(SPIModeFlag) error
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	SPI_CPHA      SPIModeFlag = 0x0001 /* clock phase */
+	SPI_CPOL      SPIModeFlag = 0x0002 /* clock polarity */
+	SPI_CS_HIGH   SPIModeFlag = 0x0004 /* chip select active high */
+	SPI_LSB_FIRST SPIModeFlag = 0x0008 /* per-word bits-on-wire */
+	SPI_3WIRE     SPIModeFlag = 0x0010 /* SI/SO signals shared */
+	SPI_LOOP      SPIModeFlag = 0x0020 /* loopback mode */
+	SPI_NO_CS     SPIModeFlag = 0x0040 /* one device per bus, no chip select */
+	SPI_READY     SPIModeFlag = 0x0080 /* slave pulls low to pause */
+	SPI_TX_DUAL   SPIModeFlag = 0x0100 /* transmit with 2 wires */
+	SPI_TX_QUAD   SPIModeFlag = 0x0200 /* transmit with 4 wires */
+	SPI_RX_DUAL   SPIModeFlag = 0x0400 /* receive with 2 wires */
+	SPI_RX_QUAD   SPIModeFlag = 0x0800 /* receive with 4 wires */
+	SPI_MODE_MASK SPIModeFlag = SPI_CPHA | SPI_CPOL
+	SPI_FLAG_MAX  SPIModeFlag = SPI_RX_QUAD
+)
+
+const (
+	// Maximum number of segments in a message
+	SPI_MESSAGE_MAX = 511
+)
+
+const (
+	SPI_WIDTH_DEFAULT SPIWidth = 0
+	SPI_WIDTH_SINGLE  SPIWidth = 1
+	SPI_WIDTH_DUAL    SPIWidth = 2
+	SPI_WIDTH_QUAD    SPIWidth = 4
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (w SPIWidth) String() string {
+	switch w {
+	case SPI_WIDTH_DEFAULT:
+		return "SPI_WIDTH_DEFAULT"
+	case SPI_WIDTH_SINGLE:
+		return "SPI_WIDTH_SINGLE"
+	case SPI_WIDTH_DUAL:
+		return "SPI_WIDTH_DUAL"
+	case SPI_WIDTH_QUAD:
+		return "SPI_WIDTH_QUAD"
+	default:
+		return "[?? Invalid SPIWidth value]"
+	}
+}
+
+func (f SPIModeFlag) String() string {
+	if f == 0 {
+		return "SPI_MODE_0"
+	}
+	flags := ""
+	for flag := SPIModeFlag(1); flag != 0; flag <<= 1 {
+		if f&flag == 0 {
+			continue
+		}
+		switch flag {
+		case SPI_CPHA:
+			flags += "SPI_CPHA|"
+		case SPI_CPOL:
+			flags += "SPI_CPOL|"
+		case SPI_CS_HIGH:
+			flags += "SPI_CS_HIGH|"
+		case SPI_LSB_FIRST:
+			flags += "SPI_LSB_FIRST|"
+		case SPI_3WIRE:
+			flags += "SPI_3WIRE|"
+		case SPI_LOOP:
+			flags += "SPI_LOOP|"
+		case SPI_NO_CS:
+			flags += "SPI_NO_CS|"
+		case SPI_READY:
+			flags += "SPI_READY|"
+		case SPI_TX_DUAL:
+			flags += "SPI_TX_DUAL|"
+		case SPI_TX_QUAD:
+			flags += "SPI_TX_QUAD|"
+		case SPI_RX_DUAL:
+			flags += "SPI_RX_DUAL|"
+		case SPI_RX_QUAD:
+			flags += "SPI_RX_QUAD|"
+		default:
+			flags += "[?? Invalid SPIModeFlag value]|"
+		}
+	}
+	return strings.TrimSuffix(flags, "|")
+}
+
+func (s SPISegment) String() string {
+	return fmt.Sprintf("<hw.spi.Segment>{ len=%v speed_hz=%v bits_per_word=%v delay=%vus cs_change=%v tx_width=%v rx_width=%v }", s.Len(), s.SpeedHz, s.BitsPerWord, s.Delay, s.CSChange, s.TxWidth, s.RxWidth)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Mode returns the clock polarity and phase
+func (f SPIModeFlag) Mode() gopi.SPIMode {
+	return gopi.SPIMode(f & SPI_MODE_MASK)
+}
+
+// Len returns the number of bytes transferred in the segment
+func (s SPISegment) Len() int {
+	if len(s.Tx) > len(s.Rx) {
+		return len(s.Tx)
+	} else {
+		return len(s.Rx)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (w SPIWidth) valid() bool {
+	switch w {
+	case SPI_WIDTH_DEFAULT, SPI_WIDTH_SINGLE, SPI_WIDTH_DUAL, SPI_WIDTH_QUAD:
+		return true
+	default:
+		return false
+	}
+}
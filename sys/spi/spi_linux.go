@@ -13,6 +13,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"runtime"
 	"strings"
 	"sync"
 	"syscall"
@@ -57,15 +58,15 @@ type SPI struct {
 }
 
 type spi struct {
-	log           gopi.Logger  // logger
-	dev           *os.File     // device
-	bus           uint         // bus number
-	slave         uint         // slave number
-	mode          gopi.SPIMode // mode
-	speed_hz      uint32       // maximum speed in hertz
-	bits_per_word uint8        // bits per word
-	delay_usec    uint16       // Transfer delay
-	lock          sync.Mutex   // mutex lock
+	log           gopi.Logger // logger
+	dev           *os.File    // device
+	bus           uint        // bus number
+	slave         uint        // slave number
+	mode          SPIModeFlag // mode
+	speed_hz      uint32      // maximum speed in hertz
+	bits_per_word uint8       // bits per word
+	delay_usec    uint16      // Transfer delay
+	lock          sync.Mutex  // mutex lock
 }
 
 type spi_message struct {
@@ -127,7 +128,7 @@ func (config SPI) Open(log gopi.Logger) (gopi.Driver, error) {
 	}
 
 	// Get current mode, speed and bits per word
-	if mode, err := this.getMode(); err != nil {
+	if mode, err := this.getModeFlags(); err != nil {
 		return nil, err
 	} else {
 		this.mode = mode
@@ -167,6 +168,10 @@ func (this *spi) String() string {
 // GET AND SET PARAMETERS
 
 func (this *spi) Mode() gopi.SPIMode {
+	return this.mode.Mode()
+}
+
+func (this *spi) ModeFlags() SPIModeFlag {
 	return this.mode
 }
 
@@ -178,11 +183,24 @@ func (this *spi) BitsPerWord() uint8 {
 	return this.bits_per_word
 }
 
+// SetMode sets the clock polarity and phase, keeping the other mode flags.
+// The 32-bit mode is written, or the 8-bit mode for kernels which do not
+// support writing the 32-bit mode
 func (this *spi) SetMode(mode gopi.SPIMode) error {
 	this.log.Debug2("<sys.hw.linux.SPI.SetMode>{ mode=%v }", mode)
-	if err := this.spi_ioctl(this.dev.Fd(), SPI_IOC_WR_MODE, unsafe.Pointer(&mode)); err != 0 {
+	if SPIModeFlag(mode)&^SPI_MODE_MASK != 0 {
+		return gopi.ErrBadParameter
+	}
+	value32 := uint32(this.mode&^SPI_MODE_MASK) | uint32(mode)
+	value := uint8(value32)
+	if err := this.spi_ioctl(this.dev.Fd(), SPI_IOC_WR_MODE32, unsafe.Pointer(&value32)); err != 0 && err != syscall.ENOTTY {
 		return os.NewSyscallError("SetMode", err)
-	} else if mode, err := this.getMode(); err != nil {
+	} else if err == syscall.ENOTTY {
+		if err := this.spi_ioctl(this.dev.Fd(), SPI_IOC_WR_MODE, unsafe.Pointer(&value)); err != 0 {
+			return os.NewSyscallError("SetMode", err)
+		}
+	}
+	if mode, err := this.getModeFlags(); err != nil {
 		return err
 	} else {
 		this.mode = mode
@@ -190,6 +208,27 @@ func (this *spi) SetMode(mode gopi.SPIMode) error {
 	}
 }
 
+// SetModeFlags sets the 32-bit mode. The mode is read back, and an
+// error is returned if the controller did not accept every flag
+func (this *spi) SetModeFlags(flags SPIModeFlag) error {
+	this.log.Debug2("<sys.hw.linux.SPI.SetModeFlags>{ flags=%v }", flags)
+	if flags > SPI_FLAG_MAX<<1-1 {
+		return gopi.ErrBadParameter
+	} else if flags&(SPI_TX_DUAL|SPI_TX_QUAD) == SPI_TX_DUAL|SPI_TX_QUAD || flags&(SPI_RX_DUAL|SPI_RX_QUAD) == SPI_RX_DUAL|SPI_RX_QUAD {
+		return gopi.ErrBadParameter
+	}
+	value := uint32(flags)
+	if err := this.spi_ioctl(this.dev.Fd(), SPI_IOC_WR_MODE32, unsafe.Pointer(&value)); err != 0 {
+		return os.NewSyscallError("SetModeFlags", err)
+	} else if mode, err := this.getModeFlags(); err != nil {
+		return err
+	} else if this.mode = mode; mode != flags {
+		return gopi.ErrNotImplemented
+	} else {
+		return nil
+	}
+}
+
 func (this *spi) SetMaxSpeedHz(speed uint32) error {
 	this.log.Debug2("<sys.hw.linux.SPI.SetMaxSpeedHz>{ speed=%v }", speed)
 	if err := this.spi_ioctl(this.dev.Fd(), SPI_IOC_WR_MAX_SPEED_HZ, unsafe.Pointer(&speed)); err != 0 {
@@ -282,15 +321,74 @@ func (this *spi) Write(send []byte) error {
 	}
 }
 
+// TransferSegments sends segments as a single message with the chip
+// select asserted, except where a segment sets CSChange. The driver
+// transfer delay is not used, and each segment sets its own delay
+func (this *spi) TransferSegments(segments []SPISegment) error {
+	this.log.Debug2("<sys.hw.linux.SPI.TransferSegments>{ segments=%v }", segments)
+	if messages, err := spi_messages(segments, this.speed_hz, this.bits_per_word); err != nil {
+		return err
+	} else if err := this.spi_ioctl(this.dev.Fd(), uintptr(C._SPI_IOC_MESSAGE(C.int(len(messages)))), unsafe.Pointer(&messages[0])); err != 0 {
+		return os.NewSyscallError("TransferSegments", err)
+	} else {
+		runtime.KeepAlive(segments)
+		return nil
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
-func (this *spi) getMode() (gopi.SPIMode, error) {
+// spi_messages converts segments to the kernel representation, using the
+// driver speed and bits per word where a segment does not set them
+func spi_messages(segments []SPISegment, speed_hz uint32, bits_per_word uint8) ([]spi_message, error) {
+	if len(segments) == 0 || len(segments) > SPI_MESSAGE_MAX {
+		return nil, gopi.ErrBadParameter
+	}
+	messages := make([]spi_message, len(segments))
+	for i, segment := range segments {
+		if segment.Tx != nil && segment.Rx != nil && len(segment.Tx) != len(segment.Rx) {
+			return nil, gopi.ErrBadParameter
+		} else if segment.TxWidth.valid() == false || segment.RxWidth.valid() == false {
+			return nil, gopi.ErrBadParameter
+		}
+		message := &messages[i]
+		message.len = uint32(segment.Len())
+		if len(segment.Tx) > 0 {
+			message.tx_buf = uint64(uintptr(unsafe.Pointer(&segment.Tx[0])))
+		}
+		if len(segment.Rx) > 0 {
+			message.rx_buf = uint64(uintptr(unsafe.Pointer(&segment.Rx[0])))
+		}
+		if message.speed_hz = segment.SpeedHz; message.speed_hz == 0 {
+			message.speed_hz = speed_hz
+		}
+		if message.bits_per_word = segment.BitsPerWord; message.bits_per_word == 0 {
+			message.bits_per_word = bits_per_word
+		}
+		message.delay_usecs = segment.Delay
+		if segment.CSChange {
+			message.cs_change = 1
+		}
+		message.tx_nbits = uint8(segment.TxWidth)
+		message.rx_nbits = uint8(segment.RxWidth)
+	}
+	return messages, nil
+}
+
+// getModeFlags returns the 32-bit mode, or the 8-bit mode for kernels
+// which do not support reading the 32-bit mode
+func (this *spi) getModeFlags() (SPIModeFlag, error) {
+	var mode32 uint32
 	var mode uint8
-	if err := this.spi_ioctl(this.dev.Fd(), SPI_IOC_RD_MODE, unsafe.Pointer(&mode)); err != 0 {
-		return gopi.SPI_MODE_NONE, os.NewSyscallError("spi_ioctl", err)
+	if err := this.spi_ioctl(this.dev.Fd(), SPI_IOC_RD_MODE32, unsafe.Pointer(&mode32)); err == 0 {
+		return SPIModeFlag(mode32), nil
+	} else if err != syscall.ENOTTY {
+		return 0, os.NewSyscallError("spi_ioctl", err)
+	} else if err := this.spi_ioctl(this.dev.Fd(), SPI_IOC_RD_MODE, unsafe.Pointer(&mode)); err != 0 {
+		return 0, os.NewSyscallError("spi_ioctl", err)
 	} else {
-		return gopi.SPIMode(mode), nil
+		return SPIModeFlag(mode), nil
 	}
 }
 
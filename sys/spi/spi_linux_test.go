@@ -0,0 +1,67 @@
+package spi
+
+import (
+	"testing"
+	"unsafe"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func TestSegments_000(t *testing.T) {
+	// Command with chip select held, then data read at a lower speed
+	// on four lines
+	cmd := []byte{0x6B, 0x00, 0x10, 0x00, 0x00}
+	data := make([]byte, 16)
+	messages, err := spi_messages([]SPISegment{
+		{Tx: cmd},
+		{Rx: data, SpeedHz: 1000000, RxWidth: SPI_WIDTH_QUAD, Delay: 10, CSChange: true},
+	}, 8000000, 8)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(messages) != 2 {
+		t.Fatal("Unexpected number of messages", len(messages))
+	}
+	if m := messages[0]; m.len != 5 || m.tx_buf != uint64(uintptr(unsafe.Pointer(&cmd[0]))) || m.rx_buf != 0 {
+		t.Error("Unexpected command message", m)
+	} else if m.speed_hz != 8000000 || m.bits_per_word != 8 || m.cs_change != 0 || m.delay_usecs != 0 {
+		t.Error("Unexpected command settings", m)
+	}
+	if m := messages[1]; m.len != 16 || m.tx_buf != 0 || m.rx_buf != uint64(uintptr(unsafe.Pointer(&data[0]))) {
+		t.Error("Unexpected data message", m)
+	} else if m.speed_hz != 1000000 || m.rx_nbits != 4 || m.tx_nbits != 0 || m.cs_change != 1 || m.delay_usecs != 10 {
+		t.Error("Unexpected data settings", m)
+	}
+}
+
+func TestSegments_001(t *testing.T) {
+	tests := [][]SPISegment{
+		{},
+		{{Tx: make([]byte, 2), Rx: make([]byte, 3)}},
+		{{Tx: make([]byte, 2), TxWidth: 3}},
+		make([]SPISegment, SPI_MESSAGE_MAX+1),
+	}
+	for i, segments := range tests {
+		if _, err := spi_messages(segments, 0, 8); err != gopi.ErrBadParameter {
+			t.Errorf("Test %v: expected ErrBadParameter, got %v", i, err)
+		}
+	}
+	if unsafe.Sizeof(spi_message{}) != 32 {
+		t.Error("Unexpected size of spi_message", unsafe.Sizeof(spi_message{}))
+	}
+}
+
+func TestModeFlag_000(t *testing.T) {
+	if str := SPIModeFlag(0).String(); str != "SPI_MODE_0" {
+		t.Error("Unexpected string", str)
+	}
+	if str := (SPI_CPOL | SPI_CS_HIGH | SPI_RX_QUAD).String(); str != "SPI_CPOL|SPI_CS_HIGH|SPI_RX_QUAD" {
+		t.Error("Unexpected string", str)
+	}
+	if mode := (SPI_CPOL | SPI_CPHA | SPI_LSB_FIRST).Mode(); mode != gopi.SPI_MODE_3 {
+		t.Error("Unexpected mode", mode)
+	}
+}
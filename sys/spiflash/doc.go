@@ -0,0 +1,34 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+// This package provides a driver for SPI NOR flash chips, such as the
+// Winbond W25Q and Macronix MX25L series, using the common JEDEC
+// command set on a gopi.SPI device.
+//
+// When the driver is opened, the JEDEC ID is read and the size of the
+// chip is determined from the SFDP (Serial Flash Discoverable
+// Parameters) table, or from the capacity byte of the JEDEC ID when the
+// chip has no SFDP table:
+//
+//    driver, err := gopi.Open(spiflash.Flash{ SPI: app.SPI }, app.Logger)
+//    flash := driver.(spiflash.SPIFlash)
+//    err = flash.Erase(0, flash.SectorSize())
+//    n, err := flash.WriteAt([]byte("config"), 0)
+//
+// WriteAt programs pages without crossing page boundaries, and can only
+// change bits from one to zero, so sectors need to be erased before they
+// are written. The EraseWriter adapter reads, erases and programs whole
+// sectors so that any data can be written. Erase and program operations
+// poll the status register until the chip is no longer busy.
+//
+// The block protect bits of the status register control write
+// protection. A FakeFlash simulates a flash chip in memory for testing
+// without hardware.
+//
+package spiflash
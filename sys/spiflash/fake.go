@@ -0,0 +1,242 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package spiflash
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// FakeFlash implements gopi.SPI and simulates a flash chip in memory,
+// which starts erased. Page programs wrap around within a page and only
+// change bits from one to zero, as on a real chip. When any of the block
+// protect bits are set the whole chip is protected
+type FakeFlash struct {
+	// Identifier returned by the JEDEC ID command
+	ID JEDECID
+
+	// Size of the chip in bytes, which is a multiple of the block size
+	Size int64
+
+	// Return an SFDP table with the size of the chip
+	SFDP bool
+
+	// Number of status register reads which return busy after each
+	// program or erase
+	BusyPolls uint
+
+	// Ignore writes to the status register, as when the write protect
+	// pin is asserted
+	Locked bool
+
+	memory []byte
+	status Status
+	busy   uint
+	counts map[uint8]uint
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Address of the basic parameter table in the fake SFDP data
+	fake_sfdp_table = 0x30
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *FakeFlash) String() string {
+	return fmt.Sprintf("<hw.spiflash.FakeFlash>{ id=%v size=%v sfdp=%v status=%v }", this.ID, this.Size, this.SFDP, this.status)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// gopi.SPI
+
+func (this *FakeFlash) Close() error                { return nil }
+func (this *FakeFlash) Mode() gopi.SPIMode          { return gopi.SPI_MODE_0 }
+func (this *FakeFlash) MaxSpeedHz() uint32          { return 0 }
+func (this *FakeFlash) BitsPerWord() uint8          { return 8 }
+func (this *FakeFlash) SetMode(gopi.SPIMode) error  { return nil }
+func (this *FakeFlash) SetMaxSpeedHz(uint32) error  { return nil }
+func (this *FakeFlash) SetBitsPerWord(uint8) error  { return nil }
+func (this *FakeFlash) Read(uint32) ([]byte, error) { return nil, gopi.ErrNotImplemented }
+
+func (this *FakeFlash) Write(send []byte) error {
+	_, err := this.Transfer(send)
+	return err
+}
+
+// Transfer runs a command and returns the bytes the chip sends
+func (this *FakeFlash) Transfer(send []byte) ([]byte, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.init()
+	recv := make([]byte, len(send))
+	for i := range recv {
+		recv[i] = 0xFF
+	}
+	if len(send) == 0 {
+		return recv, nil
+	}
+
+	// Commands other than reading the status are ignored when busy
+	cmd := send[0]
+	this.counts[cmd]++
+	if cmd == FLASH_CMD_READ_STATUS {
+		status := this.status
+		if this.busy > 0 {
+			status |= FLASH_STATUS_BUSY
+			this.busy--
+		}
+		for i := 1; i < len(recv); i++ {
+			recv[i] = uint8(status)
+		}
+		return recv, nil
+	} else if this.busy > 0 {
+		return recv, nil
+	}
+
+	switch cmd {
+	case FLASH_CMD_JEDEC_ID:
+		copy(recv[1:], []byte{this.ID.Manufacturer, uint8(this.ID.Device >> 8), uint8(this.ID.Device)})
+	case FLASH_CMD_WRITE_ENABLE:
+		this.status |= FLASH_STATUS_WEL
+	case FLASH_CMD_WRITE_DISABLE:
+		this.status &^= FLASH_STATUS_WEL
+	case FLASH_CMD_WRITE_STATUS:
+		if len(send) > 1 && this.writable() && this.Locked == false {
+			this.status = this.status&^(FLASH_STATUS_BP|FLASH_STATUS_SRWD) | Status(send[1])&(FLASH_STATUS_BP|FLASH_STATUS_SRWD)
+			this.busy = this.BusyPolls
+		}
+		this.status &^= FLASH_STATUS_WEL
+	case FLASH_CMD_READ:
+		if address, ok := this.address(send); ok {
+			for i := 4; i < len(send); i++ {
+				recv[i] = this.memory[(address+int64(i-4))%this.Size]
+			}
+		}
+	case FLASH_CMD_READ_SFDP:
+		if address, ok := this.address(send); ok && this.SFDP {
+			table := this.sfdp()
+			for i := 5; i < len(send); i++ {
+				if offset := address + int64(i-5); offset < int64(len(table)) {
+					recv[i] = table[offset]
+				}
+			}
+		}
+	case FLASH_CMD_PAGE_PROGRAM:
+		if address, ok := this.address(send); ok && this.writable() && this.status.BlockProtect() == 0 {
+			page := address &^ (FLASH_PAGE_SIZE - 1)
+			for i, value := range send[4:] {
+				this.memory[page+(address+int64(i))%FLASH_PAGE_SIZE] &= value
+			}
+			this.busy = this.BusyPolls
+		}
+		this.status &^= FLASH_STATUS_WEL
+	case FLASH_CMD_SECTOR_ERASE, FLASH_CMD_BLOCK_ERASE:
+		if address, ok := this.address(send); ok && this.writable() && this.status.BlockProtect() == 0 {
+			size := int64(FLASH_SECTOR_SIZE)
+			if cmd == FLASH_CMD_BLOCK_ERASE {
+				size = FLASH_BLOCK_SIZE
+			}
+			this.fill(address&^(size-1), size)
+			this.busy = this.BusyPolls
+		}
+		this.status &^= FLASH_STATUS_WEL
+	case FLASH_CMD_CHIP_ERASE:
+		if this.writable() && this.status.BlockProtect() == 0 {
+			this.fill(0, this.Size)
+			this.busy = this.BusyPolls
+		}
+		this.status &^= FLASH_STATUS_WEL
+	}
+
+	return recv, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Peek returns bytes from memory
+func (this *FakeFlash) Peek(offset, length int64) []byte {
+	this.Lock()
+	defer this.Unlock()
+	this.init()
+	return append([]byte{}, this.memory[offset:offset+length]...)
+}
+
+// Poke sets bytes in memory without programming
+func (this *FakeFlash) Poke(offset int64, data []byte) {
+	this.Lock()
+	defer this.Unlock()
+	this.init()
+	copy(this.memory[offset:], data)
+}
+
+// Count returns the number of times a command has been sent
+func (this *FakeFlash) Count(cmd uint8) uint {
+	this.Lock()
+	defer this.Unlock()
+	return this.counts[cmd]
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *FakeFlash) init() {
+	if this.memory == nil {
+		this.memory = make([]byte, this.Size)
+		this.fill(0, this.Size)
+		this.counts = make(map[uint8]uint)
+	}
+}
+
+func (this *FakeFlash) fill(offset, length int64) {
+	for i := offset; i < offset+length && i < this.Size; i++ {
+		this.memory[i] = 0xFF
+	}
+}
+
+func (this *FakeFlash) writable() bool {
+	return this.status&FLASH_STATUS_WEL != 0
+}
+
+func (this *FakeFlash) address(send []byte) (int64, bool) {
+	if len(send) < 4 {
+		return 0, false
+	} else {
+		return (int64(send[1])<<16 | int64(send[2])<<8 | int64(send[3])) % this.Size, true
+	}
+}
+
+// sfdp returns the SFDP header, a parameter header and the first two
+// words of the basic parameter table, with four kilobyte erase
+func (this *FakeFlash) sfdp() []byte {
+	table := make([]byte, fake_sfdp_table+8)
+	for i := range table {
+		table[i] = 0xFF
+	}
+	binary.LittleEndian.PutUint32(table[0:], FLASH_SFDP_SIGNATURE)
+	copy(table[4:], []byte{0x06, 0x01, 0x00, 0xFF})
+	copy(table[8:], []byte{0x00, 0x06, 0x01, 0x02, fake_sfdp_table, 0x00, 0x00, 0xFF})
+	binary.LittleEndian.PutUint32(table[fake_sfdp_table:], 0xFFF100E5|uint32(FLASH_CMD_SECTOR_ERASE)<<8)
+	binary.LittleEndian.PutUint32(table[fake_sfdp_table+4:], uint32(this.Size*8-1))
+	return table
+}
@@ -0,0 +1,450 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package spiflash
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Flash is the configuration for a flash chip on an SPI device
+type Flash struct {
+	// SPI device the chip is connected to
+	SPI gopi.SPI
+
+	// Size of the chip in bytes, or zero to read the size from the chip
+	Size int64
+}
+
+type flash struct {
+	log        gopi.Logger
+	spi        gopi.SPI
+	id         JEDECID
+	size       int64
+	sectorsize int64
+	erase      uint8
+	sync.Mutex
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	// Timeouts for programming a page, erasing a sector or block and
+	// erasing the whole chip
+	FLASH_TIMEOUT_PROGRAM = 100 * time.Millisecond
+	FLASH_TIMEOUT_ERASE   = 5 * time.Second
+	FLASH_TIMEOUT_CHIP    = 400 * time.Second
+
+	// Interval between reads of the status register
+	FLASH_POLL_INTERVAL = 100 * time.Microsecond
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// OPEN AND CLOSE
+
+// Open
+func (config Flash) Open(logger gopi.Logger) (gopi.Driver, error) {
+	logger.Debug("<hw.spiflash>Open{ size=%v }", config.Size)
+
+	this := new(flash)
+	this.log = logger
+	this.sectorsize = FLASH_SECTOR_SIZE
+	this.erase = FLASH_CMD_SECTOR_ERASE
+
+	// SPI device is required
+	if config.SPI == nil {
+		return nil, gopi.ErrBadParameter
+	} else {
+		this.spi = config.SPI
+	}
+
+	// Read the JEDEC ID, where no chip returns all zeros or all ones
+	if id, err := this.jedecid(); err != nil {
+		return nil, err
+	} else if id.Manufacturer == 0x00 || id.Manufacturer == 0xFF {
+		logger.Error("<hw.spiflash>Open: No flash chip found")
+		return nil, gopi.ErrNotFound
+	} else {
+		this.id = id
+	}
+
+	// Determine the size and sector size from SFDP, or the size from the
+	// capacity byte of the JEDEC ID
+	if size, err := this.sfdp(); err != nil {
+		return nil, err
+	} else if config.Size != 0 {
+		this.size = config.Size
+	} else if size != 0 {
+		this.size = size
+	} else if capacity := uint(this.id.Device & 0xFF); capacity >= 16 && capacity <= 32 {
+		this.size = int64(1) << capacity
+	} else {
+		logger.Error("<hw.spiflash>Open: Unable to determine size of %v", this.id)
+		return nil, gopi.ErrUnexpectedResponse
+	}
+
+	// Four byte addresses are not supported
+	if this.size <= 0 || this.size%this.sectorsize != 0 {
+		return nil, gopi.ErrBadParameter
+	} else if this.size > FLASH_SIZE_MAX {
+		logger.Error("<hw.spiflash>Open: Chips larger than %v bytes are not supported", FLASH_SIZE_MAX)
+		return nil, gopi.ErrNotImplemented
+	}
+
+	// Success
+	return this, nil
+}
+
+// Close
+func (this *flash) Close() error {
+	this.log.Debug("<hw.spiflash>Close{ id=%v }", this.id)
+
+	// Zero out member variables
+	this.spi = nil
+
+	// Return success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (this *flash) String() string {
+	return fmt.Sprintf("<hw.spiflash>{ id=%v size=%v sector_size=%v }", this.id, this.size, this.sectorsize)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES
+
+func (this *flash) JEDECID() JEDECID {
+	return this.id
+}
+
+func (this *flash) Size() int64 {
+	return this.size
+}
+
+func (this *flash) PageSize() int64 {
+	return FLASH_PAGE_SIZE
+}
+
+func (this *flash) SectorSize() int64 {
+	return this.sectorsize
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// READ AND WRITE
+
+// ReadAt reads bytes from an offset, returning io.EOF when the end of
+// the chip is reached
+func (this *flash) ReadAt(p []byte, offset int64) (int, error) {
+	this.log.Debug2("<hw.spiflash>ReadAt{ offset=0x%06X length=%v }", offset, len(p))
+
+	this.Lock()
+	defer this.Unlock()
+
+	if offset < 0 {
+		return 0, gopi.ErrBadParameter
+	} else if offset >= this.size {
+		return 0, io.EOF
+	}
+
+	// Read in chunks which fit into a transfer
+	n := 0
+	for n < len(p) && offset < this.size {
+		length := min(int64(len(p)-n), this.size-offset, FLASH_TRANSFER_MAX-4)
+		if data, err := this.command(FLASH_CMD_READ, offset, nil, int(length)); err != nil {
+			return n, err
+		} else {
+			n += copy(p[n:], data)
+			offset += length
+		}
+	}
+	if n < len(p) {
+		return n, io.EOF
+	} else {
+		return n, nil
+	}
+}
+
+// WriteAt programs bytes at an offset, one page at a time, waiting for
+// each page to be programmed. The sectors should be erased first
+func (this *flash) WriteAt(p []byte, offset int64) (int, error) {
+	this.log.Debug2("<hw.spiflash>WriteAt{ offset=0x%06X length=%v }", offset, len(p))
+
+	this.Lock()
+	defer this.Unlock()
+
+	if offset < 0 || offset+int64(len(p)) > this.size {
+		return 0, gopi.ErrBadParameter
+	}
+
+	// Program up to the end of each page
+	n := 0
+	for n < len(p) {
+		length := min(int64(len(p)-n), FLASH_PAGE_SIZE-offset%FLASH_PAGE_SIZE, FLASH_TRANSFER_MAX-4)
+		if err := this.writeEnable(); err != nil {
+			return n, err
+		} else if _, err := this.command(FLASH_CMD_PAGE_PROGRAM, offset, p[n:n+int(length)], 0); err != nil {
+			return n, err
+		} else if err := this.wait(FLASH_TIMEOUT_PROGRAM); err != nil {
+			return n, err
+		}
+		n += int(length)
+		offset += length
+	}
+
+	// Success
+	return n, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// ERASE
+
+// Erase sets sectors to 0xFF, using block erase for aligned blocks
+func (this *flash) Erase(offset, length int64) error {
+	this.log.Debug2("<hw.spiflash>Erase{ offset=0x%06X length=%v }", offset, length)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if offset < 0 || length < 0 || offset+length > this.size {
+		return gopi.ErrBadParameter
+	} else if offset%this.sectorsize != 0 || length%this.sectorsize != 0 {
+		return gopi.ErrBadParameter
+	}
+
+	for length > 0 {
+		cmd, size := this.erase, this.sectorsize
+		if offset%FLASH_BLOCK_SIZE == 0 && length >= FLASH_BLOCK_SIZE {
+			cmd, size = FLASH_CMD_BLOCK_ERASE, FLASH_BLOCK_SIZE
+		}
+		if err := this.writeEnable(); err != nil {
+			return err
+		} else if _, err := this.command(cmd, offset, nil, 0); err != nil {
+			return err
+		} else if err := this.wait(FLASH_TIMEOUT_ERASE); err != nil {
+			return err
+		}
+		offset += size
+		length -= size
+	}
+
+	// Success
+	return nil
+}
+
+// EraseChip sets the whole chip to 0xFF
+func (this *flash) EraseChip() error {
+	this.log.Debug2("<hw.spiflash>EraseChip{ }")
+
+	this.Lock()
+	defer this.Unlock()
+
+	if err := this.writeEnable(); err != nil {
+		return err
+	} else if _, err := this.spi.Transfer([]byte{FLASH_CMD_CHIP_ERASE}); err != nil {
+		return err
+	} else {
+		return this.wait(FLASH_TIMEOUT_CHIP)
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STATUS AND PROTECTION
+
+func (this *flash) Status() (Status, error) {
+	this.Lock()
+	defer this.Unlock()
+	return this.status()
+}
+
+func (this *flash) BlockProtect() (uint8, error) {
+	this.Lock()
+	defer this.Unlock()
+	if status, err := this.status(); err != nil {
+		return 0, err
+	} else {
+		return status.BlockProtect(), nil
+	}
+}
+
+// SetBlockProtect writes the block protect bits to the status register,
+// returning ErrUnexpectedResponse if the status register is locked
+func (this *flash) SetBlockProtect(value uint8) error {
+	this.log.Debug2("<hw.spiflash>SetBlockProtect{ value=%v }", value)
+
+	this.Lock()
+	defer this.Unlock()
+
+	if value > FLASH_PROTECT_ALL {
+		return gopi.ErrBadParameter
+	} else if status, err := this.status(); err != nil {
+		return err
+	} else if status.BlockProtect() == value {
+		return nil
+	} else if err := this.writeEnable(); err != nil {
+		return err
+	} else {
+		status = status&^(FLASH_STATUS_BP|FLASH_STATUS_BUSY|FLASH_STATUS_WEL) | Status(value<<2)
+		if _, err := this.spi.Transfer([]byte{FLASH_CMD_WRITE_STATUS, uint8(status)}); err != nil {
+			return err
+		} else if err := this.wait(FLASH_TIMEOUT_ERASE); err != nil {
+			return err
+		} else if status, err := this.status(); err != nil {
+			return err
+		} else if status.BlockProtect() != value {
+			return gopi.ErrUnexpectedResponse
+		}
+	}
+
+	// Success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func (this *flash) jedecid() (JEDECID, error) {
+	if recv, err := this.spi.Transfer([]byte{FLASH_CMD_JEDEC_ID, 0, 0, 0}); err != nil {
+		return JEDECID{}, err
+	} else if len(recv) != 4 {
+		return JEDECID{}, gopi.ErrUnexpectedResponse
+	} else {
+		return JEDECID{recv[1], uint16(recv[2])<<8 | uint16(recv[3])}, nil
+	}
+}
+
+// sfdp returns the size from the basic parameter table, and sets the
+// sector size and erase command, or returns zero if there is no table
+func (this *flash) sfdp() (int64, error) {
+	header, err := this.readSFDP(0, 16)
+	if err != nil {
+		return 0, err
+	} else if binary.LittleEndian.Uint32(header[0:4]) != FLASH_SFDP_SIGNATURE {
+		this.log.Debug("<hw.spiflash>Open: No SFDP table")
+		return 0, nil
+	} else if header[8] != 0x00 || header[11] < 2 {
+		// The first parameter table is not the basic parameter table
+		return 0, nil
+	}
+
+	// Read the first two words of the basic parameter table
+	pointer := uint32(header[12]) | uint32(header[13])<<8 | uint32(header[14])<<16
+	table, err := this.readSFDP(pointer, 8)
+	if err != nil {
+		return 0, err
+	}
+
+	// Four kilobyte erase is supported when the lowest bits are 01
+	if word := binary.LittleEndian.Uint32(table[0:4]); word&0x03 == 0x01 {
+		this.sectorsize, this.erase = FLASH_SECTOR_SIZE, uint8(word>>8)
+	} else {
+		this.sectorsize, this.erase = FLASH_BLOCK_SIZE, FLASH_CMD_BLOCK_ERASE
+	}
+
+	// Density is the number of bits minus one, or a power of two when
+	// the highest bit is set
+	if density := binary.LittleEndian.Uint32(table[4:8]); density&0x80000000 == 0 {
+		return (int64(density) + 1) / 8, nil
+	} else if n := density & 0x7FFFFFFF; n < 3 || n > 62 {
+		return 0, gopi.ErrUnexpectedResponse
+	} else {
+		return int64(1) << (n - 3), nil
+	}
+}
+
+func (this *flash) readSFDP(address uint32, length int) ([]byte, error) {
+	send := make([]byte, 5+length)
+	send[0] = FLASH_CMD_READ_SFDP
+	send[1], send[2], send[3] = uint8(address>>16), uint8(address>>8), uint8(address)
+	if recv, err := this.spi.Transfer(send); err != nil {
+		return nil, err
+	} else if len(recv) != len(send) {
+		return nil, gopi.ErrUnexpectedResponse
+	} else {
+		return recv[5:], nil
+	}
+}
+
+// command sends a command with a three byte address and data, and
+// returns a number of bytes received after the address
+func (this *flash) command(cmd uint8, address int64, data []byte, length int) ([]byte, error) {
+	send := make([]byte, 4+len(data)+length)
+	send[0] = cmd
+	send[1], send[2], send[3] = uint8(address>>16), uint8(address>>8), uint8(address)
+	copy(send[4:], data)
+	if recv, err := this.spi.Transfer(send); err != nil {
+		return nil, err
+	} else if len(recv) != len(send) {
+		return nil, gopi.ErrUnexpectedResponse
+	} else {
+		return recv[4+len(data):], nil
+	}
+}
+
+func (this *flash) status() (Status, error) {
+	if recv, err := this.spi.Transfer([]byte{FLASH_CMD_READ_STATUS, 0}); err != nil {
+		return 0, err
+	} else if len(recv) != 2 {
+		return 0, gopi.ErrUnexpectedResponse
+	} else {
+		return Status(recv[1]), nil
+	}
+}
+
+// writeEnable sets the write enable latch before a program, erase or
+// status register write
+func (this *flash) writeEnable() error {
+	if _, err := this.spi.Transfer([]byte{FLASH_CMD_WRITE_ENABLE}); err != nil {
+		return err
+	} else if status, err := this.status(); err != nil {
+		return err
+	} else if status&FLASH_STATUS_WEL == 0 {
+		return gopi.ErrUnexpectedResponse
+	} else {
+		return nil
+	}
+}
+
+// wait polls the status register until the chip is not busy
+func (this *flash) wait(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if status, err := this.status(); err != nil {
+			return err
+		} else if status.Busy() == false {
+			return nil
+		} else if time.Now().After(deadline) {
+			return gopi.ErrDeadlineExceeded
+		}
+		time.Sleep(FLASH_POLL_INTERVAL)
+	}
+}
+
+func min(values ...int64) int64 {
+	m := values[0]
+	for _, value := range values[1:] {
+		if value < m {
+			m = value
+		}
+	}
+	return m
+}
@@ -0,0 +1,135 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package spiflash
+
+import (
+	"fmt"
+	"io"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// JEDECID is the manufacturer and device identifier of a chip
+type JEDECID struct {
+	Manufacturer uint8
+	Device       uint16
+}
+
+// Status is the value of the status register
+type Status uint8
+
+////////////////////////////////////////////////////////////////////////////////
+// INTERFACES
+
+// SPIFlash reads, writes and erases a flash chip. ReadAt and WriteAt
+// implement io.ReaderAt and io.WriterAt, where WriteAt only programs
+// bits from one to zero
+type SPIFlash interface {
+	gopi.Driver
+	io.ReaderAt
+	io.WriterAt
+
+	// Return the identifier of the chip
+	JEDECID() JEDECID
+
+	// Return the size of the chip, the size of a page which can be
+	// programmed at once, and the size of the smallest erasable sector
+	Size() int64
+	PageSize() int64
+	SectorSize() int64
+
+	// Erase sectors, where the offset and length are sector aligned
+	Erase(offset, length int64) error
+
+	// Erase the whole chip
+	EraseChip() error
+
+	// Return the status register
+	Status() (Status, error)
+
+	// Return and set the block protect bits, where zero removes write
+	// protection and FLASH_PROTECT_ALL protects the whole chip on most
+	// parts
+	BlockProtect() (uint8, error)
+	SetBlockProtect(uint8) error
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+// Commands
+const (
+	FLASH_CMD_WRITE_STATUS  uint8 = 0x01
+	FLASH_CMD_PAGE_PROGRAM  uint8 = 0x02
+	FLASH_CMD_READ          uint8 = 0x03
+	FLASH_CMD_WRITE_DISABLE uint8 = 0x04
+	FLASH_CMD_READ_STATUS   uint8 = 0x05
+	FLASH_CMD_WRITE_ENABLE  uint8 = 0x06
+	FLASH_CMD_SECTOR_ERASE  uint8 = 0x20
+	FLASH_CMD_READ_SFDP     uint8 = 0x5A
+	FLASH_CMD_JEDEC_ID      uint8 = 0x9F
+	FLASH_CMD_CHIP_ERASE    uint8 = 0xC7
+	FLASH_CMD_BLOCK_ERASE   uint8 = 0xD8
+)
+
+// Status register bits
+const (
+	FLASH_STATUS_BUSY Status = 0x01 /* write in progress */
+	FLASH_STATUS_WEL  Status = 0x02 /* write enable latch */
+	FLASH_STATUS_BP   Status = 0x1C /* block protect bits */
+	FLASH_STATUS_SRWD Status = 0x80 /* status register write disable */
+)
+
+const (
+	// Default page, sector and block sizes
+	FLASH_PAGE_SIZE   = 256
+	FLASH_SECTOR_SIZE = 4 * 1024
+	FLASH_BLOCK_SIZE  = 64 * 1024
+
+	// Maximum size of a chip with three address bytes
+	FLASH_SIZE_MAX = 16 * 1024 * 1024
+
+	// Maximum number of bytes in a transfer, including the command
+	FLASH_TRANSFER_MAX = 4096
+
+	// Block protect value which protects the whole chip
+	FLASH_PROTECT_ALL uint8 = 0x07
+
+	// SFDP signature, which is "SFDP" least significant byte first
+	FLASH_SFDP_SIGNATURE = 0x50444653
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (id JEDECID) String() string {
+	return fmt.Sprintf("<hw.spiflash.JEDECID>{ manufacturer=0x%02X device=0x%04X }", id.Manufacturer, id.Device)
+}
+
+func (s Status) String() string {
+	return fmt.Sprintf("<hw.spiflash.Status>{ busy=%v wel=%v bp=%v srwd=%v }", s.Busy(), s&FLASH_STATUS_WEL != 0, s.BlockProtect(), s&FLASH_STATUS_SRWD != 0)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Busy returns true if an erase or program is in progress
+func (s Status) Busy() bool {
+	return s&FLASH_STATUS_BUSY != 0
+}
+
+// BlockProtect returns the block protect bits
+func (s Status) BlockProtect() uint8 {
+	return uint8(s&FLASH_STATUS_BP) >> 2
+}
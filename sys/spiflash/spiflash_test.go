@@ -0,0 +1,155 @@
+package spiflash_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+	spiflash "github.com/djthorpe/gopi-hw/sys/spiflash"
+
+	// Modules
+	_ "github.com/djthorpe/gopi/sys/logger"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TESTS
+
+func openFlash(t *testing.T, config spiflash.Flash) spiflash.SPIFlash {
+	t.Helper()
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig()); err != nil {
+		t.Fatal(err)
+	} else if driver, err := gopi.Open(config, app.Logger); err != nil {
+		t.Fatal(err)
+	} else {
+		return driver.(spiflash.SPIFlash)
+	}
+	return nil
+}
+
+func TestFlash_000(t *testing.T) {
+	// Size from SFDP
+	chip := &spiflash.FakeFlash{ID: spiflash.JEDECID{0xEF, 0x4015}, Size: 1024 * 1024, SFDP: true}
+	flash := openFlash(t, spiflash.Flash{SPI: chip})
+	defer flash.Close()
+	if flash.JEDECID() != (spiflash.JEDECID{0xEF, 0x4015}) {
+		t.Error("Unexpected JEDEC ID", flash.JEDECID())
+	} else if flash.Size() != 1024*1024 || flash.SectorSize() != 4096 {
+		t.Error("Unexpected size", flash)
+	}
+
+	// Size from the capacity byte
+	chip = &spiflash.FakeFlash{ID: spiflash.JEDECID{0xC2, 0x2014}, Size: 1024 * 1024}
+	flash = openFlash(t, spiflash.Flash{SPI: chip})
+	defer flash.Close()
+	if flash.Size() != 1024*1024 {
+		t.Error("Unexpected size", flash)
+	}
+
+	// No chip
+	if app, err := gopi.NewAppInstance(gopi.NewAppConfig()); err != nil {
+		t.Fatal(err)
+	} else if _, err := gopi.Open(spiflash.Flash{SPI: &spiflash.FakeFlash{Size: 65536}}, app.Logger); err != gopi.ErrNotFound {
+		t.Error("Expected ErrNotFound, got", err)
+	}
+}
+
+func TestFlash_001(t *testing.T) {
+	chip := &spiflash.FakeFlash{ID: spiflash.JEDECID{0xEF, 0x4014}, Size: 128 * 1024, SFDP: true, BusyPolls: 3}
+	flash := openFlash(t, spiflash.Flash{SPI: chip})
+	defer flash.Close()
+
+	// Write across page boundaries, which needs three page programs
+	data := make([]byte, 300)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	if n, err := flash.WriteAt(data, 0xF0); err != nil {
+		t.Fatal(err)
+	} else if n != len(data) {
+		t.Error("Unexpected length", n)
+	} else if chip.Count(spiflash.FLASH_CMD_PAGE_PROGRAM) != 3 {
+		t.Error("Unexpected page programs", chip.Count(spiflash.FLASH_CMD_PAGE_PROGRAM))
+	} else if bytes.Equal(chip.Peek(0xF0, 300), data) == false {
+		t.Error("Unexpected memory", chip.Peek(0xF0, 300))
+	}
+
+	// Read back, and read past the end
+	buf := make([]byte, 300)
+	if _, err := flash.ReadAt(buf, 0xF0); err != nil {
+		t.Error(err)
+	} else if bytes.Equal(buf, data) == false {
+		t.Error("Unexpected data", buf)
+	} else if n, err := flash.ReadAt(buf, flash.Size()-100); err != io.EOF || n != 100 {
+		t.Error("Expected io.EOF, got", n, err)
+	}
+
+	// Erase sectors, where sector erase is used for the first sector
+	// and block erase for the aligned block
+	if err := flash.Erase(0x800, 4096); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+	if err := flash.Erase(0xF000, 0x11000); err != nil {
+		t.Error(err)
+	} else if chip.Count(spiflash.FLASH_CMD_SECTOR_ERASE) != 1 || chip.Count(spiflash.FLASH_CMD_BLOCK_ERASE) != 1 {
+		t.Error("Unexpected erase commands")
+	} else if err := flash.Erase(0, 4096); err != nil {
+		t.Error(err)
+	} else if bytes.Equal(chip.Peek(0xF0, 16), bytes.Repeat([]byte{0xFF}, 16)) == false {
+		t.Error("Unexpected memory after erase", chip.Peek(0xF0, 16))
+	}
+}
+
+func TestFlash_002(t *testing.T) {
+	chip := &spiflash.FakeFlash{ID: spiflash.JEDECID{0xEF, 0x4014}, Size: 128 * 1024, SFDP: true, BusyPolls: 1}
+	flash := openFlash(t, spiflash.Flash{SPI: chip})
+	defer flash.Close()
+
+	// Protected chips are not programmed
+	if err := flash.SetBlockProtect(spiflash.FLASH_PROTECT_ALL); err != nil {
+		t.Fatal(err)
+	} else if value, err := flash.BlockProtect(); err != nil {
+		t.Error(err)
+	} else if value != spiflash.FLASH_PROTECT_ALL {
+		t.Error("Unexpected block protect", value)
+	} else if _, err := flash.WriteAt([]byte{0x00}, 0); err != nil {
+		t.Error(err)
+	} else if chip.Peek(0, 1)[0] != 0xFF {
+		t.Error("Unexpected write to protected chip")
+	} else if err := flash.SetBlockProtect(0); err != nil {
+		t.Error(err)
+	} else if err := flash.SetBlockProtect(8); err != gopi.ErrBadParameter {
+		t.Error("Expected ErrBadParameter, got", err)
+	}
+
+	// A locked status register cannot be changed
+	chip.Locked = true
+	if err := flash.SetBlockProtect(1); err != gopi.ErrUnexpectedResponse {
+		t.Error("Expected ErrUnexpectedResponse, got", err)
+	}
+}
+
+func TestEraseWriter_000(t *testing.T) {
+	chip := &spiflash.FakeFlash{ID: spiflash.JEDECID{0xEF, 0x4014}, Size: 128 * 1024, SFDP: true}
+	flash := openFlash(t, spiflash.Flash{SPI: chip})
+	defer flash.Close()
+
+	// Data which only clears bits is programmed without erasing
+	writer := spiflash.EraseWriter{flash}
+	chip.Poke(0x0FFE, []byte{0x12, 0x34, 0x56, 0x78})
+	if _, err := writer.WriteAt([]byte{0x10, 0x34}, 0x0FFE); err != nil {
+		t.Error(err)
+	} else if chip.Count(spiflash.FLASH_CMD_SECTOR_ERASE) != 0 {
+		t.Error("Unexpected erase")
+	}
+
+	// Data across two sectors erases them and keeps the other bytes
+	if _, err := writer.WriteAt([]byte{0xAB, 0xCD, 0xEF}, 0x0FFF); err != nil {
+		t.Error(err)
+	} else if chip.Count(spiflash.FLASH_CMD_SECTOR_ERASE) != 2 {
+		t.Error("Unexpected erases", chip.Count(spiflash.FLASH_CMD_SECTOR_ERASE))
+	} else if data := chip.Peek(0x0FFE, 4); bytes.Equal(data, []byte{0x10, 0xAB, 0xCD, 0xEF}) == false {
+		t.Error("Unexpected memory", data)
+	}
+}
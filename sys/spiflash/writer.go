@@ -0,0 +1,88 @@
+/*
+  Go Language Raspberry Pi Interface
+  (c) Copyright David Thorpe 2018
+  All Rights Reserved
+
+  Documentation http://djthorpe.github.io/gopi/
+  For Licensing and Usage information, please see LICENSE.md
+*/
+
+package spiflash
+
+import (
+	"bytes"
+
+	// Frameworks
+	"github.com/djthorpe/gopi"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// EraseWriter implements io.WriterAt for a flash chip, so that any data
+// can be written. Each sector which is written is read, and is only
+// erased when the new data sets bits which are zero
+type EraseWriter struct {
+	Flash SPIFlash
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (this EraseWriter) WriteAt(p []byte, offset int64) (int, error) {
+	if this.Flash == nil {
+		return 0, gopi.ErrBadParameter
+	} else if offset < 0 || offset+int64(len(p)) > this.Flash.Size() {
+		return 0, gopi.ErrBadParameter
+	}
+
+	size := this.Flash.SectorSize()
+	sector := make([]byte, size)
+	n := 0
+	for n < len(p) {
+		start := offset - offset%size
+		length := min(int64(len(p)-n), start+size-offset)
+		data := p[n : n+int(length)]
+
+		// Read the existing data in the sector
+		if _, err := this.Flash.ReadAt(sector, start); err != nil {
+			return n, err
+		}
+		current := sector[offset-start : offset-start+length]
+		if bytes.Equal(current, data) == false {
+			if programmable(current, data) {
+				// Program without erasing
+				if _, err := this.Flash.WriteAt(data, offset); err != nil {
+					return n, err
+				}
+			} else {
+				// Erase the sector and program the merged data
+				copy(current, data)
+				if err := this.Flash.Erase(start, size); err != nil {
+					return n, err
+				} else if _, err := this.Flash.WriteAt(sector, start); err != nil {
+					return n, err
+				}
+			}
+		}
+		n += int(length)
+		offset += length
+	}
+
+	// Success
+	return n, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// programmable returns true if data can be programmed over the current
+// data, which only changes bits from one to zero
+func programmable(current, data []byte) bool {
+	for i := range data {
+		if current[i]&data[i] != data[i] {
+			return false
+		}
+	}
+	return true
+}